@@ -0,0 +1,75 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestAny_Accessors(t *testing.T) {
+	var rec struct {
+		BillingAddress *salesforce.Any `json:"BillingAddress"`
+		Names          *salesforce.Any `json:"Names"`
+		Label          *salesforce.Any `json:"Label"`
+		Missing        *salesforce.Any `json:"Missing"`
+		Owner          salesforce.Any  `json:"Owner"`
+	}
+	err := json.Unmarshal([]byte(`{
+		"BillingAddress": {"street": "1 Main St", "city": "Dallas", "latitude": 32.7},
+		"Names": ["a", "b"],
+		"Label": "text",
+		"Missing": null,
+		"Owner": {"attributes": {"type": "User"}, "Name": "Bob"}
+	}`), &rec)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+
+	addr, err := rec.BillingAddress.AsMap()
+	if err != nil || addr["city"] != "Dallas" || rec.BillingAddress.SObject != nil {
+		t.Errorf("expected address map; got %v %v", addr, err)
+	}
+	var sa salesforce.Address
+	if err := rec.BillingAddress.Decode(&sa); err != nil || sa.City != "Dallas" || sa.Street != "1 Main St" {
+		t.Errorf("expected Address decode; got %+v %v", sa, err)
+	}
+	var names []string
+	if err := rec.Names.Decode(&names); err != nil || len(names) != 2 {
+		t.Errorf("expected list; got %v %v", names, err)
+	}
+	if _, err := rec.Names.AsMap(); err == nil {
+		t.Errorf("expected AsMap error for a list")
+	}
+	if s, ok := rec.Label.AsString(); !ok || s != "text" {
+		t.Errorf("expected text; got %q %v", s, ok)
+	}
+	if _, ok := rec.BillingAddress.AsString(); ok {
+		t.Errorf("expected AsString false for an object")
+	}
+	if !rec.Missing.IsNull() || rec.Label.IsNull() || rec.Owner.IsNull() {
+		t.Errorf("unexpected IsNull results")
+	}
+	if m, err := rec.Missing.AsMap(); err != nil || m != nil {
+		t.Errorf("expected nil map for null; got %v %v", m, err)
+	}
+	owner, err := rec.Owner.AsMap()
+	if err != nil || owner["Name"] != "Bob" || rec.Owner.SObjectName() != "User" {
+		t.Errorf("expected User record map; got %v %v", owner, err)
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var check map[string]json.RawMessage
+	json.Unmarshal(b, &check)
+	if string(check["Names"]) != `["a","b"]` || string(check["Missing"]) != "null" {
+		t.Errorf("expected raw values marshaled; got %s", b)
+	}
+}
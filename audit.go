@@ -0,0 +1,54 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// RecentlyModified queries the records of object modified at or after since into results,
+// a pointer to a slice as used by Query.  When no fields are passed, FieldNames(results)
+// are selected along with Id and SystemModstamp.  The filter uses SystemModstamp rather
+// than LastModifiedDate because SystemModstamp is indexed and also reflects system
+// updates.  Records are ordered by SystemModstamp and Id, so the last record's values
+// may be used as the since of a following call.
+func (sv *Service) RecentlyModified(ctx context.Context, object string, since time.Time, results interface{}, fields ...string) error {
+	qry, err := auditQuery(object, "SystemModstamp", since, results, fields)
+	if err != nil {
+		return err
+	}
+	return sv.Query(ctx, qry, results)
+}
+
+// RecentlyCreated queries the records of object created at or after since into results,
+// a pointer to a slice as used by Query.  When no fields are passed, FieldNames(results)
+// are selected along with Id and CreatedDate.  Records are ordered by CreatedDate and Id.
+func (sv *Service) RecentlyCreated(ctx context.Context, object string, since time.Time, results interface{}, fields ...string) error {
+	qry, err := auditQuery(object, "CreatedDate", since, results, fields)
+	if err != nil {
+		return err
+	}
+	return sv.Query(ctx, qry, results)
+}
+
+// auditQuery returns a query of the object's records whose dateField is at or
+// after since.  The filter compares the indexed field directly to a datetime
+// literal so that the query remains selective.
+func auditQuery(object, dateField string, since time.Time, results interface{}, fields []string) (string, error) {
+	if object == "" {
+		return "", errors.New("object name may not be empty")
+	}
+	if since.IsZero() {
+		return "", errors.New("since may not be a zero time")
+	}
+	fields = customFields(results, fields, "Id", dateField)
+	return "SELECT " + strings.Join(fields, ", ") + " FROM " + object +
+		" WHERE " + dateField + " >= " + SOQLDatetime(since) +
+		" ORDER BY " + dateField + ", Id", nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_RecentlyModified(t *testing.T) {
+	var queries []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("q"))
+		encodeObject(w, map[string]interface{}{
+			"done":    true,
+			"records": []map[string]interface{}{{"Id": "001A", "Name": "A"}},
+		})
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	since := time.Date(2022, 3, 4, 10, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	var recs []salesforce.RecordMap
+	if err := sv.RecentlyModified(ctx, "Account", since, &recs, "Name"); err != nil || len(recs) != 1 {
+		t.Fatalf("expected 1 record; got %d %v", len(recs), err)
+	}
+	var accts []Account
+	if err := sv.RecentlyCreated(ctx, "Account", since, &accts, "Name", "Id"); err != nil || len(accts) != 1 {
+		t.Fatalf("expected 1 account; got %d %v", len(accts), err)
+	}
+	want := []string{
+		"SELECT Name, Id, SystemModstamp FROM Account WHERE SystemModstamp >= 2022-03-04T15:30:00Z ORDER BY SystemModstamp, Id",
+		"SELECT Name, Id, CreatedDate FROM Account WHERE CreatedDate >= 2022-03-04T15:30:00Z ORDER BY CreatedDate, Id",
+	}
+	for i, q := range want {
+		if i >= len(queries) || queries[i] != q {
+			t.Errorf("expected %s; got %v", q, queries)
+		}
+	}
+	if err := sv.RecentlyModified(ctx, "Account", time.Time{}, &recs); err == nil {
+		t.Errorf("expected zero since error")
+	}
+	if len(queries) != 2 {
+		t.Errorf("expected 2 queries; got %d", len(queries))
+	}
+}
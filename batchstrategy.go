@@ -0,0 +1,330 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchStrategy determines the batch size and concurrency of a collection call as
+// batches complete.  Set the strategy using CollectionOptions.Strategy.  Methods
+// are called from concurrent batches.
+type BatchStrategy interface {
+	// Next returns the size and concurrency for the next batch.  The size is
+	// limited by the service's CollectionBatchSize.
+	Next() (batchSize, concurrency int)
+	// Observe is called with the result of each batch.  When retry is true,
+	// the batch's records are resent after delay.
+	Observe(obs BatchObservation) (retry bool, delay time.Duration)
+}
+
+// BatchObservation describes a completed batch
+type BatchObservation struct {
+	Records int
+	Elapsed time.Duration
+	Err     error
+	// APIUsage and APILimit are the org's daily api calls used and allowed
+	// from the Sforce-Limit-Info header.  Both are zero when not reported.
+	APIUsage int
+	APILimit int
+}
+
+// APIUsage returns the used and maximum daily api calls reported by a
+// response's Sforce-Limit-Info header (e.g. api-usage=25/15000)
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_api_usage.htm
+func APIUsage(h http.Header) (used, limit int) {
+	for _, item := range strings.Split(h.Get("Sforce-Limit-Info"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 || kv[0] != "api-usage" {
+			continue
+		}
+		parts := strings.SplitN(kv[1], "/", 2)
+		if len(parts) != 2 {
+			return 0, 0
+		}
+		used, err1 := strconv.Atoi(parts[0])
+		limit, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			return 0, 0
+		}
+		return used, limit
+	}
+	return 0, 0
+}
+
+// Default values of AdaptiveBatch
+const (
+	DefaultAdaptiveMinBatch      = 10
+	DefaultAdaptiveTargetLatency = 10 * time.Second
+	DefaultAdaptiveUsageLimit    = 0.9
+	DefaultAdaptiveRampAfter     = 3
+	DefaultAdaptiveRetries       = 3
+)
+
+// AdaptiveBatch is a BatchStrategy that halves the batch size and concurrency when a batch
+// returns a limit error (see IsLimitError), takes longer than TargetLatency or the org's
+// daily api usage exceeds UsageLimit.  After RampAfter consecutive healthy batches, the
+// size grows by a quarter and concurrency by one up to the maximums.  Batches failing
+// with a limit error are retried up to Retries consecutive times.  Zero fields use the
+// DefaultAdaptive values.
+type AdaptiveBatch struct {
+	MinBatchSize  int
+	TargetLatency time.Duration
+	UsageLimit    float64 // fraction of the daily api limit
+	RampAfter     int
+	Retries       int
+	RetryDelay    time.Duration
+
+	m              sync.Mutex
+	maxSize        int
+	maxConcurrency int
+	size           int
+	concurrency    int
+	healthy        int
+	limitErrs      int
+}
+
+// NewAdaptiveBatch returns an AdaptiveBatch starting at, and limited to,
+// maxBatchSize and maxConcurrency
+func NewAdaptiveBatch(maxBatchSize, maxConcurrency int) *AdaptiveBatch {
+	if maxBatchSize < 1 {
+		maxBatchSize = 200
+	}
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &AdaptiveBatch{
+		maxSize:        maxBatchSize,
+		maxConcurrency: maxConcurrency,
+		size:           maxBatchSize,
+		concurrency:    maxConcurrency,
+	}
+}
+
+// Next returns the current batch size and concurrency
+func (ab *AdaptiveBatch) Next() (int, int) {
+	ab.m.Lock()
+	defer ab.m.Unlock()
+	return ab.size, ab.concurrency
+}
+
+// Observe adjusts the batch size and concurrency using the batch result
+func (ab *AdaptiveBatch) Observe(obs BatchObservation) (bool, time.Duration) {
+	ab.m.Lock()
+	defer ab.m.Unlock()
+	isLimit := IsLimitError(obs.Err)
+	if isLimit || obs.Elapsed > ab.targetLatency() ||
+		(obs.APILimit > 0 && float64(obs.APIUsage) >= ab.usageLimit()*float64(obs.APILimit)) {
+		ab.healthy = 0
+		ab.size /= 2
+		if minSize := ab.minSize(); ab.size < minSize {
+			ab.size = minSize
+		}
+		if ab.concurrency /= 2; ab.concurrency < 1 {
+			ab.concurrency = 1
+		}
+	} else if obs.Err == nil {
+		if ab.healthy++; ab.healthy >= ab.rampAfter() {
+			ab.healthy = 0
+			ab.size += (ab.size + 3) / 4
+			if ab.size > ab.maxSize {
+				ab.size = ab.maxSize
+			}
+			if ab.concurrency < ab.maxConcurrency {
+				ab.concurrency++
+			}
+		}
+	}
+	if !isLimit {
+		ab.limitErrs = 0
+		return false, 0
+	}
+	ab.limitErrs++
+	retries := ab.Retries
+	if retries == 0 {
+		retries = DefaultAdaptiveRetries
+	}
+	return ab.limitErrs <= retries, ab.RetryDelay
+}
+
+func (ab *AdaptiveBatch) minSize() int {
+	switch {
+	case ab.MinBatchSize > 0 && ab.MinBatchSize <= ab.maxSize:
+		return ab.MinBatchSize
+	case ab.MinBatchSize > 0 || DefaultAdaptiveMinBatch > ab.maxSize:
+		return ab.maxSize
+	}
+	return DefaultAdaptiveMinBatch
+}
+
+func (ab *AdaptiveBatch) targetLatency() time.Duration {
+	if ab.TargetLatency > 0 {
+		return ab.TargetLatency
+	}
+	return DefaultAdaptiveTargetLatency
+}
+
+func (ab *AdaptiveBatch) usageLimit() float64 {
+	if ab.UsageLimit > 0 {
+		return ab.UsageLimit
+	}
+	return DefaultAdaptiveUsageLimit
+}
+
+func (ab *AdaptiveBatch) rampAfter() int {
+	if ab.RampAfter > 0 {
+		return ab.RampAfter
+	}
+	return DefaultAdaptiveRampAfter
+}
+
+// batchRange is a span of records to send
+type batchRange struct {
+	start, end int
+}
+
+// runStrategyBatches sends cnt records in batches sized by the options' strategy.
+// Responses are returned in record order with the first error.  Progress reports
+// an estimated number of batches based on the current batch size.
+func (sv *Service) runStrategyBatches(ctx context.Context, cnt int, opts CollectionOptions, fn batchFunc) ([]OpResponse, error) {
+	bctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	parentCI, _ := ctx.Value(callInfoKey{}).(*CallInfo)
+	var (
+		m        sync.Mutex
+		cond     = sync.NewCond(&m)
+		results  = make(map[int][]OpResponse)
+		pending  []batchRange
+		next     int
+		inflight int
+		batchNum int
+		firstErr error
+		bp       BatchProgress
+		started  = time.Now()
+	)
+	setErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	maxSize := sv.CollectionBatchSize()
+	m.Lock()
+	for firstErr == nil && bctx.Err() == nil {
+		size, concurrency := opts.Strategy.Next()
+		if size < 1 || size > maxSize {
+			size = maxSize
+		}
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		if inflight >= concurrency || (len(pending) == 0 && next >= cnt && inflight > 0) {
+			cond.Wait()
+			continue
+		}
+		var br batchRange
+		switch {
+		case len(pending) > 0:
+			br, pending = pending[0], pending[1:]
+			if br.end-br.start > size {
+				pending = append(pending, batchRange{start: br.start + size, end: br.end})
+				br.end = br.start + size
+			}
+		case next < cnt:
+			br = batchRange{start: next, end: next + size}
+			if br.end > cnt {
+				br.end = cnt
+			}
+			next = br.end
+		}
+		if br.end == 0 {
+			break
+		}
+		inflight++
+		batch := batchNum
+		batchNum++
+		go func(br batchRange, batch int) {
+			var ci CallInfo
+			start := time.Now()
+			res, logRecs, err := fn(WithCallInfo(bctx, &ci), sv, br.start, br.end, batch)
+			obs := BatchObservation{Records: br.end - br.start, Elapsed: time.Since(start), Err: err}
+			if ci.Header != nil {
+				obs.APIUsage, obs.APILimit = APIUsage(ci.Header)
+			}
+			retry, delay := opts.Strategy.Observe(obs)
+			if err != nil && retry && bctx.Err() == nil {
+				select {
+				case <-time.After(delay):
+				case <-bctx.Done():
+				}
+			}
+			m.Lock()
+			defer func() {
+				inflight--
+				cond.Broadcast()
+				m.Unlock()
+			}()
+			if parentCI != nil && ci.Header != nil {
+				*parentCI = ci
+			}
+			if err != nil && retry && bctx.Err() == nil {
+				pending = append([]batchRange{br}, pending...)
+				return
+			}
+			if opts.Progress != nil {
+				bp.Batch, bp.Err = batch, err
+				bp.Completed++
+				bp.Records += br.end - br.start
+				for _, r := range res {
+					if !r.Success {
+						bp.Errors++
+					}
+				}
+				size, _ := opts.Strategy.Next()
+				if size < 1 || size > maxSize {
+					size = maxSize
+				}
+				bp.Batches = bp.Completed + (cnt-bp.Records+size-1)/size
+				bp.Elapsed = time.Since(started)
+				opts.Progress(bp)
+			}
+			if err != nil {
+				setErr(err)
+				return
+			}
+			results[br.start] = res
+			if sv.logger != nil {
+				if err := sv.logger(bctx, br.start, logRecs, res); err != nil {
+					setErr(err)
+				}
+			}
+		}(br, batch)
+	}
+	for inflight > 0 {
+		cond.Wait()
+	}
+	m.Unlock()
+
+	var starts = make([]int, 0, len(results))
+	for s := range results {
+		starts = append(starts, s)
+	}
+	sort.Ints(starts)
+	var opResp = make([]OpResponse, 0, cnt)
+	for _, s := range starts {
+		opResp = append(opResp, results[s]...)
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return opResp, firstErr
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestAPIUsage(t *testing.T) {
+	h := http.Header{"Sforce-Limit-Info": []string{"per-app-api-usage=2/250(appName=x), api-usage=25/15000"}}
+	if used, limit := salesforce.APIUsage(h); used != 25 || limit != 15000 {
+		t.Errorf("expected 25/15000; got %d/%d", used, limit)
+	}
+	if used, limit := salesforce.APIUsage(http.Header{}); used != 0 || limit != 0 {
+		t.Errorf("expected 0/0; got %d/%d", used, limit)
+	}
+}
+
+func TestAdaptiveBatch(t *testing.T) {
+	var m sync.Mutex
+	var sizes []int
+	var limitSent bool
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Records []json.RawMessage `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		m.Lock()
+		sizes = append(sizes, len(body.Records))
+		sendLimit := !limitSent && len(sizes) == 2
+		limitSent = limitSent || sendLimit
+		m.Unlock()
+		if sendLimit {
+			http.Error(w, `[{"errorCode":"REQUEST_LIMIT_EXCEEDED","message":"ConcurrentPerOrgLongTxn Limit exceeded"}]`, http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Sforce-Limit-Info", "api-usage=10/15000")
+		var res = make([]salesforce.OpResponse, len(body.Records))
+		for i := range res {
+			res[i] = salesforce.OpResponse{Success: true, ID: fmt.Sprintf("003%03d", i)}
+		}
+		encodeObject(w, res)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var recs = make([]salesforce.SObject, 500)
+	for i := range recs {
+		recs[i] = Contact{LastName: fmt.Sprintf("Smith%d", i)}
+	}
+	ab := salesforce.NewAdaptiveBatch(100, 1)
+	ab.RampAfter, ab.RetryDelay = 2, time.Millisecond
+	var progress []salesforce.BatchProgress
+	res, err := sv.CreateRecordsWithOptions(ctx, recs, salesforce.CollectionOptions{
+		Strategy: ab,
+		Progress: func(bp salesforce.BatchProgress) { progress = append(progress, bp) },
+	})
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(res) != 500 {
+		t.Fatalf("expected 500 responses; got %d", len(res))
+	}
+	for i, r := range res {
+		if r.RecordIndex != i || r.SObject.(Contact).LastName != fmt.Sprintf("Smith%d", i) {
+			t.Fatalf("response %d out of order: %d %v", i, r.RecordIndex, r.SObject)
+		}
+	}
+	// limit error on the second batch is retried as 50 and 50, then ramps after 2 healthy batches
+	if fmt.Sprint(sizes) != "[100 100 50 50 63 63 79 79 16]" {
+		t.Errorf("unexpected batch sizes %v", sizes)
+	}
+	last := progress[len(progress)-1]
+	if last.Records != 500 || last.Completed != last.Batches || last.Completed != len(sizes)-1 {
+		t.Errorf("unexpected final progress %+v for sizes %v", last, sizes)
+	}
+
+	// api usage above the limit halves the batch
+	ab = salesforce.NewAdaptiveBatch(100, 4)
+	ab.UsageLimit = 0.0001
+	ab.Observe(salesforce.BatchObservation{Records: 100, APIUsage: 10, APILimit: 15000})
+	if size, conc := ab.Next(); size != 50 || conc != 2 {
+		t.Errorf("expected 50, 2; got %d %d", size, conc)
+	}
+	ab.Observe(salesforce.BatchObservation{Records: 50, Elapsed: time.Minute})
+	if size, conc := ab.Next(); size != 25 || conc != 1 {
+		t.Errorf("expected 25, 1; got %d %d", size, conc)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+	"github.com/jfcote87/salesforce/bench"
+)
+
+func TestServer(t *testing.T) {
+	srv := bench.NewServer(4500)
+	defer srv.Close()
+	sv := srv.Service()
+	ctx := context.Background()
+
+	res, err := sv.CreateRecords(ctx, false, bench.Contacts(450))
+	if err != nil {
+		t.Fatalf("create records: %v", err)
+	}
+	if len(res) != 450 || !res[449].Success || res[449].ID == "" {
+		t.Fatalf("expected 450 successful responses; got %d", len(res))
+	}
+	calls, recs, _ := srv.Stats()
+	if calls != 3 || recs != 450 {
+		t.Errorf("expected 3 calls creating 450 records; got %d %d", calls, recs)
+	}
+
+	srv.Reset()
+	var contacts []bench.Contact
+	if err := sv.Query(ctx, "SELECT Id, FirstName FROM Contact", &contacts); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(contacts) != 4500 || contacts[4499].LastName != "Last4499" {
+		t.Fatalf("expected 4500 contacts; got %d", len(contacts))
+	}
+	if calls, _, _ = srv.Stats(); calls != 3 {
+		t.Errorf("expected 3 query pages; got %d", calls)
+	}
+
+	srv.Reset()
+	jw, err := sv.NewJobWriter(ctx, salesforce.JobDefinition{Object: "Contact", Operation: "insert"}, 0)
+	if err != nil {
+		t.Fatalf("new job writer: %v", err)
+	}
+	if err := jw.WriteRecords(bench.Contacts(100)); err != nil {
+		t.Fatalf("write records: %v", err)
+	}
+	if err := jw.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, _, n := srv.Stats(); n == 0 || len(jw.Pipeline().JobIDs()) != 1 {
+		t.Errorf("expected upload to one job; got %d bytes %v", n, jw.Pipeline().JobIDs())
+	}
+}
+
+func BenchmarkCreateRecords(b *testing.B) {
+	srv := bench.NewServer(0)
+	defer srv.Close()
+	sv := srv.Service()
+	ctx := context.Background()
+	for _, size := range []int{1, 200, 2000} {
+		recs := bench.Contacts(size)
+		b.Run(fmt.Sprintf("records=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if _, err := sv.CreateRecords(ctx, false, recs); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*size)/time.Since(start).Seconds(), "records/s")
+		})
+	}
+}
+
+func BenchmarkQuery(b *testing.B) {
+	srv := bench.NewServer(10000)
+	defer srv.Close()
+	sv := srv.Service()
+	ctx := context.Background()
+	b.Run("struct", func(b *testing.B) {
+		b.ReportAllocs()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			var recs []bench.Contact
+			if err := sv.Query(ctx, "SELECT Id FROM Contact", &recs); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(b.N*srv.TotalRecords)/time.Since(start).Seconds(), "records/s")
+	})
+	b.Run("RecordMap", func(b *testing.B) {
+		b.ReportAllocs()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			var recs []salesforce.RecordMap
+			if err := sv.Query(ctx, "SELECT Id FROM Contact", &recs); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(b.N*srv.TotalRecords)/time.Since(start).Seconds(), "records/s")
+	})
+}
+
+func BenchmarkJobWriter(b *testing.B) {
+	srv := bench.NewServer(0)
+	defer srv.Close()
+	sv := srv.Service()
+	ctx := context.Background()
+	recs := bench.Contacts(10000)
+	jd := salesforce.JobDefinition{Object: "Contact", Operation: "insert"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		jw, err := sv.NewJobWriter(ctx, jd, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := jw.WriteRecords(recs); err != nil {
+			b.Fatal(err)
+		}
+		if err := jw.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	_, _, n := srv.Stats()
+	b.SetBytes(n / int64(b.N))
+}
+
+func BenchmarkDecodeJobResults(b *testing.B) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write([]string{"sf__Id", "sf__Created", "FirstName", "LastName", "Email", "Phone", "Birthdate", "DoNotCall"})
+	for i := 0; i < 10000; i++ {
+		c := bench.NewContact(i)
+		cw.Write([]string{fmt.Sprintf("003BENCH%010d", i), "true", c.FirstName, c.LastName, c.Email, c.Phone,
+			string(*c.Birthdate), fmt.Sprint(c.DoNotCall)})
+	}
+	cw.Flush()
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var recs []bench.Contact
+		if _, err := salesforce.DecodeJobResults(bytes.NewReader(data), nil, &recs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
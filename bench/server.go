@@ -0,0 +1,217 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bench provides a fake salesforce server for benchmarking collection, query
+// and bulk operations.  Use the Server to capacity plan batch sizes and concurrency by
+// setting Latency to the observed round trip time of an org.  The package benchmarks
+// may be profiled with
+//
+//	go test -run none -bench . -benchmem -cpuprofile cpu.out ./bench
+package bench // import github.com/jfcote87/salesforce/bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+// APIVersion is the api version of the fake server's paths
+const APIVersion = "v55.0"
+
+// DefaultPageSize is the number of records of each query page
+const DefaultPageSize = 2000
+
+const basePath = "/services/data/" + APIVersion + "/"
+
+// Server is a fake salesforce instance that implements sobject collection create and
+// update, query pagination and bulk ingest jobs.  Records returned by queries are
+// generated Contacts.  Fields should be set before the first call.
+type Server struct {
+	*httptest.Server
+	// Latency is added to each response to simulate network and processing time
+	Latency time.Duration
+	// TotalRecords is the number of records returned by a query
+	TotalRecords int
+	// PageSize is the number of records of each query page.  Zero indicates DefaultPageSize.
+	PageSize int
+
+	calls       int64
+	records     int64
+	uploadBytes int64
+	ids         int64
+}
+
+// NewServer starts a Server returning totalRecords from each query.  The caller
+// must call Close when finished.
+func NewServer(totalRecords int) *Server {
+	s := &Server{TotalRecords: totalRecords}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Service returns a salesforce service calling the server
+func (s *Server) Service() *salesforce.Service {
+	client := s.Client()
+	return salesforce.New("bench.my.salesforce.com", APIVersion, nil).
+		WithCtxClientFunc(func(ctx context.Context) (*http.Client, error) {
+			return client, nil
+		}).
+		WithURL(s.URL + basePath)
+}
+
+// Stats reports the calls made, records created, updated or returned
+// and the bytes of bulk data uploaded since the last Reset.
+func (s *Server) Stats() (calls, records, uploadBytes int64) {
+	return atomic.LoadInt64(&s.calls), atomic.LoadInt64(&s.records), atomic.LoadInt64(&s.uploadBytes)
+}
+
+// Reset zeroes the Stats counters
+func (s *Server) Reset() {
+	atomic.StoreInt64(&s.calls, 0)
+	atomic.StoreInt64(&s.records, 0)
+	atomic.StoreInt64(&s.uploadBytes, 0)
+}
+
+func (s *Server) pageSize() int {
+	if s.PageSize > 0 {
+		return s.PageSize
+	}
+	return DefaultPageSize
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&s.calls, 1)
+	if s.Latency > 0 {
+		time.Sleep(s.Latency)
+	}
+	path := strings.TrimPrefix(r.URL.Path, basePath)
+	switch {
+	case path == "composite/sobjects" && (r.Method == "POST" || r.Method == "PATCH"):
+		s.collection(w, r)
+	case path == "query/":
+		s.query(w, 0)
+	case strings.HasPrefix(path, "query/01gBENCH-"):
+		offset, _ := strconv.Atoi(strings.TrimPrefix(path, "query/01gBENCH-"))
+		s.query(w, offset)
+	case path == "jobs/ingest/" && r.Method == "POST":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": s.newID("750"), "state": "Open"})
+	case strings.HasPrefix(path, "jobs/ingest/") && strings.HasSuffix(path, "/batches") && r.Method == "PUT":
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		atomic.AddInt64(&s.uploadBytes, n)
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "jobs/ingest/") && r.Method == "PATCH":
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": strings.TrimPrefix(path, "jobs/ingest/"), "state": body["state"]})
+	default:
+		writeJSON(w, http.StatusNotFound, []salesforce.Error{{StatusCode: "NOT_FOUND", Message: r.Method + " " + r.URL.Path}})
+	}
+}
+
+// collection responds to a collection create or update with a success for each record
+func (s *Server) collection(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Records []json.RawMessage `json:"records"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, []salesforce.Error{{StatusCode: "JSON_PARSER_ERROR", Message: err.Error()}})
+		return
+	}
+	atomic.AddInt64(&s.records, int64(len(body.Records)))
+	var res = make([]salesforce.OpResponse, len(body.Records))
+	for i := range res {
+		res[i] = salesforce.OpResponse{ID: s.newID("003"), Success: true, Errors: []salesforce.Error{}}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// query returns the page of generated contacts beginning at offset
+func (s *Server) query(w http.ResponseWriter, offset int) {
+	end := offset + s.pageSize()
+	if end > s.TotalRecords {
+		end = s.TotalRecords
+	}
+	var res = map[string]interface{}{"totalSize": s.TotalRecords, "done": end >= s.TotalRecords}
+	if end < s.TotalRecords {
+		res["nextRecordsUrl"] = fmt.Sprintf("%squery/01gBENCH-%d", basePath, end)
+	}
+	var recs = make([]Contact, 0, end-offset)
+	for i := offset; i < end; i++ {
+		c := NewContact(i)
+		c.ID = fmt.Sprintf("003BENCH%010d", i)
+		c.Attributes = &salesforce.Attributes{Type: "Contact", URL: basePath + "sobjects/Contact/" + c.ID}
+		recs = append(recs, c)
+	}
+	res["records"] = recs
+	atomic.AddInt64(&s.records, int64(len(recs)))
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) newID(prefix string) string {
+	return fmt.Sprintf("%sBENCH%010d", prefix, atomic.AddInt64(&s.ids, 1))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Contact is the record used by the server and benchmarks
+type Contact struct {
+	Attributes *salesforce.Attributes `json:"attributes,omitempty"`
+	ID         string                 `json:"Id,omitempty"`
+	FirstName  string                 `json:"FirstName,omitempty"`
+	LastName   string                 `json:"LastName,omitempty"`
+	Email      string                 `json:"Email,omitempty"`
+	Phone      string                 `json:"Phone,omitempty"`
+	Birthdate  *salesforce.Date       `json:"Birthdate,omitempty"`
+	DoNotCall  bool                   `json:"DoNotCall,omitempty"`
+	Score      float64                `json:"Score__c,omitempty"`
+}
+
+// SObjectName returns Contact
+func (c Contact) SObjectName() string {
+	return "Contact"
+}
+
+// WithAttr returns a new Contact with attributes of Type="Contact" and Ref=ref
+func (c Contact) WithAttr(ref string) salesforce.SObject {
+	c.Attributes = &salesforce.Attributes{Type: "Contact", Ref: ref}
+	return c
+}
+
+// NewContact returns a generated contact for the index i
+func NewContact(i int) Contact {
+	bd := salesforce.Date(fmt.Sprintf("19%02d-%02d-%02d", 50+i%50, 1+i%12, 1+i%28))
+	return Contact{
+		FirstName: fmt.Sprintf("First%d", i),
+		LastName:  fmt.Sprintf("Last%d", i),
+		Email:     fmt.Sprintf("contact%d@example.com", i),
+		Phone:     fmt.Sprintf("555-%04d", i%10000),
+		Birthdate: &bd,
+		DoNotCall: i%2 == 0,
+		Score:     float64(i%1000) / 10,
+	}
+}
+
+// Contacts returns n generated contacts as SObjects
+func Contacts(n int) []salesforce.SObject {
+	var recs = make([]salesforce.SObject, n)
+	for i := range recs {
+		recs[i] = NewContact(i)
+	}
+	return recs
+}
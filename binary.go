@@ -0,0 +1,98 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// DefaultMaxBinarySize is the largest base64 field value accepted by the REST api
+// (37.5MB), and is the limit used by NewBinary when maxSize is not set.  Use
+// UploadFile for larger files.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_sobject_insert_update_blob.htm
+const DefaultMaxBinarySize = 37<<20 + 1<<19
+
+// ErrBinaryTooLarge is returned by NewBinary when data exceeds the maximum size
+var ErrBinaryTooLarge = errors.New("binary data exceeds maximum size")
+
+// Binary handles base64Binary type
+type Binary []byte
+
+// NewBinary reads rdr returning a Binary.  An error wrapping ErrBinaryTooLarge is
+// returned when rdr contains more than maxSize bytes.  A maxSize <= 0 indicates
+// DefaultMaxBinarySize.
+func NewBinary(rdr io.Reader, maxSize int64) (Binary, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBinarySize
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(rdr, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBinaryTooLarge, maxSize)
+	}
+	return Binary(b), nil
+}
+
+// Reader returns a reader of the binary data
+func (b Binary) Reader() *bytes.Reader {
+	return bytes.NewReader(b)
+}
+
+// BlobURL returns the path of a blob field's value when b was decoded from a
+// record retrieved by Get or Query.  Salesforce returns the path (e.g.
+// /services/data/v53.0/sobjects/Attachment/00P.../Body) rather than the
+// data; use GetAttachment or Call with an *HTTPBody result to read it.
+// An empty string is returned when b contains data.
+func (b Binary) BlobURL() string {
+	if s := string(b); strings.HasPrefix(s, "/services/data/") && strings.Contains(s, "/sobjects/") {
+		return s
+	}
+	return ""
+}
+
+// MarshalJSON handles outputting json of base64Binary.  Empty value
+// outputs null, a nil ptr is omitted with omitempty.
+func (b Binary) MarshalJSON() ([]byte, error) {
+	if len(b) == 0 {
+		return []byte("null"), nil
+	}
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(b))+2)
+	buf[0], buf[len(buf)-1] = '"', '"'
+	base64.StdEncoding.Encode(buf[1:len(buf)-1], b)
+	return buf, nil
+}
+
+// UnmarshalJSON decodes a base64 string or null.  The blob url returned by
+// Get and Query is kept as is (see BlobURL).
+func (b *Binary) UnmarshalJSON(buff []byte) error {
+	var s *string
+	if err := json.Unmarshal(buff, &s); err != nil {
+		return err
+	}
+	if s == nil {
+		*b = nil
+		return nil
+	}
+	bx, err := base64.StdEncoding.DecodeString(*s)
+	if err != nil {
+		if Binary(*s).BlobURL() > "" {
+			*b = Binary(*s)
+			return nil
+		}
+		return err
+	}
+	*b = bx
+	return nil
+}
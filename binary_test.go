@@ -0,0 +1,70 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+type document struct {
+	Name string             `json:"Name,omitempty"`
+	Body *salesforce.Binary `json:"Body,omitempty"`
+	Data salesforce.Binary  `json:"Data__c,omitempty"`
+}
+
+func TestBinary_RoundTrip(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	b, err := salesforce.NewBinary(bytes.NewReader(data), 0)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	doc := document{Name: "a", Body: &b}
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(buf), "Data__c") {
+		t.Errorf("expected empty Data__c to be omitted; got %s", buf)
+	}
+	var got document
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Body == nil || !bytes.Equal(*got.Body, data) {
+		t.Errorf("expected round trip of data")
+	}
+	rb, _ := ioutil.ReadAll(got.Body.Reader())
+	if !bytes.Equal(rb, data) || got.Body.BlobURL() != "" {
+		t.Errorf("expected reader of data and no blob url")
+	}
+
+	if err := json.Unmarshal([]byte(`{"Body":null,"Data__c":"/services/data/v53.0/sobjects/Document/015A/Body"}`), &got); err != nil {
+		t.Fatalf("unmarshal blob url: %v", err)
+	}
+	if got.Body != nil || got.Data.BlobURL() != "/services/data/v53.0/sobjects/Document/015A/Body" {
+		t.Errorf("expected nil body and blob url; got %v %q", got.Body, got.Data)
+	}
+	if err := json.Unmarshal([]byte(`{"Data__c":"not base64!"}`), &got); err == nil {
+		t.Errorf("expected base64 error")
+	}
+
+	if _, err := salesforce.NewBinary(bytes.NewReader(data), 999); !errors.Is(err, salesforce.ErrBinaryTooLarge) {
+		t.Errorf("expected ErrBinaryTooLarge; got %v", err)
+	}
+	if b, err := salesforce.NewBinary(bytes.NewReader(data), 1000); err != nil || len(b) != 1000 {
+		t.Errorf("expected 1000 bytes; got %d %v", len(b), err)
+	}
+}
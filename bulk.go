@@ -0,0 +1,411 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// DefaultMaxUploadBytes is the default maximum size of csv data uploaded to a single
+// ingest job.  Salesforce limits uploads to 150MB after base64 encoding, so
+// the raw data must be no larger than 100MB.
+// https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/bulk_common_limits.htm
+const DefaultMaxUploadBytes = 100 * 1024 * 1024
+
+// BulkPipeline splits a large csv data set across multiple ingest jobs.  Each job
+// is created from the pipeline's Definition.  The IDs of created jobs are tracked so
+// that results may be aggregated after processing.
+type BulkPipeline struct {
+	Definition JobDefinition
+	MaxBytes   int64 // maximum bytes per job, DefaultMaxUploadBytes used if <= 0
+
+	sv     *Service
+	m      sync.Mutex
+	jobIDs []string
+}
+
+// NewBulkPipeline returns a pipeline that creates jobs using jd
+func (sv *Service) NewBulkPipeline(jd JobDefinition, maxBytes int64) *BulkPipeline {
+	return &BulkPipeline{
+		Definition: jd,
+		MaxBytes:   maxBytes,
+		sv:         sv,
+	}
+}
+
+// JobIDs returns the ids of all jobs created by the pipeline
+func (bp *BulkPipeline) JobIDs() []string {
+	bp.m.Lock()
+	defer bp.m.Unlock()
+	return append([]string{}, bp.jobIDs...)
+}
+
+func (bp *BulkPipeline) maxBytes() int64 {
+	if bp.MaxBytes <= 0 {
+		return DefaultMaxUploadBytes
+	}
+	return bp.MaxBytes
+}
+
+// ErrRecordTooLarge indicates a single csv record plus header exceeds the pipeline's MaxBytes
+var ErrRecordTooLarge = errors.New("csv record exceeds maximum upload size")
+
+// Upload reads csv data from rdr and splits the data into chunks no larger than
+// MaxBytes.  Records are split using the Definition's ColumnDelimiter and LineEnding.
+// Each chunk begins with the header row and is uploaded to a newly created job which
+// is then closed.  A job whose upload fails is aborted.  If rdr is an io.Closer,
+// function will close stream.
+func (bp *BulkPipeline) Upload(ctx context.Context, rdr io.Reader) error {
+	if rdrc, ok := rdr.(io.Closer); ok {
+		defer rdrc.Close()
+	}
+	if _, ok := jobDelimiters[bp.Definition.ColumnDelimiter]; !ok {
+		return fmt.Errorf("unknown column delimiter %s", bp.Definition.ColumnDelimiter)
+	}
+	crlf := bp.Definition.LineEnding == "CRLF"
+	br := bufio.NewReader(rdr)
+	header, err := readCSVRecord(br, crlf)
+	if err != nil {
+		if err == io.EOF {
+			return ErrZeroRecords
+		}
+		return err
+	}
+	max := bp.maxBytes()
+	var buff = &bytes.Buffer{}
+	var rows int
+	for {
+		rec, err := readCSVRecord(br, crlf)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(rec)) > 0 {
+			if int64(len(header)+len(rec)) > max {
+				return ErrRecordTooLarge
+			}
+			if rows > 0 && int64(buff.Len()+len(rec)) > max {
+				if err := bp.uploadChunk(ctx, buff); err != nil {
+					return err
+				}
+				rows = 0
+			}
+			if rows == 0 {
+				buff.Reset()
+				buff.Write(header)
+			}
+			buff.Write(rec)
+			rows++
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if rows == 0 {
+		if len(bp.JobIDs()) == 0 {
+			return ErrZeroRecords
+		}
+		return nil
+	}
+	return bp.uploadChunk(ctx, buff)
+}
+
+func (bp *BulkPipeline) uploadChunk(ctx context.Context, buff *bytes.Buffer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	jd := bp.Definition
+	job, err := bp.sv.CreateJob(ctx, &jd)
+	if err != nil {
+		return err
+	}
+	bp.m.Lock()
+	bp.jobIDs = append(bp.jobIDs, job.ID)
+	bp.m.Unlock()
+	if err := bp.sv.UploadJobData(ctx, job.ID, bytes.NewReader(buff.Bytes())); err != nil {
+		// an open job is never processed, so abort rather than leave it in the org
+		if _, aerr := bp.sv.AbortJob(ctx, job.ID); aerr != nil {
+			return fmt.Errorf("%w (abort job %s: %v)", err, job.ID, aerr)
+		}
+		return err
+	}
+	_, err = bp.sv.CloseJob(ctx, job.ID)
+	return err
+}
+
+// readCSVRecord returns the next csv record including line ending.  Quoted
+// fields may contain line endings, so a record ends at the first line ending
+// found outside of quotes.  When crlf is set, only \r\n ends a record.
+func readCSVRecord(br *bufio.Reader, crlf bool) ([]byte, error) {
+	var rec []byte
+	var quotes int
+	for {
+		line, err := br.ReadBytes('\n')
+		rec = append(rec, line...)
+		quotes += bytes.Count(line, []byte{'"'})
+		if err != nil {
+			if err == io.EOF && len(rec) > 0 {
+				switch {
+				case crlf && !bytes.HasSuffix(rec, []byte("\r\n")):
+					rec = append(rec, '\r', '\n')
+				case !crlf && rec[len(rec)-1] != '\n':
+					rec = append(rec, '\n')
+				}
+				return rec, nil
+			}
+			return nil, err
+		}
+		if quotes%2 == 0 && (!crlf || bytes.HasSuffix(line, []byte("\r\n"))) {
+			return rec, nil
+		}
+	}
+}
+
+// Jobs returns the current state of every job in the pipeline
+func (bp *BulkPipeline) Jobs(ctx context.Context) ([]*Job, error) {
+	var jobs []*Job
+	for _, id := range bp.JobIDs() {
+		job, err := bp.sv.GetJob(ctx, id)
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Abort aborts all pipeline jobs
+func (bp *BulkPipeline) Abort(ctx context.Context) error {
+	for _, id := range bp.JobIDs() {
+		if _, err := bp.sv.AbortJob(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SuccessfulRecords returns a single csv stream combining the successful
+// results of every pipeline job.  The header row is written once.
+func (bp *BulkPipeline) SuccessfulRecords(ctx context.Context) io.ReadCloser {
+	return bp.combinedResults(ctx, bp.sv.GetSuccessfulJobRecords)
+}
+
+// FailedRecords returns a single csv stream combining the failed
+// results of every pipeline job.  The header row is written once.
+func (bp *BulkPipeline) FailedRecords(ctx context.Context) io.ReadCloser {
+	return bp.combinedResults(ctx, bp.sv.GetFailedJobRecords)
+}
+
+// UnprocessedRecords returns a single csv stream combining the unprocessed
+// records of every pipeline job.  The header row is written once.
+func (bp *BulkPipeline) UnprocessedRecords(ctx context.Context) io.ReadCloser {
+	return bp.combinedResults(ctx, bp.sv.GetUnprocessedJobRecords)
+}
+
+func (bp *BulkPipeline) combinedResults(ctx context.Context, f func(context.Context, string) (*HTTPBody, error)) io.ReadCloser {
+	return &combinedReader{
+		ctx:    ctx,
+		jobIDs: bp.JobIDs(),
+		fetch:  f,
+	}
+}
+
+// combinedReader reads each job's results in turn, fetching the
+// next job's results only after the previous stream is exhausted.
+type combinedReader struct {
+	ctx     context.Context
+	jobIDs  []string
+	fetch   func(context.Context, string) (*HTTPBody, error)
+	current io.ReadCloser
+	br      *bufio.Reader
+	idx     int
+	err     error
+}
+
+func (cr *combinedReader) Read(p []byte) (int, error) {
+	for cr.err == nil {
+		if cr.br == nil {
+			if cr.idx >= len(cr.jobIDs) {
+				cr.err = io.EOF
+				break
+			}
+			body, err := cr.fetch(cr.ctx, cr.jobIDs[cr.idx])
+			if err != nil {
+				cr.err = err
+				break
+			}
+			cr.current, cr.br = body.Rdr, bufio.NewReader(body.Rdr)
+			if cr.idx > 0 {
+				// skip header row of subsequent jobs
+				if _, err := readCSVRecord(cr.br, false); err != nil && err != io.EOF {
+					cr.err = err
+					break
+				}
+			}
+			cr.idx++
+		}
+		n, err := cr.br.Read(p)
+		if err == io.EOF {
+			cr.current.Close()
+			cr.current, cr.br = nil, nil
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, cr.err
+}
+
+// Close closes the current results stream
+func (cr *combinedReader) Close() error {
+	cr.err = errors.New("read on closed reader")
+	if cr.current != nil {
+		err := cr.current.Close()
+		cr.current, cr.br = nil, nil
+		return err
+	}
+	return nil
+}
+
+// JobResult contains the salesforce generated columns of a bulk ingest
+// results row.
+type JobResult struct {
+	Row     int    // 1 based row number of the results data excluding the header
+	ID      string // sf__Id
+	Created bool   // sf__Created
+	Error   string // sf__Error
+}
+
+// DecodeJobResults reads csv results returned from GetSuccessfulJobRecords,
+// GetFailedJobRecords or GetUnprocessedJobRecords of job.  The job's ColumnDelimiter
+// is used to split columns; a nil job uses a comma.  Original columns are decoded into
+// results which must be a pointer to a []<struct> (or []*<struct>).  Columns are matched
+// to struct fields using the field's json tag name.  Decoded records are appended to
+// results in the order of the results data, and the returned JobResults are in the same
+// order, so the JobResult at index i describes the record at index i of the appended
+// records.  Failed inserts have an empty ID, so use the Row or the index rather than
+// the ID to match a result to its record.
+func DecodeJobResults(rdr io.Reader, job *Job, results interface{}) ([]JobResult, error) {
+	const expected = "*[]<struct>"
+	sliceVal, err := validatePtrTo(results, reflect.Slice, expected)
+	if err != nil {
+		return nil, err
+	}
+	elemType := sliceVal.Type().Elem()
+	structType, err := validateStructElem(reflect.TypeOf(results), elemType, expected)
+	if err != nil {
+		return nil, err
+	}
+	var delimiter string
+	if job != nil {
+		delimiter = job.ColumnDelimiter
+	}
+	delim, ok := jobDelimiters[delimiter]
+	if !ok {
+		return nil, fmt.Errorf("unknown column delimiter %s", delimiter)
+	}
+
+	cr := csv.NewReader(rdr)
+	cr.Comma = delim
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, ErrZeroRecords
+		}
+		return nil, err
+	}
+	fieldMap := jsonfield.Index(structType)
+	var colIndexes = make([][]int, len(header))
+	for i, col := range header {
+		colIndexes[i] = fieldMap[col]
+	}
+
+	var jobResults []JobResult
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return jobResults, err
+		}
+		var jr = JobResult{Row: len(jobResults) + 1}
+		rec := reflect.New(structType).Elem()
+		for i, col := range header {
+			switch col {
+			case "sf__Id":
+				jr.ID = row[i]
+			case "sf__Error":
+				jr.Error = row[i]
+			case "sf__Created":
+				jr.Created = row[i] == "true"
+			}
+			if colIndexes[i] != nil {
+				if err := setFieldFromString(rec.FieldByIndex(colIndexes[i]), row[i]); err != nil {
+					return jobResults, fmt.Errorf("row %d column %s: %w", jr.Row, col, err)
+				}
+			}
+		}
+		jobResults = append(jobResults, jr)
+		if elemType.Kind() == reflect.Ptr {
+			rec = rec.Addr()
+		}
+		sliceVal.Set(reflect.Append(sliceVal, rec))
+	}
+	return jobResults, nil
+}
+
+// setFieldFromString converts s to the field's type.  An empty
+// string leaves the field as a zero value.
+func setFieldFromString(fld reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+	if fld.Kind() == reflect.Ptr {
+		fld.Set(reflect.New(fld.Type().Elem()))
+		fld = fld.Elem()
+	}
+	if tu, ok := fld.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+	switch fld.Kind() {
+	case reflect.String:
+		fld.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fld.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fld.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		fld.SetFloat(f)
+	default:
+		return fmt.Errorf("unable to convert string to %v", fld.Type())
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+// bulkTestServer simulates the ingest job endpoints and stores uploaded data by job id
+type bulkTestServer struct {
+	m       sync.Mutex
+	uploads map[string][]byte
+	cnt     int
+}
+
+func (bs *bulkTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if !checkAuth(w, strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", 1)) {
+		return
+	}
+	bs.m.Lock()
+	defer bs.m.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/jobs/ingest/")
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	switch {
+	case r.Method == "POST" && path == "":
+		id := fmt.Sprintf("JOB%04d", bs.cnt)
+		bs.cnt++
+		encodeObject(w, salesforce.Job{ID: id, State: "Open"})
+	case r.Method == "PUT" && len(pathParts) == 2 && pathParts[1] == "batches":
+		b, _ := ioutil.ReadAll(r.Body)
+		bs.uploads[pathParts[0]] = b
+		w.WriteHeader(201)
+	case r.Method == "PATCH" && len(pathParts) == 1:
+		encodeObject(w, salesforce.Job{ID: pathParts[0], State: "UploadComplete"})
+	case r.Method == "GET" && len(pathParts) == 1:
+		encodeObject(w, salesforce.Job{ID: pathParts[0], State: "JobComplete"})
+	case r.Method == "GET" && len(pathParts) == 2 && pathParts[1] == "successfulResults":
+		w.Header().Set("Content-type", "text/csv")
+		rows, _ := csv.NewReader(bytes.NewReader(bs.uploads[pathParts[0]])).ReadAll()
+		cw := csv.NewWriter(w)
+		for i, row := range rows {
+			prefix := []string{"sf__Created", "sf__Id"}
+			if i > 0 {
+				prefix = []string{"true", "ID" + pathParts[0]}
+			}
+			cw.Write(append(prefix, row...))
+		}
+		cw.Flush()
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func TestBulkPipeline(t *testing.T) {
+	bs := &bulkTestServer{uploads: make(map[string][]byte)}
+	ws := httptest.NewServer(bs)
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var data = "Name,Description,Vendor_ID__c\n" +
+		"Name1,Desc1,VN00001\n" +
+		"Name2,\"Multi\nLine\",VN00002\n" +
+		"Name3,Desc3,VN00003\n" +
+		"Name4,Desc4,VN00004\n" +
+		"Name5,Desc5,VN00005"
+
+	bp := sv.NewBulkPipeline(salesforce.JobDefinition{Object: "Account", Operation: "insert"}, 80)
+	if err := bp.Upload(ctx, strings.NewReader("Name,Description\n")); err != salesforce.ErrZeroRecords {
+		t.Errorf("expected %v; got %v", salesforce.ErrZeroRecords, err)
+	}
+	if err := bp.Upload(ctx, strings.NewReader(data)); err != nil {
+		t.Fatalf("upload expected success; got %v", err)
+	}
+	ids := bp.JobIDs()
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 jobs; got %d", len(ids))
+	}
+	for _, id := range ids {
+		b := bs.uploads[id]
+		if len(b) > 80 {
+			t.Errorf("job %s: upload of %d bytes exceeds max of 80", id, len(b))
+		}
+		if !strings.HasPrefix(string(b), "Name,Description,Vendor_ID__c\n") {
+			t.Errorf("job %s: expected header row; got %s", id, b)
+		}
+	}
+	jobs, err := bp.Jobs(ctx)
+	if err != nil || len(jobs) != 3 {
+		t.Errorf("expected 3 jobs; got %d %v", len(jobs), err)
+	}
+
+	rdr := bp.SuccessfulRecords(ctx)
+	defer rdr.Close()
+	rows, err := csv.NewReader(rdr).ReadAll()
+	if err != nil {
+		t.Fatalf("expected combined results; got %v", err)
+	}
+	if len(rows) != 6 {
+		t.Errorf("expected header plus 5 rows; got %d", len(rows))
+	}
+	if len(rows) > 2 && rows[2][3] != "Multi\nLine" {
+		t.Errorf("expected multi-line field; got %q", rows[2][3])
+	}
+
+	if err := sv.NewBulkPipeline(salesforce.JobDefinition{}, 20).Upload(ctx, strings.NewReader(data)); err != salesforce.ErrRecordTooLarge {
+		t.Errorf("expected %v; got %v", salesforce.ErrRecordTooLarge, err)
+	}
+	if err := sv.NewBulkPipeline(salesforce.JobDefinition{ColumnDelimiter: "COLON"}, 0).Upload(ctx, strings.NewReader(data)); err == nil {
+		t.Errorf("expected unknown column delimiter error")
+	}
+
+	// CRLF records may contain a bare line feed
+	bp = sv.NewBulkPipeline(salesforce.JobDefinition{Object: "Account", Operation: "insert", ColumnDelimiter: "PIPE", LineEnding: "CRLF"}, 35)
+	if err := bp.Upload(ctx, strings.NewReader("Name|Description\r\nA|line1\nline2\r\nB|x")); err != nil {
+		t.Fatalf("upload expected success; got %v", err)
+	}
+	if ids = bp.JobIDs(); len(ids) != 2 || string(bs.uploads[ids[0]]) != "Name|Description\r\nA|line1\nline2\r\n" ||
+		string(bs.uploads[ids[1]]) != "Name|Description\r\nB|x\r\n" {
+		t.Errorf("unexpected CRLF uploads %v", ids)
+	}
+}
+
+func TestBulkPipeline_AbortFailedUpload(t *testing.T) {
+	bs := &bulkTestServer{uploads: make(map[string][]byte)}
+	var patches []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			http.Error(w, `[{"errorCode":"INVALIDJOBSTATE","message":"failed"}]`, http.StatusBadRequest)
+			return
+		case "PATCH":
+			b, _ := ioutil.ReadAll(r.Body)
+			patches = append(patches, r.URL.Path+" "+string(b))
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		}
+		bs.ServeHTTP(w, r)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	bp := sv.NewBulkPipeline(salesforce.JobDefinition{Object: "Account", Operation: "insert"}, 0)
+	if err := bp.Upload(ctx, strings.NewReader("Name\nA\n")); err == nil {
+		t.Fatalf("expected upload error")
+	}
+	if len(patches) != 1 || !strings.Contains(patches[0], "JOB0000") || !strings.Contains(patches[0], `"Aborted"`) {
+		t.Errorf("expected failed job to be aborted; got %v", patches)
+	}
+}
+
+func TestDecodeJobResults(t *testing.T) {
+	f, err := os.Open("testfiles/get/successrecords.csv")
+	if err != nil {
+		t.Fatalf("open failed %v", err)
+	}
+	defer f.Close()
+
+	if _, err := salesforce.DecodeJobResults(f, nil, []Account{}); err == nil ||
+		!strings.HasPrefix(err.Error(), "expected *[]<struct>") {
+		t.Errorf("expected *[]<struct> error; got %v", err)
+	}
+	var accts []*Account
+	results, err := salesforce.DecodeJobResults(f, nil, &accts)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(results) != 4 || len(accts) != 4 {
+		t.Fatalf("expected 4 results; got %d and %d", len(results), len(accts))
+	}
+	if jr := results[3]; jr.Row != 4 || !jr.Created || jr.ID != "SFID04" || accts[3].VendorID != "VN00004" {
+		t.Errorf("expected row 4 VN00004 created with id SFID04; got %#v %#v", jr, accts[3])
+	}
+	if a := accts[1]; a.AccountName != "Name2" || a.RecordTypeID != "bbbbbb" || results[1].Row != 2 {
+		t.Errorf("expected row 2 Name2 bbbbbb; got %#v %#v", results[1], a)
+	}
+
+	// failed inserts have no sf__Id and must not overwrite each other
+	f2, err := os.Open("testfiles/get/failedrecords.csv")
+	if err != nil {
+		t.Fatalf("open failed %v", err)
+	}
+	defer f2.Close()
+	var failed []Account
+	results, err = salesforce.DecodeJobResults(f2, &salesforce.Job{}, &failed)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(results) != 2 || len(failed) != 2 {
+		t.Fatalf("expected 2 failed results; got %d and %d", len(results), len(failed))
+	}
+	for i, jr := range results {
+		if jr.Row != i+1 || jr.Error != "Duplicate Vendor_ID__c" || failed[i].VendorID != fmt.Sprintf("VN0000%d", i+5) {
+			t.Errorf("result %d: unexpected %#v %#v", i, jr, failed[i])
+		}
+	}
+
+	failed = nil
+	data := "sf__Id|sf__Created|Name|BillingLatitude\nSFID01|true|A|1.5\nSFID02|true|B|x\n"
+	results, err = salesforce.DecodeJobResults(strings.NewReader(data), &salesforce.Job{ColumnDelimiter: "PIPE"}, &failed)
+	if err == nil || !strings.HasPrefix(err.Error(), "row 2 column BillingLatitude:") {
+		t.Errorf("expected row 2 column BillingLatitude error; got %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "SFID01" || len(failed) != 1 || failed[0].AccountName != "A" {
+		t.Errorf("expected first pipe delimited row decoded; got %v %v", results, failed)
+	}
+	if _, err := salesforce.DecodeJobResults(strings.NewReader(data), &salesforce.Job{ColumnDelimiter: "X"}, &failed); err == nil ||
+		err.Error() != "unknown column delimiter X" {
+		t.Errorf("expected unknown column delimiter X; got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultJobPollInterval is the time between job status checks of WaitJob
+// and WaitQueryJob when no interval is given
+const DefaultJobPollInterval = 5 * time.Second
+
+// BulkDeleteResult reports the outcome of BulkDeleteIDs and BulkDeleteQuery
+type BulkDeleteResult struct {
+	JobIDs    []string
+	Processed int // records processed by all jobs
+	// Failed contains the id and error of each record that was not deleted.  The
+	// ID of each JobResult is the record id.
+	Failed []JobResult
+}
+
+// BulkDeleteIDs deletes the records of object identified by ids using Bulk API 2.0 ingest
+// jobs.  The ids are uploaded as a single column csv, split across jobs as needed (see
+// BulkPipeline), and each job is waited on until complete.  A hardDelete job bypasses the
+// recycle bin and requires the Bulk API Hard Delete permission.  The returned result
+// contains the job ids and the failed records of completed jobs even when an error is
+// returned; a job that fails or is aborted returns an error describing the job.
+// https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/datafiles_prepare_data.htm
+func (sv *Service) BulkDeleteIDs(ctx context.Context, object string, ids []string, hardDelete bool) (*BulkDeleteResult, error) {
+	if object == "" {
+		return nil, errors.New("object may not be empty")
+	}
+	if len(ids) == 0 {
+		return nil, ErrZeroRecords
+	}
+	op := JobOperationDelete
+	if hardDelete {
+		op = JobOperationHardDelete
+	}
+	bp := sv.NewBulkPipeline(JobDefinition{Object: object, Operation: op}, 0)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeIDColumn(pw, ids))
+	}()
+	err := bp.Upload(ctx, pr)
+	res := &BulkDeleteResult{JobIDs: bp.JobIDs()}
+	if err != nil {
+		return res, err
+	}
+	var failed bool
+	for _, id := range res.JobIDs {
+		job, err := sv.WaitJob(ctx, id, 0)
+		if err != nil {
+			return res, err
+		}
+		if !job.State.IsSuccess() {
+			return res, fmt.Errorf("delete job %s %s: %s", job.ID, job.State, job.ErrorMessage)
+		}
+		res.Processed += job.NumberRecordsProcessed
+		failed = failed || job.NumberRecordsFailed > 0
+	}
+	if failed {
+		rdr := bp.FailedRecords(ctx)
+		defer rdr.Close()
+		res.Failed, err = decodeFailedDeletes(rdr)
+	}
+	return res, err
+}
+
+// BulkDeleteQuery deletes the records returned by soql, which must select the Id
+// field, using BulkDeleteIDs.  The object is the object of the query's FROM clause.
+// A query returning no records returns an empty result.
+func (sv *Service) BulkDeleteQuery(ctx context.Context, soql string, hardDelete bool) (*BulkDeleteResult, error) {
+	object := QueryObject(soql)
+	if object == "" {
+		return nil, errors.New("unable to determine sobject from query")
+	}
+	ids, err := sv.queryIDs(ctx, soql)
+	if err != nil || len(ids) == 0 {
+		return &BulkDeleteResult{}, err
+	}
+	return sv.BulkDeleteIDs(ctx, object, ids, hardDelete)
+}
+
+// WaitJob polls the ingest job until the job is complete, failed or aborted.
+// An interval <= 0 indicates DefaultJobPollInterval.
+func (sv *Service) WaitJob(ctx context.Context, jobID string, interval time.Duration) (*Job, error) {
+	return waitJob(ctx, jobID, interval, sv.GetJob)
+}
+
+// waitJob calls getJob every interval until the job reaches a terminal
+// state or ctx is done.  An interval <= 0 indicates DefaultJobPollInterval.
+func waitJob(ctx context.Context, jobID string, interval time.Duration, getJob func(context.Context, string) (*Job, error)) (*Job, error) {
+	if interval <= 0 {
+		interval = DefaultJobPollInterval
+	}
+	for {
+		job, err := getJob(ctx, jobID)
+		if err != nil || job.State.IsTerminal() {
+			return job, err
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// writeIDColumn writes ids as a csv with an Id header
+func writeIDColumn(w io.Writer, ids []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Id"}); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := cw.Write([]string{id}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// decodeFailedDeletes reads the failed results of delete jobs
+func decodeFailedDeletes(rdr io.Reader) ([]JobResult, error) {
+	cr := csv.NewReader(rdr)
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var idCol, errCol = -1, -1
+	for i, col := range header {
+		switch col {
+		case "Id":
+			idCol = i
+		case "sf__Error":
+			errCol = i
+		}
+	}
+	if idCol < 0 || errCol < 0 {
+		return nil, fmt.Errorf("failed results missing Id or sf__Error column: %v", header)
+	}
+	var failed []JobResult
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return failed, nil
+		}
+		if err != nil {
+			return failed, err
+		}
+		failed = append(failed, JobResult{ID: row[idCol], Error: row[errCol]})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_BulkDeleteIDs(t *testing.T) {
+	bs := &bulkTestServer{uploads: make(map[string][]byte)}
+	var definitions []salesforce.JobDefinition
+	var jobState = salesforce.JobStateJobComplete
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/query/":
+			encodeObject(w, map[string]interface{}{
+				"done":    true,
+				"records": []map[string]string{{"Id": "003A"}, {"Id": "003B"}},
+			})
+		case r.Method == "POST" && r.URL.Path == "/jobs/ingest/":
+			b, _ := ioutil.ReadAll(r.Body)
+			var jd salesforce.JobDefinition
+			json.Unmarshal(b, &jd)
+			definitions = append(definitions, jd)
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			bs.ServeHTTP(w, r)
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/failedResults/"):
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte("\"sf__Id\",\"sf__Error\",Id\n\"\",\"ENTITY_IS_DELETED:entity is deleted:--\",003B\n"))
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/jobs/ingest/JOB"):
+			encodeObject(w, salesforce.Job{ID: strings.TrimPrefix(r.URL.Path, "/jobs/ingest/"), State: jobState,
+				NumberRecordsProcessed: 3, NumberRecordsFailed: 1, ErrorMessage: "InvalidBatch"})
+		default:
+			bs.ServeHTTP(w, r)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	res, err := sv.BulkDeleteIDs(ctx, "Contact", []string{"003A", "003B", "003C"}, true)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(res.JobIDs) != 1 || res.Processed != 3 || len(res.Failed) != 1 || res.Failed[0].ID != "003B" ||
+		!strings.HasPrefix(res.Failed[0].Error, "ENTITY_IS_DELETED") {
+		t.Errorf("unexpected result %#v", res)
+	}
+	if upload := string(bs.uploads[res.JobIDs[0]]); upload != "Id\n003A\n003B\n003C\n" {
+		t.Errorf("unexpected upload %q", upload)
+	}
+	if len(definitions) != 1 || definitions[0].Object != "Contact" || definitions[0].Operation != salesforce.JobOperationHardDelete {
+		t.Errorf("expected Contact hardDelete job; got %v", definitions)
+	}
+
+	if res, err = sv.BulkDeleteQuery(ctx, "SELECT Id FROM Account WHERE Name = 'x'", false); err != nil || len(res.JobIDs) != 1 {
+		t.Fatalf("expected query delete success; got %v", err)
+	}
+	if jd := definitions[len(definitions)-1]; jd.Object != "Account" || jd.Operation != salesforce.JobOperationDelete {
+		t.Errorf("expected Account delete job; got %v", jd)
+	}
+	if upload := string(bs.uploads[res.JobIDs[0]]); upload != "Id\n003A\n003B\n" {
+		t.Errorf("unexpected query upload %q", upload)
+	}
+
+	jobState = salesforce.JobStateFailed
+	if res, err = sv.BulkDeleteIDs(ctx, "Contact", []string{"003A"}, false); err == nil ||
+		!strings.Contains(err.Error(), "InvalidBatch") || len(res.JobIDs) != 1 {
+		t.Errorf("expected failed job error; got %v", err)
+	}
+	if _, err := sv.BulkDeleteIDs(ctx, "Contact", nil, false); !errors.Is(err, salesforce.ErrZeroRecords) {
+		t.Errorf("expected ErrZeroRecords; got %v", err)
+	}
+}
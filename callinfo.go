@@ -0,0 +1,79 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// CallInfo describes the http response of a call.  Use RequestID to correlate
+// client logs with salesforce event monitoring logs.
+type CallInfo struct {
+	StatusCode int
+	RequestID  string // value of X-Request-Id or X-SFDC-Request-Id header
+	Header     http.Header
+	Elapsed    time.Duration
+}
+
+type callInfoKey struct{}
+
+// WithCallInfo returns a context that causes calls made with it to populate ci.
+// When an operation makes multiple calls (e.g. a paginated query), ci describes
+// the last call.  ci is not safe for use by concurrent calls.
+func WithCallInfo(ctx context.Context, ci *CallInfo) context.Context {
+	return context.WithValue(ctx, callInfoKey{}, ci)
+}
+
+// CallInfoOption returns a CallOption that populates ci with the response of
+// a single Call, including a 304 Not Modified response to a conditional request
+// sent using CallHeader("If-None-Match", etag).
+func CallInfoOption(ci *CallInfo) CallOption {
+	return CallOption{info: ci}
+}
+
+// setCallInfo populates the context's CallInfo and the CallInfo of opts,
+// if any, from the response or a NotSuccess error
+func setCallInfo(ctx context.Context, start time.Time, res *http.Response, err error, opts ...CallOption) {
+	targets := callInfoTargets(ctx, opts)
+	if len(targets) == 0 {
+		return
+	}
+	var ci = CallInfo{Elapsed: time.Since(start)}
+	var ns *ctxclient.NotSuccess
+	switch {
+	case res != nil:
+		ci.StatusCode, ci.Header = res.StatusCode, res.Header
+	case errors.As(err, &ns):
+		ci.StatusCode, ci.Header = ns.StatusCode, ns.Header
+	}
+	if ci.Header != nil {
+		if ci.RequestID = ci.Header.Get("X-Request-Id"); ci.RequestID == "" {
+			ci.RequestID = ci.Header.Get("X-SFDC-Request-Id")
+		}
+	}
+	for _, t := range targets {
+		*t = ci
+	}
+}
+
+// callInfoTargets returns the CallInfo of ctx and opts
+func callInfoTargets(ctx context.Context, opts []CallOption) []*CallInfo {
+	var targets []*CallInfo
+	if ci, _ := ctx.Value(callInfoKey{}).(*CallInfo); ci != nil {
+		targets = append(targets, ci)
+	}
+	for _, o := range opts {
+		if o.info != nil {
+			targets = append(targets, o.info)
+		}
+	}
+	return targets
+}
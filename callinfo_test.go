@@ -0,0 +1,75 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestWithCallInfo(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Header().Set("X-Request-Id", "REQ001")
+			encodeObject(w, map[string]string{"a": "b"})
+		default:
+			w.Header().Set("X-SFDC-Request-Id", "REQ002")
+			http.Error(w, `[{"errorCode":"NOT_FOUND"}]`, http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	var ci salesforce.CallInfo
+	ctx := salesforce.WithCallInfo(context.WithValue(context.Background(), "TK", "CALL OK"), &ci)
+	var result map[string]string
+	if err := sv.Call(ctx, "ok", "GET", nil, &result); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if ci.StatusCode != 200 || ci.RequestID != "REQ001" || ci.Elapsed <= 0 {
+		t.Errorf("expected 200 REQ001; got %d %s %v", ci.StatusCode, ci.RequestID, ci.Elapsed)
+	}
+	if err := sv.Call(ctx, "missing", "GET", nil, &result); err == nil {
+		t.Fatalf("expected not found error")
+	}
+	if ci.StatusCode != 404 || ci.RequestID != "REQ002" {
+		t.Errorf("expected 404 REQ002; got %d %s", ci.StatusCode, ci.RequestID)
+	}
+}
+
+func TestCallInfoOption(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"E1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"E1"`)
+		encodeObject(w, map[string]string{"a": "b"})
+	}))
+	defer ws.Close()
+
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	var ctxInfo, ci salesforce.CallInfo
+	ctx := salesforce.WithCallInfo(context.WithValue(context.Background(), "TK", "CALL OK"), &ctxInfo)
+	var result map[string]string
+	if err := sv.Call(ctx, "ok", "GET", nil, &result, salesforce.CallInfoOption(&ci)); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if ci.StatusCode != 200 || ci.Header.Get("ETag") != `"E1"` || ctxInfo.StatusCode != 200 {
+		t.Errorf("expected 200 with ETag; got %d %v and %d", ci.StatusCode, ci.Header, ctxInfo.StatusCode)
+	}
+	err := sv.Call(ctx, "ok", "GET", nil, &result, salesforce.CallInfoOption(&ci), salesforce.CallHeader("If-None-Match", ci.Header.Get("ETag")))
+	if err == nil || ci.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 not modified; got %d %v", ci.StatusCode, err)
+	}
+}
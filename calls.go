@@ -6,21 +6,24 @@
 package salesforce
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jfcote87/ctxclient"
 	"github.com/jfcote87/oauth2"
+	"github.com/jfcote87/salesforce/internal/jsonfield"
 )
 
 const currentAPIVersion = "v53.0"
@@ -38,17 +41,42 @@ func init() {
 }
 
 // Service handles creation, authorization and execution of REST Api calls
-// via its methods
+// via its methods.  A Service is never modified after creation; With* methods
+// return a copy holding its own url, headers and maps, so a Service is safe for
+// concurrent use.
 type Service struct {
-	baseURL     *url.URL
-	cf          ctxclient.Func
-	ts          oauth2.TokenSource
-	isqry       bool
-	batchSize   int
-	maxrows     int
-	contentType string
-	accept      string
-	logger      func(context.Context, int, []SObject, []OpResponse) error //BatchLogger
+	baseURL             *url.URL
+	cf                  ctxclient.Func
+	ts                  oauth2.TokenSource
+	isqry               bool
+	batchSize           int
+	queryBatchSize      int
+	collectionBatchSize int
+	maxrows             int
+	contentType         string
+	accept              string
+	pkChunking          string
+	limiter             Limiter
+	encoding            Encoding
+	autoAssign          string
+	duplicateRules      string
+	truncLengths        map[string]map[string]int // sobject name to field lengths
+	truncFunc           TruncateFunc
+	describeStore       DescribeStore
+	describeTTL         time.Duration
+	recordStore         RecordStore
+	recordTTL           time.Duration
+	nullEmpty           bool
+	fieldMask           map[string]bool
+	queryBulk           *QueryBulkOptions
+	externalIDs         map[string]string
+	headers             http.Header
+	lockRetry           *LockRetry
+	queryPreflight      bool
+	maxErrorBody        int64
+	userAgent           string
+	forClause           ForClause
+	logger              func(context.Context, int, []SObject, []OpResponse) error //BatchLogger
 }
 
 // New creates a salesforce service.  The host should be in the format
@@ -63,12 +91,54 @@ func New(host string, version string, ts oauth2.TokenSource) *Service {
 	return &Service{baseURL: baseURL, ts: ts}
 }
 
+// clone returns a copy of sv that shares no url, header or map values with sv
+func (sv *Service) clone() *Service {
+	snew := *sv
+	if sv.baseURL != nil {
+		u := *sv.baseURL
+		snew.baseURL = &u
+	}
+	snew.headers = sv.headers.Clone()
+	if sv.externalIDs != nil {
+		snew.externalIDs = make(map[string]string, len(sv.externalIDs))
+		for k, v := range sv.externalIDs {
+			snew.externalIDs[k] = v
+		}
+	}
+	if sv.fieldMask != nil {
+		snew.fieldMask = make(map[string]bool, len(sv.fieldMask))
+		for k, v := range sv.fieldMask {
+			snew.fieldMask[k] = v
+		}
+	}
+	if sv.truncLengths != nil {
+		snew.truncLengths = make(map[string]map[string]int, len(sv.truncLengths))
+		for nm, lengths := range sv.truncLengths {
+			m := make(map[string]int, len(lengths))
+			for k, v := range lengths {
+				m[k] = v
+			}
+			snew.truncLengths[nm] = m
+		}
+	}
+	return &snew
+}
+
+// querySvc returns a copy of sv for query calls, which send the
+// Sforce-Query-Options header.  As a Service is never modified after
+// creation, the copy shares sv's url, headers and maps.
+func (sv *Service) querySvc() *Service {
+	qsv := *sv
+	qsv.isqry = true
+	return &qsv
+}
+
 // WithCtxClientFunc returns a service that uses the ctxclient.Func for determining
 // the http client to use for calls.  Use mainly for debugging and testing.
 func (sv *Service) WithCtxClientFunc(f ctxclient.Func) *Service {
-	svnew := *sv
-	svnew.cf = f
-	return &svnew
+	snew := sv.clone()
+	snew.cf = f
+	return snew
 }
 
 // WithAcceptContentType replaces default accept and contentType headers
@@ -76,10 +146,10 @@ func (sv *Service) WithCtxClientFunc(f ctxclient.Func) *Service {
 // such text/csv or text/xml.  Empty strings in accept or contentType
 // parameters assume existing values.
 func (sv *Service) WithAcceptContentType(accept, contentType string) *Service {
-	svnew := *sv
-	svnew.contentType = contentType
-	svnew.accept = accept
-	return &svnew
+	snew := sv.clone()
+	snew.contentType = contentType
+	snew.accept = accept
+	return snew
 }
 
 // WithBatchSize returns a service that uses batchSz to regulate batches from query and
@@ -88,24 +158,66 @@ func (sv *Service) WithAcceptContentType(accept, contentType string) *Service {
 // returned rows is 2000 and minimum is 200. If batch size is set to less than 200, query operations
 // will 200 as the batch size. Collection updates use the setting to determine
 // the maximum number of update records per call, and the maximum setting is 200.  If the batch size
-// setting is greater than 200, batch calls will use 200 as the setting.
+// setting is greater than 200, batch calls will use 200 as the setting.  Settings of
+// WithQueryBatchSize and WithCollectionBatchSize take precedence.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_queryoptions.htm?search_text=batchSize
+//
+// Deprecated: use WithQueryBatchSize and WithCollectionBatchSize.
 func (sv *Service) WithBatchSize(batchSz int) *Service {
-	snew := *sv
+	snew := sv.clone()
 	if batchSz < 0 {
 		batchSz = 0
 	}
 	snew.batchSize = batchSz
 
-	return &snew
+	return snew
+}
+
+// WithQueryBatchSize returns a service that requests batchSz records per query page
+// using the Sforce-Query-Options header.  Values are limited to 200-2000, and zero
+// indicates the salesforce default (2000).
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_queryoptions.htm
+func (sv *Service) WithQueryBatchSize(batchSz int) *Service {
+	snew := sv.clone()
+	if batchSz < 0 {
+		batchSz = 0
+	}
+	snew.queryBatchSize = batchSz
+	return snew
+}
+
+// WithCollectionBatchSize returns a service that sends no more than batchSz records
+// per sobject collections call.  Values are limited to 1-200, and zero indicates 200.
+// CollectionOptions.BatchSize overrides the setting for a single call.
+func (sv *Service) WithCollectionBatchSize(batchSz int) *Service {
+	snew := sv.clone()
+	if batchSz < 0 {
+		batchSz = 0
+	}
+	snew.collectionBatchSize = batchSz
+	return snew
 }
 
 // WithURL creates a new service that uses the passed URL as the
 // prefix for calls.  Created to allow testing with httptest
 func (sv *Service) WithURL(newURL string) *Service {
-	snew := *sv
+	snew := sv.clone()
 	snew.baseURL, _ = url.Parse(newURL)
-	return &snew
+	return snew
+}
+
+// WithTooling returns a service that calls the Tooling API.  Query, ObjectList, Describe and the
+// sobject calls of the returned service use the tooling resources (e.g. tooling/sobjects/ApexClass).
+// https://developer.salesforce.com/docs/atlas.en-us.api_tooling.meta/api_tooling/intro_rest_resources.htm
+func (sv *Service) WithTooling() *Service {
+	snew := sv.clone()
+	if sv.baseURL != nil && !strings.HasSuffix(sv.baseURL.Path, "/tooling/") {
+		u := *sv.baseURL
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/tooling/"
+		u.RawPath = ""
+		snew.baseURL = &u
+	}
+	return snew
 }
 
 // WithMaxrows sets the max total rows returned for a query (not
@@ -114,17 +226,17 @@ func (sv *Service) WithMaxrows(maxrows int) *Service {
 	if maxrows < 0 {
 		maxrows = 0
 	}
-	snew := *sv
+	snew := sv.clone()
 	snew.maxrows = maxrows
-	return &snew
+	return snew
 }
 
 // contentTypeHeader returns the service's content-type header
 func (sv *Service) contentTypeHeader() string {
-	if sv == nil || sv.contentType > "" {
+	if sv != nil && sv.contentType > "" {
 		return sv.contentType
 	}
-	return defaultContentType
+	return sv.enc().ContentType()
 }
 
 // acceptHeader returns the service's accept header
@@ -132,22 +244,44 @@ func (sv *Service) acceptHeader() string {
 	if sv != nil && sv.accept > "" {
 		return sv.accept
 	}
-	return defaultAccept
+	return sv.enc().Accept()
 }
 
-// MaxBatchSize returns the batchSize property and used to limit batch sizes
+// MaxBatchSize returns QueryBatchSize for services created internally by query
+// operations and CollectionBatchSize otherwise.
+//
+// Deprecated: use QueryBatchSize or CollectionBatchSize.
 func (sv *Service) MaxBatchSize() int {
-	var defaultMax, defaultMin = 2000, 200
-	if !sv.isqry {
-		defaultMax, defaultMin = 200, 1
+	if sv.isqry {
+		return sv.QueryBatchSize()
 	}
-	if sv.batchSize == 0 || sv.batchSize > defaultMax {
-		return defaultMax
+	return sv.CollectionBatchSize()
+}
+
+// QueryBatchSize returns the number of records requested per query page
+func (sv *Service) QueryBatchSize() int {
+	return limitBatchSize(sv.queryBatchSize, sv.batchSize, 200, 2000)
+}
+
+// CollectionBatchSize returns the maximum number of records sent per
+// sobject collections call
+func (sv *Service) CollectionBatchSize() int {
+	return limitBatchSize(sv.collectionBatchSize, sv.batchSize, 1, 200)
+}
+
+// limitBatchSize returns size, or legacy if size is zero, limited to min-max.
+// Zero returns max.
+func limitBatchSize(size, legacy, min, max int) int {
+	if size == 0 {
+		size = legacy
 	}
-	if sv.batchSize < defaultMin {
-		return defaultMin
+	switch {
+	case size == 0 || size > max:
+		return max
+	case size < min:
+		return min
 	}
-	return sv.batchSize
+	return size
 }
 
 // WithLogger returns a new service that uses the passed BatchLogFunc
@@ -158,9 +292,9 @@ func (sv *Service) WithLogger(blf BatchLogFunc) *Service {
 	if sv == nil {
 		return sv
 	}
-	snew := *sv
+	snew := sv.clone()
 	snew.logger = blf
-	return &snew
+	return snew
 }
 
 // Instance returns the serviced instance
@@ -171,25 +305,141 @@ func (sv *Service) Instance() string {
 	return sv.baseURL.Host
 }
 
+// APIVersion returns the api version of the service's base url (e.g. v53.0)
+func (sv *Service) APIVersion() string {
+	if sv == nil || sv.baseURL == nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(sv.baseURL.Path, "/"), "/")
+	for i := 0; i < len(parts)-2; i++ {
+		if parts[i] == "services" && parts[i+1] == "data" {
+			return parts[i+2]
+		}
+	}
+	return ""
+}
+
 // HTTPBody allows salesforce calls to be returned as a stream rather than
-// a decoded json object.
+// a decoded json object.  HTTPBody implements io.ReadCloser, and the caller
+// must call Close (or WriteTo) to release the underlying connection and the
+// service's Limiter slot.  The body is closed automatically when the context
+// of the originating call is done.
 type HTTPBody struct {
 	Rdr           io.ReadCloser
 	ContentType   string
 	ContentLength int64
 }
 
-func (sv *Service) generateRequest(ctx context.Context, method, path string,
-	body io.Reader, setAccept bool) (*http.Request, error) {
+// Read reads from the response body
+func (hb *HTTPBody) Read(p []byte) (int, error) {
+	return hb.Rdr.Read(p)
+}
+
+// Close closes the response body.  Close may be called multiple times.
+func (hb *HTTPBody) Close() error {
+	return hb.Rdr.Close()
+}
+
+// WriteTo copies the response body to w and closes the body
+func (hb *HTTPBody) WriteTo(w io.Writer) (int64, error) {
+	defer hb.Close()
+	return io.Copy(w, hb.Rdr)
+}
+
+// newHTTPBody returns an HTTPBody of res that calls onClose when closed
+func newHTTPBody(ctx context.Context, res *http.Response, onClose func()) *HTTPBody {
+	bc := &bodyCloser{ReadCloser: res.Body, done: make(chan struct{}), onClose: onClose}
+	if ctxDone := ctx.Done(); ctxDone != nil {
+		go func() {
+			select {
+			case <-ctxDone:
+				bc.Close()
+			case <-bc.done:
+			}
+		}()
+	}
+	return &HTTPBody{
+		Rdr:           bc,
+		ContentType:   res.Header.Get("Content-type"),
+		ContentLength: res.ContentLength,
+	}
+}
+
+// bodyCloser ensures the body is closed only once and signals
+// the context watcher when closed
+type bodyCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	done    chan struct{}
+	onClose func()
+	err     error
+}
+
+func (bc *bodyCloser) Close() error {
+	bc.once.Do(func() {
+		bc.err = bc.ReadCloser.Close()
+		close(bc.done)
+		if bc.onClose != nil {
+			bc.onClose()
+		}
+	})
+	return bc.err
+}
+
+var versionPrefix = regexp.MustCompile(`^v[0-9]+\.[0-9]+/`)
+
+// ResolveURL normalizes path into a full call url. Salesforce returns urls
+// (e.g. nextRecordsUrl) in several forms which are handled as follows:
+//
+// https://<host>/services/data/v53.0/query/01g... is used as is when host is the
+// service's host; urls of other hosts return an error so that the service's
+// authorization is never sent to another host.
+// /services/data/v53.0/query/01g... uses the service's scheme and host.
+// services/data/v53.0/query/01g... and v53.0/query/01g... are treated as
+// relative to the /services/data path.
+// query/01g... is appended to the service's base path.
+func (sv *Service) ResolveURL(path string) (*url.URL, error) {
+	if sv == nil || sv.baseURL == nil {
+		return nil, errors.New("nil baseURL")
+	}
 	callURL, err := url.Parse(path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse path %s; %v", path, err)
 	}
-	if !strings.HasPrefix(path, "/") {
-		callURL.Path = sv.baseURL.Path + callURL.Path
+	if callURL.IsAbs() || callURL.Host > "" {
+		if !strings.EqualFold(callURL.Host, sv.baseURL.Host) {
+			return nil, fmt.Errorf("url %s is not on the service host %s", path, sv.baseURL.Host)
+		}
+		callURL.Scheme = sv.baseURL.Scheme
+		callURL.Host = sv.baseURL.Host
+		return callURL, nil
+	}
+	var prefix, rawPrefix string
+	switch {
+	case strings.HasPrefix(callURL.Path, "/"):
+	case strings.HasPrefix(callURL.Path, "services/data/"):
+		prefix, rawPrefix = "/", "/"
+	case versionPrefix.MatchString(callURL.Path):
+		prefix, rawPrefix = "/services/data/", "/services/data/"
+	default:
+		prefix, rawPrefix = sv.baseURL.Path, sv.baseURL.EscapedPath()
+	}
+	callURL.Path = prefix + callURL.Path
+	if callURL.RawPath > "" {
+		// keep escaped segments such as an external id containing a slash
+		callURL.RawPath = rawPrefix + callURL.RawPath
 	}
 	callURL.Scheme = sv.baseURL.Scheme
 	callURL.Host = sv.baseURL.Host
+	return callURL, nil
+}
+
+func (sv *Service) generateRequest(ctx context.Context, method, path string,
+	body io.Reader, setAccept bool, opts ...CallOption) (*http.Request, error) {
+	callURL, err := sv.ResolveURL(path)
+	if err != nil {
+		return nil, err
+	}
 
 	// leave URL empty as callURL already constructed
 	r, err := http.NewRequest(method, "", body)
@@ -197,9 +447,31 @@ func (sv *Service) generateRequest(ctx context.Context, method, path string,
 		return nil, err
 	}
 	r.URL = callURL
+	switch b := body.(type) {
+	case *pooledBody:
+		r.ContentLength = int64(b.Len())
+		r.GetBody = func() (io.ReadCloser, error) {
+			return b.pool.newBody(), nil
+		}
+	case io.ReadSeeker:
+		// http.NewRequest sets GetBody for bytes and strings readers
+		if r.GetBody == nil {
+			setSeekerBody(r, b)
+		}
+	}
 
+	sv.setDefaultHeaders(r)
 	if sv.isqry {
-		r.Header.Set("Sforce-Query-Options", fmt.Sprintf("batchSize=%d", sv.MaxBatchSize()))
+		r.Header.Set("Sforce-Query-Options", fmt.Sprintf("batchSize=%d", sv.QueryBatchSize()))
+	}
+	if sv.pkChunking > "" {
+		r.Header.Set("Sforce-Enable-PKChunking", sv.pkChunking)
+	}
+	if sv.autoAssign > "" {
+		r.Header.Set("Sforce-Auto-Assign", sv.autoAssign)
+	}
+	if sv.duplicateRules > "" {
+		r.Header.Set("Sforce-Duplicate-Rule-Header", sv.duplicateRules)
 	}
 	if body != nil {
 		r.Header.Set("Content-Type", sv.contentTypeHeader())
@@ -207,27 +479,76 @@ func (sv *Service) generateRequest(ctx context.Context, method, path string,
 	if setAccept {
 		r.Header.Set("Accept", sv.acceptHeader())
 	}
+	if err := setCallOptions(r, opts); err != nil {
+		return nil, err
+	}
 	if sv.ts != nil {
 		tk, err := sv.ts.Token(ctx)
 		if err != nil {
 			return nil, err
 		}
 		tk.SetAuthHeader(r)
+		if strings.HasPrefix(callURL.Path, "/services/async/") {
+			// Bulk API (v1) authorizes using the session header
+			r.Header.Set("X-SFDC-Session", tk.AccessToken)
+		}
 	}
 	return r, nil
 }
 
+// setSeekerBody sets the ContentLength and GetBody of a request from the remaining
+// length of rs.  GetBody rewinds rs to its current offset, so redirects and retries
+// resend the entire body.  The transport does not close rs.  The request is unchanged
+// when rs is not seekable (e.g. a pipe).
+func setSeekerBody(r *http.Request, rs io.ReadSeeker) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return
+	}
+	r.ContentLength = end - start
+	if r.ContentLength == 0 {
+		r.Body = http.NoBody
+		r.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return
+	}
+	r.Body = ioutil.NopCloser(rs)
+	r.GetBody = func() (io.ReadCloser, error) {
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(rs), nil
+	}
+}
+
 // Call performs all api operations.  All other service operations call
 // this func, so rarely should there be a need to use directly.
 //
 // If path begins with "/", it will be used as
 // an absolute path otherwise it is appended to the service's base path.
-// body may be nil, io.Reader or an interface{}.  An interface{} is marshaled as json.
-// result must be a pointer to an expected result type.
-func (sv *Service) Call(ctx context.Context, path, method string, body interface{}, result interface{}) error {
+// body may be nil, io.Reader or an interface{}.  An interface{} is marshaled using the
+// service's Encoding (json by default).  An io.ReadSeeker body (e.g. *os.File) is sent with
+// its length and is rewound by the request's GetBody, so redirects and retries may resend
+// it.  result must be a pointer to an expected result type.
+// Use WithCallInfo or CallInfoOption to capture the status code and request id of the
+// response, and opts (e.g. AcceptHeader) to set headers of the single call.  A non-2xx response returns a
+// *ctxclient.NotSuccess whose Body contains salesforce's error json (see WithMaxErrorBody).
+func (sv *Service) Call(ctx context.Context, path, method string, body interface{}, result interface{}, opts ...CallOption) error {
 	if sv == nil || sv.baseURL == nil {
 		return errors.New("nil baseURL")
 	}
+	// the limiter slot is held until the result is decoded or,
+	// for an *HTTPBody result, until the body is closed
+	release, err := sv.acquire(ctx)
+	if err != nil {
+		return err
+	}
 	var rqBody io.Reader
 	switch val := body.(type) {
 	case nil:
@@ -236,37 +557,66 @@ func (sv *Service) Call(ctx context.Context, path, method string, body interface
 		// set rqBody to reader
 		rqBody = val
 	default:
-		// marshal body into byte reader
-		b, _ := json.MarshalIndent(body, "", "    ")
-		rqBody = bytes.NewReader(b)
+		// encode body into a pooled buffer
+		pool, err := sv.encodeBody(sv.prepareBody(body))
+		if err != nil {
+			release(nil)
+			return err
+		}
+		defer pool.release()
+		rqBody = pool.newBody()
 	}
-	r, err := sv.generateRequest(ctx, method, path, rqBody, result != nil)
+	r, err := sv.generateRequest(ctx, method, path, rqBody, result != nil, opts...)
 	if err != nil {
+		closeBody(rqBody)
+		release(nil)
 		return err
 	}
-
-	res, err := sv.cf.Do(ctx, r)
+	start := time.Now()
+	res, err := sv.do(ctx, r)
+	closeSeeker(rqBody)
+	setCallInfo(ctx, start, res, err, opts...)
 	if err != nil {
+		release(err)
 		return err
 	}
 	switch rx := result.(type) {
 	case **HTTPBody:
 		if rx != nil {
-			*rx = &HTTPBody{
-				Rdr:           res.Body,
-				ContentType:   res.Header.Get("Content-type"),
-				ContentLength: res.ContentLength,
-			}
+			*rx = newHTTPBody(ctx, res, func() { release(nil) })
 			return nil
 		}
 		err = errors.New("result may not be a nil ptr")
 	case interface{}: // non-nil value
-		err = json.NewDecoder(res.Body).Decode(result)
+		err = sv.enc().Decode(res.Body, result)
 	}
 	res.Body.Close()
+	release(nil)
 	return err
 }
 
+// closeBody returns a pooled body's buffer when the
+// request is not sent
+func closeBody(body io.Reader) {
+	if pb, ok := body.(*pooledBody); ok {
+		pb.Close()
+	}
+}
+
+// closeSeeker closes an io.ReadSeeker body once the call completes
+// as the transport does not close a rewindable body.
+func closeSeeker(body io.Reader) {
+	if _, ok := body.(*pooledBody); ok {
+		return
+	}
+	if rsc, ok := body.(interface {
+		io.ReadSeeker
+		io.Closer
+	}); ok {
+		rsc.Close()
+	}
+}
+
 // ObjectList returns all objects with top level metadata
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_describeGlobal.htm
 func (sv *Service) ObjectList(ctx context.Context) ([]SObjectDefinition, error) {
@@ -303,12 +653,65 @@ type DeletedRecord struct {
 	DeletedDate Datetime `json:"deletedDate,omitempty"`
 }
 
-// GetDeletedRecords returns a list of ids for records deleted in the time range
+// ReplicationWindow is the number of days before now that GetDeletedRecords and
+// GetUpdatedRecords accept as a start time
+const ReplicationWindow = 30
+
+// ErrInvalidTimeRange is wrapped by the TimeRangeError returned by GetDeletedRecords
+// and GetUpdatedRecords
+var ErrInvalidTimeRange = errors.New("invalid replication time range")
+
+// TimeRangeError describes a start and end rejected by GetDeletedRecords or
+// GetUpdatedRecords.  Start and End are truncated to the minute.
+type TimeRangeError struct {
+	Start  time.Time
+	End    time.Time
+	Reason string
+}
+
+// Error returns the reason and range
+func (e *TimeRangeError) Error() string {
+	return fmt.Sprintf("%v: %s (start %s, end %s)", ErrInvalidTimeRange, e.Reason,
+		e.Start.Format(time.RFC3339), e.End.Format(time.RFC3339))
+}
+
+// Unwrap returns ErrInvalidTimeRange
+func (e *TimeRangeError) Unwrap() error {
+	return ErrInvalidTimeRange
+}
+
+// replicationRange validates start and end returning the query values of a
+// get deleted or updated call.  The api ignores seconds, so both times are
+// truncated to the minute in UTC before checking that end is after start and
+// that start is within ReplicationWindow days of now.
+func replicationRange(start, end time.Time) (url.Values, error) {
+	start, end = start.UTC().Truncate(time.Minute), end.UTC().Truncate(time.Minute)
+	var reason string
+	switch {
+	case start.IsZero() || end.IsZero():
+		reason = "start and end must be set"
+	case !end.After(start):
+		reason = "end must be at least a minute after start"
+	case start.Before(time.Now().UTC().AddDate(0, 0, -ReplicationWindow).Truncate(time.Minute)):
+		reason = fmt.Sprintf("start may not be more than %d days ago", ReplicationWindow)
+	default:
+		var q = make(url.Values)
+		q.Set("start", start.Format(time.RFC3339))
+		q.Set("end", end.Format(time.RFC3339))
+		return q, nil
+	}
+	return nil, &TimeRangeError{Start: start, End: end, Reason: reason}
+}
+
+// GetDeletedRecords returns a list of ids for records deleted in the time range.  A
+// *TimeRangeError is returned when end is not after start or start is older than
+// ReplicationWindow days.  Times are sent in UTC truncated to the minute.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_get_deleted.htm
 func (sv *Service) GetDeletedRecords(ctx context.Context, sobjectName string, start, end time.Time) (*GetDeletedResponse, error) {
-	var q = make(url.Values)
-	q.Set("start", start.Format(time.RFC3339))
-	q.Set("end", end.Format(time.RFC3339))
+	q, err := replicationRange(start, end)
+	if err != nil {
+		return nil, err
+	}
 	path := fmt.Sprintf("sobjects/%s/deleted/?%s", sobjectName, q.Encode())
 	var res *GetDeletedResponse
 	return res, sv.Call(ctx, path, "GET", nil, &res)
@@ -320,12 +723,14 @@ type GetUpdatedResponse struct {
 	LatestDateCovered Datetime `json:"latestDateCovered,omitempty"`
 }
 
-// GetUpdatedRecords returns a list of ids for records updated in the time range
+// GetUpdatedRecords returns a list of ids for records updated in the time range.  Start
+// and end are validated as in GetDeletedRecords.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_get_updated.htm
 func (sv *Service) GetUpdatedRecords(ctx context.Context, sobjectName string, start, end time.Time) (*GetUpdatedResponse, error) {
-	var q = make(url.Values)
-	q.Set("start", start.Format(time.RFC3339))
-	q.Set("end", end.Format(time.RFC3339))
+	q, err := replicationRange(start, end)
+	if err != nil {
+		return nil, err
+	}
 	path := fmt.Sprintf("sobjects/%s/updated/?%s", sobjectName, q.Encode())
 
 	var res *GetUpdatedResponse
@@ -338,7 +743,8 @@ type OpResponse struct {
 	Success     bool    `json:"success"`
 	Errors      []Error `json:"errors"`
 	Created     bool    `json:"created,omitempty"`
-	RecordIndex int     `json:"-"`
+	RecordIndex int     `json:"-"` // index of record in slice passed to collection func
+	BatchNumber int     `json:"-"` // zero based number of the batch containing the record
 	SObject     SObject `json:"-"`
 }
 
@@ -365,13 +771,17 @@ func (or OpResponse) SObjectValue(ix interface{}) error {
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_sobject_create.htm
 func (sv *Service) Create(ctx context.Context, rec SObject) (*OpResponse, error) {
 	var res *OpResponse
-	return res, sv.Call(ctx, "sobjects/"+rec.SObjectName(), "POST", rec, &res)
+	return res, sv.Call(ctx, "sobjects/"+rec.SObjectName(), "POST", sv.truncate(rec), &res)
 }
 
-// Update updates a row.  ID must not be set on the rec.
+// Update updates a row.  ID must not be set on the rec.  See WithLockRetry to retry
+// updates failing with UNABLE_TO_LOCK_ROW.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_update_fields.htm
 func (sv *Service) Update(ctx context.Context, rec SObject, id string) error {
-	return sv.Call(ctx, "sobjects/"+rec.SObjectName()+"/"+id, "PATCH", rec, nil)
+	body := sv.truncate(rec)
+	return sv.retryLockError(ctx, func() error {
+		return sv.Call(ctx, "sobjects/"+rec.SObjectName()+"/"+id, "PATCH", body, nil)
+	})
 }
 
 // Delete deletes a row
@@ -380,8 +790,64 @@ func (sv *Service) Delete(ctx context.Context, sobjectName string, id string) er
 	return sv.Call(ctx, "sobjects/"+sobjectName+"/"+id, "DELETE", nil, nil)
 }
 
+// Exists returns true if the sobjectName row with id exists.  A HEAD request is
+// used, and a 404 status returns false without an error.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_sobject_retrieve.htm
+func (sv *Service) Exists(ctx context.Context, sobjectName, id string) (bool, error) {
+	if id == "" {
+		return false, errors.New("id may not be empty")
+	}
+	return sv.exists(ctx, "sobjects/"+sobjectName+"/"+id)
+}
+
+// ExistsByExternalID returns true if a sobjectName row with the externalIDField value
+// exists.  An empty externalIDField uses the default registered by WithExternalIDs.
+// externalID is path escaped by the function.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_sobject_upsert.htm
+func (sv *Service) ExistsByExternalID(ctx context.Context, sobjectName, externalIDField, externalID string) (bool, error) {
+	externalIDField, err := sv.externalIDField(sobjectName, externalIDField)
+	if err != nil {
+		return false, err
+	}
+	if externalID == "" {
+		return false, errors.New("externalID may not be empty")
+	}
+	return sv.exists(ctx, "sobjects/"+sobjectName+"/"+externalIDField+"/"+url.PathEscape(externalID))
+}
+
+// exists sends a HEAD request to path.  A 300 status (multiple external id
+// matches) returns true.
+func (sv *Service) exists(ctx context.Context, path string) (bool, error) {
+	err := sv.Call(ctx, path, "HEAD", nil, nil)
+	var ns *ctxclient.NotSuccess
+	if errors.As(err, &ns) {
+		switch ns.StatusCode {
+		case http.StatusNotFound:
+			return false, nil
+		case http.StatusMultipleChoices:
+			return true, nil
+		}
+	}
+	return err == nil, err
+}
+
+// DeleteByExternalID deletes the row of sobjectName whose externalIDField equals externalID.
+// An empty externalIDField uses the default registered by WithExternalIDs.  externalID is
+// path escaped by the function.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_sobject_upsert_delete.htm
+func (sv *Service) DeleteByExternalID(ctx context.Context, sobjectName, externalIDField, externalID string) error {
+	externalIDField, err := sv.externalIDField(sobjectName, externalIDField)
+	if err != nil {
+		return err
+	}
+	if externalID == "" {
+		return errors.New("externalID may not be empty")
+	}
+	return sv.Call(ctx, "sobjects/"+sobjectName+"/"+externalIDField+"/"+url.PathEscape(externalID), "DELETE", nil, nil)
+}
+
 // Get retrieves values of a single record identified by sf ID. The result parameterf
-// must be a pointer to an SObject
+// must be a pointer to an SObject.  See WithRecordStore to cache results.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_get_field_values.htm
 func (sv *Service) Get(ctx context.Context, result interface{}, id string, flds ...string) error {
 	sobj, err := isSObjectPointer(result)
@@ -389,45 +855,130 @@ func (sv *Service) Get(ctx context.Context, result interface{}, id string, flds
 		return err
 	}
 	path := fmt.Sprintf("sobjects/%s/%s?fields=%s", sobj.SObjectName(), id, strings.Join(flds, ","))
-	return sv.Call(ctx, path, "GET", nil, result)
+	return sv.cachedGet(ctx, path, result, sobj.SObjectName(), "Id", id, flds)
 }
 
 // GetByExternalID retrieves values of a single record identified by external ID. The result parameter
-// must be a pointer to an SObject
+// must be a pointer to an SObject.  externalID is path escaped by the function.  See WithRecordStore
+// to cache results.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/using_resources_retrieve_with_externalid.htm
 func (sv *Service) GetByExternalID(ctx context.Context, result interface{}, externalIDField, externalID string, flds ...string) error {
 	sobj, err := isSObjectPointer(result)
 	if err != nil {
 		return err
 	}
-	path := fmt.Sprintf("sobjects/%s/%s/%s?fields=%s", sobj.SObjectName(), externalIDField, externalID, strings.Join(flds, ","))
-	return sv.Call(ctx, path, "GET", nil, result)
+	path := fmt.Sprintf("sobjects/%s/%s/%s?fields=%s", sobj.SObjectName(), externalIDField, url.PathEscape(externalID), strings.Join(flds, ","))
+	return sv.cachedGet(ctx, path, result, sobj.SObjectName(), externalIDField, externalID, flds)
 }
 
 func isSObjectPointer(result interface{}) (SObject, error) {
+	const expected = "pointer to an SObject"
 	resType := reflect.TypeOf(result)
 	resVal := reflect.ValueOf(result)
-	if resType.Kind() != reflect.Ptr || resVal.IsNil() || !resVal.Elem().CanInterface() {
-		return nil, fmt.Errorf("expected result to be a non-nil pointer; got %s", resType.Name())
+	if resType == nil || resType.Kind() != reflect.Ptr || resVal.IsNil() || !resVal.Elem().CanInterface() {
+		return nil, &TypeError{Expected: expected, Got: resType, Hint: "did you pass a value instead of a pointer?"}
 	}
 	ptx := resVal.Elem()
-	sobj, ok := ptx.Interface().(SObject)
-	if ok && ptx.Kind() == reflect.Ptr && ptx.IsNil() {
-		sobj, ok = reflect.New(ptx.Type().Elem()).Interface().(SObject)
+	if err := validateSObjectType(ptx.Type(), resType, expected); err != nil {
+		return nil, err
 	}
-	if !ok {
-		return nil, errors.New("unable to convert result ptr to an SObject")
+	if ptx.Kind() == reflect.Interface && ptx.IsNil() {
+		return nil, &TypeError{Expected: expected, Got: resType, Hint: "interface value is nil"}
+	}
+	sobj := ptx.Interface().(SObject)
+	if ptx.Kind() == reflect.Ptr && ptx.IsNil() {
+		sobj = reflect.New(ptx.Type().Elem()).Interface().(SObject)
 	}
 	return sobj, nil
-
 }
 
-// Upsert inserts/updates a row using an external id
+// Upsert inserts/updates a row using an external id.  An empty externalIDField uses
+// the default registered by WithExternalIDs, and an empty externalID uses the rec's
+// value of the field.  As with the other external id funcs, externalID is path escaped
+// by the function, so pass the unescaped value.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_upsert.htm
 func (sv *Service) Upsert(ctx context.Context, rec SObject, externalIDField, externalID string) (*OpResponse, error) {
 	var res *OpResponse
-	path := "sobjects/" + rec.SObjectName() + "/" + externalIDField + "/" + externalID
-	return res, sv.Call(ctx, path, "PATCH", rec, &res)
+	externalIDField, err := sv.externalIDField(rec.SObjectName(), externalIDField)
+	if err != nil {
+		return nil, err
+	}
+	if externalID == "" {
+		if externalID = fieldValue(rec, externalIDField); externalID == "" {
+			return nil, fmt.Errorf("%s has no value for %s", rec.SObjectName(), externalIDField)
+		}
+	}
+	path := "sobjects/" + rec.SObjectName() + "/" + externalIDField + "/" + url.PathEscape(externalID)
+	return res, sv.Call(ctx, path, "PATCH", sv.truncate(rec), &res)
+}
+
+// ErrMultipleMatches is returned by FindOrCreate when the filter
+// matches more than one record.
+var ErrMultipleMatches = errors.New("filter matches multiple records")
+
+// FindOrCreate updates the record matching filter (a SOQL WHERE condition such as
+// "Email = 'a@example.com'") or creates rec if no record matches, returning the
+// record's id and whether it was created.  When externalIDField (or the default
+// registered by WithExternalIDs) is not empty and rec contains a value for that field,
+// an Upsert is used instead of a query so that concurrent calls do not create duplicates.
+// ErrMultipleMatches is returned if the filter matches more than one record.
+func (sv *Service) FindOrCreate(ctx context.Context, rec SObject, filter, externalIDField string) (string, bool, error) {
+	if rec == nil {
+		return "", false, errors.New("rec may not be nil")
+	}
+	if externalIDField == "" {
+		externalIDField = sv.ExternalIDField(rec.SObjectName())
+	}
+	if externalIDField > "" {
+		if extID := fieldValue(rec, externalIDField); extID > "" {
+			res, err := sv.Upsert(ctx, rec, externalIDField, extID)
+			if err != nil || res == nil {
+				return "", false, err
+			}
+			return res.ID, res.Created, nil
+		}
+	}
+	var matches []RecordMap
+	qry := "SELECT Id FROM " + rec.SObjectName() + " WHERE " + filter + " LIMIT 2"
+	if err := sv.Query(ctx, qry, &matches); err != nil {
+		return "", false, err
+	}
+	switch len(matches) {
+	case 0:
+		res, err := sv.Create(ctx, rec)
+		if err != nil {
+			return "", false, err
+		}
+		return res.ID, true, nil
+	case 1:
+		id, _ := matches[0]["Id"].(string)
+		return id, false, sv.Update(ctx, rec, id)
+	}
+	return "", false, ErrMultipleMatches
+}
+
+// fieldValue returns the string value of the rec's field with
+// the json name nm.
+func fieldValue(rec SObject, nm string) string {
+	if m, ok := rec.(RecordMap); ok {
+		if v, ok := m[nm]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	val := reflect.Indirect(reflect.ValueOf(rec))
+	if val.Kind() != reflect.Struct {
+		return ""
+	}
+	idx, ok := jsonfield.Index(val.Type())[nm]
+	if !ok {
+		return ""
+	}
+	fld := reflect.Indirect(val.FieldByIndex(idx))
+	if !fld.IsValid() || fld.IsZero() {
+		return ""
+	}
+	return fmt.Sprint(fld.Interface())
 }
 
 // Query executes the query. All results are decoded into the results parameter that
@@ -453,37 +1004,107 @@ func (sv *Service) query(ctx context.Context, path, qry string, results interfac
 	if err != nil {
 		return err
 	}
-	var res = &QueryResponse{
-		Records: rs,
+	if sv.queryPreflight {
+		if err := sv.checkQueryable(ctx, qry); err != nil {
+			return err
+		}
 	}
-	fmtQry := path + url.QueryEscape(qry)
-	qsv := *sv
-	qsv.isqry = true
-	for !res.Done {
-		err = qsv.Call(ctx, fmtQry, "GET", nil, res)
-		if err != nil {
+	qsv := sv.querySvc()
+	return qsv.paginate(ctx, path+url.QueryEscape(sv.forClause.Append(qry)), func() pager {
+		return &QueryResponse{Records: rs}
+	}, func(pager) error {
+		if sv.maxrows > 0 && rs.rows() >= sv.maxrows {
+			rs.slice(0, sv.maxrows)
+			return ErrStopPaging
+		}
+		return nil
+	})
+}
+
+// ErrStopPaging may be returned from a Paginate page func to end
+// pagination without returning an error.
+var ErrStopPaging = errors.New("stop paging")
+
+// Paginate performs GET calls beginning with firstPath and continues with the
+// page's nextRecordsUrl until done is true or no nextRecordsUrl is returned.
+// Use for endpoints returning the done/nextRecordsUrl pattern (query, jobs list, list
+// views, tooling query, etc.).  The f func is passed the json of each page.  Returning
+// ErrStopPaging from f ends pagination without error.
+func (sv *Service) Paginate(ctx context.Context, firstPath string, f func(page json.RawMessage) error) error {
+	return sv.paginate(ctx, firstPath, func() pager {
+		return &rawPage{}
+	}, func(pg pager) error {
+		return f(pg.(*rawPage).raw)
+	})
+}
+
+// pager is implemented by the decoded pages of done/nextRecordsUrl endpoints
+type pager interface {
+	// nextPage returns the path of the next page or "" when paging is done
+	nextPage() string
+}
+
+// paginate performs the GET calls of Paginate decoding each response body
+// directly into the pager returned by newPage, which is then passed to f.
+func (sv *Service) paginate(ctx context.Context, firstPath string, newPage func() pager, f func(pg pager) error) error {
+	if err := sv.requireJSON(); err != nil {
+		return err
+	}
+	path := firstPath
+	for path > "" {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pg := newPage()
+		if err := sv.Call(ctx, path, "GET", nil, pg); err != nil {
 			return err
 		}
-		if sv.maxrows > 0 {
-			if rs.rows() >= sv.maxrows {
-				rs.slice(0, sv.maxrows)
+		if err := f(pg); err != nil {
+			if err == ErrStopPaging {
 				return nil
 			}
+			return err
 		}
-		fmtQry = res.NextRecordsURL
+		path = pg.nextPage()
 	}
 	return nil
+}
+
+// pageInfo contains the paging fields of a done/nextRecordsUrl page
+type pageInfo struct {
+	Done           bool   `json:"done"`
+	NextRecordsURL string `json:"nextRecordsUrl"`
+}
+
+func (pi *pageInfo) nextPage() string {
+	if pi.Done {
+		return ""
+	}
+	return pi.NextRecordsURL
+}
+
+// rawPage keeps the json of a page for Paginate
+type rawPage struct {
+	raw json.RawMessage
+	pageInfo
+}
 
+// UnmarshalJSON copies b and reads the paging fields
+func (rp *rawPage) UnmarshalJSON(b []byte) error {
+	rp.raw = append(rp.raw[:0], b...)
+	return json.Unmarshal(b, &rp.pageInfo)
 }
 
 // TODO: create/update binary
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_sobject_insert_update_blob.htm
 
-// GetAttachment retrieves a binary file from an attachment sobject
+// GetAttachment retrieves a binary file from an attachment sobject.  The Accept header
+// is */* unless set by opts.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_sobject_blob_retrieve.htm
-func (sv *Service) GetAttachment(ctx context.Context, sobjectName, id string) (*HTTPBody, error) {
+func (sv *Service) GetAttachment(ctx context.Context, sobjectName, id string, opts ...CallOption) (*HTTPBody, error) {
 	var rdr *HTTPBody
-	if err := sv.WithAcceptContentType("*/*", "").Call(ctx, "sobjects/"+sobjectName+"/"+id, "GET", nil, &rdr); err != nil {
+	opts = append([]CallOption{AcceptHeader("*/*")}, opts...)
+	if err := sv.Call(ctx, "sobjects/"+sobjectName+"/"+id, "GET", nil, &rdr, opts...); err != nil {
 		return nil, err
 	}
 	return rdr, nil
@@ -514,14 +1135,14 @@ func (sv *Service) UploadJobData(ctx context.Context, job string, rdr io.Reader)
 		defer rdrc.Close()
 	}
 	path := fmt.Sprintf("jobs/ingest/%s/batches", job)
-	return sv.WithAcceptContentType("application/json", "text/csv").Call(ctx, path, "PUT", rdr, nil)
+	return sv.Call(ctx, path, "PUT", rdr, nil, AcceptHeader("application/json"), ContentTypeHeader("text/csv"))
 }
 
 // CloseJob starts job processing
 // https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/close_job.htm
 func (sv *Service) CloseJob(ctx context.Context, jobID string) (*Job, error) {
 	var result *Job
-	var mx = map[string]string{"state": "UploadComplete"}
+	var mx = map[string]JobState{"state": JobStateUploadComplete}
 	err := sv.Call(ctx, "jobs/ingest/"+jobID, "PATCH", mx, &result)
 	return result, err
 }
@@ -530,7 +1151,7 @@ func (sv *Service) CloseJob(ctx context.Context, jobID string) (*Job, error) {
 // https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/close_job.htm
 func (sv *Service) AbortJob(ctx context.Context, jobID string) (*Job, error) {
 	var result *Job
-	var mx = map[string]string{"state": "Aborted"}
+	var mx = map[string]JobState{"state": JobStateAborted}
 	err := sv.Call(ctx, "jobs/ingest/"+jobID, "PATCH", mx, &result)
 	return result, err
 }
@@ -556,8 +1177,7 @@ func (sv *Service) GetJob(ctx context.Context, jobID string) (*Job, error) {
 func (sv *Service) GetSuccessfulJobRecords(ctx context.Context, jobID string) (*HTTPBody, error) {
 	path := fmt.Sprintf("jobs/ingest/%s/successfulResults/", jobID)
 	var sr *HTTPBody
-	sv2 := sv.WithAcceptContentType("text/csv", "")
-	err := sv2.Call(ctx, path, "GET", nil, &sr)
+	err := sv.Call(ctx, path, "GET", nil, &sr, AcceptHeader("text/csv"))
 	if err != nil {
 		return nil, err
 	}
@@ -569,7 +1189,7 @@ func (sv *Service) GetSuccessfulJobRecords(ctx context.Context, jobID string) (*
 func (sv *Service) GetFailedJobRecords(ctx context.Context, jobID string) (*HTTPBody, error) {
 	path := fmt.Sprintf("jobs/ingest/%s/failedResults/", jobID)
 	var sr *HTTPBody
-	err := sv.WithAcceptContentType("text/csv", "").Call(ctx, path, "GET", nil, &sr)
+	err := sv.Call(ctx, path, "GET", nil, &sr, AcceptHeader("text/csv"))
 	if err != nil {
 		return nil, err
 	}
@@ -581,7 +1201,7 @@ func (sv *Service) GetFailedJobRecords(ctx context.Context, jobID string) (*HTTP
 func (sv *Service) GetUnprocessedJobRecords(ctx context.Context, jobID string) (*HTTPBody, error) {
 	path := fmt.Sprintf("jobs/ingest/%s/unprocessedrecords/", jobID)
 	var sr *HTTPBody
-	err := sv.WithAcceptContentType("text/csv", "").Call(ctx, path, "GET", nil, &sr)
+	err := sv.Call(ctx, path, "GET", nil, &sr, AcceptHeader("text/csv"))
 	if err != nil {
 		return nil, err
 	}
@@ -594,7 +1214,7 @@ func (sv *Service) GetUnprocessedJobRecords(ctx context.Context, jobID string) (
 func (sv *Service) ListJobs(ctx context.Context, nextURL string) (*JobList, error) {
 	var result *JobList
 	if nextURL > "" {
-		return result, sv.WithURL(nextURL).Call(ctx, "", "GET", nil, &result)
+		return result, sv.Call(ctx, nextURL, "GET", nil, &result)
 	}
 	return result, sv.Call(ctx, "jobs/ingest/", "GET", nil, &result)
 }
@@ -622,13 +1242,13 @@ type BulkQuery struct {
 // queryAll to true.
 // https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/query_create_job.htm
 func (sv *Service) QueryCreateJob(ctx context.Context, bulkQuery BulkQuery, queryAll bool) (*Job, error) {
-	op := "query"
+	op := JobOperationQuery
 	if queryAll {
-		op = "queryAll"
+		op = JobOperationQueryAll
 	}
 	var body = struct {
-		Operation   string `json:"operation,omitempty"`
-		ContentType string `json:"contentType,omitempty"`
+		Operation   JobOperation `json:"operation,omitempty"`
+		ContentType string       `json:"contentType,omitempty"`
 		BulkQuery
 	}{
 		Operation:   op,
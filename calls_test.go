@@ -10,14 +10,18 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -143,6 +147,27 @@ func TestQueryCall(t *testing.T) {
 		return
 	}
 
+	var ptrRows []*Contact
+	if err = sv.Query(ctx, "firstset", &ptrRows); err != nil {
+		t.Errorf("firstset pointers: %v", err)
+		return
+	}
+	if len(ptrRows) != len(resultRows) {
+		t.Errorf("pointer read expected %d rows; got %d", len(resultRows), len(ptrRows))
+		return
+	}
+	for i, c := range ptrRows {
+		if c == nil || !reflect.DeepEqual(*c, resultRows[i]) {
+			t.Errorf("pointer read row %d expected %v; got %v", i, resultRows[i], c)
+			return
+		}
+	}
+	ptrRows = nil
+	if err = sv.WithMaxrows(rowsLimit).Query(ctx, "firstset", &ptrRows); err != nil || len(ptrRows) != rowsLimit {
+		t.Errorf("pointer read expected %d rows; got %d %v", rowsLimit, len(ptrRows), err)
+		return
+	}
+
 	err = sv.WithMaxrows(rowsLimit).Query(ctx, "firstsetx", &newRows)
 	notSuccess, ok := err.(*ctxclient.NotSuccess)
 	if !ok || notSuccess.StatusCode != 404 {
@@ -199,7 +224,7 @@ func (ct callTests) testService_Describe(t *testing.T) {
 }
 
 func (ct callTests) testService_GetDeleted(t *testing.T) {
-	start, end := time.Now().Add(30*24*time.Hour), time.Now()
+	start, end := time.Now().Add(-24*time.Hour), time.Now()
 	dels, err := ct.sv.GetDeletedRecords(ct.ctx401, "Contact", start, end)
 	ex, ok := err.(*ctxclient.NotSuccess)
 	if !ok || ex.StatusCode != 401 {
@@ -217,7 +242,7 @@ func (ct callTests) testService_GetDeleted(t *testing.T) {
 }
 
 func (ct callTests) testService_GetUpdated(t *testing.T) {
-	start, end := time.Now().Add(30*24*time.Hour), time.Now()
+	start, end := time.Now().Add(-24*time.Hour), time.Now()
 	upd, err := ct.sv.GetUpdatedRecords(ct.ctx401, "Contact", start, end)
 	ex, ok := err.(*ctxclient.NotSuccess)
 	if !ok || ex.StatusCode != 401 {
@@ -234,6 +259,52 @@ func (ct callTests) testService_GetUpdated(t *testing.T) {
 	}
 }
 
+func TestService_GetUpdated_TimeRange(t *testing.T) {
+	var gotStart, gotEnd string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotStart, gotEnd = r.URL.Query().Get("start"), r.URL.Query().Get("end")
+		encodeObject(w, salesforce.GetUpdatedResponse{IDs: []string{"003A"}})
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	now := time.Now()
+	tests := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{name: "reversed", start: now, end: now.Add(-time.Hour)},
+		{name: "same minute", start: now.Truncate(time.Minute), end: now.Truncate(time.Minute).Add(59 * time.Second)},
+		{name: "too old", start: now.AddDate(0, 0, -31), end: now},
+		{name: "zero", end: now},
+	}
+	for _, tt := range tests {
+		_, err := sv.GetUpdatedRecords(ctx, "Contact", tt.start, tt.end)
+		var tre *salesforce.TimeRangeError
+		if !errors.As(err, &tre) || !errors.Is(err, salesforce.ErrInvalidTimeRange) {
+			t.Errorf("%s: expected TimeRangeError; got %v", tt.name, err)
+		}
+		if _, err := sv.GetDeletedRecords(ctx, "Contact", tt.start, tt.end); !errors.Is(err, salesforce.ErrInvalidTimeRange) {
+			t.Errorf("%s: expected deleted ErrInvalidTimeRange; got %v", tt.name, err)
+		}
+	}
+	if gotStart != "" {
+		t.Fatalf("expected no calls for invalid ranges")
+	}
+
+	loc := time.FixedZone("EST", -5*60*60)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 1, 2, 33, 500, loc).AddDate(0, 0, -2)
+	if _, err := sv.GetUpdatedRecords(ctx, "Contact", start, start.Add(90*time.Second)); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if want := start.UTC().Truncate(time.Minute); gotStart != want.Format(time.RFC3339) ||
+		gotEnd != want.Add(2*time.Minute).Format(time.RFC3339) || !strings.HasSuffix(gotStart, ":00Z") {
+		t.Errorf("expected minute precision utc; got %s %s", gotStart, gotEnd)
+	}
+}
+
 func (ct callTests) testService_Create(t *testing.T) {
 	acctRecord := &Account{
 		AccountName: "My Account",
@@ -316,8 +387,8 @@ func (ct callTests) testService_Delete(t *testing.T) {
 }
 
 func (ct callTests) testService_Get(t *testing.T) {
-	var errstr00 = "unable to convert result ptr to an SObject"
-	var errstr01 = "expected result to be a non-nil pointer"
+	var errstr00 = "got *int (missing method SObjectName); int is not an SObject"
+	var errstr01 = "expected pointer to an SObject; got int; did you pass a value instead of a pointer?"
 	id := "a1f4S000000cj9mQAA"
 	flds := []string{"Name", "Type", "RecordType", "Vendor_ID__C"}
 	var resultA = 5
@@ -336,7 +407,7 @@ func (ct callTests) testService_Get(t *testing.T) {
 }
 
 func (ct callTests) testService_GetByExternalID(t *testing.T) {
-	var errstr00 = "unable to convert result ptr to an SObject"
+	var errstr00 = "got *int (missing method SObjectName); int is not an SObject"
 	id := "VN12345"
 	flds := []string{"Name", "Type", "RecordType", "Vendor_ID__C"}
 	var resultA = 5
@@ -376,6 +447,72 @@ func (ct callTests) testService_GetAttachment(t *testing.T) {
 
 }
 
+func (ct callTests) testService_HTTPBody(t *testing.T) {
+	body, err := ct.sv.GetAttachment(ct.ctxOK, "Attachment", "att4S000000cj9mQAA")
+	if err != nil {
+		t.Errorf("expected success; got %v", err)
+		return
+	}
+	buff := &bytes.Buffer{}
+	if numBytes, err := body.WriteTo(buff); err != nil || numBytes != 8316 {
+		t.Errorf("expected 8316 bytes; got %d %v", numBytes, err)
+	}
+	if err := body.Close(); err != nil {
+		t.Errorf("expected repeated close to succeed; got %v", err)
+	}
+
+	closed := make(chan struct{})
+	sv := ct.sv.WithCtxClientFunc(func(ctx context.Context) (*http.Client, error) {
+		cl, err := getTokenClientFunc()(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Client{Transport: closeNotifyTransport{RoundTripper: cl.Transport, closed: closed}}, nil
+	})
+	ctx, cancel := context.WithCancel(ct.ctxOK)
+	body, err = sv.GetAttachment(ctx, "Attachment", "att4S000000cj9mQAA")
+	cancel()
+	if err != nil {
+		t.Errorf("expected success; got %v", err)
+		return
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatalf("expected body to close when context is cancelled")
+	}
+	if _, err := io.Copy(ioutil.Discard, body); err == nil {
+		t.Errorf("expected read on closed body error")
+	}
+}
+
+// closeNotifyTransport wraps response bodies to close the closed
+// channel when the body is closed
+type closeNotifyTransport struct {
+	http.RoundTripper
+	closed chan struct{}
+}
+
+func (tr closeNotifyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	res, err := tr.RoundTripper.RoundTrip(r)
+	if err == nil {
+		res.Body = &closeNotifyBody{ReadCloser: res.Body, closed: tr.closed}
+	}
+	return res, err
+}
+
+type closeNotifyBody struct {
+	io.ReadCloser
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (b *closeNotifyBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { close(b.closed) })
+	return err
+}
+
 func (ct callTests) testService_CreateJob(t *testing.T) {
 	jc := &salesforce.JobDefinition{
 		Object:              "Account",
@@ -534,6 +671,47 @@ func (ct callTests) testService_ListJobs(t *testing.T) {
 	}
 }
 
+func (ct callTests) testService_Paginate(t *testing.T) {
+	var jobs []salesforce.Job
+	var pages int
+	err := ct.sv.Paginate(ct.ctxOK, "jobs/ingest/", func(page json.RawMessage) error {
+		var jl salesforce.JobList
+		if err := json.Unmarshal(page, &jl); err != nil {
+			return err
+		}
+		pages++
+		jobs = append(jobs, jl.Records...)
+		return nil
+	})
+	if err != nil || pages != 2 || len(jobs) != 4 {
+		t.Errorf("expected 2 pages and 4 jobs; got %d, %d %v", pages, len(jobs), err)
+	}
+	pages = 0
+	err = ct.sv.Paginate(ct.ctxOK, "jobs/ingest/", func(page json.RawMessage) error {
+		pages++
+		return salesforce.ErrStopPaging
+	})
+	if err != nil || pages != 1 {
+		t.Errorf("expected 1 page; got %d %v", pages, err)
+	}
+	var errPage = errors.New("page error")
+	if err = ct.sv.Paginate(ct.ctxOK, "jobs/ingest/", func(page json.RawMessage) error {
+		return errPage
+	}); err != errPage {
+		t.Errorf("expected page error; got %v", err)
+	}
+	ctx, cancel := context.WithCancel(ct.ctxOK)
+	pages = 0
+	err = ct.sv.Paginate(ctx, "jobs/ingest/", func(page json.RawMessage) error {
+		pages++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled || pages != 1 {
+		t.Errorf("expected 1 page and %v; got %d %v", context.Canceled, pages, err)
+	}
+}
+
 func (ct callTests) testService_QueryCreateJob(t *testing.T) {
 	bq := salesforce.BulkQuery{
 		Query:           "Select ID FROM Account",
@@ -554,10 +732,10 @@ func (ct callTests) testService_RetrieveRecords(t *testing.T) {
 	var flds = []string{"Id", "AccountId", "FirstName", "External_ID__c"}
 	var recs []Contact
 	var nilResults = "results parameter may not be nil"
-	var notptrslice = "results must be a pointer to a slice"
+	var notptrslice = "expected *[]<SObject>; got *salesforce_test.Contact; expected a pointer to a slice"
 	var contactPtr *Contact
 	var nrec []NotSObject
-	var notSObject = "NotSObject is not an SObject"
+	var notSObject = "expected *[]<SObject>; got *[]salesforce_test.NotSObject (missing method SObjectName); salesforce_test.NotSObject is not an SObject"
 	var tests = []struct {
 		name   string
 		cx     context.Context
@@ -640,6 +818,7 @@ func TestService_Call(t *testing.T) {
 	t.Run("get", ct.testService_Get)
 	t.Run("getByExternalID", ct.testService_GetByExternalID)
 	t.Run("getattachment", ct.testService_GetAttachment)
+	t.Run("httpbody", ct.testService_HTTPBody)
 	t.Run("createjob", ct.testService_CreateJob)
 	t.Run("updatejobdata", ct.testService_UploadJobData)
 	t.Run("closejob", ct.testService_CloseJob)
@@ -650,6 +829,7 @@ func TestService_Call(t *testing.T) {
 	t.Run("getfailedjobrecords", ct.testService_GetFailedJobRecords)
 	t.Run("getfailedjobrecords", ct.testService_GetUnprocessedJobRecords)
 	t.Run("listjobs", ct.testService_ListJobs)
+	t.Run("paginate", ct.testService_Paginate)
 	t.Run("querycreatejob", ct.testService_QueryCreateJob)
 	t.Run("retrieverecords", ct.testService_RetrieveRecords)
 }
@@ -802,7 +982,7 @@ func testSrvPatch(c context.Context, w http.ResponseWriter, r *http.Request) {
 			ContentType:         "CSV",
 			ExternalIDFieldName: "Vendor_ID__c",
 			Object:              "Account",
-			State:               args["state"],
+			State:               salesforce.JobState(args["state"]),
 		})
 		return
 	}
@@ -974,6 +1154,46 @@ func encodeObject(w http.ResponseWriter, obj interface{}) {
 	return
 }
 
+func TestService_BatchSizes(t *testing.T) {
+	var queryOpts []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queryOpts = append(queryOpts, r.Header.Get("Sforce-Query-Options"))
+		encodeObject(w, map[string]interface{}{"totalSize": 0, "done": true, "records": []interface{}{}})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	tests := []struct {
+		sv                *salesforce.Service
+		query, collection int
+	}{
+		{sv: sv, query: 2000, collection: 200},
+		{sv: sv.WithBatchSize(100), query: 200, collection: 100},
+		{sv: sv.WithBatchSize(500), query: 500, collection: 200},
+		{sv: sv.WithQueryBatchSize(1000), query: 1000, collection: 200},
+		{sv: sv.WithCollectionBatchSize(50).WithQueryBatchSize(5000), query: 2000, collection: 50},
+		{sv: sv.WithBatchSize(500).WithCollectionBatchSize(20), query: 500, collection: 20},
+		{sv: sv.WithQueryBatchSize(-1).WithCollectionBatchSize(-1), query: 2000, collection: 200},
+	}
+	for i, tt := range tests {
+		if tt.sv.QueryBatchSize() != tt.query || tt.sv.CollectionBatchSize() != tt.collection {
+			t.Errorf("test %d: expected %d/%d; got %d/%d", i, tt.query, tt.collection, tt.sv.QueryBatchSize(), tt.sv.CollectionBatchSize())
+		}
+		if tt.sv.MaxBatchSize() != tt.collection {
+			t.Errorf("test %d: expected MaxBatchSize %d; got %d", i, tt.collection, tt.sv.MaxBatchSize())
+		}
+		var recs []salesforce.RecordMap
+		if err := tt.sv.Query(ctx, "SELECT Id FROM Contact", &recs); err != nil {
+			t.Fatalf("test %d: query %v", i, err)
+		}
+		if want := fmt.Sprintf("batchSize=%d", tt.query); queryOpts[len(queryOpts)-1] != want {
+			t.Errorf("test %d: expected %s; got %s", i, want, queryOpts[len(queryOpts)-1])
+		}
+	}
+}
+
 func TestDeleteID(t *testing.T) {
 	var val salesforce.DeleteID = "DELID"
 
@@ -1012,3 +1232,408 @@ func TestOpResponse_SObjectValue(t *testing.T) {
 		})
 	}
 }
+
+func TestService_ResolveURL(t *testing.T) {
+	sv := salesforce.New("example.my.salesforce.com", "v53.0", nil)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "query/01g-2000", want: "https://example.my.salesforce.com/services/data/v53.0/query/01g-2000"},
+		{path: "/services/data/v53.0/query/01g-2000", want: "https://example.my.salesforce.com/services/data/v53.0/query/01g-2000"},
+		{path: "services/data/v53.0/query/01g-2000", want: "https://example.my.salesforce.com/services/data/v53.0/query/01g-2000"},
+		{path: "v52.0/query/01g-2000", want: "https://example.my.salesforce.com/services/data/v52.0/query/01g-2000"},
+		{path: "https://example.my.salesforce.com/services/data/v53.0/query/01g-2000", want: "https://example.my.salesforce.com/services/data/v53.0/query/01g-2000"},
+		{path: "query/?q=SELECT+Id+FROM+Contact", want: "https://example.my.salesforce.com/services/data/v53.0/query/?q=SELECT+Id+FROM+Contact"},
+	}
+	for _, tt := range tests {
+		u, err := sv.ResolveURL(tt.path)
+		if err != nil {
+			t.Errorf("%s: expected success; got %v", tt.path, err)
+			continue
+		}
+		if u.String() != tt.want {
+			t.Errorf("%s: expected %s; got %s", tt.path, tt.want, u.String())
+		}
+	}
+	for _, foreign := range []string{
+		"https://other.my.salesforce.com/services/data/v53.0/query/01g-2000",
+		"//other.my.salesforce.com/services/data/v53.0/query/01g-2000",
+	} {
+		if u, err := sv.ResolveURL(foreign); err == nil {
+			t.Errorf("%s: expected foreign host error; got %v", foreign, u)
+		}
+	}
+	var nilsv *salesforce.Service
+	if _, err := nilsv.ResolveURL("query"); err == nil || err.Error() != "nil baseURL" {
+		t.Errorf("expected nil baseURL; got %v", err)
+	}
+}
+
+type xmlRecord struct {
+	XMLName xml.Name `xml:"record"`
+	Name    string   `xml:"Name"`
+}
+
+func TestService_WithEncoding(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/xml; charset=UTF-8" || r.Header.Get("Accept") != "application/xml" {
+			http.Error(w, "invalid headers", http.StatusBadRequest)
+			return
+		}
+		var rec xmlRecord
+		if err := xml.NewDecoder(r.Body).Decode(&rec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rec.Name = rec.Name + " Updated"
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(rec)
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithEncoding(salesforce.XML)
+
+	var result xmlRecord
+	if err := sv.Call(ctx, "xml", "POST", xmlRecord{Name: "Rec"}, &result); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if result.Name != "Rec Updated" {
+		t.Errorf("expected Rec Updated; got %s", result.Name)
+	}
+	if err := sv.Call(ctx, "xml", "POST", map[string]string{"a": "b"}, &result); err == nil {
+		t.Errorf("expected xml marshal error for map")
+	}
+	var contacts []Contact
+	if err := sv.Query(ctx, "SELECT Id FROM Contact", &contacts); err != salesforce.ErrJSONEncodingRequired {
+		t.Errorf("query expected %v; got %v", salesforce.ErrJSONEncodingRequired, err)
+	}
+	if _, err := sv.CreateRecords(ctx, false, []salesforce.SObject{Contact{LastName: "X"}}); err != salesforce.ErrJSONEncodingRequired {
+		t.Errorf("create records expected %v; got %v", salesforce.ErrJSONEncodingRequired, err)
+	}
+}
+
+func TestService_WithAutoTruncate(t *testing.T) {
+	var bodies []map[string]interface{}
+	var assignHdr string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		assignHdr = r.Header.Get("Sforce-Auto-Assign")
+		encodeObject(w, salesforce.OpResponse{ID: "ID", Success: true})
+	}))
+	defer ws.Close()
+
+	def := &salesforce.SObjectDefinition{
+		Name: "Account",
+		Fields: []salesforce.Field{
+			{Name: "Name", Length: 5, SoapType: "xsd:string"},
+			{Name: "Website", Length: 3, SoapType: "xsd:string"},
+		},
+	}
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithAutoTruncate(def).WithAutoAssign(false)
+	var truncated []string
+	sv = sv.WithTruncateFunc(func(rec salesforce.SObject, fields []string) {
+		truncated = append(truncated, fmt.Sprintf("%T%v", rec, fields))
+	})
+
+	acct := Account{AccountName: "Account Name", Website: "héllo"}
+	if _, err := sv.Create(ctx, acct); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if _, err := sv.Create(ctx, salesforce.RecordMap{"attributes": map[string]string{"type": "Account"}, "Name": "Map Account"}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(bodies) != 2 || bodies[0]["Name"] != "Accou" || bodies[0]["Website"] != "hél" || bodies[1]["Name"] != "Map A" {
+		t.Errorf("expected truncated values; got %v", bodies)
+	}
+	if acct.AccountName != "Account Name" {
+		t.Errorf("expected original record unchanged; got %s", acct.AccountName)
+	}
+	if assignHdr != "FALSE" {
+		t.Errorf("expected Sforce-Auto-Assign FALSE; got %s", assignHdr)
+	}
+	if err := sv.WithAutoTruncate().Update(ctx, acct, "ID"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(bodies) != 3 || bodies[2]["Name"] != "Account Name" {
+		t.Errorf("expected untruncated value; got %v", bodies[2])
+	}
+	ptr := &Account{AccountName: "Pointer Account"}
+	if _, err := sv.Create(ctx, ptr); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(bodies) != 4 || bodies[3]["Name"] != "Point" || ptr.AccountName != "Pointer Account" {
+		t.Errorf("expected truncated copy of pointer record; got %v %s", bodies[3], ptr.AccountName)
+	}
+	if _, err := sv.Create(ctx, Account{AccountName: "Short", Website: "hé"}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	want := []string{
+		"salesforce_test.Account[Name Website]",
+		"salesforce.RecordMap[Name]",
+		"*salesforce_test.Account[Name]",
+	}
+	if strings.Join(truncated, ",") != strings.Join(want, ",") {
+		t.Errorf("expected truncated fields %v; got %v", want, truncated)
+	}
+}
+
+func TestService_WithDuplicateRuleHeader(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hdr := r.Header.Get("Sforce-Duplicate-Rule-Header"); hdr != "allowSave=false, includeRecordDetails=true, runAsCurrentUser=false" {
+			http.Error(w, "invalid header "+hdr, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `[{"duplicateResult":{"allowSave":false,"duplicateRule":"Standard_Account_Duplicate_Rule",`+
+			`"matchResults":[{"entityType":"Account","matchRecords":[{"matchConfidence":100,"record":{"Id":"001A","Name":"Acme"}}]}]},`+
+			`"errorCode":"DUPLICATES_DETECTED","message":"Use one of these records?"}]`)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithDuplicateRuleHeader(&salesforce.DuplicateRuleHeader{IncludeRecordDetails: true})
+	_, err := sv.Create(ctx, Account{AccountName: "Acme"})
+	if err == nil {
+		t.Fatalf("expected duplicate error")
+	}
+	drs := salesforce.DuplicateResults(err)
+	if len(drs) != 1 || drs[0].DuplicateRule != "Standard_Account_Duplicate_Rule" || len(drs[0].MatchResults) != 1 {
+		t.Fatalf("expected duplicate result; got %#v", drs)
+	}
+	if rec := drs[0].MatchResults[0].MatchRecords[0].Record; rec["Id"] != "001A" {
+		t.Errorf("expected matched record 001A; got %v", rec)
+	}
+	if salesforce.DuplicateResults(errors.New("other")) != nil {
+		t.Errorf("expected nil results for non-api error")
+	}
+}
+
+func TestService_CreateWithDuplicateRule(t *testing.T) {
+	var hdrs []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hdr := r.Header.Get("Sforce-Duplicate-Rule-Header")
+		hdrs = append(hdrs, hdr)
+		switch {
+		case strings.HasPrefix(hdr, "allowSave=true"):
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"id":"001B","success":true,"errors":[]}`)
+		case r.URL.Path == "/sobjects/Account/Vendor_ID__c/V1":
+			http.Error(w, `[{"errorCode":"INVALID_FIELD","message":"bad field"}]`, http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `[{"duplicateResult":{"allowSave":true,"duplicateRule":"Standard_Account_Duplicate_Rule",`+
+				`"matchResults":[{"entityType":"Account","matchRecords":[{"matchConfidence":100,"record":{"Id":"001A","Name":"Acme"}}]}]},`+
+				`"errorCode":"DUPLICATES_DETECTED","message":"Use one of these records?"}]`)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	_, err := sv.CreateWithDuplicateRule(ctx, Account{AccountName: "Acme"}, &salesforce.DuplicateRuleHeader{IncludeRecordDetails: true})
+	var de *salesforce.DuplicateError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected DuplicateError; got %v", err)
+	}
+	if ids := de.MatchIDs(); len(ids) != 1 || ids[0] != "001A" || de.Message != "Use one of these records?" || de.StatusCode != 400 {
+		t.Errorf("unexpected duplicate error %#v", de)
+	}
+	var ns *ctxclient.NotSuccess
+	if !errors.As(err, &ns) {
+		t.Errorf("expected wrapped NotSuccess")
+	}
+	res, err := sv.CreateWithDuplicateRule(ctx, Account{AccountName: "Acme"}, &salesforce.DuplicateRuleHeader{AllowSave: true})
+	if err != nil || res.ID != "001B" {
+		t.Errorf("expected created 001B; got %v %v", res, err)
+	}
+	_, err = sv.UpsertWithDuplicateRule(ctx, Account{AccountName: "Acme"}, "Vendor_ID__c", "V1", nil)
+	if _, ok := err.(*ctxclient.NotSuccess); !ok {
+		t.Errorf("expected NotSuccess for non-duplicate error; got %v", err)
+	}
+	if len(hdrs) != 3 || hdrs[0] != "allowSave=false, includeRecordDetails=true, runAsCurrentUser=false" || hdrs[2] != "" {
+		t.Errorf("unexpected headers %q", hdrs)
+	}
+}
+
+func TestService_FindOrCreate(t *testing.T) {
+	var calls []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /query/":
+			var recs []map[string]string
+			switch r.URL.Query().Get("q") {
+			case "SELECT Id FROM Contact WHERE Email = 'one@example.com' LIMIT 2":
+				recs = append(recs, map[string]string{"Id": "C1"})
+			case "SELECT Id FROM Contact WHERE Email = 'many@example.com' LIMIT 2":
+				recs = append(recs, map[string]string{"Id": "C1"}, map[string]string{"Id": "C2"})
+			}
+			encodeObject(w, map[string]interface{}{"done": true, "records": recs})
+		case "POST /sobjects/Contact":
+			encodeObject(w, salesforce.OpResponse{ID: "NEW", Success: true, Created: true})
+		case "PATCH /sobjects/Contact/C1":
+			w.WriteHeader(http.StatusNoContent)
+		case "PATCH /sobjects/Contact/PID__c/P001":
+			encodeObject(w, salesforce.OpResponse{ID: "C9", Success: true})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	tests := []struct {
+		rec     salesforce.SObject
+		filter  string
+		extID   string
+		id      string
+		created bool
+		err     error
+		call    string
+	}{
+		{rec: Contact{LastName: "A"}, filter: "Email = 'one@example.com'", id: "C1", call: "PATCH /sobjects/Contact/C1"},
+		{rec: Contact{LastName: "B"}, filter: "Email = 'none@example.com'", id: "NEW", created: true, call: "POST /sobjects/Contact"},
+		{rec: Contact{LastName: "C"}, filter: "Email = 'many@example.com'", err: salesforce.ErrMultipleMatches, call: "GET /query/"},
+		{rec: Contact{LastName: "D", ExternalPID: "P001"}, extID: "PID__c", id: "C9", call: "PATCH /sobjects/Contact/PID__c/P001"},
+		{rec: Contact{LastName: "E"}, filter: "Email = 'none@example.com'", extID: "PID__c", id: "NEW", created: true, call: "POST /sobjects/Contact"},
+	}
+	for i, tt := range tests {
+		calls = nil
+		id, created, err := sv.FindOrCreate(ctx, tt.rec, tt.filter, tt.extID)
+		if id != tt.id || created != tt.created || err != tt.err {
+			t.Errorf("test %d: expected %s %v %v; got %s %v %v", i, tt.id, tt.created, tt.err, id, created, err)
+		}
+		if len(calls) == 0 || calls[len(calls)-1] != tt.call {
+			t.Errorf("test %d: expected last call %s; got %v", i, tt.call, calls)
+		}
+	}
+}
+
+func TestService_ValidateFields(t *testing.T) {
+	defs := map[string]salesforce.SObjectDefinition{
+		"Contact": {Name: "Contact", Fields: []salesforce.Field{
+			{Name: "Id"}, {Name: "AccountId", RelationshipName: "Account", ReferenceTo: []string{"Account"}},
+		}},
+		"Account": {Name: "Account", Fields: []salesforce.Field{
+			{Name: "Id"}, {Name: "Name"}, {Name: "OwnerId", RelationshipName: "Owner", ReferenceTo: []string{"User"}},
+		}},
+		"User": {Name: "User", Fields: []salesforce.Field{{Name: "Id"}, {Name: "Email"}}},
+	}
+	var describes int
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nm := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/sobjects/"), "/describe")
+		def, ok := defs[nm]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		describes++
+		encodeObject(w, def)
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	if err := sv.ValidateFields(ctx, "Contact", "Id", "account.name", "Account.Owner.Email", "Account.Id"); err != nil {
+		t.Errorf("expected success; got %v", err)
+	}
+	if describes != 3 {
+		t.Errorf("expected 3 describes; got %d", describes)
+	}
+	tests := []struct {
+		field  string
+		errMsg string
+	}{
+		{"Account.Nme", "Account.Nme: Account has no field Nme"},
+		{"Acct.Name", "Acct.Name: Contact has no relationship Acct"},
+		{"Owner", "Owner: Contact has no field Owner"},
+	}
+	for _, tt := range tests {
+		if err := sv.ValidateFields(ctx, "Contact", tt.field); err == nil || err.Error() != tt.errMsg {
+			t.Errorf("expected %s; got %v", tt.errMsg, err)
+		}
+	}
+}
+
+func TestService_WithTooling(t *testing.T) {
+	var paths []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		encodeObject(w, map[string]interface{}{"totalSize": 0, "done": true, "records": []interface{}{}})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/").WithTooling().WithTooling()
+	var results []salesforce.RecordMap
+	if err := sv.Query(ctx, "SELECT Id, Name FROM ApexClass", &results); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/services/data/v55.0/tooling/query/" {
+		t.Errorf("expected tooling query path; got %v", paths)
+	}
+	if v := sv.APIVersion(); v != "v55.0" {
+		t.Errorf("expected api version v55.0; got %s", v)
+	}
+	if v := salesforce.New("aninstance.my.salesforce", "", nil).APIVersion(); v != "v53.0" {
+		t.Errorf("expected api version v53.0; got %s", v)
+	}
+}
+
+func TestService_Call_rewindableBody(t *testing.T) {
+	var bodies []string
+	var lengths []int64
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		lengths = append(lengths, r.ContentLength)
+		if !strings.HasPrefix(r.URL.Path, "/moved/") {
+			http.Redirect(w, r, "/moved"+r.URL.Path, http.StatusTemporaryRedirect)
+			return
+		}
+		if r.Method == "POST" {
+			encodeObject(w, salesforce.OpResponse{ID: "003A", Success: true})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	f, err := ioutil.TempFile("", "upload*.csv")
+	if err != nil {
+		t.Fatalf("tempfile %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("Id,Name\n001A,Acme\n")
+	f.Close()
+	if err := sv.UploadJobDataFile(ctx, "JOB0000", f.Name()); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] != "Id,Name\n001A,Acme\n" || lengths[1] != 18 {
+		t.Errorf("expected file body resent on redirect; got %q %v", bodies, lengths)
+	}
+
+	bodies, lengths = nil, nil
+	if _, err := sv.Create(ctx, Contact{LastName: "Smith"}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], `"LastName":"Smith"`) || lengths[1] <= 0 {
+		t.Errorf("expected encoded body resent on redirect; got %q %v", bodies, lengths)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cdc decodes Change Data Capture event payloads and merges
+// changes into the structs created by genpkgs.
+// https://developer.salesforce.com/docs/atlas.en-us.change_data_capture.meta/change_data_capture/cdc_intro.htm
+package cdc // import github.com/jfcote87/salesforce/cdc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// Change types
+const (
+	Create       = "CREATE"
+	Update       = "UPDATE"
+	Delete       = "DELETE"
+	Undelete     = "UNDELETE"
+	GapCreate    = "GAP_CREATE"
+	GapUpdate    = "GAP_UPDATE"
+	GapDelete    = "GAP_DELETE"
+	GapUndelete  = "GAP_UNDELETE"
+	GapOverflow  = "GAP_OVERFLOW"
+	SyncCreate   = "SYNC_CREATE"
+	SyncUpdate   = "SYNC_UPDATE"
+	SyncDelete   = "SYNC_DELETE"
+	SyncUndelete = "SYNC_UNDELETE"
+)
+
+const headerJSONName = "ChangeEventHeader"
+
+// ChangeEventHeader describes the change of a change event
+// https://developer.salesforce.com/docs/atlas.en-us.change_data_capture.meta/change_data_capture/cdc_event_fields_header.htm
+type ChangeEventHeader struct {
+	EntityName      string   `json:"entityName"`
+	RecordIDs       []string `json:"recordIds"`
+	ChangeType      string   `json:"changeType"`
+	ChangeOrigin    string   `json:"changeOrigin"`
+	TransactionKey  string   `json:"transactionKey"`
+	SequenceNumber  int      `json:"sequenceNumber"`
+	CommitTimestamp int64    `json:"commitTimestamp"` // milliseconds since epoch
+	CommitNumber    int64    `json:"commitNumber"`
+	CommitUser      string   `json:"commitUser"`
+	ChangedFields   []string `json:"changedFields"`
+	DiffFields      []string `json:"diffFields"`
+	NulledFields    []string `json:"nulledFields"`
+}
+
+// IsGap returns true for gap events which contain no field values.  Retrieve
+// the records to obtain their current values.
+func (h *ChangeEventHeader) IsGap() bool {
+	return strings.HasPrefix(h.ChangeType, "GAP_")
+}
+
+// Event is a change event received from the streaming api
+type Event struct {
+	Schema  string          `json:"schema"`
+	Payload json.RawMessage `json:"payload"`
+	Event   struct {
+		ReplayID int64 `json:"replayId"`
+	} `json:"event"`
+}
+
+// ReplayID returns the replay id of the event
+func (e *Event) ReplayID() int64 {
+	return e.Event.ReplayID
+}
+
+// Header decodes the ChangeEventHeader of the event's payload
+func (e *Event) Header() (*ChangeEventHeader, error) {
+	return DecodeHeader(e.Payload)
+}
+
+// DecodeHeader decodes the ChangeEventHeader of a change event payload
+func DecodeHeader(payload json.RawMessage) (*ChangeEventHeader, error) {
+	var p struct {
+		Header *ChangeEventHeader `json:"ChangeEventHeader"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	if p.Header == nil {
+		return nil, errors.New("payload has no ChangeEventHeader")
+	}
+	return p.Header, nil
+}
+
+// DecodeBitmap converts the hex bitmaps of the changedFields, diffFields or
+// nulledFields of a Pub/Sub API event into field names.  fields lists the
+// event schema's field names in schema order.  A bitmap of the form
+// "n-0x..." refers to the nested fields of compound field n and requires
+// nested[n] to list the compound field's schema fields.  Nested fields are
+// returned as <compound>.<field> (e.g. Name.LastName).
+// https://developer.salesforce.com/docs/platform/pub-sub-api/guide/event-deserialization-considerations.html
+func DecodeBitmap(bitmaps []string, fields []string, nested map[int][]string) ([]string, error) {
+	var names []string
+	for _, bm := range bitmaps {
+		flds, prefix := fields, ""
+		if idx := strings.Index(bm, "-"); idx > 0 {
+			n, err := strconv.Atoi(bm[:idx])
+			if err != nil || n < 0 || n >= len(fields) {
+				return nil, fmt.Errorf("invalid bitmap %s", bm)
+			}
+			if flds = nested[n]; flds == nil {
+				return nil, fmt.Errorf("bitmap %s: no nested fields for %s", bm, fields[n])
+			}
+			prefix = fields[n] + "."
+			bm = bm[idx+1:]
+		}
+		bits, ok := new(big.Int).SetString(strings.TrimPrefix(strings.ToLower(bm), "0x"), 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid bitmap %s", bm)
+		}
+		for i := 0; i < bits.BitLen(); i++ {
+			if bits.Bit(i) == 0 {
+				continue
+			}
+			if i >= len(flds) {
+				return nil, fmt.Errorf("bitmap %s: bit %d exceeds %d fields", bm, i, len(flds))
+			}
+			names = append(names, prefix+flds[i])
+		}
+	}
+	return names, nil
+}
+
+// Merge applies the changes of a change event payload to dst, a pointer to a struct
+// (e.g. a genpkgs generated struct).  Payload fields are matched to struct fields
+// using the field's json tag name.  For CREATE and UNDELETE events, all non-null
+// payload fields are set.  Otherwise only changedFields are set and nulledFields
+// are set to zero values.  A changed compound field (e.g. Name.LastName) is read
+// from the compound object and set using the nested name.  Gap events return an error.
+func Merge(dst interface{}, payload json.RawMessage) (*ChangeEventHeader, error) {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected pointer to a struct; got %v", reflect.TypeOf(dst))
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return nil, err
+	}
+	hdr, err := DecodeHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.IsGap() {
+		return hdr, fmt.Errorf("%s event contains no field values", hdr.ChangeType)
+	}
+	val := ptr.Elem()
+	fieldMap := jsonfield.Index(val.Type())
+	var changed []string
+	switch hdr.ChangeType {
+	case Create, Undelete, SyncCreate, SyncUndelete:
+		for nm, raw := range values {
+			if nm != headerJSONName && string(raw) != "null" {
+				changed = append(changed, nm)
+			}
+		}
+	default:
+		changed = hdr.ChangedFields
+	}
+	for _, nm := range changed {
+		raw, fldName, ok := lookupValue(values, nm)
+		if !ok {
+			continue
+		}
+		idx, ok := fieldMap[fldName]
+		if !ok {
+			continue
+		}
+		fld := val.FieldByIndex(idx)
+		if string(raw) == "null" {
+			fld.Set(reflect.Zero(fld.Type()))
+			continue
+		}
+		nv := reflect.New(fld.Type())
+		if err := json.Unmarshal(raw, nv.Interface()); err != nil {
+			return hdr, fmt.Errorf("%s: %w", nm, err)
+		}
+		fld.Set(nv.Elem())
+	}
+	for _, nm := range hdr.NulledFields {
+		if idx := strings.LastIndex(nm, "."); idx >= 0 {
+			nm = nm[idx+1:]
+		}
+		if idx, ok := fieldMap[nm]; ok {
+			fld := val.FieldByIndex(idx)
+			fld.Set(reflect.Zero(fld.Type()))
+		}
+	}
+	return hdr, nil
+}
+
+// lookupValue returns the payload value of a field name.  Compound names
+// (Name.LastName) are read from the compound field's object.
+func lookupValue(values map[string]json.RawMessage, nm string) (json.RawMessage, string, bool) {
+	idx := strings.Index(nm, ".")
+	if idx < 0 {
+		raw, ok := values[nm]
+		return raw, nm, ok
+	}
+	var compound map[string]json.RawMessage
+	if err := json.Unmarshal(values[nm[:idx]], &compound); err != nil || compound == nil {
+		return nil, "", false
+	}
+	return lookupValue(compound, nm[idx+1:])
+}
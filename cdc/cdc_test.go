@@ -0,0 +1,94 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cdc_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+	"github.com/jfcote87/salesforce/cdc"
+)
+
+type Contact struct {
+	Attributes *salesforce.Attributes `json:"attributes,omitempty"`
+	ContactID  string                 `json:"Id,omitempty"`
+	FirstName  string                 `json:"FirstName,omitempty"`
+	LastName   string                 `json:"LastName,omitempty"`
+	Email      string                 `json:"Email,omitempty"`
+	Score      *float64               `json:"Score__c,omitempty"`
+}
+
+func TestDecodeBitmap(t *testing.T) {
+	fields := []string{"ChangeEventHeader", "Name", "Email", "Phone", "Score__c"}
+	nested := map[int][]string{1: {"Salutation", "FirstName", "LastName"}}
+	got, err := cdc.DecodeBitmap([]string{"0x14", "1-0x04"}, fields, nested)
+	want := []string{"Email", "Score__c", "Name.LastName"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v; got %v %v", want, got, err)
+	}
+	for _, bm := range []string{"0xZZ", "0x40", "9-0x01", "3-0x01"} {
+		if _, err := cdc.DecodeBitmap([]string{bm}, fields, nested); err == nil {
+			t.Errorf("%s: expected error", bm)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	score := 5.0
+	rec := Contact{ContactID: "003A", FirstName: "Jim", LastName: "Smith", Email: "jim@example.com", Score: &score}
+	var ev cdc.Event
+	err := json.Unmarshal([]byte(`{"schema":"abc","event":{"replayId":42},"payload":{
+		"ChangeEventHeader":{"entityName":"Contact","recordIds":["003A"],"changeType":"UPDATE",
+			"transactionKey":"tk1","sequenceNumber":1,"commitTimestamp":1650000000000,
+			"changedFields":["Name.LastName","Score__c"],"nulledFields":["Email"]},
+		"Name":{"FirstName":null,"LastName":"Jones"},"Email":null,"Score__c":null}}`), &ev)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if ev.ReplayID() != 42 {
+		t.Errorf("expected replay id 42; got %d", ev.ReplayID())
+	}
+	hdr, err := cdc.Merge(&rec, ev.Payload)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if hdr.TransactionKey != "tk1" || hdr.ChangeType != cdc.Update || len(hdr.RecordIDs) != 1 {
+		t.Errorf("unexpected header %#v", hdr)
+	}
+	if rec.FirstName != "Jim" || rec.LastName != "Jones" || rec.Email != "" || rec.Score != nil {
+		t.Errorf("unexpected merged record %#v", rec)
+	}
+
+	var created Contact
+	_, err = cdc.Merge(&created, json.RawMessage(`{"ChangeEventHeader":{"changeType":"CREATE"},
+		"Name":null,"FirstName":"Ann","Email":"ann@example.com","Score__c":7}`))
+	if err != nil || created.FirstName != "Ann" || created.Email != "ann@example.com" || created.Score == nil || *created.Score != 7 {
+		t.Errorf("unexpected created record %#v %v", created, err)
+	}
+
+	// fields of embedded structs are merged
+	var ext struct {
+		Contact
+		Region string `json:"Region__c"`
+	}
+	_, err = cdc.Merge(&ext, json.RawMessage(`{"ChangeEventHeader":{"changeType":"UPDATE","changedFields":["Email","Region__c"]},
+		"Email":"ext@example.com","Region__c":"West"}`))
+	if err != nil || ext.Email != "ext@example.com" || ext.Region != "West" {
+		t.Errorf("unexpected embedded record %#v %v", ext, err)
+	}
+
+	if _, err := cdc.Merge(&created, json.RawMessage(`{"ChangeEventHeader":{"changeType":"GAP_UPDATE"}}`)); err == nil {
+		t.Errorf("expected gap event error")
+	}
+	if _, err := cdc.Merge(created, json.RawMessage(`{}`)); err == nil {
+		t.Errorf("expected pointer error")
+	}
+	if _, err := cdc.Merge(&created, json.RawMessage(`{"Email":"x"}`)); err == nil {
+		t.Errorf("expected missing header error")
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CollectionOptions configures an sobject collections call
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections.htm
+type CollectionOptions struct {
+	AllOrNone bool // roll back the batch when any record fails
+	// DuplicateRule overrides the service's Sforce-Duplicate-Rule-Header
+	// (e.g. AllowSave to permit duplicates) for the call.
+	DuplicateRule *DuplicateRuleHeader
+	// BatchSize overrides the service's batch size.  Values are limited to 1-200.
+	BatchSize int
+	// Concurrency is the number of batches sent simultaneously.  Values
+	// less than 2 send batches sequentially.  When sent concurrently, the
+	// service's BatchLogFunc is not called in batch order.
+	Concurrency int
+	// Strategy, when set, determines the batch size and concurrency of each batch
+	// and replaces BatchSize and Concurrency (see AdaptiveBatch).
+	Strategy BatchStrategy
+	// WriteBackIDs assigns the id of each successful OpResponse to its record
+	// using SetID.  If SetID is nil, the id is set on records implementing
+	// SObjectWithID and on RecordMaps.
+	WriteBackIDs bool
+	SetID        func(rec SObject, id string)
+	// Progress is called after each batch completes.  Calls are not
+	// concurrent, but are not in batch order when Concurrency is set.
+	Progress func(BatchProgress)
+}
+
+// BatchProgress reports the completion of a batch of a collection call
+type BatchProgress struct {
+	Batch     int           // zero based number of the completed batch
+	Completed int           // number of batches completed
+	Batches   int           // total number of batches
+	Records   int           // records sent in completed batches
+	Errors    int           // unsuccessful records in completed batches
+	Elapsed   time.Duration // time since the call started
+	Err       error         // error returned by the batch
+}
+
+// progressFunc returns a func reporting batch completion to opts.Progress.
+// The returned func is not safe for concurrent use.
+func (opts CollectionOptions) progressFunc(cnt, batchSz int) func(batch, records int, res []OpResponse, err error) {
+	if opts.Progress == nil {
+		return func(int, int, []OpResponse, error) {}
+	}
+	var start = time.Now()
+	var bp = BatchProgress{Batches: (cnt + batchSz - 1) / batchSz}
+	return func(batch, records int, res []OpResponse, err error) {
+		bp.Batch, bp.Err = batch, err
+		bp.Completed++
+		bp.Records += records
+		for _, r := range res {
+			if !r.Success {
+				bp.Errors++
+			}
+		}
+		bp.Elapsed = time.Since(start)
+		opts.Progress(bp)
+	}
+}
+
+// writeBackIDs assigns response ids to their records
+func (opts CollectionOptions) writeBackIDs(res []OpResponse) {
+	if !opts.WriteBackIDs {
+		return
+	}
+	for _, r := range res {
+		if !r.Success || r.ID == "" || r.SObject == nil {
+			continue
+		}
+		if opts.SetID != nil {
+			opts.SetID(r.SObject, r.ID)
+			continue
+		}
+		switch rec := r.SObject.(type) {
+		case SObjectWithID:
+			rec.SetID(r.ID)
+		case RecordMap:
+			rec["Id"] = r.ID
+		}
+	}
+}
+
+// collectionOptions returns the first opts value
+func collectionOptions(opts []CollectionOptions) CollectionOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return CollectionOptions{}
+}
+
+// withOptions returns a service with the options' batch size and duplicate rule
+func (sv *Service) withOptions(opts CollectionOptions) *Service {
+	snew := sv.clone()
+	if opts.BatchSize > 0 {
+		snew.collectionBatchSize = opts.BatchSize
+	}
+	if opts.DuplicateRule != nil {
+		snew.duplicateRules = opts.DuplicateRule.String()
+	}
+	return snew
+}
+
+// CreateRecordsWithOptions inserts records from recs.  Only the first opts value is used.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_create.htm
+func (sv *Service) CreateRecordsWithOptions(ctx context.Context, recs []SObject, opts ...CollectionOptions) ([]OpResponse, error) {
+	return sv.CompositeCallWithOptions(ctx, "composite/sobjects", "POST", recs, opts...)
+}
+
+// UpdateRecordsWithOptions updates records from recs.  Only the first opts value is used.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_update.htm
+func (sv *Service) UpdateRecordsWithOptions(ctx context.Context, recs []SObject, opts ...CollectionOptions) ([]OpResponse, error) {
+	return sv.CompositeCallWithOptions(ctx, "composite/sobjects", "PATCH", recs, opts...)
+}
+
+// UpsertRecordsWithOptions updates/inserts records based upon the external id field.  All recs
+// must be of the same Object Type.  An empty externalIDField uses the default registered by
+// WithExternalIDs.  Only the first opts value is used.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_upsert.htm
+func (sv *Service) UpsertRecordsWithOptions(ctx context.Context, externalIDField string, recs []SObject, opts ...CollectionOptions) ([]OpResponse, error) {
+	if len(recs) == 0 {
+		return nil, ErrZeroRecords
+	}
+	externalIDField, err := sv.externalIDField(recs[0].SObjectName(), externalIDField)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("composite/sobjects/%s/%s", recs[0].SObjectName(), externalIDField)
+	return sv.withMaskField(externalIDField).CompositeCallWithOptions(ctx, path, "PATCH", recs, opts...)
+}
+
+// DeleteRecordsWithOptions deletes records from the list of ids.  Only the first opts value is used.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_delete.htm
+func (sv *Service) DeleteRecordsWithOptions(ctx context.Context, ids []string, opts ...CollectionOptions) ([]OpResponse, error) {
+	if len(ids) <= 0 {
+		return nil, ErrZeroRecords
+	}
+	o := collectionOptions(opts)
+	return sv.withOptions(o).runBatches(ctx, len(ids), o, func(ctx context.Context, svx *Service, start, end, batch int) ([]OpResponse, []SObject, error) {
+		delIDs := ids[start:end]
+		path := "composite/sobjects?ids=" + strings.Join(delIDs, ",")
+		if o.AllOrNone {
+			path += "&allOrNone=true"
+		}
+		var res []OpResponse
+		if err := svx.Call(ctx, path, "DELETE", nil, &res); err != nil {
+			return nil, nil, err
+		}
+		var delrecids = make([]SObject, 0, len(delIDs))
+		for _, s := range delIDs {
+			delrecids = append(delrecids, DeleteID(s))
+		}
+		setRecordIndexes(res, delrecids, start, batch)
+		return res, delrecids, nil
+	})
+}
+
+// DeleteSObjects deletes recs using the id returned by each record's GetID method.  Only
+// the first opts value is used.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_delete.htm
+func (sv *Service) DeleteSObjects(ctx context.Context, recs []SObject, opts ...CollectionOptions) ([]OpResponse, error) {
+	if len(recs) == 0 {
+		return nil, ErrZeroRecords
+	}
+	var ids = make([]string, 0, len(recs))
+	for i, r := range recs {
+		id := RecordID(r)
+		if id == "" {
+			return nil, fmt.Errorf("record %d (%s) has no id", i, r.SObjectName())
+		}
+		ids = append(ids, id)
+	}
+	return sv.DeleteRecordsWithOptions(ctx, ids, opts...)
+}
+
+// CompositeCallWithOptions updates/inserts/upserts all records in batches.  Only the
+// first opts value is used.  A done context returns the OpResponses of completed
+// batches along with the context's error.
+func (sv *Service) CompositeCallWithOptions(ctx context.Context, path, method string, recs []SObject, opts ...CollectionOptions) ([]OpResponse, error) {
+	if len(recs) == 0 {
+		return nil, ErrZeroRecords
+	}
+	o := collectionOptions(opts)
+	return sv.withOptions(o).runBatches(ctx, len(recs), o, func(ctx context.Context, svx *Service, start, end, batch int) ([]OpResponse, []SObject, error) {
+		cmdRecs := make([]SObject, 0, end-start)
+		for _, r := range recs[start:end] {
+			cmdRecs = append(cmdRecs, svx.truncate(r.WithAttr("")))
+		}
+		body := BatchBody{AllOrNone: o.AllOrNone, Records: cmdRecs}
+		var res []OpResponse
+		if err := svx.Call(ctx, path, method, body, &res); err != nil {
+			return nil, nil, err
+		}
+		if method == "PATCH" {
+			var err error
+			res, err = svx.retryLockedRecords(ctx, o.AllOrNone, res, func(idxs []int) ([]OpResponse, error) {
+				retry := BatchBody{AllOrNone: o.AllOrNone, Records: make([]SObject, 0, len(idxs))}
+				for _, ix := range idxs {
+					retry.Records = append(retry.Records, cmdRecs[ix])
+				}
+				var rres []OpResponse
+				return rres, svx.Call(ctx, path, method, retry, &rres)
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		setRecordIndexes(res, recs[start:end], start, batch)
+		o.writeBackIDs(res)
+		return res, cmdRecs, nil
+	})
+}
+
+// batchFunc sends the records[start:end] and returns the responses
+// and the records passed to the service's BatchLogFunc
+type batchFunc func(ctx context.Context, sv *Service, start, end, batch int) ([]OpResponse, []SObject, error)
+
+// runBatches splits cnt records into batches and calls fn for each batch using the
+// options' concurrency.  The context is checked between batches.  Responses of
+// completed batches are returned in record order along with the first error.
+func (sv *Service) runBatches(ctx context.Context, cnt int, opts CollectionOptions, fn batchFunc) ([]OpResponse, error) {
+	if err := sv.requireJSON(); err != nil {
+		return nil, err
+	}
+	if opts.Strategy != nil {
+		return sv.runStrategyBatches(ctx, cnt, opts, fn)
+	}
+	batchSz := sv.CollectionBatchSize()
+	concurrency := opts.Concurrency
+	progress := opts.progressFunc(cnt, batchSz)
+	var opResp = make([]OpResponse, 0, cnt)
+	if concurrency < 2 {
+		for i := 0; i < cnt; i += batchSz {
+			if err := ctx.Err(); err != nil {
+				return opResp, err
+			}
+			end := i + batchSz
+			if end > cnt {
+				end = cnt
+			}
+			res, logRecs, err := fn(ctx, sv, i, end, i/batchSz)
+			progress(i/batchSz, end-i, res, err)
+			if err != nil {
+				return opResp, err
+			}
+			opResp = append(opResp, res...)
+			if sv.logger != nil {
+				if err := sv.logger(ctx, i, logRecs, res); err != nil {
+					return opResp, err
+				}
+			}
+		}
+		return opResp, nil
+	}
+
+	bctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		results  = make([][]OpResponse, (cnt+batchSz-1)/batchSz)
+		firstErr error
+		m        sync.Mutex
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, concurrency)
+	)
+	setErr := func(err error) {
+		m.Lock()
+		defer m.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	for i := 0; i < cnt; i += batchSz {
+		select {
+		case sem <- struct{}{}:
+		case <-bctx.Done():
+		}
+		if bctx.Err() != nil {
+			break
+		}
+		end := i + batchSz
+		if end > cnt {
+			end = cnt
+		}
+		wg.Add(1)
+		go func(start, end, batch int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, logRecs, err := fn(bctx, sv, start, end, batch)
+			m.Lock()
+			progress(batch, end-start, res, err)
+			m.Unlock()
+			if err != nil {
+				setErr(err)
+				return
+			}
+			results[batch] = res
+			if sv.logger != nil {
+				m.Lock()
+				err = sv.logger(bctx, start, logRecs, res)
+				m.Unlock()
+				if err != nil {
+					setErr(err)
+				}
+			}
+		}(i, end, i/batchSz)
+	}
+	wg.Wait()
+	for _, res := range results {
+		opResp = append(opResp, res...)
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return opResp, firstErr
+}
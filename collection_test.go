@@ -0,0 +1,213 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_CompositeCallWithOptions(t *testing.T) {
+	var m sync.Mutex
+	var calls, maxActive, active int
+	var dupHeaders []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		calls++
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		dupHeaders = append(dupHeaders, r.Header.Get("Sforce-Duplicate-Rule-Header"))
+		m.Unlock()
+		defer func() {
+			m.Lock()
+			active--
+			m.Unlock()
+		}()
+		var ids []string
+		if r.Method == "DELETE" {
+			if r.URL.Query().Get("allOrNone") != "true" {
+				http.Error(w, "allOrNone not set", http.StatusBadRequest)
+				return
+			}
+			ids = strings.Split(r.URL.Query().Get("ids"), ",")
+		} else {
+			var body struct {
+				AllOrNone bool              `json:"allOrNone"`
+				Records   []json.RawMessage `json:"records"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body.AllOrNone {
+				http.Error(w, "allOrNone not set", http.StatusBadRequest)
+				return
+			}
+			for _, rec := range body.Records {
+				var c Contact
+				json.Unmarshal(rec, &c)
+				if c.LastName == "FAIL" {
+					http.Error(w, "failed batch", http.StatusBadRequest)
+					return
+				}
+				ids = append(ids, "ID"+c.LastName)
+			}
+		}
+		var res []salesforce.OpResponse
+		for _, id := range ids {
+			res = append(res, salesforce.OpResponse{ID: id, Success: true})
+		}
+		encodeObject(w, res)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	var logged int
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").
+		WithLogger(func(ctx context.Context, idx int, recs []salesforce.SObject, res []salesforce.OpResponse) error {
+			logged += len(res)
+			return nil
+		})
+	var recs []salesforce.SObject
+	for _, nm := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		recs = append(recs, Contact{LastName: nm})
+	}
+	opts := salesforce.CollectionOptions{
+		AllOrNone:     true,
+		DuplicateRule: &salesforce.DuplicateRuleHeader{AllowSave: true},
+		BatchSize:     2,
+		Concurrency:   3,
+	}
+	res, err := sv.CreateRecordsWithOptions(ctx, recs, opts)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if calls != 4 || maxActive > 3 || logged != 7 || len(res) != 7 {
+		t.Fatalf("expected 4 calls, 7 logged and 7 responses; got %d calls (%d max concurrent) %d logged %d responses",
+			calls, maxActive, logged, len(res))
+	}
+	for i, r := range res {
+		c := recs[i].(Contact)
+		if r.RecordIndex != i || r.BatchNumber != i/2 || r.ID != "ID"+c.LastName {
+			t.Errorf("response %d expected ID%s; got %d %d %s", i, c.LastName, r.RecordIndex, r.BatchNumber, r.ID)
+		}
+	}
+	for _, h := range dupHeaders {
+		if h != "allowSave=true, includeRecordDetails=false, runAsCurrentUser=false" {
+			t.Errorf("unexpected Sforce-Duplicate-Rule-Header %q", h)
+		}
+	}
+
+	recs[4] = Contact{LastName: "FAIL"}
+	res, err = sv.CreateRecordsWithOptions(ctx, recs, salesforce.CollectionOptions{AllOrNone: true, BatchSize: 2, Concurrency: 2})
+	if err == nil {
+		t.Errorf("expected failed batch error")
+	}
+	for _, r := range res {
+		if r.BatchNumber == 2 {
+			t.Errorf("expected no responses from failed batch; got %v", r)
+		}
+	}
+
+	var progress []salesforce.BatchProgress
+	_, err = sv.CreateRecordsWithOptions(ctx, recs, salesforce.CollectionOptions{AllOrNone: true, BatchSize: 2,
+		Progress: func(bp salesforce.BatchProgress) {
+			progress = append(progress, bp)
+		}})
+	if err == nil || len(progress) != 3 {
+		t.Fatalf("expected failed batch error and 3 progress reports; got %v %d", err, len(progress))
+	}
+	if bp := progress[1]; bp.Batch != 1 || bp.Completed != 2 || bp.Batches != 4 || bp.Records != 4 || bp.Errors != 0 || bp.Err != nil {
+		t.Errorf("unexpected progress %#v", bp)
+	}
+	if bp := progress[2]; bp.Batch != 2 || bp.Completed != 3 || bp.Err == nil || bp.Elapsed <= 0 {
+		t.Errorf("expected failed batch progress; got %#v", bp)
+	}
+
+	if res, err = sv.DeleteRecordsWithOptions(ctx, []string{"1", "2", "3"}, opts); err != nil || len(res) != 3 {
+		t.Errorf("expected 3 delete responses; got %d %v", len(res), err)
+	}
+
+	if _, err = sv.DeleteSObjects(ctx, []salesforce.SObject{&Contact{ContactID: "1"}, Contact{}}, opts); err == nil {
+		t.Errorf("expected record has no id error")
+	}
+	if res, err = sv.DeleteSObjects(ctx, []salesforce.SObject{&Contact{ContactID: "1"}, Contact{ContactID: "2"}, salesforce.DeleteID("3")}, opts); err != nil || len(res) != 3 {
+		t.Errorf("expected 3 delete responses; got %d %v", len(res), err)
+	}
+}
+
+func TestRecordID(t *testing.T) {
+	var rec salesforce.SObject = &Contact{}
+	sid, ok := rec.(salesforce.SObjectWithID)
+	if !ok {
+		t.Fatalf("expected *Contact to implement SObjectWithID")
+	}
+	sid.SetID("003A")
+	if id := salesforce.RecordID(rec); id != "003A" {
+		t.Errorf("expected 003A; got %s", id)
+	}
+	if id := salesforce.RecordID(salesforce.DeleteID("003B")); id != "003B" {
+		t.Errorf("expected 003B; got %s", id)
+	}
+	if id := salesforce.RecordID(salesforce.RecordMap{"Id": "003C"}); id != "003C" {
+		t.Errorf("expected 003C; got %s", id)
+	}
+	if id := salesforce.RecordID(CustomTable{ID: "a01"}); id != "" {
+		t.Errorf("expected empty id for CustomTable; got %s", id)
+	}
+}
+
+func TestService_WriteBackIDs(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Records []Contact `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		var res []salesforce.OpResponse
+		for _, c := range body.Records {
+			if c.LastName == "FAIL" {
+				res = append(res, salesforce.OpResponse{Errors: []salesforce.Error{{StatusCode: "REQUIRED_FIELD_MISSING"}}})
+				continue
+			}
+			res = append(res, salesforce.OpResponse{ID: "ID" + c.LastName, Success: true, Created: true})
+		}
+		encodeObject(w, res)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	c1, c2 := &Contact{LastName: "A"}, &Contact{LastName: "FAIL"}
+	rm := salesforce.RecordMap{"attributes": map[string]interface{}{"type": "Contact"}, "LastName": "B"}
+	recs := []salesforce.SObject{c1, c2, rm, Contact{LastName: "C"}}
+	if _, err := sv.CreateRecordsWithOptions(ctx, recs, salesforce.CollectionOptions{WriteBackIDs: true}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if c1.ContactID != "IDA" || c2.ContactID != "" || rm["Id"] != "IDB" {
+		t.Errorf("expected IDA, empty and IDB; got %s, %s, %v", c1.ContactID, c2.ContactID, rm["Id"])
+	}
+
+	var setIDs = make(map[string]string)
+	opts := salesforce.CollectionOptions{
+		WriteBackIDs: true,
+		SetID: func(rec salesforce.SObject, id string) {
+			setIDs[rec.(Contact).LastName] = id
+		},
+	}
+	if _, err := sv.CreateRecordsWithOptions(ctx, []salesforce.SObject{Contact{LastName: "D"}, Contact{LastName: "FAIL"}}, opts); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(setIDs) != 1 || setIDs["D"] != "IDD" {
+		t.Errorf("expected SetID called for D only; got %v", setIDs)
+	}
+}
@@ -7,6 +7,7 @@ package salesforce
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -16,7 +17,8 @@ import (
 // RetrieveRecords returns sobjects rows pointed to by the passed ids, results must be a pointer
 // to a slice of types implementing SObject interface.  If retrieving SObjects of different types,
 // have the results be a *[]GenericSObject and read the objest' Attributes.Type field to identify
-// the object type.
+// the object type.  Use FieldNames to request parent fields typed into the result struct,
+// and ValidateFields to check dot-notation relationship fields against Describe.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_retrieve.htm
 func (sv *Service) RetrieveRecords(ctx context.Context, results interface{}, ids []string, fields ...string) error {
 	var body = struct {
@@ -33,29 +35,39 @@ func (sv *Service) RetrieveRecords(ctx context.Context, results interface{}, ids
 		return errors.New("no fields specified")
 	}
 
-	var ty = reflect.TypeOf((*SObject)(nil)).Elem()
-
 	if results == nil {
 		return errors.New("results parameter may not be nil")
 	}
 
+	const expected = "*[]<SObject>"
 	resultsType := reflect.TypeOf(results)
-	if resultsType.Kind() != reflect.Ptr ||
-		resultsType.Elem().Kind() != reflect.Slice {
-		return errors.New("results must be a pointer to a slice")
+	if _, err := validatePtrTo(results, reflect.Slice, expected); err != nil {
+		return err
 	}
-	if !resultsType.Elem().Elem().Implements(ty) {
-		return fmt.Errorf("%s is not an SObject", resultsType.Elem().Elem().Name())
+	if err := validateSObjectType(resultsType.Elem().Elem(), resultsType, expected); err != nil {
+		return err
 	}
 
 	err := sv.Call(ctx, fmt.Sprintf("composite/sobjects/%s", resultsType.Elem().Elem().Name()), "POST", body, results)
 	return err
 }
 
+// RetrieveRecordsFor calls RetrieveRecords requesting the fields returned by FieldNames for
+// the element type of results, so the json tags of the destination struct determine the
+// retrieved fields.  Map fields (e.g. relationship maps) are not requested.
+func (sv *Service) RetrieveRecordsFor(ctx context.Context, results interface{}, ids []string) error {
+	fields := FieldNames(results)
+	if len(fields) == 0 {
+		return &TypeError{Expected: "*[]<struct>", Got: reflect.TypeOf(results), Hint: "no fields found"}
+	}
+	return sv.RetrieveRecords(ctx, results, ids, fields...)
+}
+
 // GetRelatedRecords retrieves related records from an SObject's defined relationship.  result should be a pointer to
 // a single SObject record when the relationship is one to one, otherwise use a pointer to a slice of a specific SObject.
 // If no relationship exists in a one to one relationship, a 404 error is returned.  A one to many relationship will
-// return an empty slice.
+// return an empty slice.  One to many results are paged using nextRecordsUrl like Query, so all child
+// records are appended to the slice (limited by WithMaxrows).
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_sobject_relationships.htm
 func (sv *Service) GetRelatedRecords(ctx context.Context, result interface{}, sobjectName, id, relationship string, fields ...string) error {
 	if result == nil {
@@ -65,6 +77,9 @@ func (sv *Service) GetRelatedRecords(ctx context.Context, result interface{}, so
 	if len(fields) > 0 {
 		path = path + "?fields=" + strings.Join(fields, ",")
 	}
+	if ty := reflect.TypeOf(result); ty.Kind() == reflect.Ptr && ty.Elem().Kind() == reflect.Slice {
+		return sv.query(ctx, path, "", result)
+	}
 	return sv.Call(ctx, path, "GET", nil, result)
 }
 
@@ -95,97 +110,253 @@ type BatchBody struct {
 	Records   []SObject `json:"records,omitempty"`
 }
 
+// MarshalJSON encodes the batch ensuring that each record contains
+// attributes.type.  Salesforce requires the type when a batch contains
+// multiple SObject types, so a missing or empty type is set from the
+// record's SObjectName().
+func (b BatchBody) MarshalJSON() ([]byte, error) {
+	var recs = make([]attrRecord, 0, len(b.Records))
+	for _, r := range b.Records {
+		recs = append(recs, attrRecord{r})
+	}
+	return json.Marshal(struct {
+		AllOrNone bool         `json:"allOrNone,omitempty"`
+		Records   []attrRecord `json:"records,omitempty"`
+	}{AllOrNone: b.AllOrNone, Records: recs})
+}
+
+// attrRecord wraps an SObject to add attributes.type during marshaling
+type attrRecord struct {
+	SObject
+}
+
+// MarshalJSON adds an attributes object to the SObject's json when
+// attributes or attributes.type is missing.  The SObject's json is returned
+// unchanged when it contains attributes.type.
+func (ar attrRecord) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(ar.SObject)
+	if err != nil || ar.SObject == nil {
+		return b, err
+	}
+	nm := ar.SObjectName()
+	if nm == "" {
+		return b, nil
+	}
+	var hdr struct {
+		Attributes json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal(b, &hdr); err != nil {
+		return b, nil
+	}
+	var attr map[string]interface{}
+	if len(hdr.Attributes) > 0 {
+		if err := json.Unmarshal(hdr.Attributes, &attr); err != nil {
+			return nil, fmt.Errorf("attributes decode %w", err)
+		}
+	}
+	if tp, _ := attr["type"].(string); tp > "" {
+		return b, nil
+	}
+	var flds map[string]json.RawMessage
+	if err := json.Unmarshal(b, &flds); err != nil {
+		return b, nil
+	}
+	if attr == nil {
+		attr = make(map[string]interface{})
+	}
+	attr["type"] = nm
+	if flds["attributes"], err = json.Marshal(attr); err != nil {
+		return nil, err
+	}
+	return json.Marshal(flds)
+}
+
 // CreateRecords inserts records from recs.  Salesforce will return an error for any record that
-// has a RecordID set.  The OpResponses will contain the new RecordIDs.
+// has a RecordID set.  The OpResponses will contain the new RecordIDs.  Use CreateRecordsWithOptions
+// to set duplicate rule handling, batch size or concurrency.
 // https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_create.htm
 func (sv *Service) CreateRecords(ctx context.Context, allOrNone bool, recs []SObject) ([]OpResponse, error) {
 	return sv.CompositeCall(ctx, allOrNone, "composite/sobjects", "POST", recs)
 }
 
-// UpdateRecords update records from recs.  The each record must set the Salesforce RecordID for the object.
-// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_update.htm
-func (sv *Service) UpdateRecords(ctx context.Context, allOrNone bool, recs []SObject) ([]OpResponse, error) {
-	return sv.CompositeCall(ctx, allOrNone, "composite/sobjects", "PATCH", recs)
-}
+// DependencyFunc returns the reference id of rec and the reference ids of the
+// records that must be created before rec.  An empty ref indicates that no other
+// record depends upon rec.
+type DependencyFunc func(rec SObject) (ref string, dependsOn []string)
 
-// UpsertRecords updates/inserts records based upon the external id field.  All recs must be of the same
-// Object Type.
-// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_upsert.htm
-func (sv *Service) UpsertRecords(ctx context.Context, allOrNone bool, externalIDField string, recs []SObject) ([]OpResponse, error) {
-	if len(recs) == 0 {
-		return nil, ErrZeroRecords
-	}
-	sobjNm := recs[0].SObjectName()
+// ResolveFunc returns rec with its reference fields set from ids, a map of
+// reference ids to newly created record ids.
+type ResolveFunc func(rec SObject, ids map[string]string) SObject
 
-	return sv.CompositeCall(ctx, allOrNone, fmt.Sprintf("composite/sobjects/%s/%s", sobjNm, externalIDField), "PATCH", recs)
-}
+// ErrDependencyFailed is the OpResponse error status code for records
+// not created because a record they depend upon was not created.
+const ErrDependencyFailed = "DEPENDENCY_FAILED"
 
-// DeleteRecords deletes a list sobject from the list of ids
-// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_delete.htm
-func (sv *Service) DeleteRecords(ctx context.Context, allOrNone bool, ids []string) ([]OpResponse, error) {
-	if len(ids) <= 0 {
+// CreateRecordsWithRefs inserts recs that reference each other's to-be-assigned ids.
+// The collections api does not resolve reference ids between records, so recs are
+// reordered into dependency levels using depends.  Each level is created (in batches)
+// after its parents, and resolve sets the parents' new ids on each child record
+// before it is sent.  Returned OpResponses are in the same order as recs.  A record
+// whose parent was not created is not sent, and its OpResponse contains an
+// ErrDependencyFailed error.  allOrNone applies to each batch.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_create.htm
+func (sv *Service) CreateRecordsWithRefs(ctx context.Context, allOrNone bool, recs []SObject, depends DependencyFunc, resolve ResolveFunc) ([]OpResponse, error) {
+	if len(recs) == 0 {
 		return nil, ErrZeroRecords
 	}
-	var opResp = make([]OpResponse, 0, len(ids))
-	batchSz := sv.MaxBatchSize()
-	for i := 0; i < len(ids); i += batchSz {
-		numRecs := i + batchSz
-		if numRecs > len(ids) {
-			numRecs = len(ids)
-		}
-		delIDs := ids[i:numRecs]
-		path := "composite/sobjects?ids=" + strings.Join(delIDs, ",")
-		var res []OpResponse
-		if err := sv.Call(ctx, path, "DELETE", nil, &res); err != nil {
+	if depends == nil || resolve == nil {
+		return nil, errors.New("depends and resolve funcs may not be nil")
+	}
+	levels, parents, refs, err := dependencyLevels(recs, depends)
+	if err != nil {
+		return nil, err
+	}
+	var opResp = make([]OpResponse, len(recs))
+	var ids = make(map[string]string)
+	for _, level := range levels {
+		if err := ctx.Err(); err != nil {
 			return opResp, err
 		}
-		opResp = append(opResp, res...)
-		var delrecids = make([]SObject, 0, len(res))
-		for _, s := range delIDs {
-			delrecids = append(delrecids, DeleteID(s))
+		var idxs []int
+		var lvlRecs []SObject
+		for _, idx := range level {
+			var missing []string
+			for _, p := range parents[idx] {
+				if ids[p] == "" {
+					missing = append(missing, p)
+				}
+			}
+			if len(missing) > 0 {
+				opResp[idx] = OpResponse{Errors: []Error{{
+					StatusCode: ErrDependencyFailed,
+					Message:    "dependency not created: " + strings.Join(missing, ","),
+				}}}
+				continue
+			}
+			rec := recs[idx]
+			if len(parents[idx]) > 0 {
+				rec = resolve(rec, ids)
+			}
+			idxs = append(idxs, idx)
+			lvlRecs = append(lvlRecs, rec)
 		}
-		if sv.logger != nil {
-			if err := sv.logger(ctx, i, delrecids, res); err != nil {
-				return nil, err
+		if len(lvlRecs) == 0 {
+			continue
+		}
+		res, err := sv.CreateRecords(ctx, allOrNone, lvlRecs)
+		for i := range res {
+			if i >= len(idxs) {
+				break
+			}
+			res[i].RecordIndex = idxs[i]
+			opResp[idxs[i]] = res[i]
+			if ref := refs[idxs[i]]; ref > "" && res[i].Success {
+				ids[ref] = res[i].ID
 			}
 		}
+		if err != nil {
+			return opResp, err
+		}
 	}
-
 	return opResp, nil
 }
 
-// CompositeCall updates/inserts/upserts all records in batches based upon the Service
-// batch size (generally 200).
-func (sv *Service) CompositeCall(ctx context.Context, allOrNone bool, path, method string, recs []SObject) ([]OpResponse, error) {
-	if len(recs) == 0 {
-		return nil, ErrZeroRecords
-	}
-	var opResp = make([]OpResponse, 0, len(recs))
-	batchSz := sv.MaxBatchSize()
-
-	for i := 0; i < len(recs); i += batchSz {
-		cmdRecs := make([]SObject, 0, batchSz)
-		numRecs := i + batchSz
-		if numRecs > len(recs) {
-			numRecs = len(recs)
+// dependencyLevels groups the indexes of recs so that each record appears in a
+// level after the records it depends upon.
+func dependencyLevels(recs []SObject, depends DependencyFunc) ([][]int, [][]string, []string, error) {
+	var refs = make([]string, len(recs))
+	var parents = make([][]string, len(recs))
+	var refIdx = make(map[string]int)
+	for i, r := range recs {
+		refs[i], parents[i] = depends(r)
+		if refs[i] == "" {
+			continue
 		}
-		for _, r := range recs[i:numRecs] {
-			cmdRecs = append(cmdRecs, r.WithAttr(""))
+		if _, ok := refIdx[refs[i]]; ok {
+			return nil, nil, nil, fmt.Errorf("duplicate reference id %s", refs[i])
 		}
-		body := BatchBody{AllOrNone: allOrNone, Records: cmdRecs}
-		var res []OpResponse
-
-		if err := sv.Call(ctx, path, method, body, &res); err != nil {
-			return opResp, err
+		refIdx[refs[i]] = i
+	}
+	var depth = make([]int, len(recs))
+	var state = make([]int, len(recs)) // 0 = unvisited, 1 = visiting, 2 = done
+	var visit func(int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case 1:
+			return fmt.Errorf("circular reference at %s", refs[i])
+		case 2:
+			return nil
 		}
-		opResp = append(opResp, res...)
-		if sv.logger != nil {
-			if err := sv.logger(ctx, i, cmdRecs, res); err != nil {
-				return opResp, err
+		state[i] = 1
+		for _, p := range parents[i] {
+			pidx, ok := refIdx[p]
+			if !ok {
+				return fmt.Errorf("reference id %s not found", p)
+			}
+			if err := visit(pidx); err != nil {
+				return err
+			}
+			if depth[pidx]+1 > depth[i] {
+				depth[i] = depth[pidx] + 1
 			}
 		}
+		state[i] = 2
+		return nil
+	}
+	var levels [][]int
+	for i := range recs {
+		if err := visit(i); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	for i := range recs {
+		for len(levels) <= depth[i] {
+			levels = append(levels, nil)
+		}
+		levels[depth[i]] = append(levels[depth[i]], i)
+	}
+	return levels, parents, refs, nil
+}
+
+// UpdateRecords update records from recs.  The each record must set the Salesforce RecordID for the object.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_update.htm
+func (sv *Service) UpdateRecords(ctx context.Context, allOrNone bool, recs []SObject) ([]OpResponse, error) {
+	return sv.CompositeCall(ctx, allOrNone, "composite/sobjects", "PATCH", recs)
+}
+
+// UpsertRecords updates/inserts records based upon the external id field.  All recs must be of the same
+// Object Type.  An empty externalIDField uses the default registered by WithExternalIDs.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_upsert.htm
+func (sv *Service) UpsertRecords(ctx context.Context, allOrNone bool, externalIDField string, recs []SObject) ([]OpResponse, error) {
+	return sv.UpsertRecordsWithOptions(ctx, externalIDField, recs, CollectionOptions{AllOrNone: allOrNone})
+}
+
+// DeleteRecords deletes a list sobject from the list of ids.  When allOrNone is set, the
+// allOrNone query parameter is sent and a failed delete rolls back the deletes of its batch;
+// as with other collection calls, previously completed batches are not rolled back.  Like
+// CompositeCall, a done context returns the OpResponses of completed batches along with the
+// context's error.  Use DeleteRecordsWithOptions to set batch size or concurrency.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_sobjects_collections_delete.htm
+func (sv *Service) DeleteRecords(ctx context.Context, allOrNone bool, ids []string) ([]OpResponse, error) {
+	return sv.DeleteRecordsWithOptions(ctx, ids, CollectionOptions{AllOrNone: allOrNone})
+}
+
+// CompositeCall updates/inserts/upserts all records in batches based upon the Service
+// batch size (generally 200).  The context is checked between batches, and a done context
+// returns the OpResponses of completed batches along with the context's error.
+func (sv *Service) CompositeCall(ctx context.Context, allOrNone bool, path, method string, recs []SObject) ([]OpResponse, error) {
+	return sv.CompositeCallWithOptions(ctx, path, method, recs, CollectionOptions{AllOrNone: allOrNone})
+}
+
+// setRecordIndexes attributes each response of a batch to its record
+func setRecordIndexes(res []OpResponse, recs []SObject, startIndex, batchNumber int) {
+	for j := range res {
+		res[j].RecordIndex = startIndex + j
+		res[j].BatchNumber = batchNumber
+		if j < len(recs) {
+			res[j].SObject = recs[j]
+		}
 	}
-	return opResp, nil
 }
 
 // ErrZeroRecords indicates a zero length SObject slice is passed to collection func
@@ -194,7 +365,8 @@ var ErrZeroRecords = errors.New("must have at least 1 record")
 // OpResponses is a slice of OpResponse records
 type OpResponses []OpResponse
 
-// Errors returns unsuccessful OpResponses
+// Errors returns unsuccessful OpResponses setting RecordIndex and SObject using startIndex
+// and sobjects.  OpResponses returned from collection funcs already contain these values.
 func (oprs OpResponses) Errors(startIndex int, sobjects []SObject) []OpResponse {
 	var errReponses []OpResponse
 	for i := range oprs {
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
@@ -24,6 +25,47 @@ func TestService_RetrieveRecords(t *testing.T) {
 	_ = recs
 }
 
+func TestService_RetrieveRecordsFor(t *testing.T) {
+	var fields []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			IDs    []string `json:"ids"`
+			Fields []string `json:"fields"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fields = body.Fields
+		if r.URL.Path != "/composite/sobjects/Contact" {
+			http.Error(w, "not found "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		encodeObject(w, []map[string]interface{}{{"Id": body.IDs[0], "LastName": "Smith"}})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	var recs []Contact
+	if err := sv.RetrieveRecordsFor(ctx, &recs, []string{"003A"}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(recs) != 1 || recs[0].ContactID != "003A" || recs[0].LastName != "Smith" {
+		t.Errorf("unexpected records %v", recs)
+	}
+	if want := strings.Join(salesforce.FieldNames(Contact{}), ","); strings.Join(fields, ",") != want {
+		t.Errorf("expected fields %s; got %v", want, fields)
+	}
+	for _, f := range fields {
+		if f == "Account" || f == "RecordType" || f == "attributes" {
+			t.Errorf("unexpected field %s", f)
+		}
+	}
+	var bad []salesforce.RecordMap
+	if err := sv.RetrieveRecordsFor(ctx, &bad, []string{"003A"}); err == nil {
+		t.Errorf("expected error for map records")
+	}
+}
+
 func TestService_BatchCall(t *testing.T) {
 	ws := httptest.NewServer(http.HandlerFunc(serviceCompositeHandlerFunc))
 	defer ws.Close()
@@ -75,6 +117,15 @@ func testBatch_OpResponse(ctx context.Context, sv *salesforce.Service, crrecs []
 	if err != nil || len(resp) != len(crrecs) {
 		return fmt.Errorf("createrecords expected %d recs; got %d %w", len(crrecs), len(resp), err)
 	}
+	batchSz := sv.MaxBatchSize()
+	for i, r := range resp {
+		if r.RecordIndex != i || r.BatchNumber != i/batchSz {
+			return fmt.Errorf("response %d: expected index %d batch %d; got %d %d", i, i, i/batchSz, r.RecordIndex, r.BatchNumber)
+		}
+		if c, ok := r.SObject.(Contact); !ok || c.ExternalPID != crrecs[i].(Contact).ExternalPID {
+			return fmt.Errorf("response %d: expected record %v; got %v", i, crrecs[i], r.SObject)
+		}
+	}
 
 	errors := salesforce.OpResponses(resp).Errors(0, crrecs)
 	if len(errors) != 1 {
@@ -3934,3 +3985,277 @@ func upsertRecs() []salesforce.SObject {
 	}
 	return retval
 }
+
+// noAttrRecord is an SObject whose WithAttr does not set attributes
+type noAttrRecord struct {
+	Name string `json:"Name,omitempty"`
+}
+
+func (n noAttrRecord) SObjectName() string {
+	return "NoAttr__c"
+}
+
+func (n noAttrRecord) WithAttr(ref string) salesforce.SObject {
+	return n
+}
+
+func TestBatchBody_MarshalJSON(t *testing.T) {
+	body := salesforce.BatchBody{
+		AllOrNone: true,
+		Records: []salesforce.SObject{
+			noAttrRecord{Name: "A"},
+			Account{AccountName: "B"}.WithAttr("ref01"),
+			salesforce.RecordMap{"Name": "C", "attributes": map[string]interface{}{"referenceId": "ref02"}},
+			salesforce.RecordMap{"Name": "D"},
+		},
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	var result struct {
+		AllOrNone bool `json:"allOrNone"`
+		Records   []struct {
+			Name       string                 `json:"Name"`
+			Attributes *salesforce.Attributes `json:"attributes"`
+		} `json:"records"`
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatalf("unmarshal failed %v", err)
+	}
+	if !result.AllOrNone || len(result.Records) != 4 {
+		t.Fatalf("expected allOrNone and 4 records; got %s", b)
+	}
+	var expected = []struct{ tp, ref string }{
+		{"NoAttr__c", ""},
+		{"Account", "ref01"},
+		{"", "ref02"},
+		{"", ""},
+	}
+	for i, rec := range result.Records[:3] {
+		if rec.Attributes == nil || rec.Attributes.Type != expected[i].tp || rec.Attributes.Ref != expected[i].ref {
+			t.Errorf("record %d: expected %v; got %#v", i, expected[i], rec.Attributes)
+		}
+	}
+	if result.Records[3].Attributes != nil {
+		t.Errorf("record 3: expected nil attributes; got %#v", result.Records[3].Attributes)
+	}
+
+	// records with attributes.type are encoded unchanged
+	typed := Account{AccountName: "B", BillingCity: "Dallas"}.WithAttr("ref01")
+	want, _ := json.Marshal(typed)
+	b, err = json.Marshal(salesforce.BatchBody{Records: []salesforce.SObject{typed}})
+	if err != nil || string(b) != `{"records":[`+string(want)+`]}` {
+		t.Errorf("expected unchanged record %s; got %s %v", want, b, err)
+	}
+}
+
+func TestService_CreateRecordsWithRefs(t *testing.T) {
+	var batches [][]map[string]interface{}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Records []map[string]interface{} `json:"records"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, body.Records)
+		var responses []salesforce.OpResponse
+		for _, rec := range body.Records {
+			nm, _ := rec["Name"].(string)
+			if nm == "" {
+				nm, _ = rec["LastName"].(string)
+			}
+			if nm == "BadAcct" {
+				responses = append(responses, salesforce.OpResponse{Errors: []salesforce.Error{{StatusCode: "X"}}})
+				continue
+			}
+			responses = append(responses, salesforce.OpResponse{Success: true, Created: true, ID: "ID" + nm})
+		}
+		encodeObject(w, responses)
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	recs := []salesforce.SObject{
+		Contact{LastName: "C1", AccountID: "@A1"},
+		Account{AccountName: "A1"},
+		Contact{LastName: "C2", AccountID: "@BadAcct"},
+		Account{AccountName: "BadAcct"},
+	}
+	depends := func(rec salesforce.SObject) (string, []string) {
+		switch r := rec.(type) {
+		case Account:
+			return "@" + r.AccountName, nil
+		case Contact:
+			return "", []string{r.AccountID}
+		}
+		return "", nil
+	}
+	resolve := func(rec salesforce.SObject, ids map[string]string) salesforce.SObject {
+		c := rec.(Contact)
+		c.AccountID = ids[c.AccountID]
+		return c
+	}
+	res, err := sv.CreateRecordsWithRefs(ctx, false, recs, depends, resolve)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("expected batches of 2 accounts and 1 contact; got %v", batches)
+	}
+	if batches[1][0]["AccountId"] != "IDA1" {
+		t.Errorf("expected AccountId IDA1; got %v", batches[1][0]["AccountId"])
+	}
+	if len(res) != 4 || res[0].ID != "IDC1" || res[1].ID != "IDA1" || res[3].Success {
+		t.Errorf("unexpected responses %#v", res)
+	}
+	if len(res) > 2 && (res[2].Success || len(res[2].Errors) != 1 || res[2].Errors[0].StatusCode != salesforce.ErrDependencyFailed) {
+		t.Errorf("expected %s; got %#v", salesforce.ErrDependencyFailed, res[2])
+	}
+
+	circular := func(rec salesforce.SObject) (string, []string) {
+		c := rec.(Contact)
+		return c.LastName, []string{c.FirstName}
+	}
+	_, err = sv.CreateRecordsWithRefs(ctx, false, []salesforce.SObject{
+		Contact{LastName: "X", FirstName: "Y"}, Contact{LastName: "Y", FirstName: "X"},
+	}, circular, resolve)
+	if err == nil || !strings.HasPrefix(err.Error(), "circular reference") {
+		t.Errorf("expected circular reference error; got %v", err)
+	}
+	_, err = sv.CreateRecordsWithRefs(ctx, false, []salesforce.SObject{Contact{LastName: "X", FirstName: "Z"}}, circular, resolve)
+	if err == nil || err.Error() != "reference id Z not found" {
+		t.Errorf("expected reference id Z not found; got %v", err)
+	}
+}
+
+func BenchmarkService_CreateRecords(b *testing.B) {
+	ws := httptest.NewServer(http.HandlerFunc(serviceCompositeHandlerFunc))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	recs := getSORecords(insertcontacts)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sv.CreateRecords(ctx, false, recs); err != nil {
+			b.Fatalf("expected success; got %v", err)
+		}
+	}
+}
+
+func TestService_CompositeCallCancel(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(serviceCompositeHandlerFunc))
+	defer ws.Close()
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "TK", "CALL OK"))
+	defer cancel()
+	var batches int
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithBatchSize(10).
+		WithLogger(func(ctx context.Context, idx int, recs []salesforce.SObject, res []salesforce.OpResponse) error {
+			batches++
+			cancel()
+			return nil
+		})
+	res, err := sv.CreateRecords(ctx, false, getSORecords(insertcontacts))
+	if err != context.Canceled {
+		t.Errorf("expected %v; got %v", context.Canceled, err)
+	}
+	if batches != 1 || len(res) != 10 {
+		t.Errorf("expected 1 batch of 10 responses; got %d batches %d responses", batches, len(res))
+	}
+	_, err = sv.DeleteRecords(ctx, false, delIDS)
+	if err != context.Canceled {
+		t.Errorf("expected %v; got %v", context.Canceled, err)
+	}
+}
+
+func TestService_DeleteRecords_AllOrNone(t *testing.T) {
+	var queries []url.Values
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/composite/sobjects" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		queries = append(queries, q)
+		var res []salesforce.OpResponse
+		for _, id := range strings.Split(q.Get("ids"), ",") {
+			res = append(res, salesforce.OpResponse{ID: id, Success: true})
+		}
+		encodeObject(w, res)
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithCollectionBatchSize(2)
+
+	res, err := sv.DeleteRecords(ctx, true, []string{"001A", "001B", "001C"})
+	if err != nil || len(res) != 3 || res[2].ID != "001C" || res[2].BatchNumber != 1 {
+		t.Fatalf("expected 3 responses in 2 batches; got %v %v", res, err)
+	}
+	if len(queries) != 2 || queries[0].Get("allOrNone") != "true" || queries[1].Get("allOrNone") != "true" ||
+		queries[0].Get("ids") != "001A,001B" {
+		t.Errorf("expected allOrNone on each batch; got %v", queries)
+	}
+	queries = nil
+	if _, err := sv.DeleteRecords(ctx, false, []string{"001A"}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(queries) != 1 || queries[0].Get("allOrNone") != "" {
+		t.Errorf("expected no allOrNone parameter; got %v", queries)
+	}
+}
+
+func TestService_GetRelatedRecords(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sobjects/Account/A1/Contacts":
+			if r.URL.Query().Get("fields") != "Id,LastName" {
+				http.Error(w, "invalid fields", http.StatusBadRequest)
+				return
+			}
+			encodeObject(w, map[string]interface{}{
+				"done":           false,
+				"totalSize":      3,
+				"nextRecordsUrl": "/services/data/v53.0/sobjects/Account/A1/Contacts/next",
+				"records":        []Contact{{ContactID: "C1"}, {ContactID: "C2"}},
+			})
+		case "/services/data/v53.0/sobjects/Account/A1/Contacts/next":
+			encodeObject(w, map[string]interface{}{
+				"done":      true,
+				"totalSize": 3,
+				"records":   []Contact{{ContactID: "C3"}},
+			})
+		case "/sobjects/Contact/C1/Account":
+			encodeObject(w, Account{AccountName: "Acme"})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var contacts []Contact
+	if err := sv.GetRelatedRecords(ctx, &contacts, "Account", "A1", "Contacts", "Id", "LastName"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(contacts) != 3 || contacts[2].ContactID != "C3" {
+		t.Errorf("expected 3 contacts; got %v", contacts)
+	}
+	contacts = nil
+	if err := sv.WithForClause(salesforce.ForView).GetRelatedRecords(ctx, &contacts, "Account", "A1", "Contacts", "Id", "LastName"); err != nil || len(contacts) != 3 {
+		t.Errorf("expected for clause to be ignored; got %d %v", len(contacts), err)
+	}
+	var acct Account
+	if err := sv.GetRelatedRecords(ctx, &acct, "Contact", "C1", "Account"); err != nil || acct.AccountName != "Acme" {
+		t.Errorf("expected Acme; got %s %v", acct.AccountName, err)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// MaxCompositeSubrequests is the maximum number of subrequests in a composite request
+const MaxCompositeSubrequests = 25
+
+// CompositeSubrequest is a single call within a CompositeRequest.  URL may
+// be relative to the service's base path (e.g. sobjects/Account) or begin
+// with /services/data/.  Method may be GET, POST, PATCH, PUT or DELETE.
+// HTTPHeaders are sent with only this subrequest, allowing, for example,
+// Sforce-Auto-Assign: FALSE on one create but not another.  When Result is a
+// non-nil pointer, a successful subresponse body is decoded into Result.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/requests_composite.htm
+type CompositeSubrequest struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	ReferenceID string            `json:"referenceId"`
+	Body        interface{}       `json:"body,omitempty"`
+	HTTPHeaders map[string]string `json:"httpHeaders,omitempty"`
+	Result      interface{}       `json:"-"`
+}
+
+// CompositeRequest executes a series of subrequests in a single call.  Subrequests
+// may reference the results of previous subrequests using @{referenceId.field}.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_composite.htm
+type CompositeRequest struct {
+	AllOrNone          bool                   `json:"allOrNone"`
+	CollateSubrequests bool                   `json:"collateSubrequests,omitempty"`
+	Subrequests        []*CompositeSubrequest `json:"compositeRequest"`
+}
+
+// Add appends a subrequest whose successful response body is decoded into result.
+// A nil result leaves the body in the CompositeSubresponse.
+func (cr *CompositeRequest) Add(method, url, referenceID string, body, result interface{}) *CompositeRequest {
+	cr.Subrequests = append(cr.Subrequests, &CompositeSubrequest{
+		Method:      method,
+		URL:         url,
+		ReferenceID: referenceID,
+		Body:        body,
+		Result:      result,
+	})
+	return cr
+}
+
+// AddWithHeaders appends a subrequest sent with headers.  See Add.
+//
+//	cr.AddWithHeaders("POST", "sobjects/Lead", "lead1", map[string]string{"Sforce-Auto-Assign": "FALSE"}, lead, nil)
+func (cr *CompositeRequest) AddWithHeaders(method, url, referenceID string, headers map[string]string, body, result interface{}) *CompositeRequest {
+	cr.Add(method, url, referenceID, body, result)
+	cr.Subrequests[len(cr.Subrequests)-1].HTTPHeaders = headers
+	return cr
+}
+
+// compositeMethods are the methods allowed in a subrequest
+var compositeMethods = map[string]bool{"GET": true, "POST": true, "PATCH": true, "PUT": true, "DELETE": true}
+
+// checkSubrequest validates the method and headers of sr
+func checkSubrequest(sr *CompositeSubrequest) error {
+	if !compositeMethods[strings.ToUpper(sr.Method)] {
+		return fmt.Errorf("subrequest %s: invalid method %q", sr.ReferenceID, sr.Method)
+	}
+	for k, v := range sr.HTTPHeaders {
+		key := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(k))
+		if key == "" || blockedHeaders[key] && key != "Content-Type" && key != "Accept" {
+			return fmt.Errorf("subrequest %s: %w: %q", sr.ReferenceID, ErrInvalidHeader, k)
+		}
+		if strings.ContainsAny(key+v, "\r\n") {
+			return fmt.Errorf("subrequest %s: %w: %q contains a line break", sr.ReferenceID, ErrInvalidHeader, k)
+		}
+	}
+	return nil
+}
+
+// CompositeSubresponse is the result of a subrequest
+type CompositeSubresponse struct {
+	Body           json.RawMessage   `json:"body,omitempty"`
+	HTTPHeaders    map[string]string `json:"httpHeaders,omitempty"`
+	HTTPStatusCode int               `json:"httpStatusCode"`
+	ReferenceID    string            `json:"referenceId"`
+}
+
+// Success returns true for a 2xx status code
+func (sr CompositeSubresponse) Success() bool {
+	return sr.HTTPStatusCode >= 200 && sr.HTTPStatusCode <= 299
+}
+
+// Errors decodes the body of an unsuccessful subresponse
+func (sr CompositeSubresponse) Errors() []Error {
+	if sr.Success() {
+		return nil
+	}
+	var errs []Error
+	if err := json.Unmarshal(sr.Body, &errs); err != nil {
+		return []Error{{StatusCode: fmt.Sprintf("%d", sr.HTTPStatusCode), Message: string(sr.Body)}}
+	}
+	return errs
+}
+
+// CompositeResponse contains the subresponses of a composite request
+type CompositeResponse struct {
+	Subresponses []CompositeSubresponse `json:"compositeResponse"`
+}
+
+// Subresponse returns the subresponse with referenceID
+func (cr *CompositeResponse) Subresponse(referenceID string) *CompositeSubresponse {
+	for i := range cr.Subresponses {
+		if cr.Subresponses[i].ReferenceID == referenceID {
+			return &cr.Subresponses[i]
+		}
+	}
+	return nil
+}
+
+// CompositeCrossReference is a subrequest referencing the result of a subrequest
+// that would be sent in a previous call.
+type CompositeCrossReference struct {
+	ReferenceID string // referencing subrequest
+	DependsOn   string // referenced subrequest
+}
+
+// CompositeSplitError is returned when a composite request with more than
+// MaxCompositeSubrequests subrequests cannot be split into separate calls,
+// either because the request is AllOrNone or because References cross the
+// boundary of every possible split.
+type CompositeSplitError struct {
+	Index      int  // index of the first subrequest that could not be placed in a call
+	AllOrNone  bool // an all or none request may not be split
+	References []CompositeCrossReference
+}
+
+// Error lists the crossing references
+func (e *CompositeSplitError) Error() string {
+	if e.AllOrNone {
+		return fmt.Sprintf("all or none composite request cannot be split at subrequest %d", e.Index)
+	}
+	var refs = make([]string, len(e.References))
+	for i, r := range e.References {
+		refs[i] = r.ReferenceID + " -> " + r.DependsOn
+	}
+	return fmt.Sprintf("composite request cannot be split at subrequest %d; references cross calls: %s", e.Index, strings.Join(refs, ", "))
+}
+
+// compositeReferencePattern matches the referenceId of @{referenceId.field}
+var compositeReferencePattern = regexp.MustCompile(`@\{([^.}\[]+)`)
+
+// compositeSplits divides subrequests into calls of no more than MaxCompositeSubrequests
+// such that no subrequest references a subrequest of a previous call.
+func compositeSplits(subrequests []*CompositeSubrequest) ([][]*CompositeSubrequest, error) {
+	var n = len(subrequests)
+	if n <= MaxCompositeSubrequests {
+		return [][]*CompositeSubrequest{subrequests}, nil
+	}
+	var indexes = make(map[string]int)
+	var deps = make([][]int, n)
+	for i, sr := range subrequests {
+		var s = sr.URL
+		if sr.Body != nil {
+			b, _ := json.Marshal(sr.Body)
+			s += string(b)
+		}
+		for _, v := range sr.HTTPHeaders {
+			s += v
+		}
+		for _, m := range compositeReferencePattern.FindAllStringSubmatch(s, -1) {
+			if ix, ok := indexes[m[1]]; ok {
+				deps[i] = append(deps[i], ix)
+			}
+		}
+		indexes[sr.ReferenceID] = i
+	}
+	// minDep[k] is the lowest index referenced by subrequests k and after.  A split
+	// before k is valid when minDep[k] >= k.
+	var minDep = make([]int, n+1)
+	minDep[n] = n
+	for k := n - 1; k >= 0; k-- {
+		minDep[k] = minDep[k+1]
+		if k < minDep[k] {
+			minDep[k] = k
+		}
+		for _, d := range deps[k] {
+			if d < minDep[k] {
+				minDep[k] = d
+			}
+		}
+	}
+	var splits [][]*CompositeSubrequest
+	for start := 0; start < n; {
+		end := start + MaxCompositeSubrequests
+		if end >= n {
+			splits = append(splits, subrequests[start:])
+			break
+		}
+		for ; end > start && minDep[end] < end; end-- {
+		}
+		if end == start {
+			end = start + MaxCompositeSubrequests
+			var serr = &CompositeSplitError{Index: end}
+			for j := end; j < n; j++ {
+				for _, d := range deps[j] {
+					if d >= start && d < end {
+						serr.References = append(serr.References, CompositeCrossReference{
+							ReferenceID: subrequests[j].ReferenceID,
+							DependsOn:   subrequests[d].ReferenceID,
+						})
+					}
+				}
+			}
+			return nil, serr
+		}
+		splits = append(splits, subrequests[start:end])
+		start = end
+	}
+	return splits, nil
+}
+
+// Composite executes req.  Successful subresponse bodies are decoded into the Result
+// of the corresponding subrequest.  Unsuccessful subresponses do not return an error;
+// check each subresponse's Errors.  An error decoding a Result is returned after
+// all results are decoded.
+//
+// A request with more than MaxCompositeSubrequests subrequests is split into multiple
+// calls keeping referencing subrequests in the same call as the subrequests they reference.
+// A *CompositeSplitError is returned if no such split exists.  As separate calls cannot be
+// rolled back together, an AllOrNone request with more than MaxCompositeSubrequests
+// subrequests returns a *CompositeSplitError without making any call.  When a call fails,
+// the response holding the subresponses of the completed calls is returned with the call's
+// error, so that committed subrequests may be identified.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_composite.htm
+func (sv *Service) Composite(ctx context.Context, req *CompositeRequest) (*CompositeResponse, error) {
+	if req == nil || len(req.Subrequests) == 0 {
+		return nil, ErrZeroRecords
+	}
+	if sv == nil || sv.baseURL == nil {
+		return nil, errors.New("nil baseURL")
+	}
+	if err := sv.requireJSON(); err != nil {
+		return nil, err
+	}
+	for _, sr := range req.Subrequests {
+		if err := checkSubrequest(sr); err != nil {
+			return nil, err
+		}
+	}
+	if req.AllOrNone && len(req.Subrequests) > MaxCompositeSubrequests {
+		return nil, &CompositeSplitError{Index: MaxCompositeSubrequests, AllOrNone: true}
+	}
+	splits, err := compositeSplits(req.Subrequests)
+	if err != nil {
+		return nil, err
+	}
+	var res = &CompositeResponse{}
+	var callErr error
+	for _, split := range splits {
+		subs, err := sv.composite(ctx, req, split)
+		if err != nil {
+			callErr = err
+			break
+		}
+		res.Subresponses = append(res.Subresponses, subs...)
+	}
+	var errs []string
+	for _, sr := range req.Subrequests {
+		if sr.Result == nil {
+			continue
+		}
+		sub := res.Subresponse(sr.ReferenceID)
+		if sub == nil || !sub.Success() || len(sub.Body) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(sub.Body, sr.Result); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", sr.ReferenceID, err))
+		}
+	}
+	if callErr != nil {
+		return res, callErr
+	}
+	if len(errs) > 0 {
+		return res, fmt.Errorf("composite result decode: %s", strings.Join(errs, "; "))
+	}
+	return res, nil
+}
+
+// composite sends subrequests as a single composite call
+func (sv *Service) composite(ctx context.Context, req *CompositeRequest, subrequests []*CompositeSubrequest) ([]CompositeSubresponse, error) {
+	var body = *req
+	body.Subrequests = make([]*CompositeSubrequest, len(subrequests))
+	for i, sr := range subrequests {
+		s := *sr
+		s.Method = strings.ToUpper(s.Method)
+		if !strings.HasPrefix(s.URL, "/") {
+			s.URL = sv.baseURL.Path + s.URL
+		}
+		body.Subrequests[i] = &s
+	}
+	var res *CompositeResponse
+	if err := sv.Call(ctx, "composite", "POST", body, &res); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("empty composite response")
+	}
+	return res.Subresponses, nil
+}
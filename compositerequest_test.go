@@ -0,0 +1,201 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_Composite(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v55.0/composite" || r.Method != "POST" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			AllOrNone   bool `json:"allOrNone"`
+			Subrequests []struct {
+				Method      string `json:"method"`
+				URL         string `json:"url"`
+				ReferenceID string `json:"referenceId"`
+			} `json:"compositeRequest"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Subrequests) != 3 || req.Subrequests[0].URL != "/services/data/v55.0/sobjects/Account" ||
+			req.Subrequests[1].URL != "/services/data/v55.0/sobjects/Account/@{newAccount.id}" {
+			http.Error(w, "invalid subrequests", http.StatusBadRequest)
+			return
+		}
+		encodeObject(w, map[string]interface{}{
+			"compositeResponse": []map[string]interface{}{
+				{"httpStatusCode": 201, "referenceId": "newAccount", "body": map[string]interface{}{"id": "001A", "success": true}},
+				{"httpStatusCode": 200, "referenceId": "getAccount", "body": map[string]interface{}{"Id": "001A", "Name": "Acme"}},
+				{"httpStatusCode": 400, "referenceId": "badContact", "body": []map[string]interface{}{{"errorCode": "REQUIRED_FIELD_MISSING", "message": "LastName"}}},
+			},
+		})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/")
+
+	if _, err := sv.Composite(ctx, &salesforce.CompositeRequest{}); err != salesforce.ErrZeroRecords {
+		t.Errorf("expected %v; got %v", salesforce.ErrZeroRecords, err)
+	}
+
+	var created salesforce.OpResponse
+	var acct Account
+	var contact Contact
+	req := (&salesforce.CompositeRequest{AllOrNone: false}).
+		Add("POST", "sobjects/Account", "newAccount", Account{AccountName: "Acme"}, &created).
+		Add("GET", "sobjects/Account/@{newAccount.id}", "getAccount", nil, &acct).
+		Add("POST", "/services/data/v55.0/sobjects/Contact", "badContact", Contact{}, &contact)
+	res, err := sv.Composite(ctx, req)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if created.ID != "001A" || !created.Success || acct.AccountName != "Acme" || contact.ContactID != "" {
+		t.Errorf("unexpected results %v %v %v", created, acct, contact)
+	}
+	sub := res.Subresponse("badContact")
+	if sub == nil || sub.Success() || len(sub.Errors()) != 1 || sub.Errors()[0].Message != "LastName" {
+		t.Errorf("expected badContact error; got %v", sub)
+	}
+	if res.Subresponse("missing") != nil {
+		t.Errorf("expected nil subresponse for missing reference")
+	}
+}
+
+func TestService_Composite_split(t *testing.T) {
+	var calls []int
+	var failSecond bool
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Subrequests []struct {
+				ReferenceID string `json:"referenceId"`
+			} `json:"compositeRequest"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		calls = append(calls, len(req.Subrequests))
+		if failSecond && len(calls) == 2 {
+			http.Error(w, `[{"errorCode":"UNKNOWN_EXCEPTION","message":"oops"}]`, http.StatusInternalServerError)
+			return
+		}
+		var subs []map[string]interface{}
+		for _, sr := range req.Subrequests {
+			subs = append(subs, map[string]interface{}{"httpStatusCode": 201, "referenceId": sr.ReferenceID,
+				"body": map[string]interface{}{"id": "ID" + sr.ReferenceID, "success": true}})
+		}
+		encodeObject(w, map[string]interface{}{"compositeResponse": subs})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/")
+
+	// newRequest creates 30 subrequests where each refs entry references the result of another
+	newRequest := func(allOrNone bool, refs map[int]int) *salesforce.CompositeRequest {
+		req := &salesforce.CompositeRequest{AllOrNone: allOrNone}
+		for i := 0; i < 30; i++ {
+			ref := fmt.Sprintf("r%d", i)
+			var body interface{} = Contact{LastName: ref}
+			if d, ok := refs[i]; ok {
+				body = map[string]string{"AccountId": fmt.Sprintf("@{r%d.id}", d)}
+			}
+			req.Add("POST", "sobjects/Contact", ref, body, nil)
+		}
+		return req
+	}
+
+	res, err := sv.Composite(ctx, newRequest(false, map[int]int{21: 20, 26: 20, 29: 28}))
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(calls) != 2 || calls[0] != 20 || calls[1] != 10 || len(res.Subresponses) != 30 || res.Subresponse("r29") == nil {
+		t.Errorf("expected calls of 20 and 10 subrequests; got %v %d", calls, len(res.Subresponses))
+	}
+
+	calls = nil
+	_, err = sv.Composite(ctx, newRequest(false, map[int]int{10: 2, 27: 3, 28: 20}))
+	var serr *salesforce.CompositeSplitError
+	if !errors.As(err, &serr) || len(calls) > 0 {
+		t.Fatalf("expected CompositeSplitError; got %v %v", err, calls)
+	}
+	if serr.Index != 27 || len(serr.References) != 2 || serr.References[0] != (salesforce.CompositeCrossReference{ReferenceID: "r27", DependsOn: "r3"}) {
+		t.Errorf("unexpected cross references %#v", serr)
+	}
+
+	calls = nil
+	_, err = sv.Composite(ctx, newRequest(true, nil))
+	if !errors.As(err, &serr) || !serr.AllOrNone || len(calls) > 0 {
+		t.Errorf("expected all or none CompositeSplitError without calls; got %v %v", err, calls)
+	}
+
+	calls, failSecond = nil, true
+	res, err = sv.Composite(ctx, newRequest(false, nil))
+	if err == nil || len(calls) != 2 || res == nil || len(res.Subresponses) != 25 || res.Subresponse("r24") == nil {
+		t.Errorf("expected first call's subresponses with error; got %v %v", err, calls)
+	}
+}
+
+func TestService_Composite_Headers(t *testing.T) {
+	var subs []salesforce.CompositeSubrequest
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req salesforce.CompositeRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		var resp []map[string]interface{}
+		for _, sr := range req.Subrequests {
+			subs = append(subs, *sr)
+			resp = append(resp, map[string]interface{}{"httpStatusCode": 201, "referenceId": sr.ReferenceID})
+		}
+		encodeObject(w, map[string]interface{}{"compositeResponse": resp})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/")
+
+	req := (&salesforce.CompositeRequest{AllOrNone: true}).
+		AddWithHeaders("post", "sobjects/Lead", "lead1", map[string]string{"Sforce-Auto-Assign": "FALSE"}, salesforce.RecordMap{"LastName": "L"}, nil).
+		Add("POST", "sobjects/Lead", "lead2", salesforce.RecordMap{"LastName": "L"}, nil)
+	if _, err := sv.Composite(ctx, req); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(subs) != 2 || subs[0].Method != "POST" || subs[0].HTTPHeaders["Sforce-Auto-Assign"] != "FALSE" || subs[1].HTTPHeaders != nil {
+		t.Errorf("unexpected subrequests %v", subs)
+	}
+	if req.Subrequests[0].Method != "post" {
+		t.Errorf("expected request to be unchanged; got %s", req.Subrequests[0].Method)
+	}
+
+	for _, bad := range []*salesforce.CompositeRequest{
+		(&salesforce.CompositeRequest{}).AddWithHeaders("GET", "limits", "a", map[string]string{"X-Test": "a\r\nb"}, nil, nil),
+		(&salesforce.CompositeRequest{}).AddWithHeaders("GET", "limits", "a", map[string]string{"authorization": "Bearer x"}, nil, nil),
+	} {
+		if _, err := sv.Composite(ctx, bad); !errors.Is(err, salesforce.ErrInvalidHeader) {
+			t.Errorf("expected ErrInvalidHeader; got %v", err)
+		}
+	}
+	for _, method := range []string{"OPTIONS", "HEAD"} {
+		if _, err := sv.Composite(ctx, (&salesforce.CompositeRequest{}).Add(method, "limits", "a", nil, nil)); err == nil {
+			t.Errorf("expected invalid method error for %s", method)
+		}
+	}
+	if len(subs) != 2 {
+		t.Errorf("expected invalid requests not to be sent; got %d subrequests", len(subs))
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+// TestService_ConcurrentWith derives and uses services from a shared base in
+// parallel.  Run with -race to detect shared mutable state.
+func TestService_ConcurrentWith(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := r.Header.Get("X-Worker"); !strings.Contains(r.Header.Get("User-Agent"), "worker"+want) {
+			http.Error(w, `[{"errorCode":"BAD","message":"`+r.Header.Get("User-Agent")+`"}]`, http.StatusBadRequest)
+			return
+		}
+		encodeObject(w, map[string]interface{}{"done": true, "totalSize": 0, "records": []interface{}{}})
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	ids := map[string]string{"Account": "Vendor_ID__c"}
+	base := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithExternalIDs(ids).WithFieldMask(salesforce.FieldMask{"Name"})
+	ids["Account"] = "Changed__c"
+	if fld := base.ExternalIDField("Account"); fld != "Vendor_ID__c" {
+		t.Fatalf("expected external ids to be copied; got %s", fld)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sv, err := base.WithUserAgent(fmt.Sprintf("worker%d", i), "1").
+				WithExternalIDs(map[string]string{"Contact": fmt.Sprintf("Ext%d__c", i)}).
+				WithAutoTruncate(&salesforce.SObjectDefinition{Name: "Account"}).
+				WithHeaders(map[string]string{"X-Worker": fmt.Sprint(i)})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if i%2 == 0 {
+				sv = sv.WithTooling()
+			}
+			var recs []salesforce.RecordMap
+			if err := sv.Query(ctx, "SELECT Id FROM Account", &recs); err != nil {
+				errs <- fmt.Errorf("worker %d: %v", i, err)
+			}
+			if fld := sv.ExternalIDField("Contact"); fld != fmt.Sprintf("Ext%d__c", i) {
+				errs <- fmt.Errorf("worker %d: unexpected external id %s", i, fld)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if base.ExternalIDField("Contact") != "" {
+		t.Errorf("base service was modified")
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// ContentVersion describes the file created by UploadFile.  PathOnClient, the file
+// name including extension, is required.
+// https://developer.salesforce.com/docs/atlas.en-us.object_reference.meta/object_reference/sforce_api_objects_contentversion.htm
+type ContentVersion struct {
+	Attributes        *Attributes `json:"attributes,omitempty"`
+	ID                string      `json:"Id,omitempty"`
+	Title             string      `json:"Title,omitempty"`
+	PathOnClient      string      `json:"PathOnClient,omitempty"`
+	Description       string      `json:"Description,omitempty"`
+	ReasonForChange   string      `json:"ReasonForChange,omitempty"`
+	ContentDocumentID string      `json:"ContentDocumentId,omitempty"`
+	OwnerID           string      `json:"OwnerId,omitempty"`
+	SharingPrivacy    string      `json:"SharingPrivacy,omitempty"`
+}
+
+// SObjectName returns ContentVersion
+func (cv ContentVersion) SObjectName() string {
+	return "ContentVersion"
+}
+
+// WithAttr returns a new ContentVersion with attributes of Type="ContentVersion" and Ref=ref
+func (cv ContentVersion) WithAttr(ref string) SObject {
+	cv.Attributes = &Attributes{Type: "ContentVersion", Ref: ref}
+	return cv
+}
+
+// FileLink determines the access given to the parent records of an uploaded file.
+// An empty ShareType indicates "V" (viewer) and an empty Visibility uses the org default.
+// https://developer.salesforce.com/docs/atlas.en-us.object_reference.meta/object_reference/sforce_api_objects_contentdocumentlink.htm
+type FileLink struct {
+	ShareType  string // V (viewer), C (collaborator) or I (inferred)
+	Visibility string // AllUsers, InternalUsers or SharedUsers
+}
+
+// UploadedFile contains the ids created by UploadFile
+type UploadedFile struct {
+	ContentVersionID  string
+	ContentDocumentID string
+	// Links are the responses of the ContentDocumentLink inserts in parent id order
+	Links []OpResponse
+}
+
+// ErrFileLink is returned by UploadFile when a ContentDocumentLink is not created
+var ErrFileLink = errors.New("content document link failed")
+
+// UploadFile streams rdr to a new ContentVersion described by cv, reads the ContentDocumentId
+// of the new version and creates a ContentDocumentLink attaching the document to each of
+// parentIDs.  The file is sent as a multipart request without buffering, so the request
+// cannot be retried or redirected.  When links are not created, the returned UploadedFile
+// contains the created ids and the link responses, and the error wraps ErrFileLink.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_sobject_insert_update_blob.htm
+func (sv *Service) UploadFile(ctx context.Context, rdr io.Reader, cv ContentVersion, link FileLink, parentIDs ...string) (*UploadedFile, error) {
+	if cv.PathOnClient == "" {
+		return nil, errors.New("PathOnClient may not be empty")
+	}
+	if cv.Title == "" {
+		cv.Title = cv.PathOnClient
+	}
+	cv.Attributes, cv.ID, cv.ContentDocumentID = nil, "", ""
+	entity, err := json.Marshal(cv)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeFileParts(mw, entity, cv.PathOnClient, rdr))
+	}()
+	var res *OpResponse
+	if err := sv.Call(ctx, "sobjects/ContentVersion", "POST", pr, &res,
+		ContentTypeHeader(mw.FormDataContentType())); err != nil {
+		return nil, err
+	}
+	if res == nil || !res.Success || res.ID == "" {
+		return nil, fmt.Errorf("content version not created: %v", res)
+	}
+	uf := &UploadedFile{ContentVersionID: res.ID}
+	var created ContentVersion
+	if err := sv.Get(ctx, &created, res.ID, "ContentDocumentId"); err != nil {
+		return uf, err
+	}
+	if created.ContentDocumentID == "" {
+		return uf, fmt.Errorf("content version %s has no ContentDocumentId", res.ID)
+	}
+	uf.ContentDocumentID = created.ContentDocumentID
+	uf.Links, err = sv.linkDocument(ctx, uf.ContentDocumentID, link, parentIDs)
+	return uf, err
+}
+
+// linkDocument creates a ContentDocumentLink for each parent id
+func (sv *Service) linkDocument(ctx context.Context, documentID string, link FileLink, parentIDs []string) ([]OpResponse, error) {
+	if len(parentIDs) == 0 {
+		return nil, nil
+	}
+	if link.ShareType == "" {
+		link.ShareType = "V"
+	}
+	var links = make([]SObject, 0, len(parentIDs))
+	for _, id := range parentIDs {
+		lnk := RecordMap{
+			"attributes":        map[string]interface{}{"type": "ContentDocumentLink"},
+			"ContentDocumentId": documentID,
+			"LinkedEntityId":    id,
+			"ShareType":         link.ShareType,
+		}
+		if link.Visibility > "" {
+			lnk["Visibility"] = link.Visibility
+		}
+		links = append(links, lnk)
+	}
+	res, err := sv.CreateRecords(ctx, false, links)
+	if err != nil {
+		return res, err
+	}
+	var failed []string
+	for i, r := range res {
+		if !r.Success && i < len(parentIDs) {
+			failed = append(failed, parentIDs[i])
+		}
+	}
+	if len(failed) > 0 {
+		return res, fmt.Errorf("%w: %s", ErrFileLink, strings.Join(failed, ","))
+	}
+	return res, nil
+}
+
+// writeFileParts writes the entity_content and VersionData parts of a
+// ContentVersion insert
+func writeFileParts(mw *multipart.Writer, entity []byte, fileName string, rdr io.Reader) error {
+	hdr := make(textproto.MIMEHeader)
+	hdr.Set("Content-Disposition", `form-data; name="entity_content"`)
+	hdr.Set("Content-Type", "application/json")
+	w, err := mw.CreatePart(hdr)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(entity); err != nil {
+		return err
+	}
+	hdr = make(textproto.MIMEHeader)
+	hdr.Set("Content-Disposition", fmt.Sprintf(`form-data; name="VersionData"; filename=%q`, fileName))
+	hdr.Set("Content-Type", "application/octet-stream")
+	if w, err = mw.CreatePart(hdr); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, rdr); err != nil {
+		return err
+	}
+	return mw.Close()
+}
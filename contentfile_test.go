@@ -0,0 +1,85 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_UploadFile(t *testing.T) {
+	var entity map[string]interface{}
+	var data, fileName string
+	var links []map[string]interface{}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /sobjects/ContentVersion":
+			mr, err := r.MultipartReader()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for p, err := mr.NextPart(); err == nil; p, err = mr.NextPart() {
+				b, _ := ioutil.ReadAll(p)
+				switch p.FormName() {
+				case "entity_content":
+					json.Unmarshal(b, &entity)
+				case "VersionData":
+					data, fileName = string(b), p.FileName()
+				}
+			}
+			encodeObject(w, salesforce.OpResponse{ID: "068A", Success: true})
+		case "GET /sobjects/ContentVersion/068A":
+			encodeObject(w, map[string]interface{}{"Id": "068A", "ContentDocumentId": "069A"})
+		case "POST /composite/sobjects":
+			var body struct {
+				Records []map[string]interface{} `json:"records"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			links = body.Records
+			encodeObject(w, []salesforce.OpResponse{{ID: "06AA", Success: true}, {Success: false, Errors: []salesforce.Error{{StatusCode: "INSUFFICIENT_ACCESS_OR_READONLY"}}}})
+		default:
+			http.Error(w, "not found "+r.URL.Path, http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	uf, err := sv.UploadFile(ctx, strings.NewReader("file contents"), salesforce.ContentVersion{PathOnClient: "report.txt"},
+		salesforce.FileLink{Visibility: "AllUsers"}, "001A", "001B")
+	if !errors.Is(err, salesforce.ErrFileLink) || !strings.HasSuffix(err.Error(), "001B") {
+		t.Fatalf("expected link error for 001B; got %v", err)
+	}
+	if uf == nil || uf.ContentVersionID != "068A" || uf.ContentDocumentID != "069A" || len(uf.Links) != 2 || !uf.Links[0].Success {
+		t.Fatalf("unexpected result %#v", uf)
+	}
+	if entity["PathOnClient"] != "report.txt" || entity["Title"] != "report.txt" || data != "file contents" || fileName != "report.txt" {
+		t.Errorf("unexpected upload %v %q %q", entity, data, fileName)
+	}
+	if len(links) != 2 || links[1]["LinkedEntityId"] != "001B" || links[0]["ContentDocumentId"] != "069A" ||
+		links[0]["ShareType"] != "V" || links[0]["Visibility"] != "AllUsers" {
+		t.Errorf("unexpected links %v", links)
+	}
+
+	uf, err = sv.UploadFile(ctx, strings.NewReader("x"), salesforce.ContentVersion{PathOnClient: "a.txt", Title: "A"}, salesforce.FileLink{})
+	if err != nil || uf.ContentDocumentID != "069A" || uf.Links != nil {
+		t.Errorf("expected upload without links; got %#v %v", uf, err)
+	}
+	if _, err := sv.UploadFile(ctx, strings.NewReader("x"), salesforce.ContentVersion{}, salesforce.FileLink{}); err == nil {
+		t.Errorf("expected error for empty PathOnClient")
+	}
+}
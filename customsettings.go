@@ -0,0 +1,209 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrRecordNotFound is returned when a custom metadata or custom setting record does not exist
+var ErrRecordNotFound = errors.New("record not found")
+
+// customFields returns fields or the FieldNames of v along with required
+func customFields(v interface{}, fields []string, required ...string) []string {
+	if len(fields) == 0 {
+		fields = FieldNames(v)
+	}
+	var found = make(map[string]bool)
+	for _, f := range fields {
+		found[strings.ToLower(f)] = true
+	}
+	for _, r := range required {
+		if !found[strings.ToLower(r)] {
+			fields = append(fields, r)
+		}
+	}
+	return fields
+}
+
+// CustomMetadata queries the records of the custom metadata type (e.g. Region__mdt) into results,
+// a pointer to a slice as used by Query.  When no fields are passed, FieldNames(results) are selected.
+// https://developer.salesforce.com/docs/atlas.en-us.custommetadatatypes.meta/custommetadatatypes/custommetadatatypes_overview.htm
+func (sv *Service) CustomMetadata(ctx context.Context, typeName string, results interface{}, fields ...string) error {
+	if !strings.HasSuffix(typeName, "__mdt") {
+		return fmt.Errorf("%s is not a custom metadata type", typeName)
+	}
+	fields = customFields(results, fields, "DeveloperName")
+	return sv.Query(ctx, "SELECT "+strings.Join(fields, ", ")+" FROM "+typeName, results)
+}
+
+// CustomMetadataRecord decodes the custom metadata record with developerName into result,
+// a pointer to a struct or RecordMap.  ErrRecordNotFound is returned when no record exists.
+// When no fields are passed, FieldNames(result) are selected.
+func (sv *Service) CustomMetadataRecord(ctx context.Context, typeName, developerName string, result interface{}, fields ...string) error {
+	if !strings.HasSuffix(typeName, "__mdt") {
+		return fmt.Errorf("%s is not a custom metadata type", typeName)
+	}
+	fields = customFields(result, fields, "DeveloperName")
+	qry := "SELECT " + strings.Join(fields, ", ") + " FROM " + typeName +
+		" WHERE DeveloperName = " + SOQLString(developerName) + " LIMIT 1"
+	return sv.querySingle(ctx, qry, result)
+}
+
+// ListSetting decodes the list custom setting record with name into result, a pointer to a
+// struct or RecordMap.  ErrRecordNotFound is returned when no record exists.  When no fields
+// are passed, FieldNames(result) are selected.
+// https://help.salesforce.com/s/articleView?id=sf.cs_about.htm
+func (sv *Service) ListSetting(ctx context.Context, settingName, name string, result interface{}, fields ...string) error {
+	fields = customFields(result, fields, "Name")
+	qry := "SELECT " + strings.Join(fields, ", ") + " FROM " + settingName +
+		" WHERE Name = " + SOQLString(name) + " LIMIT 1"
+	return sv.querySingle(ctx, qry, result)
+}
+
+// querySingle decodes the first record returned by qry into result
+func (sv *Service) querySingle(ctx context.Context, qry string, result interface{}) error {
+	var recs []RecordMap
+	if err := sv.Query(ctx, qry, &recs); err != nil {
+		return err
+	}
+	if len(recs) == 0 {
+		return ErrRecordNotFound
+	}
+	return decodeRecordMap(recs[0], result)
+}
+
+// validateRecordPtr checks that v is a non-nil pointer to a struct or map
+func validateRecordPtr(v interface{}) error {
+	ty := reflect.TypeOf(v)
+	if ty != nil && ty.Kind() == reflect.Ptr && !reflect.ValueOf(v).IsNil() {
+		switch ty.Elem().Kind() {
+		case reflect.Struct, reflect.Map:
+			return nil
+		}
+	}
+	return &TypeError{Expected: "non-nil pointer to a struct or map", Got: ty}
+}
+
+// decodeRecordMap decodes m into result
+func decodeRecordMap(m RecordMap, result interface{}) error {
+	if err := validateRecordPtr(result); err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, result)
+}
+
+// SettingLevel identifies the owner of a hierarchy custom setting record
+type SettingLevel int
+
+// Hierarchy custom setting levels from least to most specific
+const (
+	SettingNotFound SettingLevel = iota
+	SettingOrganization
+	SettingProfile
+	SettingUser
+)
+
+// String returns the name of the level
+func (sl SettingLevel) String() string {
+	switch sl {
+	case SettingOrganization:
+		return "Organization"
+	case SettingProfile:
+		return "Profile"
+	case SettingUser:
+		return "User"
+	}
+	return "NotFound"
+}
+
+// settingLevel returns the level of a SetupOwnerId using its key prefix
+func settingLevel(ownerID string) SettingLevel {
+	switch {
+	case strings.HasPrefix(ownerID, "00D"):
+		return SettingOrganization
+	case strings.HasPrefix(ownerID, "00e"):
+		return SettingProfile
+	case strings.HasPrefix(ownerID, "005"):
+		return SettingUser
+	}
+	return SettingNotFound
+}
+
+// HierarchySetting decodes the values of the hierarchy custom setting (e.g. Integration_Settings__c)
+// in effect for userID into result, a pointer to a struct or RecordMap.  Like the Apex getInstance
+// method, the organization default record is overlaid with the non-null fields of the record
+// owned by the user's profile and then with those of the record owned by the user.  An empty
+// userID returns the organization default values.  The returned SettingLevel is the most specific
+// level of the records found; SettingNotFound indicates no record applies and result is unchanged.
+// When no fields are passed, FieldNames(result) are selected, so pass fields for a RecordMap result.
+// Resolving a user requires queries of User and Organization in addition to the setting.
+// https://developer.salesforce.com/docs/atlas.en-us.apexcode.meta/apexcode/apex_customsettings.htm#apex_customsettings_hierarchy
+func (sv *Service) HierarchySetting(ctx context.Context, settingName, userID string, result interface{}, fields ...string) (SettingLevel, error) {
+	if err := validateRecordPtr(result); err != nil {
+		return SettingNotFound, err
+	}
+	var org []RecordMap
+	if err := sv.Query(ctx, "SELECT Id FROM Organization LIMIT 1", &org); err != nil {
+		return SettingNotFound, err
+	}
+	if len(org) == 0 {
+		return SettingNotFound, errors.New("organization id not found")
+	}
+	var owners = []string{fmt.Sprint(org[0]["Id"])}
+	if userID > "" {
+		var users []RecordMap
+		if err := sv.Query(ctx, "SELECT Id, ProfileId FROM User WHERE Id = "+SOQLString(userID), &users); err != nil {
+			return SettingNotFound, err
+		}
+		if len(users) == 0 {
+			return SettingNotFound, fmt.Errorf("user %s not found", userID)
+		}
+		owners = append(owners, fmt.Sprint(users[0]["ProfileId"]), userID)
+	}
+	for i := range owners {
+		owners[i] = SOQLString(owners[i])
+	}
+	fields = customFields(result, fields, "SetupOwnerId")
+	var recs []RecordMap
+	qry := "SELECT " + strings.Join(fields, ", ") + " FROM " + settingName +
+		" WHERE SetupOwnerId IN (" + strings.Join(owners, ", ") + ")"
+	if err := sv.Query(ctx, qry, &recs); err != nil {
+		return SettingNotFound, err
+	}
+	var byLevel = make(map[SettingLevel]RecordMap)
+	for _, r := range recs {
+		ownerID, _ := r["SetupOwnerId"].(string)
+		byLevel[settingLevel(ownerID)] = r
+	}
+	var merged = make(RecordMap)
+	var level = SettingNotFound
+	for _, lvl := range []SettingLevel{SettingOrganization, SettingProfile, SettingUser} {
+		r, ok := byLevel[lvl]
+		if !ok {
+			continue
+		}
+		level = lvl
+		for k, v := range r {
+			if v != nil {
+				merged[k] = v
+			}
+		}
+	}
+	if level == SettingNotFound {
+		return level, nil
+	}
+	return level, decodeRecordMap(merged, result)
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+type RegionMeta struct {
+	DeveloperName string `json:"DeveloperName"`
+	Code          string `json:"Code__c"`
+}
+
+type IntegrationSettings struct {
+	SetupOwnerID string  `json:"SetupOwnerId"`
+	Endpoint     string  `json:"Endpoint__c"`
+	BatchSize    float64 `json:"Batch_Size__c"`
+	Enabled      bool    `json:"Enabled__c"`
+}
+
+func TestService_CustomSettings(t *testing.T) {
+	var queries []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qry := r.URL.Query().Get("q")
+		queries = append(queries, qry)
+		var recs []map[string]interface{}
+		switch {
+		case strings.HasSuffix(qry, "FROM Region__mdt"):
+			recs = append(recs, map[string]interface{}{"DeveloperName": "East", "Code__c": "E"},
+				map[string]interface{}{"DeveloperName": "West", "Code__c": "W"})
+		case strings.Contains(qry, "FROM Region__mdt WHERE DeveloperName = 'West'"):
+			recs = append(recs, map[string]interface{}{"DeveloperName": "West", "Code__c": "W"})
+		case qry == "SELECT Id FROM Organization LIMIT 1":
+			recs = append(recs, map[string]interface{}{"Id": "00DA"})
+		case strings.HasPrefix(qry, "SELECT Id, ProfileId FROM User"):
+			recs = append(recs, map[string]interface{}{"Id": "005A", "ProfileId": "00eA"})
+		case strings.Contains(qry, "FROM Integration_Settings__c WHERE SetupOwnerId IN ('00DA', '00eA', '005A')"):
+			recs = append(recs,
+				map[string]interface{}{"SetupOwnerId": "00DA", "Endpoint__c": "https://org", "Batch_Size__c": 200, "Enabled__c": true},
+				map[string]interface{}{"SetupOwnerId": "00eA", "Endpoint__c": "https://profile", "Batch_Size__c": nil, "Enabled__c": false},
+				map[string]interface{}{"SetupOwnerId": "005A", "Endpoint__c": nil, "Batch_Size__c": 50, "Enabled__c": false})
+		case strings.Contains(qry, "FROM Integration_Settings__c WHERE SetupOwnerId IN ('00DA')"):
+			recs = append(recs, map[string]interface{}{"SetupOwnerId": "00DA", "Endpoint__c": "https://org", "Batch_Size__c": 200, "Enabled__c": true})
+		}
+		encodeObject(w, map[string]interface{}{"totalSize": len(recs), "done": true, "records": recs})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var regions []RegionMeta
+	if err := sv.CustomMetadata(ctx, "Region__mdt", &regions); err != nil || len(regions) != 2 {
+		t.Fatalf("expected 2 regions; got %v %v", regions, err)
+	}
+	if queries[0] != "SELECT DeveloperName, Code__c FROM Region__mdt" {
+		t.Errorf("unexpected query %s", queries[0])
+	}
+	if err := sv.CustomMetadata(ctx, "Region__c", &regions); err == nil {
+		t.Errorf("expected invalid type name error")
+	}
+	var region RegionMeta
+	if err := sv.CustomMetadataRecord(ctx, "Region__mdt", "West", &region); err != nil || region.Code != "W" {
+		t.Errorf("expected West region; got %v %v", region, err)
+	}
+	if err := sv.CustomMetadataRecord(ctx, "Region__mdt", "North", &region); err != salesforce.ErrRecordNotFound {
+		t.Errorf("expected %v; got %v", salesforce.ErrRecordNotFound, err)
+	}
+	if err := sv.CustomMetadataRecord(ctx, "Region__mdt", "West", region); err == nil {
+		t.Errorf("expected non-pointer error")
+	}
+
+	var settings IntegrationSettings
+	level, err := sv.HierarchySetting(ctx, "Integration_Settings__c", "005A", &settings)
+	if err != nil || level != salesforce.SettingUser {
+		t.Fatalf("expected user level; got %v %v", level, err)
+	}
+	if settings.Endpoint != "https://profile" || settings.BatchSize != 50 || settings.Enabled || settings.SetupOwnerID != "005A" {
+		t.Errorf("unexpected merged settings %#v", settings)
+	}
+	var orgSettings = make(salesforce.RecordMap)
+	level, err = sv.HierarchySetting(ctx, "Integration_Settings__c", "", &orgSettings, "Endpoint__c")
+	if err != nil || level != salesforce.SettingOrganization || orgSettings["Endpoint__c"] != "https://org" {
+		t.Errorf("expected org settings; got %v %v %v", level, orgSettings, err)
+	}
+}
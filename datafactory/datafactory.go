@@ -0,0 +1,319 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package datafactory generates random records that satisfy an SObject's describe
+// for load testing sandboxes.  Generated values respect field lengths, scale and
+// precision, active picklist values and required fields, and unique and external id
+// fields receive values unique within the Factory.  Records may be sent using the
+// collection calls (e.g. CreateRecords) or a bulk pipeline.
+//
+//	def, _ := sv.Describe(ctx, "Contact")
+//	f := datafactory.New(def, time.Now().UnixNano())
+//	f.References = map[string][]string{"AccountId": accountIDs}
+//	recs, err := f.Records(1000)
+//	...
+//	res, err := sv.CreateRecordsWithOptions(ctx, recs)
+package datafactory // import github.com/jfcote87/salesforce/datafactory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+// ErrNoReference is returned when a required reference field has no ids
+// in the Factory's References
+var ErrNoReference = errors.New("no reference ids")
+
+// Factory generates random records of an SObject.  A Factory is safe for
+// concurrent use.  Set fields before generating records.
+type Factory struct {
+	// RequiredOnly limits generated fields to those required on create
+	RequiredOnly bool
+	// Skip lists fields that are not generated
+	Skip []string
+	// References lists the ids that reference fields (e.g. AccountId) are set to.
+	// Optional reference fields without ids are not generated.
+	References map[string][]string
+	// Values returns the value of a field for the i-th record generated,
+	// replacing the random value
+	Values map[string]func(i int) interface{}
+	// Now is the reference time of generated dates, which fall within the
+	// year before Now.  A zero value indicates time.Now.
+	Now time.Time
+
+	def  *salesforce.SObjectDefinition
+	m    sync.Mutex
+	rnd  *rand.Rand
+	cnt  int
+	used map[string]map[string]bool // unique field values
+}
+
+// New returns a Factory for def seeded with seed.  The same seed and
+// settings generate the same records.
+func New(def *salesforce.SObjectDefinition, seed int64) *Factory {
+	return &Factory{
+		def:  def,
+		rnd:  rand.New(rand.NewSource(seed)),
+		used: make(map[string]map[string]bool),
+	}
+}
+
+// Record returns a random record
+func (f *Factory) Record() (salesforce.RecordMap, error) {
+	if f == nil || f.def == nil {
+		return nil, errors.New("nil definition")
+	}
+	f.m.Lock()
+	defer f.m.Unlock()
+	skip := make(map[string]bool)
+	for _, nm := range f.Skip {
+		skip[nm] = true
+	}
+	rec := salesforce.RecordMap{"attributes": map[string]interface{}{"type": f.def.Name}}
+	for i := range f.def.Fields {
+		fld := &f.def.Fields[i]
+		if !Generated(fld) || skip[fld.Name] || f.RequiredOnly && !Required(fld) {
+			continue
+		}
+		if fn, ok := f.Values[fld.Name]; ok {
+			rec[fld.Name] = fn(f.cnt)
+			continue
+		}
+		v, err := f.value(fld)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			rec[fld.Name] = v
+		}
+	}
+	f.cnt++
+	return rec, nil
+}
+
+// Records returns n random records
+func (f *Factory) Records(n int) ([]salesforce.SObject, error) {
+	var recs = make([]salesforce.SObject, 0, n)
+	for i := 0; i < n; i++ {
+		rec, err := f.Record()
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// Fill sets the fields of v, a pointer to a struct such as those generated
+// by the genpkgs package, from a random record.  Fields are matched by json name.
+func (f *Factory) Fill(v interface{}) error {
+	rec, err := f.Record()
+	if err != nil {
+		return err
+	}
+	delete(rec, "attributes")
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Generated returns true if fld may be set on create and is
+// not calculated by salesforce
+func Generated(fld *salesforce.Field) bool {
+	return fld.Createable && !fld.Calculated && !fld.AutoNumber && fld.Type != "id"
+}
+
+// Required returns true if fld must be set on create
+func Required(fld *salesforce.Field) bool {
+	return Generated(fld) && !fld.Nillable && !fld.DefaultedOnCreate && fld.Type != "boolean"
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyz"
+
+// value returns a random value for fld.  A nil value indicates that
+// the field is not set.  Caller must hold lock.
+func (f *Factory) value(fld *salesforce.Field) (interface{}, error) {
+	unique := fld.Unique || fld.ExternalID
+	switch fld.Type {
+	case "string", "textarea", "encryptedstring", "combobox":
+		if fld.Type == "combobox" && len(f.activeValues(fld)) > 0 && !unique {
+			return f.picklist(fld), nil
+		}
+		return f.unique(fld, unique, f.text(fld.Length), ""), nil
+	case "email":
+		return f.unique(fld, unique, f.word(8), "@example.com"), nil
+	case "phone":
+		return f.unique(fld, unique, fmt.Sprintf("(555) 555-%04d", f.rnd.Intn(10000)), ""), nil
+	case "url":
+		return f.unique(fld, unique, "https://example.com/"+f.word(8), ""), nil
+	case "picklist":
+		if fld.DependentPicklist && !Required(fld) {
+			return nil, nil
+		}
+		return f.picklist(fld), nil
+	case "multipicklist":
+		vals := f.activeValues(fld)
+		if len(vals) == 0 {
+			return nil, nil
+		}
+		f.rnd.Shuffle(len(vals), func(i, j int) { vals[i], vals[j] = vals[j], vals[i] })
+		return strings.Join(vals[:1+f.rnd.Intn(len(vals))], ";"), nil
+	case "boolean":
+		return f.rnd.Intn(2) == 1, nil
+	case "int":
+		digits := fld.Digits
+		if digits <= 0 || digits > 6 {
+			digits = 6
+		}
+		if unique {
+			return f.cnt + 1, nil
+		}
+		return f.rnd.Intn(int(math.Pow10(digits))), nil
+	case "double", "currency", "percent":
+		return f.number(fld, unique), nil
+	case "date":
+		return f.date().Format("2006-01-02"), nil
+	case "datetime":
+		return f.date().Add(time.Duration(f.rnd.Int63n(int64(24 * time.Hour)))).
+			Truncate(time.Second).Format("2006-01-02T15:04:05.000Z"), nil
+	case "time":
+		return time.Date(0, 1, 1, f.rnd.Intn(24), f.rnd.Intn(60), 0, 0, time.UTC).Format("15:04:05.000Z"), nil
+	case "reference":
+		ids := f.References[fld.Name]
+		if len(ids) == 0 {
+			if Required(fld) {
+				return nil, fmt.Errorf("%w for required field %s.%s", ErrNoReference, f.def.Name, fld.Name)
+			}
+			return nil, nil
+		}
+		return ids[f.rnd.Intn(len(ids))], nil
+	}
+	// address, location, base64 and other compound types are not generated
+	return nil, nil
+}
+
+// unique returns s followed by sfx truncated to the field length.  When unique is set,
+// the value is made unique within the factory by appending a number to s.
+func (f *Factory) unique(fld *salesforce.Field, unique bool, s, sfx string) string {
+	max := fld.Length
+	if max > 0 {
+		max -= len(sfx)
+	}
+	if !unique {
+		return truncate(s, max) + sfx
+	}
+	used := f.used[fld.Name]
+	if used == nil {
+		used = make(map[string]bool)
+		f.used[fld.Name] = used
+	}
+	for n := f.cnt; ; n++ {
+		num := strconv.Itoa(n)
+		v := num + sfx
+		if keep := max - len(num); max <= 0 || keep > 0 {
+			v = truncate(s, keep) + v
+		}
+		if !used[v] {
+			used[v] = true
+			return v
+		}
+	}
+}
+
+// truncate returns s shortened to n bytes.  n <= 0 indicates no limit.
+func truncate(s string, n int) string {
+	if n > 0 && len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// word returns n random letters
+func (f *Factory) word(n int) string {
+	var b = make([]byte, n)
+	for i := range b {
+		b[i] = letters[f.rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// text returns random words no longer than max.  max <= 0 indicates 255.
+func (f *Factory) text(max int) string {
+	if max <= 0 {
+		max = 255
+	}
+	n := 5 + f.rnd.Intn(20)
+	if n > max {
+		n = max
+	}
+	var sb strings.Builder
+	for sb.Len() < n {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.word(1 + f.rnd.Intn(8)))
+	}
+	s := strings.TrimSpace(truncate(sb.String(), n))
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// activeValues returns the active picklist values of fld
+func (f *Factory) activeValues(fld *salesforce.Field) []string {
+	var vals []string
+	for _, pv := range fld.PicklistValues {
+		if pv.Active {
+			vals = append(vals, pv.Value)
+		}
+	}
+	return vals
+}
+
+// picklist returns a random active value of fld or random
+// text when fld has no active values
+func (f *Factory) picklist(fld *salesforce.Field) string {
+	vals := f.activeValues(fld)
+	if len(vals) == 0 {
+		return f.text(fld.Length)
+	}
+	return vals[f.rnd.Intn(len(vals))]
+}
+
+// number returns a random number fitting the precision and scale of fld
+func (f *Factory) number(fld *salesforce.Field, unique bool) float64 {
+	if unique {
+		return float64(f.cnt + 1)
+	}
+	whole := fld.Precision - fld.Scale
+	if whole <= 0 || whole > 6 {
+		whole = 6
+	}
+	max := math.Pow10(whole)
+	if fld.Type == "percent" && max > 100 {
+		max = 100
+	}
+	scale := math.Pow10(fld.Scale)
+	return math.Floor(f.rnd.Float64()*max*scale) / scale
+}
+
+// date returns a random date in the year before Now
+func (f *Factory) date() time.Time {
+	now := f.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -f.rnd.Intn(365))
+}
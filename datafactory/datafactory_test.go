@@ -0,0 +1,122 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package datafactory_test
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+	"github.com/jfcote87/salesforce/datafactory"
+)
+
+var contactDef = &salesforce.SObjectDefinition{
+	Name: "Contact",
+	Fields: []salesforce.Field{
+		{Name: "Id", Type: "id"},
+		{Name: "LastName", Type: "string", Length: 20, Createable: true},
+		{Name: "Email", Type: "email", Length: 80, Createable: true, Nillable: true},
+		{Name: "Vendor_ID__c", Type: "string", Length: 6, Createable: true, Nillable: true, ExternalID: true, Unique: true},
+		{Name: "LeadSource", Type: "picklist", Createable: true, Nillable: true, PicklistValues: []salesforce.PickListValue{
+			{Value: "Web", Active: true}, {Value: "Phone", Active: true}, {Value: "Old"}}},
+		{Name: "Amount__c", Type: "currency", Precision: 5, Scale: 2, Createable: true, Nillable: true},
+		{Name: "Birthdate", Type: "date", Createable: true, Nillable: true},
+		{Name: "DoNotCall", Type: "boolean", Createable: true},
+		{Name: "AccountId", Type: "reference", Createable: true, Nillable: true},
+		{Name: "Region__c", Type: "reference", Createable: true},
+		{Name: "Name", Type: "string", Length: 121},
+		{Name: "Formula__c", Type: "string", Createable: true, Calculated: true},
+	},
+}
+
+func TestFactory(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := datafactory.New(contactDef, 1)
+	f.Now = now
+	if _, err := f.Record(); !errors.Is(err, datafactory.ErrNoReference) {
+		t.Fatalf("expected ErrNoReference; got %v", err)
+	}
+	f.References = map[string][]string{"Region__c": {"a01A", "a01B"}}
+	recs, err := f.Records(500)
+	if err != nil {
+		t.Fatalf("expected records; got %v", err)
+	}
+	vendorIDs := make(map[string]bool)
+	for _, sobj := range recs {
+		rec := sobj.(salesforce.RecordMap)
+		if rec.SObjectName() != "Contact" {
+			t.Fatalf("expected Contact; got %s", rec.SObjectName())
+		}
+		for _, nm := range []string{"Id", "Name", "Formula__c", "AccountId"} {
+			if _, ok := rec[nm]; ok {
+				t.Fatalf("unexpected field %s in %v", nm, rec)
+			}
+		}
+		if nm, _ := rec["LastName"].(string); nm == "" || len(nm) > 20 {
+			t.Errorf("invalid LastName %q", nm)
+		}
+		id, _ := rec["Vendor_ID__c"].(string)
+		if id == "" || len(id) > 6 || vendorIDs[id] {
+			t.Errorf("invalid or duplicate Vendor_ID__c %q", id)
+		}
+		vendorIDs[id] = true
+		if ls := rec["LeadSource"]; ls != "Web" && ls != "Phone" {
+			t.Errorf("invalid LeadSource %v", ls)
+		}
+		amt, _ := rec["Amount__c"].(float64)
+		if s := strconv.FormatFloat(amt, 'f', -1, 64); amt < 0 || amt >= 1000 ||
+			strings.Contains(s, ".") && len(s)-strings.Index(s, ".") > 3 {
+			t.Errorf("invalid Amount__c %v", rec["Amount__c"])
+		}
+		bd, err := time.Parse("2006-01-02", rec["Birthdate"].(string))
+		if err != nil || bd.After(now) || bd.Before(now.AddDate(-1, 0, 0)) {
+			t.Errorf("invalid Birthdate %v", rec["Birthdate"])
+		}
+		if email, _ := rec["Email"].(string); !strings.HasSuffix(email, "@example.com") {
+			t.Errorf("invalid Email %q", email)
+		}
+		if r := rec["Region__c"]; r != "a01A" && r != "a01B" {
+			t.Errorf("invalid Region__c %v", r)
+		}
+	}
+
+	f2 := datafactory.New(contactDef, 1)
+	f2.Now, f2.References = now, f.References
+	f2.RequiredOnly = true
+	f2.Values = map[string]func(int) interface{}{"LastName": func(i int) interface{} { return "Test" }}
+	rec, err := f2.Record()
+	if err != nil {
+		t.Fatalf("expected required only record; got %v", err)
+	}
+	delete(rec, "attributes")
+	if len(rec) != 2 || rec["LastName"] != "Test" || rec["Region__c"] == nil {
+		t.Errorf("expected LastName and Region__c; got %v", rec)
+	}
+
+	type contact struct {
+		LastName  string           `json:"LastName"`
+		Amount    float64          `json:"Amount__c"`
+		Birthdate *salesforce.Date `json:"Birthdate"`
+		DoNotCall bool             `json:"DoNotCall"`
+	}
+	var c contact
+	if err := f.Fill(&c); err != nil || c.LastName == "" || c.Birthdate == nil {
+		t.Errorf("expected filled struct; got %v %#v", err, c)
+	}
+
+	a, b := datafactory.New(contactDef, 7), datafactory.New(contactDef, 7)
+	a.Now, b.Now = now, now
+	a.References, b.References = f.References, f.References
+	ra, _ := a.Record()
+	rb, _ := b.Record()
+	if !reflect.DeepEqual(ra, rb) {
+		t.Errorf("expected same seed to generate same record; got %v and %v", ra, rb)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// DeleteByQueryOptions determine how DeleteByQuery deletes records
+type DeleteByQueryOptions struct {
+	AllOrNone     bool // collection deletes roll back a batch if any record fails
+	BulkThreshold int  // use a Bulk API delete job when the number of ids >= BulkThreshold; 0 disables
+	HardDelete    bool // bulk jobs use hardDelete rather than delete
+}
+
+// DeleteByQueryResult contains the results of a DeleteByQuery.  Responses
+// contains the collection delete responses.  When Bulk API jobs are used,
+// Bulk contains the BulkDeleteIDs result of the completed jobs, including
+// the records that failed to delete.
+type DeleteByQueryResult struct {
+	Count     int
+	Responses []OpResponse
+	Bulk      *BulkDeleteResult
+}
+
+// DeleteByQuery pages through the results of soql, which must select the Id field, and
+// deletes the returned records using collection deletes or, for large volumes, Bulk API
+// delete jobs (see BulkDeleteIDs).  The object of bulk jobs is the object of the query's
+// FROM clause.  DeleteByQuery waits until the bulk jobs complete, so use a ctx deadline
+// long enough for large deletes.
+func (sv *Service) DeleteByQuery(ctx context.Context, soql string, opts *DeleteByQueryOptions) (*DeleteByQueryResult, error) {
+	if opts == nil {
+		opts = &DeleteByQueryOptions{}
+	}
+	ids, err := sv.queryIDs(ctx, soql)
+	if err != nil {
+		return nil, err
+	}
+	var result = &DeleteByQueryResult{Count: len(ids)}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	if opts.BulkThreshold > 0 && len(ids) >= opts.BulkThreshold {
+		object := QueryObject(soql)
+		if object == "" {
+			return nil, errors.New("unable to determine sobject from query")
+		}
+		result.Bulk, err = sv.BulkDeleteIDs(ctx, object, ids, opts.HardDelete)
+		return result, err
+	}
+	result.Responses, err = sv.DeleteRecords(ctx, opts.AllOrNone, ids)
+	return result, err
+}
+
+// queryIDs returns the Id field of each record returned by soql
+func (sv *Service) queryIDs(ctx context.Context, soql string) ([]string, error) {
+	var ids []string
+	var page struct {
+		pageInfo
+		Records []struct {
+			ID string `json:"Id"`
+		} `json:"records"`
+	}
+	qsv := sv.querySvc()
+	err := qsv.paginate(ctx, "query/?q="+url.QueryEscape(soql), func() pager {
+		page.pageInfo, page.Records = pageInfo{}, nil
+		return &page
+	}, func(pager) error {
+		for _, r := range page.Records {
+			if r.ID > "" {
+				ids = append(ids, r.ID)
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_DeleteByQuery(t *testing.T) {
+	bs := &bulkTestServer{uploads: make(map[string][]byte)}
+	var deleted []string
+	var definitions []salesforce.JobDefinition
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/query/" && r.URL.Query().Get("q") == "SELECT Id FROM Contact WHERE Old__c = true":
+			encodeObject(w, map[string]interface{}{
+				"done":           false,
+				"nextRecordsUrl": "/query/01g-2",
+				"records":        []map[string]string{{"Id": "C1"}, {"Id": "C2"}},
+			})
+		case r.URL.Path == "/query/":
+			encodeObject(w, map[string]interface{}{"done": true, "records": []map[string]string{}})
+		case r.URL.Path == "/query/01g-2":
+			encodeObject(w, map[string]interface{}{
+				"done":    true,
+				"records": []map[string]string{{"Id": "C3"}},
+			})
+		case r.Method == "POST" && r.URL.Path == "/jobs/ingest/":
+			b, _ := ioutil.ReadAll(r.Body)
+			var jd salesforce.JobDefinition
+			json.Unmarshal(b, &jd)
+			definitions = append(definitions, jd)
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			bs.ServeHTTP(w, r)
+		case r.Method == "DELETE" && r.URL.Path == "/composite/sobjects":
+			var responses []salesforce.OpResponse
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				deleted = append(deleted, id)
+				responses = append(responses, salesforce.OpResponse{ID: id, Success: true})
+			}
+			encodeObject(w, responses)
+		default:
+			bs.ServeHTTP(w, r)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithBatchSize(2)
+	qry := "SELECT Id FROM Contact WHERE Old__c = true"
+
+	res, err := sv.DeleteByQuery(ctx, qry, nil)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if res.Count != 3 || len(res.Responses) != 3 || res.Bulk != nil || strings.Join(deleted, ",") != "C1,C2,C3" {
+		t.Errorf("expected 3 collection deletes; got %d %d %v", res.Count, len(res.Responses), deleted)
+	}
+
+	res, err = sv.DeleteByQuery(ctx, qry, &salesforce.DeleteByQueryOptions{BulkThreshold: 3, HardDelete: true})
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if res.Bulk == nil || len(res.Bulk.JobIDs) != 1 {
+		t.Fatalf("expected a single bulk job; got %#v", res.Bulk)
+	}
+	if len(definitions) != 1 || definitions[0].Object != "Contact" || definitions[0].Operation != salesforce.JobOperationHardDelete {
+		t.Errorf("expected Contact hardDelete job; got %v", definitions)
+	}
+	if upload := string(bs.uploads[res.Bulk.JobIDs[0]]); upload != "Id\nC1\nC2\nC3\n" {
+		t.Errorf("unexpected upload %q", upload)
+	}
+
+	res, err = sv.DeleteByQuery(ctx, "SELECT Id FROM Contact", nil)
+	if err != nil || res.Count != 0 {
+		t.Errorf("expected 0 records; got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DescribeEntry is a describe result saved in a DescribeStore
+type DescribeEntry struct {
+	Definition   *SObjectDefinition `json:"definition,omitempty"`
+	ETag         string             `json:"etag,omitempty"`
+	LastModified string             `json:"last_modified,omitempty"`
+	Fetched      time.Time          `json:"fetched"` // time of last retrieval or validation
+}
+
+// DescribeStore saves describe results between calls to CachedDescribe.  Get
+// returns a nil entry when key is not found.
+type DescribeStore interface {
+	Get(ctx context.Context, key string) (*DescribeEntry, error)
+	Put(ctx context.Context, key string, entry *DescribeEntry) error
+}
+
+// WithDescribeStore returns a service whose CachedDescribe func saves describe results
+// in store.  Entries younger than ttl are returned without a call.  Older entries are
+// revalidated using the If-None-Match and If-Modified-Since headers.  A nil store
+// causes CachedDescribe to always call Describe.
+func (sv *Service) WithDescribeStore(store DescribeStore, ttl time.Duration) *Service {
+	snew := sv.clone()
+	snew.describeStore = store
+	snew.describeTTL = ttl
+	return snew
+}
+
+// CachedDescribe returns the describe results of an SObject using the service's
+// DescribeStore.  See WithDescribeStore.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_sobject_describe.htm
+func (sv *Service) CachedDescribe(ctx context.Context, name string) (*SObjectDefinition, error) {
+	if sv == nil || sv.baseURL == nil {
+		return nil, errors.New("nil baseURL")
+	}
+	if sv.describeStore == nil {
+		return sv.Describe(ctx, name)
+	}
+	key := sv.baseURL.Host + sv.baseURL.Path + name
+	entry, err := sv.describeStore.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil && entry.Definition == nil {
+		entry = nil
+	}
+	if entry != nil && time.Since(entry.Fetched) < sv.describeTTL {
+		return entry.Definition, nil
+	}
+	var ci CallInfo
+	var opts = []CallOption{CallInfoOption(&ci)}
+	if entry != nil {
+		// empty values are not sent
+		opts = append(opts, CallHeader("If-None-Match", entry.ETag), CallHeader("If-Modified-Since", entry.LastModified))
+	}
+	var def *SObjectDefinition
+	if err := sv.Call(ctx, fmt.Sprintf("sobjects/%s/describe", name), "GET", nil, &def, opts...); err != nil {
+		if entry != nil && ci.StatusCode == http.StatusNotModified {
+			entry.Fetched = time.Now()
+			return entry.Definition, sv.describeStore.Put(ctx, key, entry)
+		}
+		return nil, err
+	}
+	entry = &DescribeEntry{
+		Definition:   def,
+		ETag:         ci.Header.Get("ETag"),
+		LastModified: ci.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}
+	return def, sv.describeStore.Put(ctx, key, entry)
+}
+
+// MemoryDescribeStore is a DescribeStore for the life of a process.  Entries
+// are shared, so callers should not modify returned definitions.
+type MemoryDescribeStore struct {
+	m       sync.Mutex
+	entries map[string]DescribeEntry
+}
+
+// NewMemoryDescribeStore creates an empty MemoryDescribeStore
+func NewMemoryDescribeStore() *MemoryDescribeStore {
+	return &MemoryDescribeStore{entries: make(map[string]DescribeEntry)}
+}
+
+// Get returns a copy of the key's entry
+func (ms *MemoryDescribeStore) Get(ctx context.Context, key string) (*DescribeEntry, error) {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	entry, ok := ms.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Put saves a copy of entry
+func (ms *MemoryDescribeStore) Put(ctx context.Context, key string, entry *DescribeEntry) error {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	if entry == nil {
+		delete(ms.entries, key)
+		return nil
+	}
+	ms.entries[key] = *entry
+	return nil
+}
+
+// FileDescribeStore is a DescribeStore saving each entry as a json file
+// in Dir, allowing describes to be shared between processes.
+type FileDescribeStore struct {
+	Dir string
+}
+
+// NewFileDescribeStore creates a FileDescribeStore using dir.  The
+// directory is created on the first Put.
+func NewFileDescribeStore(dir string) *FileDescribeStore {
+	return &FileDescribeStore{Dir: dir}
+}
+
+var invalidFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+func (fs *FileDescribeStore) filename(key string) string {
+	return filepath.Join(fs.Dir, invalidFilenameChars.ReplaceAllString(key, "_")+".json")
+}
+
+// Get reads the key's entry from its file
+func (fs *FileDescribeStore) Get(ctx context.Context, key string) (*DescribeEntry, error) {
+	b, err := ioutil.ReadFile(fs.filename(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entry *DescribeEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, fmt.Errorf("%s: %w", fs.filename(key), err)
+	}
+	return entry, nil
+}
+
+// Put writes entry to the key's file
+func (fs *FileDescribeStore) Put(ctx context.Context, key string, entry *DescribeEntry) error {
+	fn := fs.filename(key)
+	if entry == nil {
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(fs.Dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(fs.Dir, ".describe")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fn)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
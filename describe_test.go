@@ -0,0 +1,83 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_CachedDescribe(t *testing.T) {
+	var calls, notModified int
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sobjects/Account/describe" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		encodeObject(w, salesforce.SObjectDefinition{Name: "Account", Fields: []salesforce.Field{{Name: "Id"}}})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	for i := 0; i < 2; i++ {
+		if _, err := sv.CachedDescribe(ctx, "Account"); err != nil {
+			t.Fatalf("no store: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls without store; got %d", calls)
+	}
+
+	calls = 0
+	msv := sv.WithDescribeStore(salesforce.NewMemoryDescribeStore(), time.Hour)
+	for i := 0; i < 3; i++ {
+		def, err := msv.CachedDescribe(ctx, "Account")
+		if err != nil || def == nil || def.Name != "Account" {
+			t.Fatalf("memory store: expected Account; got %v %v", def, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call with memory store; got %d", calls)
+	}
+
+	dir, err := ioutil.TempDir("", "describe")
+	if err != nil {
+		t.Fatalf("tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	calls = 0
+	if _, err := sv.WithDescribeStore(salesforce.NewFileDescribeStore(dir), 0).CachedDescribe(ctx, "Account"); err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	// a new service (process) revalidates the expired entry
+	def, err := sv.WithDescribeStore(salesforce.NewFileDescribeStore(dir), 0).CachedDescribe(ctx, "Account")
+	if err != nil || def == nil || len(def.Fields) != 1 {
+		t.Fatalf("file store: expected Account with 1 field; got %v %v", def, err)
+	}
+	if calls != 2 || notModified != 1 {
+		t.Errorf("expected 2 calls with 1 not modified; got %d %d", calls, notModified)
+	}
+	if _, err := sv.WithDescribeStore(salesforce.NewFileDescribeStore(dir), time.Hour).CachedDescribe(ctx, "Missing"); err == nil {
+		t.Errorf("expected not found error")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultDescribeConcurrency is the number of simultaneous describe calls
+// made by DescribeAll when Concurrency is not set
+const DefaultDescribeConcurrency = 4
+
+// DescribeAllOptions configures DescribeAll
+type DescribeAllOptions struct {
+	// Concurrency is the number of simultaneous describe calls.  Zero indicates
+	// DefaultDescribeConcurrency.
+	Concurrency int
+	// Progress is called after each describe completes.  Calls are not concurrent.
+	Progress func(DescribeProgress)
+}
+
+// DescribeProgress reports the completion of an object's describe
+type DescribeProgress struct {
+	Name      string // object described
+	Completed int
+	Total     int
+	Errors    int
+	Err       error // error of the describe
+}
+
+// DescribeAllError lists the objects whose describe failed
+type DescribeAllError struct {
+	Errors map[string]error // object name to describe error
+}
+
+// Error lists the failed objects in name order
+func (e *DescribeAllError) Error() string {
+	var names = make([]string, 0, len(e.Errors))
+	for nm := range e.Errors {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	var msgs = make([]string, 0, len(names))
+	for _, nm := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", nm, e.Errors[nm]))
+	}
+	return fmt.Sprintf("describe failed for %d objects; %s", len(names), strings.Join(msgs, "; "))
+}
+
+// DescribeAll returns the full describe, including fields, of each object returned by
+// ObjectList for which filter returns true.  A nil filter describes all objects.  Describes
+// are made using CachedDescribe (see WithDescribeStore) with bounded concurrency; only the
+// first opts value is used.  Results are keyed by object name.  When describes fail, the
+// successful describes are returned along with a *DescribeAllError.  An authentication or
+// request limit error stops further describes and is returned along with the partial results,
+// as is the context's error when ctx is done.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_sobject_describe.htm
+func (sv *Service) DescribeAll(ctx context.Context, filter func(SObjectDefinition) bool, opts ...DescribeAllOptions) (map[string]*SObjectDefinition, error) {
+	var o DescribeAllOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultDescribeConcurrency
+	}
+	objs, err := sv.ObjectList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, obj := range objs {
+		if filter == nil || filter(obj) {
+			names = append(names, obj.Name)
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		results  = make(map[string]*SObjectDefinition, len(names))
+		failures = make(map[string]error)
+		stopErr  error
+		m        sync.Mutex
+		wg       sync.WaitGroup
+		dp       = DescribeProgress{Total: len(names)}
+		nameCh   = make(chan string)
+	)
+	for i := 0; i < o.Concurrency && i < len(names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for nm := range nameCh {
+				def, err := sv.CachedDescribe(ctx, nm)
+				m.Lock()
+				switch {
+				case err == nil:
+					results[nm] = def
+				case ctx.Err() != nil:
+				case IsAuth(err) || HasErrorCode(err, ErrRequestLimitExceeded):
+					if stopErr == nil {
+						stopErr = fmt.Errorf("describe %s: %w", nm, err)
+					}
+					cancel()
+				default:
+					failures[nm] = err
+				}
+				dp.Name, dp.Err = nm, err
+				dp.Completed++
+				if err != nil {
+					dp.Errors++
+				}
+				if o.Progress != nil {
+					o.Progress(dp)
+				}
+				m.Unlock()
+			}
+		}()
+	}
+	for _, nm := range names {
+		select {
+		case nameCh <- nm:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(nameCh)
+	wg.Wait()
+	switch {
+	case stopErr != nil:
+		return results, stopErr
+	case ctx.Err() != nil:
+		return results, ctx.Err()
+	case len(failures) > 0:
+		return results, &DescribeAllError{Errors: failures}
+	}
+	return results, nil
+}
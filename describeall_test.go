@@ -0,0 +1,88 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_DescribeAll(t *testing.T) {
+	var m sync.Mutex
+	var active, maxActive int
+	var unauthorized bool
+	names := []string{"Account", "Bad__c", "Contact", "Lead", "Opportunity", "Skip__c"}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sobjects/" {
+			var objs []salesforce.SObjectDefinition
+			for _, nm := range names {
+				objs = append(objs, salesforce.SObjectDefinition{Name: nm, Queryable: nm != "Skip__c"})
+			}
+			encodeObject(w, map[string]interface{}{"sobjects": objs})
+			return
+		}
+		nm := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/sobjects/"), "/describe")
+		m.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		unauth := unauthorized
+		m.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		defer func() {
+			m.Lock()
+			active--
+			m.Unlock()
+		}()
+		switch {
+		case unauth && nm == "Account":
+			http.Error(w, `[{"errorCode":"INVALID_SESSION_ID","message":"Session expired"}]`, http.StatusUnauthorized)
+		case nm == "Bad__c":
+			http.Error(w, `[{"errorCode":"UNKNOWN_EXCEPTION","message":"oops"}]`, http.StatusInternalServerError)
+		default:
+			encodeObject(w, salesforce.SObjectDefinition{Name: nm, Fields: []salesforce.Field{{Name: "Id"}}})
+		}
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var progress []salesforce.DescribeProgress
+	queryable := func(o salesforce.SObjectDefinition) bool { return o.Queryable }
+	defs, err := sv.DescribeAll(ctx, queryable, salesforce.DescribeAllOptions{
+		Concurrency: 2,
+		Progress:    func(dp salesforce.DescribeProgress) { progress = append(progress, dp) },
+	})
+	var dae *salesforce.DescribeAllError
+	if !errors.As(err, &dae) || len(dae.Errors) != 1 || dae.Errors["Bad__c"] == nil {
+		t.Fatalf("expected Bad__c DescribeAllError; got %v", err)
+	}
+	if len(defs) != 4 || defs["Contact"] == nil || len(defs["Contact"].Fields) != 1 || defs["Skip__c"] != nil {
+		t.Errorf("expected 4 describes; got %v", defs)
+	}
+	if maxActive > 2 {
+		t.Errorf("expected at most 2 concurrent describes; got %d", maxActive)
+	}
+	if len(progress) != 5 || progress[4].Completed != 5 || progress[4].Total != 5 || progress[4].Errors != 1 {
+		t.Errorf("unexpected progress %v", progress)
+	}
+
+	unauthorized = true
+	defs, err = sv.DescribeAll(ctx, nil, salesforce.DescribeAllOptions{Concurrency: 1})
+	if !salesforce.IsAuth(err) || len(defs) != 0 {
+		t.Errorf("expected auth error stopping describes; got %d %v", len(defs), err)
+	}
+}
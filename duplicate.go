@@ -0,0 +1,185 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// DuplicateRuleHeader defines the Sforce-Duplicate-Rule-Header settings
+// used when creating or updating records.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_duplicaterules.htm
+type DuplicateRuleHeader struct {
+	AllowSave            bool // save records even if duplicates are detected
+	IncludeRecordDetails bool // return duplicate record fields in the DuplicateResult
+	RunAsCurrentUser     bool // apply sharing rules of the current user
+}
+
+// String returns the header value
+func (dh DuplicateRuleHeader) String() string {
+	return fmt.Sprintf("allowSave=%t, includeRecordDetails=%t, runAsCurrentUser=%t",
+		dh.AllowSave, dh.IncludeRecordDetails, dh.RunAsCurrentUser)
+}
+
+// WithDuplicateRuleHeader returns a service that sends the Sforce-Duplicate-Rule-Header
+// with each call.  A nil dh removes the header.
+func (sv *Service) WithDuplicateRuleHeader(dh *DuplicateRuleHeader) *Service {
+	snew := sv.clone()
+	snew.duplicateRules = ""
+	if dh != nil {
+		snew.duplicateRules = dh.String()
+	}
+	return snew
+}
+
+// DuplicateResult describes the duplicates detected when a record is blocked
+// by a duplicate rule.
+// https://developer.salesforce.com/docs/atlas.en-us.apexref.meta/apexref/apex_class_Datacloud_DuplicateResult.htm
+type DuplicateResult struct {
+	AllowSave               bool          `json:"allowSave"`
+	DuplicateRule           string        `json:"duplicateRule,omitempty"`
+	DuplicateRuleEntityType string        `json:"duplicateRuleEntityType,omitempty"`
+	ErrorMessage            string        `json:"errorMessage,omitempty"`
+	MatchResults            []MatchResult `json:"matchResults,omitempty"`
+}
+
+// MatchResult lists the records matched by a matching rule
+type MatchResult struct {
+	EntityType   string        `json:"entityType,omitempty"`
+	Errors       []Error       `json:"errors,omitempty"`
+	MatchEngine  string        `json:"matchEngine,omitempty"`
+	MatchRecords []MatchRecord `json:"matchRecords,omitempty"`
+	Rule         string        `json:"rule,omitempty"`
+	Size         int           `json:"size,omitempty"`
+	Success      bool          `json:"success,omitempty"`
+}
+
+// MatchRecord is a duplicate record.  Record contains field values only
+// when IncludeRecordDetails is set.
+type MatchRecord struct {
+	AdditionalInformation []interface{} `json:"additionalInformation,omitempty"`
+	FieldDiffs            []interface{} `json:"fieldDiffs,omitempty"`
+	MatchConfidence       float64       `json:"matchConfidence,omitempty"`
+	Record                RecordMap     `json:"record,omitempty"`
+}
+
+// DuplicateResults returns the DuplicateResults contained in the error
+// response of a single record call (e.g. Create or Upsert).
+func DuplicateResults(err error) []DuplicateResult {
+	_, results, _ := duplicateResults(err)
+	return results
+}
+
+// duplicateResults decodes the api errors of err returning its NotSuccess, the
+// DuplicateResults and the message of the first error with a DuplicateResult.
+func duplicateResults(err error) (*ctxclient.NotSuccess, []DuplicateResult, string) {
+	var ns *ctxclient.NotSuccess
+	if err == nil || !errors.As(err, &ns) {
+		return nil, nil, ""
+	}
+	var errs []Error
+	if json.Unmarshal(ns.Body, &errs) != nil {
+		return ns, nil, ""
+	}
+	var results []DuplicateResult
+	var msg string
+	for _, e := range errs {
+		if e.DuplicateResult != nil {
+			results = append(results, *e.DuplicateResult)
+			if msg == "" {
+				msg = e.Message
+			}
+		}
+	}
+	return ns, results, msg
+}
+
+// ErrDuplicatesDetected is the error status code returned when a duplicate rule blocks a save
+const ErrDuplicatesDetected = "DUPLICATES_DETECTED"
+
+// DuplicateError is returned by CreateWithDuplicateRule and UpsertWithDuplicateRule
+// when a duplicate rule blocks the save.  Applications may use Matches to offer
+// the existing records in place of the new record.
+type DuplicateError struct {
+	StatusCode int
+	Message    string
+	Results    []DuplicateResult
+	Err        error // underlying *ctxclient.NotSuccess
+}
+
+// Error returns the salesforce message
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrDuplicatesDetected, e.Message)
+}
+
+// Unwrap returns the underlying error
+func (e *DuplicateError) Unwrap() error {
+	return e.Err
+}
+
+// Matches returns the matched records of all results
+func (e *DuplicateError) Matches() []MatchRecord {
+	var recs []MatchRecord
+	for _, dr := range e.Results {
+		for _, mr := range dr.MatchResults {
+			recs = append(recs, mr.MatchRecords...)
+		}
+	}
+	return recs
+}
+
+// MatchIDs returns the ids of the matched records
+func (e *DuplicateError) MatchIDs() []string {
+	var ids []string
+	for _, mr := range e.Matches() {
+		if id, ok := mr.Record["Id"].(string); ok && id > "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// duplicateError returns a *DuplicateError when err contains duplicate
+// results, otherwise err.
+func duplicateError(err error) error {
+	ns, results, msg := duplicateResults(err)
+	if len(results) == 0 {
+		return err
+	}
+	return &DuplicateError{StatusCode: ns.StatusCode, Message: msg, Results: results, Err: err}
+}
+
+// withDuplicateRule returns the service with dh as its duplicate rule header.
+// A nil dh returns the service unchanged.
+func (sv *Service) withDuplicateRule(dh *DuplicateRuleHeader) *Service {
+	if dh == nil {
+		return sv
+	}
+	return sv.WithDuplicateRuleHeader(dh)
+}
+
+// CreateWithDuplicateRule inserts rec sending dh as the Sforce-Duplicate-Rule-Header.  A nil dh
+// uses the service's header.  When a duplicate rule blocks the insert, a *DuplicateError is returned.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_duplicaterules.htm
+func (sv *Service) CreateWithDuplicateRule(ctx context.Context, rec SObject, dh *DuplicateRuleHeader) (*OpResponse, error) {
+	res, err := sv.withDuplicateRule(dh).Create(ctx, rec)
+	return res, duplicateError(err)
+}
+
+// UpsertWithDuplicateRule updates/inserts rec using the external id sending dh as the
+// Sforce-Duplicate-Rule-Header.  A nil dh uses the service's header.  As with Upsert,
+// externalID is path escaped by the function.  When a duplicate rule blocks the save, a
+// *DuplicateError is returned.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_duplicaterules.htm
+func (sv *Service) UpsertWithDuplicateRule(ctx context.Context, rec SObject, externalIDField, externalID string, dh *DuplicateRuleHeader) (*OpResponse, error) {
+	res, err := sv.withDuplicateRule(dh).Upsert(ctx, rec, externalIDField, externalID)
+	return res, duplicateError(err)
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// Encoding marshals call bodies and decodes call results.  The
+// ContentType and Accept values are used as the default headers
+// for a Service using the Encoding.  Encode writes a call body to
+// a pooled buffer and Decode reads a result directly from the
+// response body.
+//
+// Call, and the single record calls (Get, Create, Update, Upsert,
+// Delete etc.), use the service's Encoding.  Queries, Paginate and
+// the collection and composite calls decode records using json tags
+// and the RecordSlice and SObject json methods, so they return
+// ErrJSONEncodingRequired when the Encoding's ContentType is not json.
+type Encoding interface {
+	ContentType() string
+	Accept() string
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// Predefined encodings
+var (
+	JSON Encoding = jsonEncoding{}
+	XML  Encoding = xmlEncoding{}
+)
+
+// ErrJSONEncodingRequired is returned by calls that support only a json Encoding
+var ErrJSONEncodingRequired = errors.New("call requires a json Encoding")
+
+// bufferPool reuses buffers for encoding call bodies
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+// pooledBuffer counts the references to a buffer returning
+// it to the pool when the last reference is released.
+type pooledBuffer struct {
+	buf  *bytes.Buffer
+	refs int32
+}
+
+func (p *pooledBuffer) retain() {
+	atomic.AddInt32(&p.refs, 1)
+}
+
+func (p *pooledBuffer) release() {
+	if atomic.AddInt32(&p.refs, -1) == 0 {
+		p.buf.Reset()
+		bufferPool.Put(p.buf)
+	}
+}
+
+// newBody returns a reader of the buffer holding a reference until closed
+func (p *pooledBuffer) newBody() *pooledBody {
+	p.retain()
+	return &pooledBody{Reader: bytes.NewReader(p.buf.Bytes()), pool: p}
+}
+
+// pooledBody is a call body that releases its buffer reference
+// when closed by the http transport.  GetBody of the request
+// returns a new pooledBody, so the buffer is returned to the
+// pool only after all bodies are closed and the call completes.
+type pooledBody struct {
+	*bytes.Reader
+	pool *pooledBuffer
+	once sync.Once
+}
+
+// Close releases the body's reference to the buffer
+func (pb *pooledBody) Close() error {
+	pb.once.Do(pb.pool.release)
+	return nil
+}
+
+// encodeBody encodes v into a pooled buffer.  The caller must
+// release the returned pooledBuffer's reference.
+func (sv *Service) encodeBody(v interface{}) (*pooledBuffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := sv.enc().Encode(buf, v); err != nil {
+		bufferPool.Put(buf)
+		return nil, err
+	}
+	return &pooledBuffer{buf: buf, refs: 1}, nil
+}
+
+// WithEncoding returns a service that uses enc to marshal bodies and decode
+// results.  A nil enc indicates JSON.
+func (sv *Service) WithEncoding(enc Encoding) *Service {
+	snew := sv.clone()
+	snew.encoding = enc
+	return snew
+}
+
+// enc returns the service's Encoding
+func (sv *Service) enc() Encoding {
+	if sv == nil || sv.encoding == nil {
+		return JSON
+	}
+	return sv.encoding
+}
+
+// requireJSON returns ErrJSONEncodingRequired unless the service's
+// Encoding has a json ContentType.
+func (sv *Service) requireJSON() error {
+	if !strings.HasPrefix(sv.enc().ContentType(), "application/json") {
+		return ErrJSONEncodingRequired
+	}
+	return nil
+}
+
+type jsonEncoding struct{}
+
+func (jsonEncoding) ContentType() string {
+	return defaultContentType
+}
+
+func (jsonEncoding) Accept() string {
+	return defaultAccept
+}
+
+func (jsonEncoding) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonEncoding) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type xmlEncoding struct{}
+
+func (xmlEncoding) ContentType() string {
+	return "application/xml; charset=UTF-8"
+}
+
+func (xmlEncoding) Accept() string {
+	return "application/xml"
+}
+
+func (xmlEncoding) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlEncoding) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// ExtraFields returns the members of the JSON object b that do not match a json field
+// of v's struct type.  Like encoding/json, names are matched case insensitively.  Generated
+// UnmarshalJSON methods use ExtraFields to save fields added to an sobject after generation.
+func ExtraFields(b []byte, v interface{}) (map[string]json.RawMessage, error) {
+	ty := reflect.TypeOf(v)
+	for ty != nil && ty.Kind() == reflect.Ptr {
+		ty = ty.Elem()
+	}
+	if ty == nil || ty.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%v is not a struct", ty)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	known := knownFields(ty)
+	for k := range m {
+		if known[strings.ToLower(k)] {
+			delete(m, k)
+		}
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// knownFieldCache stores the lower case json field names of struct types
+var knownFieldCache sync.Map
+
+// knownFields returns a set of the lower case json field names of ty
+func knownFields(ty reflect.Type) map[string]bool {
+	if m, ok := knownFieldCache.Load(ty); ok {
+		return m.(map[string]bool)
+	}
+	var m = make(map[string]bool)
+	for nm := range jsonfield.Index(ty) {
+		m[strings.ToLower(nm)] = true
+	}
+	knownFieldCache.Store(ty, m)
+	return m
+}
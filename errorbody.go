@@ -0,0 +1,69 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// DefaultMaxErrorBody is the number of bytes of a non-2xx response body
+// kept in the returned *ctxclient.NotSuccess when not set by WithMaxErrorBody
+const DefaultMaxErrorBody = 64 * 1024
+
+// errorBodyDrain is the maximum number of bytes discarded after the kept part of an
+// error body so that the connection may be reused.  Larger bodies close the connection.
+const errorBodyDrain = 1 << 20
+
+// WithMaxErrorBody returns a service that keeps at most n bytes of the body of a
+// non-2xx response in the Body of the returned *ctxclient.NotSuccess error.  The body
+// contains salesforce's error json (see ErrorCodes) and is included in the error's
+// message.  Larger bodies, such as an html error page from a proxy, are truncated.
+// n <= 0 indicates DefaultMaxErrorBody.
+func (sv *Service) WithMaxErrorBody(n int64) *Service {
+	snew := sv.clone()
+	snew.maxErrorBody = n
+	return snew
+}
+
+// do sends r using the service's client.  A non-2xx response is returned as a
+// *ctxclient.NotSuccess whose Body holds the size capped response body.  The
+// remainder of the body is drained so that the connection may be reused.
+func (sv *Service) do(ctx context.Context, r *http.Request) (*http.Response, error) {
+	res, err := sv.cf.Client(ctx).Do(r.WithContext(ctx))
+	if err != nil {
+		// a done context's error is more useful than the transport's
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	max := sv.maxErrorBody
+	if max <= 0 {
+		max = DefaultMaxErrorBody
+	}
+	buff, err := ioutil.ReadAll(io.LimitReader(res.Body, max))
+	if err != nil {
+		buff = append(buff, fmt.Sprintf(" [body read error: %v]", err)...)
+	} else {
+		io.CopyN(ioutil.Discard, res.Body, errorBodyDrain)
+	}
+	return nil, &ctxclient.NotSuccess{
+		StatusCode:    res.StatusCode,
+		StatusMessage: res.Status,
+		Header:        res.Header,
+		Body:          buff,
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jfcote87/ctxclient"
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_ErrorBody(t *testing.T) {
+	const sfError = `[{"errorCode":"INVALID_FIELD","message":"No such column 'Foo__c' on entity 'Contact'"}]`
+	var conns int32
+	ws := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sobjects/Contact/003A":
+			http.Error(w, sfError, http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>" + strings.Repeat("x", 8*salesforce.DefaultMaxErrorBody) + "</html>"))
+		}
+	}))
+	ws.Config.ConnState = func(c net.Conn, st http.ConnState) {
+		if st == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ws.Start()
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var c Contact
+	err := sv.Get(ctx, &c, "003A")
+	var ns *ctxclient.NotSuccess
+	if !errors.As(err, &ns) || ns.StatusCode != http.StatusBadRequest || strings.TrimSpace(string(ns.Body)) != sfError {
+		t.Fatalf("expected NotSuccess with error json; got %v", err)
+	}
+	if !strings.Contains(err.Error(), "No such column") || !salesforce.HasErrorCode(err, salesforce.ErrInvalidField) {
+		t.Errorf("expected error message and code from body; got %v", err)
+	}
+
+	err = sv.Call(ctx, "proxy", "GET", nil, nil)
+	if !errors.As(err, &ns) || ns.StatusCode != http.StatusBadGateway || len(ns.Body) != salesforce.DefaultMaxErrorBody {
+		t.Errorf("expected body capped at %d; got %v", salesforce.DefaultMaxErrorBody, len(ns.Body))
+	}
+	err = sv.WithMaxErrorBody(6).Call(ctx, "proxy", "GET", nil, nil)
+	if !errors.As(err, &ns) || string(ns.Body) != "<html>" {
+		t.Errorf("expected 6 byte body; got %q", ns.Body)
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("expected error responses to reuse one connection; got %d", n)
+	}
+}
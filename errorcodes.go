@@ -0,0 +1,179 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// Common salesforce error codes returned as the errorCode of a call error or the
+// statusCode of a record Error.  See also ErrUnableToLockRow, ErrDuplicatesDetected,
+// ErrInvalidQueryLocator and ErrDependencyFailed.
+// https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/sforce_api_calls_concepts_core_data_objects.htm#statuscode
+const (
+	ErrAPIDisabledForOrg                        = "API_DISABLED_FOR_ORG"
+	ErrCannotInsertUpdateActivateEntity         = "CANNOT_INSERT_UPDATE_ACTIVATE_ENTITY"
+	ErrDuplicateValue                           = "DUPLICATE_VALUE"
+	ErrEntityIsDeleted                          = "ENTITY_IS_DELETED"
+	ErrFieldCustomValidationException           = "FIELD_CUSTOM_VALIDATION_EXCEPTION"
+	ErrFieldIntegrityException                  = "FIELD_INTEGRITY_EXCEPTION"
+	ErrInsufficientAccessOnCrossReferenceEntity = "INSUFFICIENT_ACCESS_ON_CROSS_REFERENCE_ENTITY"
+	ErrInsufficientAccessOrReadonly             = "INSUFFICIENT_ACCESS_OR_READONLY"
+	ErrInvalidAuthHeader                        = "INVALID_AUTH_HEADER"
+	ErrInvalidCrossReferenceKey                 = "INVALID_CROSS_REFERENCE_KEY"
+	ErrInvalidEmailAddress                      = "INVALID_EMAIL_ADDRESS"
+	ErrInvalidField                             = "INVALID_FIELD"
+	ErrInvalidFieldForInsertUpdate              = "INVALID_FIELD_FOR_INSERT_UPDATE"
+	ErrInvalidOrNullForRestrictedPicklist       = "INVALID_OR_NULL_FOR_RESTRICTED_PICKLIST"
+	ErrInvalidSessionID                         = "INVALID_SESSION_ID"
+	ErrInvalidType                              = "INVALID_TYPE"
+	ErrJSONParserError                          = "JSON_PARSER_ERROR"
+	ErrMalformedID                              = "MALFORMED_ID"
+	ErrMalformedQuery                           = "MALFORMED_QUERY"
+	ErrNotFound                                 = "NOT_FOUND"
+	ErrQueryTimeout                             = "QUERY_TIMEOUT"
+	ErrRequestLimitExceeded                     = "REQUEST_LIMIT_EXCEEDED"
+	ErrRequiredFieldMissing                     = "REQUIRED_FIELD_MISSING"
+	ErrServerUnavailable                        = "SERVER_UNAVAILABLE"
+	ErrStorageLimitExceeded                     = "STORAGE_LIMIT_EXCEEDED"
+	ErrStringTooLong                            = "STRING_TOO_LONG"
+)
+
+var transientCodes = map[string]bool{
+	ErrUnableToLockRow:      true,
+	ErrRequestLimitExceeded: true,
+	ErrServerUnavailable:    true,
+	ErrQueryTimeout:         true,
+}
+
+var validationCodes = map[string]bool{
+	ErrDuplicateValue:                     true,
+	ErrDuplicatesDetected:                 true,
+	ErrFieldCustomValidationException:     true,
+	ErrFieldIntegrityException:            true,
+	ErrInvalidCrossReferenceKey:           true,
+	ErrInvalidEmailAddress:                true,
+	ErrInvalidFieldForInsertUpdate:        true,
+	ErrInvalidOrNullForRestrictedPicklist: true,
+	ErrMalformedID:                        true,
+	ErrRequiredFieldMissing:               true,
+	ErrStringTooLong:                      true,
+}
+
+var authCodes = map[string]bool{
+	ErrInvalidSessionID:  true,
+	ErrInvalidAuthHeader: true,
+}
+
+// ErrorCodes returns the salesforce error codes of err.  Codes are read from the
+// body of a *ctxclient.NotSuccess and from the record errors of a *DuplicateError
+// or *UnitOfWorkError.
+func ErrorCodes(err error) []string {
+	var codes []string
+	var uerr *UnitOfWorkError
+	if errors.As(err, &uerr) {
+		for _, e := range uerr.Errors {
+			codes = append(codes, e.StatusCode)
+		}
+	}
+	var derr *DuplicateError
+	if errors.As(err, &derr) {
+		codes = append(codes, ErrDuplicatesDetected)
+	}
+	var ns *ctxclient.NotSuccess
+	if errors.As(err, &ns) {
+		type codeError struct {
+			ErrorCode string `json:"errorCode"`
+		}
+		var errs []codeError
+		if json.Unmarshal(ns.Body, &errs) != nil {
+			// some resources return a single error object
+			var e codeError
+			json.Unmarshal(ns.Body, &e)
+			errs = []codeError{e}
+		}
+		for _, e := range errs {
+			if e.ErrorCode > "" {
+				codes = append(codes, e.ErrorCode)
+			}
+		}
+	}
+	return codes
+}
+
+// HasErrorCode returns true if err contains the salesforce error code
+func HasErrorCode(err error, code string) bool {
+	for _, c := range ErrorCodes(err) {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCode returns true if any error code of err is found in codes
+func hasCode(err error, codes map[string]bool) bool {
+	for _, c := range ErrorCodes(err) {
+		if codes[c] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransient returns true when err may succeed if retried later: record locks,
+// limit errors, server unavailable (500, 502, 503 and 504 status codes), query
+// timeouts and network timeouts.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsLimitError(err) || hasCode(err, transientCodes) {
+		return true
+	}
+	var ns *ctxclient.NotSuccess
+	if errors.As(err, &ns) {
+		switch ns.StatusCode {
+		case 500, 502, 503, 504:
+			return true
+		}
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// IsValidation returns true when err indicates that record data was rejected,
+// such as a missing required field, duplicate value or validation rule failure.
+// Retrying the same data will fail.
+func IsValidation(err error) bool {
+	return err != nil && hasCode(err, validationCodes)
+}
+
+// IsAuth returns true when err indicates an authentication failure: a 401
+// status code, an invalid session or an oauth2 error from the token endpoint.
+func IsAuth(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ns *ctxclient.NotSuccess
+	if errors.As(err, &ns) {
+		if ns.StatusCode == 401 {
+			return true
+		}
+		// token endpoint errors (e.g. invalid_grant) use the oauth2 error format
+		var oerr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(ns.Body, &oerr) == nil && oerr.Error > "" {
+			return true
+		}
+	}
+	return hasCode(err, authCodes)
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jfcote87/ctxclient"
+	"github.com/jfcote87/salesforce"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClassification(t *testing.T) {
+	notSuccess := func(status int, body string) error {
+		return fmt.Errorf("call: %w", &ctxclient.NotSuccess{StatusCode: status, Body: []byte(body)})
+	}
+	tests := []struct {
+		name                     string
+		err                      error
+		transient, valid, isAuth bool
+		codes                    []string
+	}{
+		{name: "nil"},
+		{name: "other", err: errors.New("other")},
+		{name: "lock", err: notSuccess(400, `[{"errorCode":"UNABLE_TO_LOCK_ROW","message":"locked"}]`), transient: true, codes: []string{salesforce.ErrUnableToLockRow}},
+		{name: "limit", err: notSuccess(403, `[{"errorCode":"REQUEST_LIMIT_EXCEEDED","message":"limit"}]`), transient: true, codes: []string{salesforce.ErrRequestLimitExceeded}},
+		{name: "unavailable", err: notSuccess(503, `Service Unavailable`), transient: true},
+		{name: "timeout", err: fmt.Errorf("get: %w", timeoutError{}), transient: true},
+		{name: "required", err: notSuccess(400, `[{"errorCode":"REQUIRED_FIELD_MISSING","message":"LastName"},{"errorCode":"STRING_TOO_LONG","message":"Name"}]`), valid: true,
+			codes: []string{salesforce.ErrRequiredFieldMissing, salesforce.ErrStringTooLong}},
+		{name: "single", err: notSuccess(400, `{"errorCode":"DUPLICATE_VALUE","message":"dup"}`), valid: true, codes: []string{salesforce.ErrDuplicateValue}},
+		{name: "uow", err: &salesforce.UnitOfWorkError{Ref: "a", Errors: []salesforce.Error{{StatusCode: "FIELD_CUSTOM_VALIDATION_EXCEPTION"}}}, valid: true,
+			codes: []string{salesforce.ErrFieldCustomValidationException}},
+		{name: "session", err: notSuccess(401, `[{"errorCode":"INVALID_SESSION_ID","message":"expired"}]`), isAuth: true, codes: []string{salesforce.ErrInvalidSessionID}},
+		{name: "grant", err: notSuccess(400, `{"error":"invalid_grant","error_description":"authentication failure"}`), isAuth: true},
+	}
+	for _, tt := range tests {
+		if got := salesforce.IsTransient(tt.err); got != tt.transient {
+			t.Errorf("%s: IsTransient expected %v", tt.name, tt.transient)
+		}
+		if got := salesforce.IsValidation(tt.err); got != tt.valid {
+			t.Errorf("%s: IsValidation expected %v", tt.name, tt.valid)
+		}
+		if got := salesforce.IsAuth(tt.err); got != tt.isAuth {
+			t.Errorf("%s: IsAuth expected %v", tt.name, tt.isAuth)
+		}
+		codes := salesforce.ErrorCodes(tt.err)
+		if fmt.Sprint(codes) != fmt.Sprint(tt.codes) {
+			t.Errorf("%s: expected codes %v; got %v", tt.name, tt.codes, codes)
+		}
+		for _, c := range tt.codes {
+			if !salesforce.HasErrorCode(tt.err, c) {
+				t.Errorf("%s: expected HasErrorCode %s", tt.name, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// EventLogFile describes an event monitoring log file.  The LogFile field contains
+// the path of the file's csv content.
+// https://developer.salesforce.com/docs/atlas.en-us.object_reference.meta/object_reference/sforce_api_objects_eventlogfile.htm
+type EventLogFile struct {
+	Attributes         *Attributes `json:"attributes,omitempty"`
+	ID                 string      `json:"Id,omitempty"`
+	EventType          string      `json:"EventType,omitempty"`
+	LogDate            *Datetime   `json:"LogDate,omitempty"`
+	LogFileLength      float64     `json:"LogFileLength,omitempty"`
+	LogFileContentType string      `json:"LogFileContentType,omitempty"`
+	Interval           string      `json:"Interval,omitempty"` // Daily or Hourly
+	Sequence           int         `json:"Sequence,omitempty"`
+	APIVersion         float64     `json:"ApiVersion,omitempty"`
+	LogFile            string      `json:"LogFile,omitempty"`
+}
+
+// SObjectName returns the api name of the object
+func (e EventLogFile) SObjectName() string {
+	return "EventLogFile"
+}
+
+// WithAttr returns the record with attributes set
+func (e EventLogFile) WithAttr(ref string) SObject {
+	e.Attributes = &Attributes{Type: "EventLogFile", Ref: ref}
+	return e
+}
+
+// Common event types
+const (
+	EventTypeAPI          = "API"
+	EventTypeLogin        = "Login"
+	EventTypeReportExport = "ReportExport"
+)
+
+// EventLogFiles returns the log files of eventType with a LogDate on or after since ordered
+// by LogDate.  An empty eventType returns all event types and a zero since returns all dates.
+func (sv *Service) EventLogFiles(ctx context.Context, eventType string, since time.Time) ([]EventLogFile, error) {
+	var conditions []string
+	if eventType != "" {
+		conditions = append(conditions, "EventType = "+SOQLString(eventType))
+	}
+	if !since.IsZero() {
+		conditions = append(conditions, "LogDate >= "+SOQLDatetime(since))
+	}
+	qry := "SELECT Id, EventType, LogDate, LogFileLength, LogFileContentType, Interval, Sequence, ApiVersion, LogFile FROM EventLogFile"
+	if len(conditions) > 0 {
+		qry += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	var results []EventLogFile
+	if err := sv.Query(ctx, qry+" ORDER BY LogDate", &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EventLog returns the csv content of the log file.  Gzip compressed content is
+// decompressed.  The caller must close the returned ReadCloser.
+func (sv *Service) EventLog(ctx context.Context, elf EventLogFile) (io.ReadCloser, error) {
+	path := elf.LogFile
+	if path == "" {
+		if elf.ID == "" {
+			return nil, errors.New("event log file has no id")
+		}
+		path = "sobjects/EventLogFile/" + elf.ID + "/LogFile"
+	}
+	var hb *HTTPBody
+	if err := sv.Call(ctx, path, "GET", nil, &hb); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(hb)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			hb.Close()
+			return nil, err
+		}
+		return &eventLogReader{Reader: gz, closers: []io.Closer{gz, hb}}, nil
+	}
+	return &eventLogReader{Reader: br, closers: []io.Closer{hb}}, nil
+}
+
+type eventLogReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (er *eventLogReader) Close() error {
+	var err error
+	for _, c := range er.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// EventLogReader returns a csv.Reader of the log file's content.  The
+// caller must close the returned Closer.
+func (sv *Service) EventLogReader(ctx context.Context, elf EventLogFile) (*csv.Reader, io.Closer, error) {
+	rc, err := sv.EventLog(ctx, elf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return csv.NewReader(rc), rc, nil
+}
+
+// DecodeEventLog reads the csv content of a log file into results which must be a
+// pointer to a []<struct> (or []*<struct>).  Columns are matched to struct fields using
+// the field's json tag name.  Unmatched columns are ignored.
+func DecodeEventLog(rdr io.Reader, results interface{}) error {
+	const expected = "*[]<struct>"
+	sliceVal, err := validatePtrTo(results, reflect.Slice, expected)
+	if err != nil {
+		return err
+	}
+	elemType := sliceVal.Type().Elem()
+	structType, err := validateStructElem(reflect.TypeOf(results), elemType, expected)
+	if err != nil {
+		return err
+	}
+	cr := csv.NewReader(rdr)
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return ErrZeroRecords
+		}
+		return err
+	}
+	fieldMap := jsonfield.Index(structType)
+	var colIndexes = make([][]int, len(header))
+	for i, col := range header {
+		colIndexes[i] = fieldMap[col]
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rec := reflect.New(structType).Elem()
+		for i, col := range header {
+			if colIndexes[i] == nil {
+				continue
+			}
+			if err := setFieldFromString(rec.FieldByIndex(colIndexes[i]), row[i]); err != nil {
+				return fmt.Errorf("row %d column %s: %w", sliceVal.Len()+1, col, err)
+			}
+		}
+		if elemType.Kind() == reflect.Ptr {
+			rec = rec.Addr()
+		}
+		sliceVal.Set(reflect.Append(sliceVal, rec))
+	}
+}
+
+// EventLogCommon contains the fields common to all event types
+// https://developer.salesforce.com/docs/atlas.en-us.object_reference.meta/object_reference/sforce_api_objects_eventlogfile_supportedeventtypes.htm
+type EventLogCommon struct {
+	EventType        string    `json:"EVENT_TYPE"`
+	Timestamp        string    `json:"TIMESTAMP"` // yyyymmddhhmmss.sss
+	RequestID        string    `json:"REQUEST_ID"`
+	OrganizationID   string    `json:"ORGANIZATION_ID"`
+	UserID           string    `json:"USER_ID"`
+	RunTime          int64     `json:"RUN_TIME"`
+	CPUTime          int64     `json:"CPU_TIME"`
+	URI              string    `json:"URI"`
+	SessionKey       string    `json:"SESSION_KEY"`
+	LoginKey         string    `json:"LOGIN_KEY"`
+	UserType         string    `json:"USER_TYPE"`
+	TimestampDerived *Datetime `json:"TIMESTAMP_DERIVED"`
+	UserIDDerived    string    `json:"USER_ID_DERIVED"`
+	ClientIP         string    `json:"CLIENT_IP"`
+	URIIDDerived     string    `json:"URI_ID_DERIVED"`
+}
+
+// APIEvent is a row of an API event log file
+type APIEvent struct {
+	EventLogCommon
+	APIType        string  `json:"API_TYPE"`
+	APIVersion     string  `json:"API_VERSION"`
+	MethodName     string  `json:"METHOD_NAME"`
+	EntityName     string  `json:"ENTITY_NAME"`
+	RowsProcessed  float64 `json:"ROWS_PROCESSED"`
+	RequestStatus  string  `json:"REQUEST_STATUS"`
+	DBTotalTime    int64   `json:"DB_TOTAL_TIME"`
+	ConnectedAppID string  `json:"CONNECTED_APP_ID"`
+	Client         string  `json:"CLIENT"`
+}
+
+// LoginEvent is a row of a Login event log file
+type LoginEvent struct {
+	EventLogCommon
+	UserName      string `json:"USER_NAME"`
+	LoginStatus   string `json:"LOGIN_STATUS"`
+	BrowserType   string `json:"BROWSER_TYPE"`
+	SourceIP      string `json:"SOURCE_IP"`
+	TLSProtocol   string `json:"TLS_PROTOCOL"`
+	CipherSuite   string `json:"CIPHER_SUITE"`
+	APIType       string `json:"API_TYPE"`
+	APIVersion    string `json:"API_VERSION"`
+	RequestStatus string `json:"REQUEST_STATUS"`
+}
+
+// ReportExportEvent is a row of a ReportExport event log file
+type ReportExportEvent struct {
+	EventLogCommon
+	ReportID    string `json:"REPORT_ID"`
+	ClientInfo  string `json:"CLIENT_INFO"`
+	ReportName  string `json:"REPORT_NAME"`
+	UserName    string `json:"USER_NAME"`
+	RowsCount   int64  `json:"NUMBER_OF_ROWS"`
+	ExportType  string `json:"EXPORT_TYPE"`
+	DisplayType string `json:"DISPLAY_TYPE"`
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+const testAPILog = `"EVENT_TYPE","TIMESTAMP","REQUEST_ID","USER_ID","RUN_TIME","TIMESTAMP_DERIVED","API_TYPE","METHOD_NAME","ENTITY_NAME","ROWS_PROCESSED","UNKNOWN"
+"API","20220101123456.789","4abc","0051","25","2022-01-01T12:34:56.789Z","R","query","Account","12","x"
+"API","20220101123457.789","4abd","0052","","2022-01-01T12:34:57.789Z","R","update","Contact","1","y"
+`
+
+func TestService_EventLog(t *testing.T) {
+	var qry string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/query/"):
+			qry = r.URL.Query().Get("q")
+			encodeObject(w, map[string]interface{}{
+				"totalSize": 2,
+				"done":      true,
+				"records": []map[string]interface{}{
+					{"Id": "0AT1", "EventType": "API", "LogFile": "/services/data/v55.0/sobjects/EventLogFile/0AT1/LogFile"},
+					{"Id": "0AT2", "EventType": "API", "LogFile": "/services/data/v55.0/sobjects/EventLogFile/0AT2/LogFile"},
+				},
+			})
+		case strings.HasSuffix(r.URL.Path, "/0AT1/LogFile"):
+			w.Write([]byte(testAPILog))
+		case strings.HasSuffix(r.URL.Path, "/0AT2/LogFile"):
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(testAPILog))
+			gz.Close()
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/")
+	files, err := sv.EventLogFiles(ctx, salesforce.EventTypeAPI, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("EventLogFiles: %v", err)
+	}
+	wantQry := "SELECT Id, EventType, LogDate, LogFileLength, LogFileContentType, Interval, Sequence, ApiVersion, LogFile FROM EventLogFile WHERE EventType = 'API' AND LogDate >= 2022-01-01T00:00:00Z ORDER BY LogDate"
+	if qry != wantQry {
+		t.Errorf("expected query %s; got %s", wantQry, qry)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files; got %d", len(files))
+	}
+	for _, elf := range files {
+		rc, err := sv.EventLog(ctx, elf)
+		if err != nil {
+			t.Fatalf("%s: EventLog %v", elf.ID, err)
+		}
+		var events []*salesforce.APIEvent
+		err = salesforce.DecodeEventLog(rc, &events)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: DecodeEventLog %v", elf.ID, err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("%s: expected 2 events; got %d", elf.ID, len(events))
+		}
+		ev := events[0]
+		if ev.RequestID != "4abc" || ev.RunTime != 25 || ev.EntityName != "Account" || ev.RowsProcessed != 12 ||
+			ev.TimestampDerived.Time() == nil {
+			t.Errorf("%s: unexpected event %#v", elf.ID, ev)
+		}
+	}
+
+	cr, closer, err := sv.EventLogReader(ctx, salesforce.EventLogFile{ID: "0AT2"})
+	if err != nil {
+		t.Fatalf("EventLogReader: %v", err)
+	}
+	defer closer.Close()
+	rows, err := cr.ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Errorf("expected 3 csv rows; got %d %v", len(rows), err)
+	}
+
+	if err := salesforce.DecodeEventLog(strings.NewReader(""), &[]string{}); err == nil {
+		t.Errorf("expected invalid results error")
+	}
+}
@@ -35,7 +35,7 @@ func ExampleService_Call() {
 	// prepare updates
 	for _, c := range records {
 		c.DoNotCall = true
-		updateRecs = append(updateRecs)
+		updateRecs = append(updateRecs, c)
 	}
 
 	opResponses, err := sv.UpdateRecords(ctx, false, updateRecs)
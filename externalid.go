@@ -0,0 +1,51 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoExternalIDField is returned when an external id field is not passed
+// and no default is registered for the SObject.  See WithExternalIDs.
+var ErrNoExternalIDField = errors.New("no external id field")
+
+// WithExternalIDs returns a service using the default external id fields of ids,
+// a map of SObjectName to field name.  Upsert, UpsertRecords and FindOrCreate use
+// the default when passed an empty external id field.  ids is copied, so later
+// changes to the map do not affect the service.
+func (sv *Service) WithExternalIDs(ids map[string]string) *Service {
+	snew := sv.clone()
+	snew.externalIDs = nil
+	if ids != nil {
+		snew.externalIDs = make(map[string]string, len(ids))
+		for k, v := range ids {
+			snew.externalIDs[k] = v
+		}
+	}
+	return snew
+}
+
+// ExternalIDField returns the default external id field registered for
+// sobjectName or an empty string if none exists
+func (sv *Service) ExternalIDField(sobjectName string) string {
+	if sv == nil {
+		return ""
+	}
+	return sv.externalIDs[sobjectName]
+}
+
+// externalIDField returns fld or, if empty, the registered default of sobjectName
+func (sv *Service) externalIDField(sobjectName, fld string) (string, error) {
+	if fld > "" {
+		return fld, nil
+	}
+	if fld = sv.ExternalIDField(sobjectName); fld == "" {
+		return "", fmt.Errorf("%w for %s", ErrNoExternalIDField, sobjectName)
+	}
+	return fld, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_WithExternalIDs(t *testing.T) {
+	var paths []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		if r.URL.Path == "/composite/sobjects/Account/Vendor_ID__c" {
+			encodeObject(w, []salesforce.OpResponse{{ID: "001A", Success: true, Created: true}})
+			return
+		}
+		encodeObject(w, salesforce.OpResponse{ID: "001A", Success: true, Created: true})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithExternalIDs(map[string]string{"Account": "Vendor_ID__c"})
+
+	acct := Account{AccountName: "Acme", VendorID: "V 1"}
+	if sv.ExternalIDField("Account") != "Vendor_ID__c" || sv.ExternalIDField("Contact") != "" {
+		t.Errorf("unexpected external id fields")
+	}
+	if _, err := sv.Upsert(ctx, acct, "", ""); err != nil {
+		t.Fatalf("upsert: expected success; got %v", err)
+	}
+	if _, err := sv.UpsertRecords(ctx, false, "", []salesforce.SObject{acct}); err != nil {
+		t.Fatalf("upsert records: expected success; got %v", err)
+	}
+	if id, created, err := sv.FindOrCreate(ctx, acct, "Name = 'Acme'", ""); err != nil || id != "001A" || !created {
+		t.Fatalf("find or create: expected upsert; got %s %v %v", id, created, err)
+	}
+	if _, err := sv.Upsert(ctx, acct, "", "V/2"); err != nil {
+		t.Fatalf("upsert with external id: expected success; got %v", err)
+	}
+	want := []string{
+		"PATCH /sobjects/Account/Vendor_ID__c/V%201",
+		"PATCH /composite/sobjects/Account/Vendor_ID__c",
+		"PATCH /sobjects/Account/Vendor_ID__c/V%201",
+		"PATCH /sobjects/Account/Vendor_ID__c/V%2F2",
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("expected %v; got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("call %d: expected %s; got %s", i, want[i], paths[i])
+		}
+	}
+
+	if _, err := sv.UpsertRecords(ctx, false, "", []salesforce.SObject{Contact{LastName: "Smith"}}); !errors.Is(err, salesforce.ErrNoExternalIDField) {
+		t.Errorf("expected ErrNoExternalIDField; got %v", err)
+	}
+	if _, err := sv.Upsert(ctx, Account{AccountName: "Acme"}, "", ""); err == nil {
+		t.Errorf("expected error for empty external id value")
+	}
+}
+
+func TestService_DeleteByExternalID(t *testing.T) {
+	var paths []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		if r.URL.Path == "/sobjects/Account/Vendor_ID__c/missing" {
+			http.Error(w, `[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithExternalIDs(map[string]string{"Account": "Vendor_ID__c"})
+
+	if err := sv.DeleteByExternalID(ctx, "Contact", "PID__c", "A 1"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := sv.DeleteByExternalID(ctx, "Account", "", "V1"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := sv.DeleteByExternalID(ctx, "Account", "", "missing"); !salesforce.HasErrorCode(err, salesforce.ErrNotFound) {
+		t.Errorf("expected NOT_FOUND; got %v", err)
+	}
+	if err := sv.DeleteByExternalID(ctx, "Contact", "", "V1"); !errors.Is(err, salesforce.ErrNoExternalIDField) {
+		t.Errorf("expected ErrNoExternalIDField; got %v", err)
+	}
+	if err := sv.DeleteByExternalID(ctx, "Account", "", ""); err == nil {
+		t.Errorf("expected empty external id error")
+	}
+	want := "[DELETE /sobjects/Contact/PID__c/A%201 DELETE /sobjects/Account/Vendor_ID__c/V1 DELETE /sobjects/Account/Vendor_ID__c/missing]"
+	if got := fmt.Sprint(paths); got != want {
+		t.Errorf("expected %s; got %s", want, got)
+	}
+}
+
+func TestService_Exists(t *testing.T) {
+	var methods []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		switch r.URL.Path {
+		case "/sobjects/Account/001A", "/sobjects/Account/Vendor_ID__c/V1":
+			w.WriteHeader(http.StatusOK)
+		case "/sobjects/Account/Vendor_ID__c/DUP":
+			w.WriteHeader(http.StatusMultipleChoices)
+		case "/sobjects/Account/BAD":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithExternalIDs(map[string]string{"Account": "Vendor_ID__c"})
+
+	tests := []struct {
+		id, ext string
+		want    bool
+		wantErr bool
+	}{
+		{id: "001A", want: true},
+		{id: "001B"},
+		{id: "BAD", wantErr: true},
+		{ext: "V1", want: true},
+		{ext: "V2"},
+		{ext: "DUP", want: true},
+	}
+	for _, tt := range tests {
+		var found bool
+		var err error
+		if tt.id > "" {
+			found, err = sv.Exists(ctx, "Account", tt.id)
+		} else {
+			found, err = sv.ExistsByExternalID(ctx, "Account", "", tt.ext)
+		}
+		if found != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s%s: expected %v, error %v; got %v %v", tt.id, tt.ext, tt.want, tt.wantErr, found, err)
+		}
+	}
+	for _, m := range methods {
+		if m != "HEAD" {
+			t.Errorf("expected HEAD; got %s", m)
+		}
+	}
+	if _, err := sv.Exists(ctx, "Account", ""); err == nil {
+		t.Errorf("expected error for empty id")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import "strings"
+
+// FieldMask lists the fields of a record to send in a call, allowing a struct used
+// to read records to also update a subset of fields.  Fields are named by either
+// the Go struct field name or the json (API) name and are matched case insensitively.
+type FieldMask []string
+
+// WithFieldMask returns a service that sends only the fields of mask (and
+// attributes) when encoding records for calls such as Create, Update,
+// UpdateRecords and Composite.  Fields not in the mask are not sent even
+// when set, except Id, which collection updates require, and the external
+// id field of UpsertRecords.  A nil mask sends all fields.
+func (sv *Service) WithFieldMask(mask FieldMask) *Service {
+	snew := sv.clone()
+	snew.fieldMask = nil
+	if mask != nil {
+		snew.fieldMask = make(map[string]bool, len(mask))
+		for _, f := range mask {
+			snew.fieldMask[strings.ToLower(f)] = true
+		}
+	}
+	return snew
+}
+
+// withMaskField returns a service whose field mask also sends fld.  A
+// service without a mask is returned unchanged.
+func (sv *Service) withMaskField(fld string) *Service {
+	if sv.fieldMask == nil || sv.fieldMask[strings.ToLower(fld)] {
+		return sv
+	}
+	snew := sv.clone()
+	snew.fieldMask[strings.ToLower(fld)] = true
+	return snew
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_WithFieldMask(t *testing.T) {
+	var bodies []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if strings.HasPrefix(r.URL.Path, "/composite/sobjects") {
+			encodeObject(w, []salesforce.OpResponse{{ID: "001A", Success: true}, {ID: "001B", Success: true}})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	acct := Account{AccountName: "Acme", AccountPhone: "555-1212", BillingCity: "Dallas", AccountNumber: "A1"}
+	// mask by Go name (AccountPhone) and api name (billingcity)
+	msv := sv.WithFieldMask(salesforce.FieldMask{"AccountPhone", "billingcity"})
+	if err := msv.Update(ctx, acct, "001A"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := msv.Update(ctx, salesforce.RecordMap{"BillingCity": "X", "Name": "Y"}, "001A"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	withID := acct
+	withID.AccountID = "001A"
+	if _, err := msv.UpdateRecords(ctx, false, []salesforce.SObject{withID, &withID}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	withExt := acct
+	withExt.VendorID = "V1"
+	if _, err := msv.UpsertRecords(ctx, false, "Vendor_ID__c", []salesforce.SObject{withExt}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := msv.WithFieldMask(nil).Update(ctx, Account{AccountName: "Acme"}, "001A"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	want := []string{
+		`{"BillingCity":"Dallas","Phone":"555-1212"}`,
+		`{"BillingCity":"X"}`,
+		`{"records":[{"BillingCity":"Dallas","Id":"001A","Phone":"555-1212","attributes":{"type":"Account"}},{"BillingCity":"Dallas","Id":"001A","Phone":"555-1212","attributes":{"type":"Account"}}]}`,
+		`{"records":[{"BillingCity":"Dallas","Phone":"555-1212","Vendor_ID__c":"V1","attributes":{"type":"Account"}}]}`,
+		`{"Name":"Acme"}`,
+	}
+	if len(bodies) != len(want) {
+		t.Fatalf("expected %d bodies; got %q", len(want), bodies)
+	}
+	for i, w := range want {
+		if bodies[i] != w+"\n" {
+			t.Errorf("call %d: expected %s; got %s", i, w, bodies[i])
+		}
+	}
+}
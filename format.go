@@ -0,0 +1,320 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// FieldFormat determines how a Formatter displays values.  Empty
+// values use those of DefaultFieldFormat.
+type FieldFormat struct {
+	DateLayout           string         // time.Format layout of date fields
+	DatetimeLayout       string         // time.Format layout of datetime fields
+	TimeLayout           string         // time.Format layout of time fields
+	Location             *time.Location // datetimes are displayed in Location; nil indicates UTC
+	CurrencySymbol       string
+	CurrencySuffix       bool // display the currency symbol after the number
+	DecimalSeparator     string
+	ThousandsSeparator   string
+	True                 string // display of a true boolean
+	False                string // display of a false boolean
+	MultiSelectSeparator string // separates the labels of a multipicklist
+}
+
+// DefaultFieldFormat is the format of en_US
+var DefaultFieldFormat = FieldFormat{
+	DateLayout:           "1/2/2006",
+	DatetimeLayout:       "1/2/2006 3:04 PM",
+	TimeLayout:           "3:04 PM",
+	CurrencySymbol:       "$",
+	DecimalSeparator:     ".",
+	ThousandsSeparator:   ",",
+	True:                 "Yes",
+	False:                "No",
+	MultiSelectSeparator: "; ",
+}
+
+var localeFormats = map[string]FieldFormat{
+	"en_US": DefaultFieldFormat,
+	"en_GB": {DateLayout: "02/01/2006", DatetimeLayout: "02/01/2006 15:04", TimeLayout: "15:04", CurrencySymbol: "£"},
+	"en_CA": {DateLayout: "2006-01-02", DatetimeLayout: "2006-01-02 3:04 PM"},
+	"de_DE": {DateLayout: "02.01.2006", DatetimeLayout: "02.01.2006, 15:04", TimeLayout: "15:04", CurrencySymbol: " €",
+		CurrencySuffix: true, DecimalSeparator: ",", ThousandsSeparator: ".", True: "Ja", False: "Nein"},
+	"fr_FR": {DateLayout: "02/01/2006", DatetimeLayout: "02/01/2006 15:04", TimeLayout: "15:04", CurrencySymbol: " €",
+		CurrencySuffix: true, DecimalSeparator: ",", ThousandsSeparator: " ", True: "Oui", False: "Non"},
+}
+
+// LocaleFieldFormat returns the format of a salesforce locale (e.g. en_GB, de_DE)
+// displayed in loc.  Unknown locales return DefaultFieldFormat.  Only a few common
+// locales are defined; modify the returned format as needed.
+func LocaleFieldFormat(locale string, loc *time.Location) FieldFormat {
+	ff := localeFormats[locale].withDefaults()
+	ff.Location = loc
+	return ff
+}
+
+// withDefaults sets empty values to those of DefaultFieldFormat
+func (ff FieldFormat) withDefaults() FieldFormat {
+	def := DefaultFieldFormat
+	for _, p := range []struct{ v, d *string }{
+		{&ff.DateLayout, &def.DateLayout},
+		{&ff.DatetimeLayout, &def.DatetimeLayout},
+		{&ff.TimeLayout, &def.TimeLayout},
+		{&ff.CurrencySymbol, &def.CurrencySymbol},
+		{&ff.DecimalSeparator, &def.DecimalSeparator},
+		{&ff.ThousandsSeparator, &def.ThousandsSeparator},
+		{&ff.True, &def.True},
+		{&ff.False, &def.False},
+		{&ff.MultiSelectSeparator, &def.MultiSelectSeparator},
+	} {
+		if *p.v == "" {
+			*p.v = *p.d
+		}
+	}
+	return ff
+}
+
+// Formatter converts field values of an SObject to display strings using the
+// SObject's describe metadata, e.g. for documents and emails.  Currency, percent
+// and number fields are displayed using the field's scale, dates and times using
+// the format's layouts and picklist values using their labels.
+type Formatter struct {
+	Format FieldFormat
+	fields map[string]*Field
+}
+
+// NewFormatter returns a Formatter for the fields of def.  See CachedDescribe.
+func NewFormatter(def *SObjectDefinition, ff FieldFormat) *Formatter {
+	f := &Formatter{Format: ff.withDefaults(), fields: make(map[string]*Field)}
+	if def != nil {
+		for i := range def.Fields {
+			f.fields[def.Fields[i].Name] = &def.Fields[i]
+		}
+	}
+	return f
+}
+
+// FormatField returns the display string of value for the field.  Nil and empty
+// values return an empty string.  Values of fields not in the describe and values
+// that cannot be converted to the field's type are displayed using fmt.Sprint.
+func (f *Formatter) FormatField(field string, value interface{}) string {
+	value = indirectValue(value)
+	if value == nil {
+		return ""
+	}
+	fld := f.fields[field]
+	if fld == nil {
+		return fmt.Sprint(value)
+	}
+	ff := f.Format
+	var s string
+	var ok bool
+	switch fld.Type {
+	case "currency":
+		if s, ok = f.number(value, fld.Scale); ok {
+			neg := strings.HasPrefix(s, "-")
+			s = strings.TrimPrefix(s, "-")
+			if ff.CurrencySuffix {
+				s += ff.CurrencySymbol
+			} else {
+				s = ff.CurrencySymbol + s
+			}
+			if neg {
+				s = "-" + s
+			}
+		}
+	case "percent":
+		if s, ok = f.number(value, fld.Scale); ok {
+			s += "%"
+		}
+	case "double", "int", "long":
+		s, ok = f.number(value, fld.Scale)
+	case "boolean":
+		var b bool
+		if b, ok = value.(bool); ok {
+			s = ff.False
+			if b {
+				s = ff.True
+			}
+		}
+	case "date":
+		var tm time.Time
+		if tm, ok = timeValue(value, defaultDateFormat); ok {
+			s = tm.Format(ff.DateLayout)
+		}
+	case "datetime":
+		var tm time.Time
+		if tm, ok = timeValue(value, defaultDatetimeFormat); ok {
+			loc := ff.Location
+			if loc == nil {
+				loc = time.UTC
+			}
+			s = tm.In(loc).Format(ff.DatetimeLayout)
+		}
+	case "time":
+		var tm time.Time
+		if tm, ok = timeValue(value, "15:04:05.000Z"); ok {
+			s = tm.Format(ff.TimeLayout)
+		}
+	case "picklist":
+		s, ok = f.picklistLabel(fld, fmt.Sprint(value)), true
+	case "multipicklist":
+		vals := strings.Split(fmt.Sprint(value), ";")
+		for i := range vals {
+			vals[i] = f.picklistLabel(fld, vals[i])
+		}
+		s, ok = strings.Join(vals, ff.MultiSelectSeparator), true
+	}
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	return s
+}
+
+// FormatRecord returns the display strings of rec's fields keyed by field name.  When
+// fields is empty, every describe field with a non-empty value is formatted.  rec must
+// be a RecordMap or a struct (or pointer to a struct) whose json tags are field names.
+func (f *Formatter) FormatRecord(rec SObject, fields ...string) map[string]string {
+	vals := recordValues(rec)
+	var out = make(map[string]string)
+	if len(fields) == 0 {
+		for nm := range f.fields {
+			if v, ok := vals[nm]; ok {
+				if s := f.FormatField(nm, v); s > "" {
+					out[nm] = s
+				}
+			}
+		}
+		return out
+	}
+	for _, nm := range fields {
+		out[nm] = f.FormatField(nm, vals[nm])
+	}
+	return out
+}
+
+// picklistLabel returns the label of value or value when not found
+func (f *Formatter) picklistLabel(fld *Field, value string) string {
+	for _, pv := range fld.PicklistValues {
+		if pv.Value == value && pv.Label > "" {
+			return pv.Label
+		}
+	}
+	return value
+}
+
+// number formats value with scale decimals and the format's separators
+func (f *Formatter) number(value interface{}, scale int) (string, bool) {
+	var n float64
+	switch v := value.(type) {
+	case float64:
+		n = v
+	case float32:
+		n = float64(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		n = reflect.ValueOf(v).Convert(reflect.TypeOf(n)).Float()
+	case json.Number:
+		var err error
+		if n, err = v.Float64(); err != nil {
+			return "", false
+		}
+	case string:
+		var err error
+		if n, err = strconv.ParseFloat(v, 64); err != nil {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	s := strconv.FormatFloat(n, 'f', scale, 64)
+	var sign string
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(f.Format.ThousandsSeparator)
+		}
+		sb.WriteByte(whole[i])
+	}
+	if frac > "" {
+		sb.WriteString(f.Format.DecimalSeparator + frac)
+	}
+	return sb.String(), true
+}
+
+// timeValue converts a time.Time, Date, Datetime, Time or string to a time.Time
+func timeValue(value interface{}, layout string) (time.Time, bool) {
+	var s string
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case Date:
+		s = string(v)
+	case Datetime:
+		s = string(v)
+	case Time:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return time.Time{}, false
+	}
+	tm, err := time.Parse(layout, s)
+	if err != nil {
+		// datetimes may be returned without milliseconds
+		if tm, err = time.Parse(time.RFC3339, s); err != nil {
+			return time.Time{}, false
+		}
+	}
+	return tm, true
+}
+
+// indirectValue dereferences pointers returning nil for nil
+// pointers and empty strings
+func indirectValue(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	for rv.IsValid() && rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() == reflect.String && rv.Len() == 0 {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// recordValues returns the field values of a RecordMap or struct keyed by json name
+func recordValues(rec SObject) map[string]interface{} {
+	if m, ok := rec.(RecordMap); ok {
+		return m
+	}
+	var vals = make(map[string]interface{})
+	rv := reflect.Indirect(reflect.ValueOf(rec))
+	if rv.Kind() != reflect.Struct {
+		return vals
+	}
+	for nm, idx := range jsonfield.Index(rv.Type()) {
+		vals[nm] = rv.FieldByIndex(idx).Interface()
+	}
+	return vals
+}
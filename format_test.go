@@ -0,0 +1,90 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+type formatOpp struct {
+	Amount    float64 `json:"Amount"`
+	CloseDate *salesforce.Date
+}
+
+func (o formatOpp) SObjectName() string { return "Opportunity" }
+
+func (o formatOpp) WithAttr(ref string) salesforce.SObject { return o }
+
+func TestFormatter(t *testing.T) {
+	def := &salesforce.SObjectDefinition{
+		Name: "Opportunity",
+		Fields: []salesforce.Field{
+			{Name: "Amount", Type: "currency", Scale: 2},
+			{Name: "Probability", Type: "percent", Scale: 0},
+			{Name: "Quantity__c", Type: "double", Scale: 1},
+			{Name: "IsWon", Type: "boolean"},
+			{Name: "CloseDate", Type: "date"},
+			{Name: "CreatedDate", Type: "datetime"},
+			{Name: "StageName", Type: "picklist", PicklistValues: []salesforce.PickListValue{
+				{Value: "closed_won", Label: "Closed Won"}}},
+			{Name: "Regions__c", Type: "multipicklist", PicklistValues: []salesforce.PickListValue{
+				{Value: "NA", Label: "North America"}, {Value: "EU", Label: "Europe"}}},
+		},
+	}
+	amt := 1234567.891
+	var nilAmt *float64
+	f := salesforce.NewFormatter(def, salesforce.FieldFormat{})
+	tests := []struct {
+		field string
+		value interface{}
+		want  string
+	}{
+		{"Amount", amt, "$1,234,567.89"},
+		{"Amount", &amt, "$1,234,567.89"},
+		{"Amount", nilAmt, ""},
+		{"Amount", json.Number("-12.5"), "-$12.50"},
+		{"Probability", 75, "75%"},
+		{"Quantity__c", "1000", "1,000.0"},
+		{"IsWon", true, "Yes"},
+		{"CloseDate", salesforce.Date("2022-03-04"), "3/4/2022"},
+		{"CreatedDate", "2022-03-04T15:30:00.000+0000", "3/4/2022 3:30 PM"},
+		{"StageName", "closed_won", "Closed Won"},
+		{"StageName", "Other", "Other"},
+		{"Regions__c", "NA;EU", "North America; Europe"},
+		{"Unknown__c", 12, "12"},
+		{"Amount", "abc", "abc"},
+	}
+	for _, tt := range tests {
+		if got := f.FormatField(tt.field, tt.value); got != tt.want {
+			t.Errorf("%s %v: expected %q; got %q", tt.field, tt.value, tt.want, got)
+		}
+	}
+
+	loc := time.FixedZone("CET", 3600)
+	f = salesforce.NewFormatter(def, salesforce.LocaleFieldFormat("de_DE", loc))
+	rec := salesforce.RecordMap{"Amount": amt, "CreatedDate": "2022-03-04T23:30:00.000+0000", "IsWon": false, "Name": "x"}
+	got := f.FormatRecord(rec)
+	want := map[string]string{"Amount": "1.234.567,89 €", "CreatedDate": "05.03.2022, 00:30", "IsWon": "Nein"}
+	if len(got) != len(want) {
+		t.Errorf("expected %v; got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: expected %q; got %q", k, v, got[k])
+		}
+	}
+
+	dt := salesforce.Date("2022-12-31")
+	got = salesforce.NewFormatter(def, salesforce.LocaleFieldFormat("en_GB", nil)).
+		FormatRecord(&formatOpp{Amount: 5, CloseDate: &dt}, "Amount", "CloseDate", "IsWon")
+	if got["Amount"] != "£5.00" || got["CloseDate"] != "31/12/2022" || got["IsWon"] != "" {
+		t.Errorf("unexpected struct format %v", got)
+	}
+}
@@ -17,6 +17,8 @@ import (
 	"fmt"
 	"go/format"
 	"log"
+	"path"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -67,14 +69,37 @@ type Config struct {
 	SkipRelationshipGlobal      map[string]bool      `json:"skip_relationship_global,omitempty"`       // relationshipnames to skip in every object
 	Packages                    []Parameters         `json:"packages,omitempty"`                       // list of Packages to create
 	IncludeCodeGeneratedComment bool                 `json:"include_code_generated_comment,omitempty"` // add Code generated .* DO NOT EDIT.$
-
+	Tooling                     bool                 `json:"tooling,omitempty"`                        // generate structs for Tooling API objects (ApexClass, ApexTrigger, etc.)
+	TypeByFieldType             map[string]string    `json:"type_by_field_type,omitempty"`             // salesforce field type (e.g. currency, percent) to go type map. Overrides SoapTypes
+	Imports                     []string             `json:"imports,omitempty"`                        // additional import paths for generated packages (e.g. github.com/shopspring/decimal)
+	IncludeExtras               bool                 `json:"include_extras,omitempty"`                 // add an Extras field and UnmarshalJSON method to save fields not defined in the struct
+	SkipFieldMatch              string               `json:"skip_field_match,omitempty"`               // exclude fields whose api name matches regexp (e.g. ^npsp__)
+	SkipNonUpdateable           bool                 `json:"skip_non_updateable,omitempty"`            // exclude fields that are not updateable except Id
+	SkipCompound                bool                 `json:"skip_compound,omitempty"`                  // exclude compound address and location fields
+	// TemplateFuncs are added to the template created by Template.
+	TemplateFuncs template.FuncMap `json:"-"`
+	// Templates are named templates parsed after the default template.  Use the names
+	// header, imports, struct, methods and footer to replace a section of the default
+	// template, or new names for the templates of TemplateFiles.
+	Templates map[string]string `json:"templates,omitempty"`
+	// TemplateFiles creates additional files for each package.
+	TemplateFiles []TemplateFile `json:"template_files,omitempty"`
+	// ManifestFilename adds a JSON Manifest of the generated content to the files
+	// returned by MakeSource (e.g. sobjects_manifest.json).
+	ManifestFilename string `json:"manifest_filename,omitempty"`
 }
 
-// MakeTemplateData generates a slice of Templates
+// MakeTemplateData generates a slice of Templates.  Objects are described using
+// sv.CachedDescribe, so use sv.WithDescribeStore to reuse describes between runs.
 func (cfg *Config) MakeTemplateData(ctx context.Context, sv *salesforce.Service) ([]*TemplateData, error) {
+	_, tds, err := cfg.makeTemplateData(ctx, sv)
+	return tds, err
+}
+
+func (cfg *Config) makeTemplateData(ctx context.Context, sv *salesforce.Service) (*Job, []*TemplateData, error) {
 	job, err := cfg.ReadSObjectDescriptions(ctx, sv)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var results = make([]*TemplateData, len(cfg.Packages))
@@ -91,7 +116,7 @@ func (cfg *Config) MakeTemplateData(ctx context.Context, sv *salesforce.Service)
 		}
 		results[idx] = td
 	}
-	return results, nil
+	return job, results, nil
 }
 
 // ErrorList contains a slice of errors.
@@ -117,6 +142,7 @@ type jobMaps struct {
 	replaceTextMap   map[*Parameters]string
 	skipMap          map[string]bool
 	typeMap          sfTypeMap
+	skipFieldRegexp  *regexp.Regexp
 }
 
 func (cfg *Config) getMaps() (*jobMaps, error) {
@@ -143,6 +169,12 @@ func (cfg *Config) getMaps() (*jobMaps, error) {
 			jm.replaceTextMap[pkg] = replaceText
 		}
 	}
+	if cfg.SkipFieldMatch > "" {
+		var err error
+		if jm.skipFieldRegexp, err = regexp.Compile(cfg.SkipFieldMatch); err != nil {
+			return nil, fmt.Errorf("skip_field_match regexp compile failed %s %w", cfg.SkipFieldMatch, err)
+		}
+	}
 	// lists to maps
 	for _, s := range cfg.SkipObjects {
 		jm.skipMap[s] = true
@@ -161,6 +193,7 @@ func (cfg *Config) getMaps() (*jobMaps, error) {
 // CreateJob initializes a Job struct with the salesforce instance's list of SObjects,
 // and creates maps for caching related data
 func (cfg *Config) CreateJob(ctx context.Context, sv *salesforce.Service) (*Job, error) {
+	sv = cfg.service(sv)
 	jm, err := cfg.getMaps()
 	if err != nil {
 		return nil, err
@@ -186,16 +219,26 @@ func (cfg *Config) CreateJob(ctx context.Context, sv *salesforce.Service) (*Job,
 	return &Job{
 		Config:       cfg,
 		InstanceName: sv.Instance(),
+		APIVersion:   sv.APIVersion(),
 		TypeMap:      jm.typeMap,
 		ObjMap:       objMap,
 		StructMap:    structMap,
 		Include:      jm.includeRegexpMap,
 		Replace:      jm.replaceRegexpMap,
 		ReplaceText:  jm.replaceTextMap,
+		SkipField:    jm.skipFieldRegexp,
 		Duplicates:   make(map[*Parameters]map[string]*Duplicate),
 	}, nil
 }
 
+// service returns the tooling service when cfg.Tooling is set
+func (cfg *Config) service(sv *salesforce.Service) *salesforce.Service {
+	if cfg.Tooling {
+		return sv.WithTooling()
+	}
+	return sv
+}
+
 // ReadSObjectDescriptions iterates through salesforce instance's objects and attaches them to
 // the appropriate package.
 func (cfg *Config) ReadSObjectDescriptions(ctx context.Context, sv *salesforce.Service) (*Job, error) {
@@ -206,6 +249,7 @@ func (cfg *Config) ReadSObjectDescriptions(ctx context.Context, sv *salesforce.S
 		mErr.Lock()
 		return len(el) > 0
 	}
+	sv = cfg.service(sv)
 	job, err := cfg.CreateJob(ctx, sv)
 	if err != nil {
 		return nil, err
@@ -301,6 +345,21 @@ func (job *Job) structName(p *Parameters, objdef *salesforce.SObjectDefinition)
 	return override
 }
 
+// skipField reports whether the config's field filters exclude fld.  The Id
+// field is never excluded.
+func (job *Job) skipField(fld salesforce.Field) bool {
+	if fld.Name == "Id" {
+		return false
+	}
+	if job.SkipField != nil && job.SkipField.MatchString(fld.Name) {
+		return true
+	}
+	if job.Config.SkipNonUpdateable && !fld.Updateable {
+		return true
+	}
+	return job.Config.SkipCompound && (fld.Type == "address" || fld.Type == "location")
+}
+
 // Struct compile all needed data for outputting a go struct definition representing the sobject
 func (job *Job) Struct(p *Parameters, objdef *salesforce.SObjectDefinition) *Struct {
 	cfg := job.Config
@@ -313,12 +372,18 @@ func (job *Job) Struct(p *Parameters, objdef *salesforce.SObjectDefinition) *Str
 	var fields = make([]*Field, 0, len(objdef.Fields))
 
 	for _, fld := range objdef.Fields {
+		if job.skipField(fld) {
+			continue
+		}
 		// selecdt basis for go field name
 		goFieldName := fld.Name
 		if p.UseLabel || (fld.Name[0] >= 97 && fld.Name[0] <= 122) {
 			goFieldName = fld.Label
 		}
-		typeNm := typeMap.Get(fld.SoapType)
+		typeNm, ok := cfg.TypeByFieldType[fld.Type]
+		if !ok {
+			typeNm = typeMap.Get(fld.SoapType)
+		}
 		skip := cfg.SkipRelationshipGlobal[fld.Name]
 		goFld := override.Field(fld, goFieldName, typeNm, skip)
 
@@ -343,15 +408,27 @@ func (job *Job) Struct(p *Parameters, objdef *salesforce.SObjectDefinition) *Str
 		fields = append(fields, goFld)
 	}
 
+	var idField string
+	for _, f := range fields {
+		if f.APIName == "Id" && f.GoType == "string" {
+			idField = f.GoName
+			break
+		}
+	}
+
 	return &Struct{
-		GoName:           goName,
-		Label:            objdef.Label,
-		APIName:          apiName,
-		Receiver:         strings.ToLower(goName[0:1]),
-		Readonly:         (!objdef.Updateable && !objdef.Createable),
-		KeyPrefix:        objdef.KeyPrefix,
-		AssociatedEntity: override.AssociateEntityName,
-		FieldProps:       fields,
+		GoName:             goName,
+		Label:              objdef.Label,
+		APIName:            apiName,
+		Receiver:           strings.ToLower(goName[0:1]),
+		Readonly:           (!objdef.Updateable && !objdef.Createable),
+		KeyPrefix:          objdef.KeyPrefix,
+		AssociatedEntity:   override.AssociateEntityName,
+		FieldProps:         fields,
+		IDField:            idField,
+		RecordTypes:        recordTypes(objdef),
+		ChildRelationships: childRelationships(objdef),
+		IncludeExtras:      cfg.IncludeExtras,
 	}
 }
 
@@ -359,12 +436,14 @@ func (job *Job) Struct(p *Parameters, objdef *salesforce.SObjectDefinition) *Str
 type Job struct {
 	*Config
 	InstanceName string
+	APIVersion   string
 	TypeMap      map[string]string                       // map of SoapTypes to go types
 	ObjMap       map[string]salesforce.SObjectDefinition //  map of all salesforce instance definitions
 	StructMap    map[*Parameters][]Struct                // slice of Struct record by package config
 	Include      map[*Parameters]*regexp.Regexp
 	Replace      map[*Parameters]*regexp.Regexp
 	ReplaceText  map[*Parameters]string
+	SkipField    *regexp.Regexp // fields matching are excluded from structs
 	Duplicates   map[*Parameters]map[string]*Duplicate
 	wg           sync.WaitGroup
 	m            sync.Mutex
@@ -432,7 +511,7 @@ func (job *Job) AssignSObjects(ctx context.Context, sv *salesforce.Service,
 		p = &cfg.Packages[idx]
 		if job.Match(p, &obj) {
 			// retreive full sobject fields
-			objdef, err := sv.Describe(ctx, obj.Name)
+			objdef, err := sv.CachedDescribe(ctx, obj.Name)
 			if err != nil {
 				// TODO: adding better logging of errors for go routine
 				log.Printf("unable to retreive info on %s, %v", obj.Name, err)
@@ -477,6 +556,7 @@ func (job *Job) TemplateData(p *Parameters) *TemplateData {
 			cnt = 0
 		}
 	}
+	job.filterChildRelationships(strx)
 	var duplicateJSON string
 	if len(job.Duplicates[p]) > 0 {
 		b, _ := json.MarshalIndent(job.Duplicates[p], "", "    ")
@@ -490,6 +570,29 @@ func (job *Job) TemplateData(p *Parameters) *TemplateData {
 		Instance:                    job.InstanceName,
 		Structs:                     strx,
 		Duplicates:                  duplicateJSON,
+		Imports:                     job.Config.Imports,
+		IncludeExtras:               job.Config.IncludeExtras,
+	}
+}
+
+// filterChildRelationships removes child relationships whose child is not included
+// in a package and sets the ChildGoName of the remaining relationships
+func (job *Job) filterChildRelationships(strx []Struct) {
+	var goNames = make(map[string]string)
+	for _, sx := range job.StructMap {
+		for _, s := range sx {
+			goNames[s.APIName] = s.GoName
+		}
+	}
+	for idx := range strx {
+		var crs []ChildRelationship
+		for _, cr := range strx[idx].ChildRelationships {
+			if goName, ok := goNames[cr.ChildSObject]; ok {
+				cr.ChildGoName = goName
+				crs = append(crs, cr)
+			}
+		}
+		strx[idx].ChildRelationships = crs
 	}
 }
 
@@ -534,19 +637,80 @@ type TemplateData struct {
 	Instance                    string   `json:"instance,omitempty"`
 	Structs                     []Struct `json:"structs,omitempty"`
 	Duplicates                  string   `json:"duplicate_json"`
+	Imports                     []string `json:"imports,omitempty"`
+	IncludeExtras               bool     `json:"include_extras,omitempty"`
 }
 
 // Struct contains all needed information to create a salesforce.SObject
 // definition
 type Struct struct {
-	GoName           string   `json:"name,omitempty"`
-	Label            string   `json:"label,omitempty"`
-	APIName          string   `json:"api_name,omitempty"`
-	Receiver         string   `json:"receiver,omitempty"`
-	Readonly         bool     `json:"readonly,omitempty"`
-	KeyPrefix        string   `json:"keyPrefix,omitempty"`
-	AssociatedEntity string   `json:"associated_entity,omitempty"`
-	FieldProps       []*Field `json:"field_props,omitempty"`
+	GoName             string              `json:"name,omitempty"`
+	Label              string              `json:"label,omitempty"`
+	APIName            string              `json:"api_name,omitempty"`
+	Receiver           string              `json:"receiver,omitempty"`
+	Readonly           bool                `json:"readonly,omitempty"`
+	KeyPrefix          string              `json:"keyPrefix,omitempty"`
+	AssociatedEntity   string              `json:"associated_entity,omitempty"`
+	FieldProps         []*Field            `json:"field_props,omitempty"`
+	IDField            string              `json:"id_field,omitempty"` // go name of the Id field
+	RecordTypes        []RecordType        `json:"record_types,omitempty"`
+	ChildRelationships []ChildRelationship `json:"child_relationships,omitempty"` // limited to children included in a package
+	IncludeExtras      bool                `json:"include_extras,omitempty"`
+}
+
+// RecordType describes a record type of the sobject for generating record type constants
+type RecordType struct {
+	GoName        string `json:"go_name,omitempty"` // DeveloperName as a go identifier
+	Name          string `json:"name,omitempty"`
+	DeveloperName string `json:"developer_name,omitempty"`
+	ID            string `json:"id,omitempty"`
+	Active        bool   `json:"active,omitempty"`
+	Master        bool   `json:"master,omitempty"`
+	Default       bool   `json:"default,omitempty"`
+}
+
+// ChildRelationship describes a relationship from a child sobject to the sobject
+type ChildRelationship struct {
+	RelationshipName string `json:"relationship_name,omitempty"`
+	ChildSObject     string `json:"child_sobject,omitempty"`
+	ChildGoName      string `json:"child_go_name,omitempty"` // struct name of the child, set when the child is included in a package
+	Field            string `json:"field,omitempty"`         // reference field of the child
+	CascadeDelete    bool   `json:"cascade_delete,omitempty"`
+}
+
+// recordTypes converts the object's RecordTypeInfos
+func recordTypes(objdef *salesforce.SObjectDefinition) []RecordType {
+	var rts []RecordType
+	for _, rti := range objdef.RecordTypeInfos {
+		rts = append(rts, RecordType{
+			GoName:        LintName(rti.DeveloperName),
+			Name:          rti.Name,
+			DeveloperName: rti.DeveloperName,
+			ID:            rti.RecordTypeID,
+			Active:        rti.Active,
+			Master:        rti.Master,
+			Default:       rti.DefaultRecordTypeMapping,
+		})
+	}
+	return rts
+}
+
+// childRelationships converts the object's named ChildRelationships
+func childRelationships(objdef *salesforce.SObjectDefinition) []ChildRelationship {
+	var crs []ChildRelationship
+	for _, cr := range objdef.ChildRelationships {
+		child, _ := cr.ChildSObject.(string)
+		if cr.RelationshipName == nil || *cr.RelationshipName == "" || child == "" || cr.DeprecatedAndHidden {
+			continue
+		}
+		crs = append(crs, ChildRelationship{
+			RelationshipName: *cr.RelationshipName,
+			ChildSObject:     child,
+			Field:            cr.Field,
+			CascadeDelete:    cr.CascadeDelete,
+		})
+	}
+	return crs
 }
 
 // Parameters contains all data needed for generating a package
@@ -643,11 +807,13 @@ func (o *Override) FieldOverride(nm, lbl string) *FldOverride {
 	return &fo
 }
 
-// FldOverride contains a replacement name and whether the field should be defined as a pointer
+// FldOverride contains a replacement name and whether the field should be defined as a pointer.
+// GoType replaces the go type determined by the field's type.
 type FldOverride struct {
 	Name             string `json:"name,omitempty"`
 	IsPointer        bool   `json:"is_pointer,omitempty"`
 	SkipRelationship bool   `json:"skip_relationship,omitempty"`
+	GoType           string `json:"go_type,omitempty"`
 }
 
 // Field determines comments, type, tag and name
@@ -658,6 +824,9 @@ func (o *Override) Field(fx salesforce.Field, goName string, typeNm string, skip
 	if override.SkipRelationship {
 		skipRelationship = true
 	}
+	if override.GoType > "" {
+		typeNm = override.GoType
+	}
 	if override.IsPointer {
 		typeNm = "*" + typeNm
 	}
@@ -758,22 +927,26 @@ func (tm sfTypeMap) Get(key string) string {
 	return "interface{}"
 }
 
-const defaultTemplateSource = `// Package {{.Name}} {{.Description}}{{if .IncludeCodeGeneratedComment}}
+const defaultTemplateSource = `{{block "header" .}}// Package {{.Name}} {{.Description}}{{if .IncludeCodeGeneratedComment}}
 // Code generated for salesforce instance {{.Instance}}; DO NOT EDIT.{{else}}
-// instance: {{.Instance}}{{end}}
+// instance: {{.Instance}}{{end}}{{end}}
 package {{.Name}}
 
-import (
-	"github.com/jfcote87/salesforce"
+{{block "imports" .}}import ({{if .IncludeExtras}}
+	"encoding/json"
+{{end}}
+	"github.com/jfcote87/salesforce"{{range .Imports}}
+	"{{.}}"{{end}}
 )
-
-{{range .Structs}}// {{.GoName}} describes the salesforce object {{.APIName}} {{.KeyPrefix}} ({{.Label}}){{if .Readonly}} [READ ONLY]{{end}}
+{{end}}
+{{range .Structs}}{{block "struct" .}}// {{.GoName}} describes the salesforce object {{.APIName}} {{.KeyPrefix}} ({{.Label}}){{if .Readonly}} [READ ONLY]{{end}}
 type {{.GoName}} struct {
 	Attributes *salesforce.Attributes ` + "`json:" + `"attributes,omitempty"` + "`" + ` 
 {{range .FieldProps}}    {{.GoName}} {{.GoType}} {{.Tag}} // {{.Comment}}
 {{if .Relationship}}    {{.Relationship.GoName}} {{.Relationship.GoType}} {{.Relationship.Tag}} // {{.Relationship.Comment}}
-{{end}}{{end}}}
-
+{{end}}{{end}}{{if .IncludeExtras}}    Extras map[string]json.RawMessage ` + "`json:" + `"-"` + "`" + ` // fields not defined in {{.GoName}}
+{{end}}}
+{{end}}{{block "methods" .}}
 // SObjectName return rest api name of {{.APIName}}
 func ({{.Receiver}} {{.GoName}}) SObjectName() string {
 	return "{{.APIName}}"
@@ -785,22 +958,83 @@ func({{.Receiver}} {{.GoName}}) WithAttr(ref string) salesforce.SObject {
 	{{.Receiver}}.Attributes = &salesforce.Attributes{Type: "{{.APIName}}", Ref: ref }
 	return {{.Receiver}}
 }
-{{end}}{{if .Duplicates}}
+{{if .IDField}}
+// GetID returns the record id
+func ({{.Receiver}} {{.GoName}}) GetID() string {
+	return {{.Receiver}}.{{.IDField}}
+}
+
+// SetID sets the record id
+func ({{.Receiver}} *{{.GoName}}) SetID(id string) {
+	{{.Receiver}}.{{.IDField}} = id
+}
+{{end}}{{if .IncludeExtras}}
+// UnmarshalJSON decodes the record saving fields not defined in {{.GoName}} to Extras
+func ({{.Receiver}} *{{.GoName}}) UnmarshalJSON(b []byte) error {
+	type record {{.GoName}}
+	var rec record
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return err
+	}
+	extras, err := salesforce.ExtraFields(b, rec)
+	if err != nil {
+		return err
+	}
+	*{{.Receiver}} = {{.GoName}}(rec)
+	{{.Receiver}}.Extras = extras
+	return nil
+}
+{{end}}{{end}}{{end}}{{block "footer" .}}{{if .Duplicates}}
 // Duplicate struct and field names
 /* 
 {{.Duplicates}}
-*/{{end}}
+*/{{end}}{{end}}
 `
 
+// TemplateFile is an additional file generated for each package by executing
+// the named template with the package's TemplateData.
+type TemplateFile struct {
+	Filename string `json:"filename"` // name of file in the directory of the package's go_filename (e.g. constants.go)
+	Template string `json:"template"` // name of template in Config.Templates
+}
+
+// Template returns the default template with the config's TemplateFuncs and Templates.
+func (cfg *Config) Template() (*template.Template, error) {
+	if len(cfg.TemplateFuncs) == 0 && len(cfg.Templates) == 0 {
+		return defaultTemplate, nil
+	}
+	tmpl := template.New("defs")
+	if len(cfg.TemplateFuncs) > 0 {
+		tmpl = tmpl.Funcs(cfg.TemplateFuncs)
+	}
+	if _, err := tmpl.Parse(defaultTemplateSource); err != nil {
+		return nil, err
+	}
+	var names = make([]string, 0, len(cfg.Templates))
+	for nm := range cfg.Templates {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	for _, nm := range names {
+		if _, err := tmpl.New(nm).Parse(cfg.Templates[nm]); err != nil {
+			return nil, fmt.Errorf("template %s: %w", nm, err)
+		}
+	}
+	return tmpl, nil
+}
+
 // MakeSource creates formatted source code from Config parameters.  The returned map's keys are the go_filename from the
-// PackageParams and the byte array is the generated and formatted code. If tmp is nil, the procedure uses the defaultTemplate.
+// PackageParams and the byte array is the generated and formatted code. If tmp is nil, the procedure uses cfg.Template().
+// Each of the config's TemplateFiles is generated in the directory of the go_filename unless its output is blank.
 func (cfg *Config) MakeSource(ctx context.Context, sv *salesforce.Service, tmpl *template.Template) (map[string][]byte, error) {
-	tds, err := cfg.MakeTemplateData(ctx, sv)
+	job, tds, err := cfg.makeTemplateData(ctx, sv)
 	if err != nil {
 		return nil, err
 	}
 	if tmpl == nil {
-		tmpl = defaultTemplate
+		if tmpl, err = cfg.Template(); err != nil {
+			return nil, err
+		}
 	}
 	fileMap := make(map[string][]byte)
 	for _, td := range tds {
@@ -817,6 +1051,27 @@ func (cfg *Config) MakeSource(ctx context.Context, sv *salesforce.Service, tmpl
 		}
 		fileMap[td.GoFilename] = fmtOut
 
+		for _, tf := range cfg.TemplateFiles {
+			tmplOut.Reset()
+			if err := tmpl.ExecuteTemplate(tmplOut, tf.Template, td); err != nil {
+				return nil, err
+			}
+			if len(bytes.TrimSpace(tmplOut.Bytes())) == 0 {
+				continue
+			}
+			fn := path.Join(path.Dir(filepath.ToSlash(td.GoFilename)), tf.Filename)
+			if fmtOut, err = format.Source(tmplOut.Bytes()); err != nil {
+				return nil, fmt.Errorf("%s: %w", fn, err)
+			}
+			fileMap[fn] = fmtOut
+		}
+	}
+	if cfg.ManifestFilename > "" {
+		b, err := json.MarshalIndent(job.Manifest(tds), "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		fileMap[cfg.ManifestFilename] = append(b, '\n')
 	}
 	return fileMap, nil
 }
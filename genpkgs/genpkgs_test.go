@@ -6,13 +6,17 @@
 package genpkgs_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/scanner"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strings"
@@ -522,11 +526,20 @@ var (
 	aetChangeEvent = "ChangeEvent"
 )
 
+var relContacts, relOpportunities = "Contacts", "Opportunities"
+
 var testObjMap = map[string]salesforce.SObjectDefinition{
 	"Account": {Name: "Account", Label: "Account", Updateable: true, Fields: []salesforce.Field{
 		{Name: "Id", Label: "Account Id", SoapType: "tns:ID", Type: "reference", Length: 18, Updateable: true},
 		{Name: "Name", Label: "Name", SoapType: "xsd:string", Type: "string", Length: 128, Updateable: true},
 		{Name: "Type", Label: "Account Type", SoapType: "xsd:string", Type: "string", Length: 80, Updateable: true},
+	}, RecordTypeInfos: []salesforce.RecordTypeInfo{
+		{Active: true, DeveloperName: "Business_Account", Name: "Business Account", RecordTypeID: "012000000000001AAA", DefaultRecordTypeMapping: true},
+		{Active: true, DeveloperName: "Master", Name: "Master", RecordTypeID: "012000000000000AAA", Master: true},
+	}, ChildRelationships: []salesforce.ChildRef{
+		{ChildSObject: "Contact", Field: "AccountId", RelationshipName: &relContacts},
+		{ChildSObject: "Opportunity", Field: "AccountId", RelationshipName: &relOpportunities},
+		{ChildSObject: "AccountHistory", Field: "AccountId"},
 	}},
 	"Contact": {Name: "Contact", Label: "People", Updateable: true, Fields: []salesforce.Field{
 		{Name: "Id", Label: "Contact Id", SoapType: "tns:ID", Type: "reference", Length: 18, Updateable: true},
@@ -791,34 +804,39 @@ func getConfigMakeTemplateDataServer(ch chan struct{}) *httptest.Server {
 			http.Error(w, "503 err", http.StatusInternalServerError)
 			return
 		}
-		parts := strings.Split(r.URL.Path, "/")
-		if strings.HasSuffix(r.URL.Path, "/describe") {
-			objnm := parts[len(parts)-2]
-			obj, ok := testObjMap[objnm]
-			if !ok {
-				http.Error(w, fmt.Sprintf("invalid object name %s", objnm), 400)
-				return
-			}
-			b, _ := json.MarshalIndent(obj, "", "    ")
-			w.Write(b)
+		serveTestObjects(w, r)
+	}))
+}
+
+// serveTestObjects returns the describe or object list results of testObjMap
+func serveTestObjects(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if strings.HasSuffix(r.URL.Path, "/describe") {
+		objnm := parts[len(parts)-2]
+		obj, ok := testObjMap[objnm]
+		if !ok {
+			http.Error(w, fmt.Sprintf("invalid object name %s", objnm), 400)
 			return
 		}
-		var objs = make([]salesforce.SObjectDefinition, 0, len(testObjMap))
-		for _, v := range testObjMap {
-			r := v
-			objs = append(objs, r)
-		}
-		var result = struct {
-			Encoding     string                         `json:"encoding,omitempty"`
-			MaxBatchSize int                            `json:"maxBatchSize,omitempty"`
-			Objects      []salesforce.SObjectDefinition `json:"sobjects,omitempty"`
-		}{
-			Encoding:     "application/json",
-			MaxBatchSize: 200,
-			Objects:      objs,
-		}
-		json.NewEncoder(w).Encode(result)
-	}))
+		b, _ := json.MarshalIndent(obj, "", "    ")
+		w.Write(b)
+		return
+	}
+	var objs = make([]salesforce.SObjectDefinition, 0, len(testObjMap))
+	for _, v := range testObjMap {
+		o := v
+		objs = append(objs, o)
+	}
+	var result = struct {
+		Encoding     string                         `json:"encoding,omitempty"`
+		MaxBatchSize int                            `json:"maxBatchSize,omitempty"`
+		Objects      []salesforce.SObjectDefinition `json:"sobjects,omitempty"`
+	}{
+		Encoding:     "application/json",
+		MaxBatchSize: 200,
+		Objects:      objs,
+	}
+	json.NewEncoder(w).Encode(result)
 }
 
 func TestConfig_MakeTemplateData(t *testing.T) {
@@ -887,6 +905,36 @@ func TestConfig_MakeTemplateData(t *testing.T) {
 	}
 }
 
+func TestConfig_Tooling(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if !strings.HasPrefix(r.URL.Path, "/services/data/53/tooling/sobjects") {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		serveTestObjects(w, r)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(srv.URL + "/services/data/53/")
+	myCfg := *testConfig
+	cfg := &myCfg
+	if _, err := cfg.MakeTemplateData(ctx, sv); err == nil {
+		t.Errorf("expected not found error for data api objects")
+	}
+	cfg.Tooling = true
+	paths = nil
+	tds, err := cfg.MakeTemplateData(ctx, sv)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(tds) != len(cfg.Packages) || len(paths) < 2 || paths[0] != "/services/data/53/tooling/sobjects/" {
+		t.Errorf("expected tooling calls; got %v", paths)
+	}
+}
+
 func doDeepTest(nm string, ws, gs genpkgs.Struct, f func(string, ...interface{})) {
 	for ix, gf := range gs.FieldProps {
 		wf := ws.FieldProps[ix]
@@ -1321,6 +1369,9 @@ func TestConfig_MakeSource(t *testing.T) {
 	if len(mx) != 2 || mx["sobjects.go"] == nil || mx["custom/custom.go"] == nil {
 		t.Errorf("expected files named sobjects.go and custom/custom.go; got %v", tfiles)
 	}
+	if !bytes.Contains(mx["sobjects.go"], []byte(") SetID(id string) {")) {
+		t.Errorf("expected sobjects.go to contain SetID funcs")
+	}
 
 	badTmpl, _ := template.New("bad").Parse("{{ .Q }}")
 	_, err = cfg.MakeSource(ctx, sv, badTmpl)
@@ -1337,3 +1388,373 @@ func TestConfig_MakeSource(t *testing.T) {
 	}
 
 }
+
+func TestConfig_Verify(t *testing.T) {
+	cfg := genpkgs.Config{
+		Packages: []genpkgs.Parameters{
+			{
+				Description:     "Standard",
+				Name:            "sobjects",
+				GoFilename:      "sobjects.go",
+				IncludeStandard: true,
+			},
+			{
+				Description:   "Custom",
+				Name:          "custom",
+				GoFilename:    "custom/custom.go",
+				IncludeCustom: true,
+			},
+		},
+	}
+	srv, _ := getTestServer(t)
+	defer srv.Close()
+
+	ctx := context.Background()
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(srv.URL + "/services/data/53/")
+
+	mx, err := cfg.MakeSource(ctx, sv, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatalf("tempdir %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for k, v := range mx {
+		fn := filepath.Join(dir, k)
+		os.MkdirAll(filepath.Dir(fn), 0755)
+		if err := ioutil.WriteFile(fn, v, 0644); err != nil {
+			t.Fatalf("write %s %v", fn, err)
+		}
+	}
+	mismatches, err := cfg.Verify(ctx, sv, dir)
+	if err != nil || len(mismatches) > 0 {
+		t.Fatalf("expected no mismatches; got %v %v", mismatches, err)
+	}
+
+	src := string(mx["sobjects.go"])
+	src = strings.Replace(src, "`json:\"Name,omitempty\"` // string(128)", "`json:\"Name__x,omitempty\"`", 1)
+	src = strings.Replace(src, "FirstName        string", "FirstName        int", 1)
+	src += "\ntype Extra struct{}\n\nfunc (e Extra) SObjectName() string { return \"Extra__c\" }\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "sobjects.go"), []byte(src), 0644); err != nil {
+		t.Fatalf("write %v", err)
+	}
+	os.Remove(filepath.Join(dir, "custom/custom.go"))
+	mismatches, err = cfg.Verify(ctx, sv, dir)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	var want = []string{
+		"sobjects.Account.Name: field not found",
+		"sobjects.Account.Name__x: field not in instance",
+		"sobjects.Contact.FirstName: type int; want string",
+		"sobjects.Extra: struct not in instance",
+		"custom: file not found " + filepath.Join(dir, "custom/custom.go"),
+	}
+	if len(mismatches) != len(want) {
+		t.Fatalf("expected %d mismatches; got %v", len(want), mismatches)
+	}
+	for i, m := range mismatches {
+		if m.String() != want[i] {
+			t.Errorf("expected %s; got %s", want[i], m)
+		}
+	}
+}
+
+func TestWriteSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genpkgs")
+	if err != nil {
+		t.Fatalf("tempdir %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileMap := map[string][]byte{
+		"sobjects.go":        []byte("package sobjects\n"),
+		"custom/a/custom.go": []byte("package custom\n"),
+	}
+	manifest, err := genpkgs.WriteSource(dir, fileMap)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(manifest) != 2 || manifest[0].Filename != "custom/a/custom.go" || manifest[0].Unchanged ||
+		manifest[1].Size != 17 || len(manifest[1].SHA256) != 64 {
+		t.Errorf("unexpected manifest %#v", manifest)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "custom", "a", "custom.go")); err != nil || string(b) != "package custom\n" {
+		t.Errorf("expected custom.go contents; got %q %v", b, err)
+	}
+	if manifest, err = genpkgs.WriteSource(dir, fileMap); err != nil || !manifest[0].Unchanged || !manifest[1].Unchanged {
+		t.Errorf("expected unchanged files; got %#v %v", manifest, err)
+	}
+	if _, err := genpkgs.WriteSource(dir, map[string][]byte{"manifest.json": []byte("{}\n")}); err != nil {
+		t.Errorf("expected non-go file written without format check; got %v", err)
+	}
+	if _, err := genpkgs.WriteSource(dir, map[string][]byte{"bad.go": []byte("package  bad\n")}); err == nil {
+		t.Errorf("expected unformatted source error")
+	}
+	if _, err := genpkgs.WriteSource(dir, map[string][]byte{"../escape.go": []byte("package escape\n")}); err == nil {
+		t.Errorf("expected invalid filename error")
+	}
+	if _, err := genpkgs.WriteSource(dir, fileMap, genpkgs.WriteOptions{Goimports: true, GoimportsPath: filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("expected goimports error")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".") {
+			t.Errorf("unexpected temporary file %s", f.Name())
+		}
+	}
+}
+
+func TestConfig_Template(t *testing.T) {
+	cfg := genpkgs.Config{
+		Packages: []genpkgs.Parameters{
+			{
+				Description:     "Standard",
+				Name:            "sobjects",
+				GoFilename:      "sobjects/sobjects.go",
+				IncludeStandard: true,
+			},
+		},
+		TemplateFuncs: template.FuncMap{"upper": strings.ToUpper},
+		Templates: map[string]string{
+			"footer": "// end of {{.Name}}\n",
+			"consts": `package {{.Name}}
+
+// SObject names
+const ({{range .Structs}}
+	{{upper .GoName}} = "{{.APIName}}"{{end}}
+)
+`,
+			"blank": "{{/* no output */}}",
+		},
+		TemplateFiles: []genpkgs.TemplateFile{
+			{Filename: "consts.go", Template: "consts"},
+			{Filename: "blank.go", Template: "blank"},
+		},
+	}
+	srv, _ := getTestServer(t)
+
+	ctx := context.Background()
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(srv.URL + "/services/data/53/")
+
+	mx, err := cfg.MakeSource(ctx, sv, nil)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(mx) != 2 || mx["sobjects/sobjects.go"] == nil || mx["sobjects/consts.go"] == nil {
+		t.Fatalf("expected files sobjects/sobjects.go and sobjects/consts.go; got %d files", len(mx))
+	}
+	if !bytes.HasSuffix(mx["sobjects/sobjects.go"], []byte("// end of sobjects\n")) {
+		t.Errorf("expected footer override in sobjects.go")
+	}
+	if !bytes.Contains(mx["sobjects/sobjects.go"], []byte(") SetID(id string) {")) {
+		t.Errorf("expected sobjects.go to contain SetID funcs")
+	}
+	if !bytes.Contains(mx["sobjects/consts.go"], []byte(`CONTACT            = "Contact"`)) {
+		t.Errorf("expected consts.go to contain CONTACT constant; got %s", mx["sobjects/consts.go"])
+	}
+
+	cfg.Templates = map[string]string{"bad": "{{ .Name "}
+	if _, err = cfg.Template(); err == nil {
+		t.Errorf("expected parse error for bad template")
+	}
+	cfg.Templates = nil
+	cfg.TemplateFiles = []genpkgs.TemplateFile{{Filename: "x.go", Template: "missing"}}
+	if _, err = cfg.MakeSource(ctx, sv, nil); err == nil {
+		t.Errorf("expected error for missing template")
+	}
+}
+
+func TestConfig_MakeTemplateData_RecordTypesAndChildren(t *testing.T) {
+	cfg := genpkgs.Config{
+		Packages: []genpkgs.Parameters{
+			{Name: "sobjects", GoFilename: "sobjects.go", IncludeNames: []string{"Account", "Contact"}},
+		},
+	}
+	srv, _ := getTestServer(t)
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(srv.URL + "/services/data/53/")
+	tds, err := cfg.MakeTemplateData(context.Background(), sv)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(tds) != 1 || len(tds[0].Structs) != 2 || tds[0].Structs[0].APIName != "Account" {
+		t.Fatalf("expected Account and Contact structs; got %#v", tds)
+	}
+	acct := tds[0].Structs[0]
+	wantRTs := []genpkgs.RecordType{
+		{GoName: "BusinessAccount", Name: "Business Account", DeveloperName: "Business_Account", ID: "012000000000001AAA", Active: true, Default: true},
+		{GoName: "Master", Name: "Master", DeveloperName: "Master", ID: "012000000000000AAA", Active: true, Master: true},
+	}
+	if !reflect.DeepEqual(acct.RecordTypes, wantRTs) {
+		t.Errorf("expected record types %v; got %v", wantRTs, acct.RecordTypes)
+	}
+	wantCRs := []genpkgs.ChildRelationship{
+		{RelationshipName: "Contacts", ChildSObject: "Contact", ChildGoName: "Contact", Field: "AccountId"},
+	}
+	if !reflect.DeepEqual(acct.ChildRelationships, wantCRs) {
+		t.Errorf("expected child relationships %v; got %v", wantCRs, acct.ChildRelationships)
+	}
+}
+
+func TestConfig_TypeByFieldType(t *testing.T) {
+	cfg := &genpkgs.Config{
+		TypeByFieldType: map[string]string{"currency": "decimal.Decimal", "percent": "float32"},
+		StructOverrides: map[string]*genpkgs.Override{
+			"Opportunity": {Fields: map[string]genpkgs.FldOverride{
+				"Probability": {GoType: "float64", IsPointer: true},
+			}},
+		},
+		Imports: []string{"github.com/shopspring/decimal"},
+		Packages: []genpkgs.Parameters{
+			{Name: "sobjects", GoFilename: "sobjects.go", IncludeNames: []string{"Opportunity"}},
+		},
+	}
+	objdef := salesforce.SObjectDefinition{Name: "Opportunity", Label: "Opportunity", Updateable: true, Fields: []salesforce.Field{
+		{Name: "Id", Label: "Opportunity Id", SoapType: "tns:ID", Type: "id", Length: 18},
+		{Name: "Amount", Label: "Amount", SoapType: "xsd:double", Type: "currency", Updateable: true},
+		{Name: "Probability", Label: "Probability", SoapType: "xsd:double", Type: "percent", Updateable: true},
+		{Name: "Discount__c", Label: "Discount", SoapType: "xsd:double", Type: "percent", Updateable: true},
+		{Name: "TotalOpportunityQuantity", Label: "Quantity", SoapType: "xsd:double", Type: "double", Updateable: true},
+	}}
+	job := &genpkgs.Job{Config: cfg, TypeMap: map[string]string{"tns:ID": "string", "xsd:double": "float64"}}
+	sx := job.Struct(&cfg.Packages[0], &objdef)
+	var want = []string{"string", "decimal.Decimal", "*float64", "float32", "float64"}
+	for i, f := range sx.FieldProps {
+		if f.GoType != want[i] {
+			t.Errorf("%s: expected type %s; got %s", f.APIName, want[i], f.GoType)
+		}
+	}
+
+	job.StructMap = map[*genpkgs.Parameters][]genpkgs.Struct{&cfg.Packages[0]: {*sx}}
+	td := job.TemplateData(&cfg.Packages[0])
+	var buf bytes.Buffer
+	tmpl, _ := cfg.Template()
+	if err := tmpl.Execute(&buf, td); err != nil {
+		t.Fatalf("template execute failed %v", err)
+	}
+	if !strings.Contains(buf.String(), "\t\"github.com/shopspring/decimal\"\n") {
+		t.Errorf("expected decimal import; got %s", buf.String())
+	}
+}
+
+func TestConfig_IncludeExtras(t *testing.T) {
+	cfg := genpkgs.Config{
+		Packages: []genpkgs.Parameters{
+			{Name: "sobjects", GoFilename: "sobjects.go", IncludeNames: []string{"Account"}},
+		},
+		IncludeExtras: true,
+	}
+	srv, _ := getTestServer(t)
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(srv.URL + "/services/data/53/")
+	mx, err := cfg.MakeSource(context.Background(), sv, nil)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	src := mx["sobjects.go"]
+	for _, want := range []string{
+		"\t\"encoding/json\"\n",
+		"map[string]json.RawMessage `json:\"-\"`",
+		"func (a *Account) UnmarshalJSON(b []byte) error {",
+		"extras, err := salesforce.ExtraFields(b, rec)",
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("expected source to contain %s", want)
+		}
+	}
+}
+
+func TestConfig_SkipFields(t *testing.T) {
+	cfg := &genpkgs.Config{
+		SkipFieldMatch:    "^npsp__",
+		SkipNonUpdateable: true,
+		SkipCompound:      true,
+		Packages: []genpkgs.Parameters{
+			{Name: "sobjects", GoFilename: "sobjects.go", IncludeNames: []string{"Account"}},
+		},
+	}
+	objdef := salesforce.SObjectDefinition{Name: "Account", Label: "Account", Updateable: true, Fields: []salesforce.Field{
+		{Name: "Id", Label: "Account Id", SoapType: "tns:ID", Type: "id", Length: 18},
+		{Name: "Name", Label: "Name", SoapType: "xsd:string", Type: "string", Updateable: true},
+		{Name: "npsp__Batch__c", Label: "Batch", SoapType: "tns:ID", Type: "reference", Updateable: true},
+		{Name: "BillingAddress", Label: "Billing Address", SoapType: "urn:address", Type: "address", Updateable: true},
+		{Name: "BillingCity", Label: "Billing City", SoapType: "xsd:string", Type: "string", Updateable: true},
+		{Name: "CreatedDate", Label: "Created Date", SoapType: "xsd:dateTime", Type: "datetime"},
+	}}
+	srv, _ := getTestServer(t)
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(srv.URL + "/services/data/53/")
+	job, err := cfg.CreateJob(context.Background(), sv)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	sx := job.Struct(&cfg.Packages[0], &objdef)
+	var names []string
+	for _, f := range sx.FieldProps {
+		names = append(names, f.APIName)
+	}
+	if want := []string{"Id", "Name", "BillingCity"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected fields %v; got %v", want, names)
+	}
+
+	cfg.SkipFieldMatch = "[a-"
+	if _, err := cfg.CreateJob(context.Background(), sv); err == nil || !strings.HasPrefix(err.Error(), "skip_field_match regexp compile failed") {
+		t.Errorf("expected skip_field_match regexp compile failed; got %v", err)
+	}
+}
+
+func TestConfig_Manifest(t *testing.T) {
+	cfg := genpkgs.Config{
+		Packages: []genpkgs.Parameters{
+			{Name: "sobjects", GoFilename: "sobjects.go", IncludeNames: []string{"Account", "Contact"}},
+			{Name: "blank", GoFilename: "blank.go", IncludeNames: []string{"None"}},
+		},
+		StructOverrides: map[string]*genpkgs.Override{
+			"Contact": {Name: "People"},
+			"Lead":    {Name: "Prospect"},
+		},
+		ManifestFilename: "manifest.json",
+	}
+	srv, _ := getTestServer(t)
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(srv.URL + "/services/data/v53.0/")
+	mx, err := cfg.MakeSource(context.Background(), sv, nil)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	var m genpkgs.Manifest
+	if err := json.Unmarshal(mx["manifest.json"], &m); err != nil {
+		t.Fatalf("manifest decode failed %v", err)
+	}
+	if m.APIVersion != "v53.0" || m.Instance == "" || m.Generated.IsZero() || len(m.Packages) != 2 {
+		t.Fatalf("unexpected manifest %#v", m)
+	}
+	if want := []genpkgs.ManifestObject{
+		{APIName: "Account", GoName: "Account", Fields: 3},
+		{APIName: "Contact", GoName: "People", Fields: 4},
+	}; !reflect.DeepEqual(m.Packages[0].Objects, want) {
+		t.Errorf("expected objects %v; got %v", want, m.Packages[0].Objects)
+	}
+	if len(m.Packages[0].Duplicates) != 1 || m.Packages[0].Duplicates["Contact"] == nil {
+		t.Errorf("expected Contact duplicate fields; got %v", m.Packages[0].Duplicates)
+	}
+	if len(m.Packages[1].Objects) != 0 {
+		t.Errorf("expected no objects in blank package; got %v", m.Packages[1].Objects)
+	}
+	if len(m.StructOverrides) != 1 || m.StructOverrides["Contact"] == nil {
+		t.Errorf("expected Contact override only; got %v", m.StructOverrides)
+	}
+	dir, err := ioutil.TempDir("", "genpkgs")
+	if err != nil {
+		t.Fatalf("tempdir %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := genpkgs.WriteSource(dir, mx); err != nil {
+		t.Errorf("expected WriteSource success; got %v", err)
+	}
+}
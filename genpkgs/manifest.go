@@ -0,0 +1,73 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genpkgs
+
+import (
+	"sort"
+	"time"
+)
+
+// Manifest describes generated content for governance and auditing of regenerations
+type Manifest struct {
+	Instance        string               `json:"instance,omitempty"`
+	APIVersion      string               `json:"api_version,omitempty"`
+	Generated       time.Time            `json:"generated"`
+	Tooling         bool                 `json:"tooling,omitempty"`
+	Packages        []ManifestPackage    `json:"packages,omitempty"`
+	StructOverrides map[string]*Override `json:"struct_overrides,omitempty"` // overrides of generated objects
+	SoapTypes       map[string]string    `json:"soap_type,omitempty"`
+	TypeByFieldType map[string]string    `json:"type_by_field_type,omitempty"`
+}
+
+// ManifestPackage lists the objects and duplicates of a generated package
+type ManifestPackage struct {
+	Name       string                `json:"name,omitempty"`
+	GoFilename string                `json:"go_filename,omitempty"`
+	Objects    []ManifestObject      `json:"objects,omitempty"`
+	Duplicates map[string]*Duplicate `json:"duplicates,omitempty"`
+}
+
+// ManifestObject identifies a generated struct
+type ManifestObject struct {
+	APIName string `json:"api_name,omitempty"`
+	GoName  string `json:"go_name,omitempty"`
+	Fields  int    `json:"fields"`
+}
+
+// Manifest creates a Manifest of the template data returned by MakeTemplateData
+func (job *Job) Manifest(tds []*TemplateData) *Manifest {
+	m := &Manifest{
+		Instance:        job.InstanceName,
+		APIVersion:      job.APIVersion,
+		Generated:       time.Now().UTC(),
+		Tooling:         job.Config.Tooling,
+		SoapTypes:       job.Config.SoapTypes,
+		TypeByFieldType: job.Config.TypeByFieldType,
+	}
+	for idx, td := range tds {
+		if td == nil {
+			continue
+		}
+		mp := ManifestPackage{Name: td.Name, GoFilename: td.GoFilename}
+		if idx < len(job.Packages) {
+			mp.Duplicates = job.Duplicates[&job.Packages[idx]]
+		}
+		for _, sx := range td.Structs {
+			mp.Objects = append(mp.Objects, ManifestObject{APIName: sx.APIName, GoName: sx.GoName, Fields: len(sx.FieldProps)})
+			if o, ok := job.Config.StructOverrides[sx.APIName]; ok {
+				if m.StructOverrides == nil {
+					m.StructOverrides = make(map[string]*Override)
+				}
+				m.StructOverrides[sx.APIName] = o
+			}
+		}
+		sort.Slice(mp.Objects, func(i, j int) bool {
+			return mp.Objects[i].APIName < mp.Objects[j].APIName
+		})
+		m.Packages = append(m.Packages, mp)
+	}
+	return m
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genpkgs
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jfcote87/salesforce"
+)
+
+// Mismatch describes a difference between previously generated source
+// and the current salesforce instance's describe output.
+type Mismatch struct {
+	Package string `json:"package,omitempty"`
+	Struct  string `json:"struct,omitempty"`   // go name of struct
+	APIName string `json:"api_name,omitempty"` // sobject name
+	Field   string `json:"field,omitempty"`    // json tag name of field
+	Issue   string `json:"issue,omitempty"`
+}
+
+// String returns a single line description of the mismatch
+func (m Mismatch) String() string {
+	nm := m.Package
+	if m.Struct > "" {
+		nm += "." + m.Struct
+	}
+	if m.Field > "" {
+		nm += "." + m.Field
+	}
+	return nm + ": " + m.Issue
+}
+
+// Verify compares the structs of previously generated source files found in baseDir against
+// the current describe output without regenerating the files.  Each package's source is read
+// from filepath.Join(baseDir, GoFilename).  Use in CI to detect drift between the generated
+// packages and the org.  An empty slice indicates the source is current.
+func (cfg *Config) Verify(ctx context.Context, sv *salesforce.Service, baseDir string) ([]Mismatch, error) {
+	tds, err := cfg.MakeTemplateData(ctx, sv)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []Mismatch
+	for _, td := range tds {
+		if td == nil || len(td.Structs) == 0 {
+			continue
+		}
+		fn := filepath.Join(baseDir, td.GoFilename)
+		srcStructs, err := parseStructs(fn)
+		if err != nil {
+			if os.IsNotExist(err) {
+				mismatches = append(mismatches, Mismatch{Package: td.Name, Issue: "file not found " + fn})
+				continue
+			}
+			return nil, err
+		}
+		mismatches = append(mismatches, verifyPackage(td, srcStructs)...)
+	}
+	return mismatches, nil
+}
+
+// sourceStruct is a struct definition read from a go source file
+type sourceStruct struct {
+	GoName  string
+	APIName string
+	Fields  map[string]*Field // key is json tag name
+}
+
+// parseStructs reads struct definitions and their SObjectName values from a go file
+func parseStructs(fn string) (map[string]*sourceStruct, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), fn, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var structs = make(map[string]*sourceStruct)
+	var apiNames = make(map[string]string)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					structs[ts.Name.Name] = &sourceStruct{GoName: ts.Name.Name, Fields: sourceFields(st)}
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Name.Name != "SObjectName" || d.Recv == nil || len(d.Recv.List) != 1 {
+				continue
+			}
+			if nm, ok := returnedString(d.Body); ok {
+				apiNames[types.ExprString(d.Recv.List[0].Type)] = nm
+			}
+		}
+	}
+	var results = make(map[string]*sourceStruct)
+	for nm, s := range structs {
+		apiName, ok := apiNames[nm]
+		if !ok {
+			continue
+		}
+		s.APIName = apiName
+		results[apiName] = s
+	}
+	return results, nil
+}
+
+// sourceFields maps the fields of a struct by json tag name
+func sourceFields(st *ast.StructType) map[string]*Field {
+	var flds = make(map[string]*Field)
+	for _, fx := range st.Fields.List {
+		if fx.Tag == nil || len(fx.Names) != 1 {
+			continue
+		}
+		tag, err := strconv.Unquote(fx.Tag.Value)
+		if err != nil {
+			continue
+		}
+		jsonNm := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+		if jsonNm == "" || jsonNm == "-" || jsonNm == "attributes" {
+			continue
+		}
+		flds[jsonNm] = &Field{
+			GoName:  fx.Names[0].Name,
+			GoType:  types.ExprString(fx.Type),
+			Tag:     fx.Tag.Value,
+			APIName: jsonNm,
+		}
+	}
+	return flds
+}
+
+// returnedString returns the value of a func body consisting
+// of a single return of a string literal
+func returnedString(body *ast.BlockStmt) (string, bool) {
+	if body == nil || len(body.List) != 1 {
+		return "", false
+	}
+	ret, ok := body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", false
+	}
+	lit, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// verifyPackage compares expected template data to the source structs
+func verifyPackage(td *TemplateData, src map[string]*sourceStruct) []Mismatch {
+	var mismatches []Mismatch
+	var found = make(map[string]bool)
+	for _, st := range td.Structs {
+		found[st.APIName] = true
+		ss, ok := src[st.APIName]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Package: td.Name, Struct: st.GoName, APIName: st.APIName, Issue: "struct not found"})
+			continue
+		}
+		var mx = Mismatch{Package: td.Name, Struct: ss.GoName, APIName: st.APIName}
+		if ss.GoName != st.GoName {
+			mx.Issue = fmt.Sprintf("struct name %s; want %s", ss.GoName, st.GoName)
+			mismatches = append(mismatches, mx)
+		}
+		var expected = make(map[string]*Field)
+		for _, fp := range st.FieldProps {
+			expected[fp.APIName] = fp
+			if fp.Relationship != nil {
+				expected[fp.Relationship.APIName] = fp.Relationship
+			}
+		}
+		for _, nm := range sortedFieldNames(expected) {
+			want := expected[nm]
+			mx.Field = nm
+			have, ok := ss.Fields[nm]
+			switch {
+			case !ok:
+				mx.Issue = "field not found"
+			case have.GoType != want.GoType:
+				mx.Issue = fmt.Sprintf("type %s; want %s", have.GoType, want.GoType)
+			case have.GoName != want.GoName:
+				mx.Issue = fmt.Sprintf("field name %s; want %s", have.GoName, want.GoName)
+			default:
+				continue
+			}
+			mismatches = append(mismatches, mx)
+		}
+		for _, nm := range sortedFieldNames(ss.Fields) {
+			if _, ok := expected[nm]; !ok {
+				mx.Field = nm
+				mx.Issue = "field not in instance"
+				mismatches = append(mismatches, mx)
+			}
+		}
+	}
+	var extra []string
+	for nm := range src {
+		if !found[nm] {
+			extra = append(extra, nm)
+		}
+	}
+	sort.Strings(extra)
+	for _, nm := range extra {
+		mismatches = append(mismatches, Mismatch{Package: td.Name, Struct: src[nm].GoName, APIName: nm, Issue: "struct not in instance"})
+	}
+	return mismatches
+}
+
+func sortedFieldNames(m map[string]*Field) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
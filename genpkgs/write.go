@@ -0,0 +1,148 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genpkgs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WriteOptions configures WriteSource
+type WriteOptions struct {
+	// Goimports runs the goimports command found in PATH (or at
+	// GoimportsPath) on each file before it is written.
+	Goimports     bool
+	GoimportsPath string
+}
+
+// ManifestEntry describes a file written by WriteSource
+type ManifestEntry struct {
+	Filename  string `json:"filename"` // slash separated path relative to the output root
+	SHA256    string `json:"sha256"`   // hex encoded hash of the contents
+	Size      int    `json:"size"`
+	Unchanged bool   `json:"unchanged,omitempty"` // file already contained the source and was not rewritten
+}
+
+// WriteSource writes the fileMap returned by MakeSource to files under outputRoot creating
+// directories as needed.  Each .go file is verified to be gofmt formatted and is written to a
+// temporary file that is renamed into place, so readers never see a partial file.  Files
+// whose contents are unchanged are not rewritten.  The returned manifest is sorted by
+// filename.  Only the first opts value is used.
+func WriteSource(outputRoot string, fileMap map[string][]byte, opts ...WriteOptions) ([]ManifestEntry, error) {
+	var o WriteOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	var names = make([]string, 0, len(fileMap))
+	for nm := range fileMap {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+	var manifest = make([]ManifestEntry, 0, len(names))
+	for _, nm := range names {
+		fn, err := outputFilename(outputRoot, nm)
+		if err != nil {
+			return manifest, err
+		}
+		src := fileMap[nm]
+		if strings.HasSuffix(nm, ".go") {
+			if src, err = verifySource(nm, src, o); err != nil {
+				return manifest, err
+			}
+		}
+		sum := sha256.Sum256(src)
+		entry := ManifestEntry{Filename: filepath.ToSlash(filepath.Clean(nm)), SHA256: hex.EncodeToString(sum[:]), Size: len(src)}
+		if existing, err := ioutil.ReadFile(fn); err == nil && bytes.Equal(existing, src) {
+			entry.Unchanged = true
+			manifest = append(manifest, entry)
+			continue
+		}
+		if err := writeFileAtomic(fn, src); err != nil {
+			return manifest, err
+		}
+		manifest = append(manifest, entry)
+	}
+	return manifest, nil
+}
+
+// verifySource runs goimports if requested and checks that src is gofmt formatted
+func verifySource(nm string, src []byte, o WriteOptions) ([]byte, error) {
+	if o.Goimports {
+		var err error
+		if src, err = goimports(o.GoimportsPath, src); err != nil {
+			return nil, fmt.Errorf("%s: %v", nm, err)
+		}
+	}
+	if fmtSrc, err := format.Source(src); err != nil {
+		return nil, fmt.Errorf("%s: %v", nm, err)
+	} else if !bytes.Equal(fmtSrc, src) {
+		return nil, fmt.Errorf("%s: source is not gofmt formatted", nm)
+	}
+	return src, nil
+}
+
+// outputFilename joins root and nm ensuring the result is within root
+func outputFilename(root, nm string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(nm))
+	if nm == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid filename %q", nm)
+	}
+	return filepath.Join(root, clean), nil
+}
+
+// writeFileAtomic writes b to a temporary file in fn's directory and renames it to fn
+func writeFileAtomic(fn string, b []byte) error {
+	dir := filepath.Dir(fn)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(fn)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, fn)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+	}
+	return err
+}
+
+// goimports returns src processed by the goimports command
+func goimports(cmdPath string, src []byte) ([]byte, error) {
+	if cmdPath == "" {
+		var err error
+		if cmdPath, err = exec.LookPath("goimports"); err != nil {
+			return nil, err
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(cmdPath)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("goimports: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// ErrInvalidHeader is returned by WithHeaders and Composite for a header that may not be set
+var ErrInvalidHeader = errors.New("invalid header")
+
+// blockedHeaders are hop-by-hop headers and headers set by the service or transport
+var blockedHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Host":                true,
+	"Content-Length":      true,
+	"Authorization":       true,
+	"Accept":              true, // use WithAcceptContentType
+	"Content-Type":        true, // use WithAcceptContentType
+}
+
+// WithHeaders returns a service that sends hdrs with every request, e.g.
+//
+//	sv, err = sv.WithHeaders(map[string]string{"Sforce-Call-Options": "client=MyTenant"})
+//
+// Headers set by other service options (e.g. WithAutoAssign) take precedence.
+// Hop-by-hop headers and headers managed by the service or transport (Authorization,
+// Host, Content-Length, Accept and Content-Type) return an error wrapping ErrInvalidHeader.
+// A nil or empty hdrs removes the default headers.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_calloptions.htm
+func (sv *Service) WithHeaders(hdrs map[string]string) (*Service, error) {
+	var h http.Header
+	for k, v := range hdrs {
+		key := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(k))
+		if key == "" || blockedHeaders[key] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, k)
+		}
+		if strings.ContainsAny(key+v, "\r\n") {
+			return nil, fmt.Errorf("%w: %q contains a line break", ErrInvalidHeader, k)
+		}
+		if h == nil {
+			h = make(http.Header)
+		}
+		h.Set(key, v)
+	}
+	snew := sv.clone()
+	snew.headers = h
+	return snew, nil
+}
+
+// WithUserAgent returns a service identifying its calls as product and version, e.g.
+// MyIntegration/1.2.  The User-Agent header is set to product/version and the client
+// of the Sforce-Call-Options header, which Event Monitoring logs record, is set to the
+// same value.  Other Sforce-Call-Options values set by WithHeaders (e.g.
+// defaultNamespace) are kept.  Characters not permitted in either header are replaced
+// with underscores.  An empty product removes the identification.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_calloptions.htm
+func (sv *Service) WithUserAgent(product, version string) *Service {
+	snew := sv.clone()
+	snew.userAgent = ""
+	if product = userAgentToken(product); product > "" {
+		snew.userAgent = product
+		if version = userAgentToken(version); version > "" {
+			snew.userAgent += "/" + version
+		}
+	}
+	return snew
+}
+
+// userAgentToken replaces characters that are not valid in a User-Agent
+// product token or a Sforce-Call-Options value
+func userAgentToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// setDefaultHeaders adds the headers of WithHeaders and WithUserAgent to r
+func (sv *Service) setDefaultHeaders(r *http.Request) {
+	for k, v := range sv.headers {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	if sv.userAgent == "" {
+		return
+	}
+	r.Header.Set("User-Agent", sv.userAgent)
+	var opts = []string{"client=" + sv.userAgent}
+	for _, o := range strings.Split(r.Header.Get("Sforce-Call-Options"), ",") {
+		if o = strings.TrimSpace(o); o > "" && !strings.HasPrefix(o, "client=") {
+			opts = append(opts, o)
+		}
+	}
+	r.Header.Set("Sforce-Call-Options", strings.Join(opts, ", "))
+}
+
+// CallOption sets a header of a single Call.  Options are applied after the
+// service's headers, so they override WithAcceptContentType and WithHeaders
+// without copying the service.  See CallInfoOption to capture the response.
+type CallOption struct {
+	name  string
+	value string
+	info  *CallInfo
+}
+
+// AcceptHeader returns a CallOption setting the Accept header of a call
+func AcceptHeader(accept string) CallOption {
+	return CallOption{name: "Accept", value: accept}
+}
+
+// ContentTypeHeader returns a CallOption setting the Content-Type header of a call
+// with a body
+func ContentTypeHeader(contentType string) CallOption {
+	return CallOption{name: "Content-Type", value: contentType}
+}
+
+// CallHeader returns a CallOption setting the header name of a call.  Call returns
+// an error wrapping ErrInvalidHeader for headers rejected by WithHeaders other than
+// Accept and Content-Type.
+func CallHeader(name, value string) CallOption {
+	return CallOption{name: textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name)), value: value}
+}
+
+// setCallOptions applies opts to r.  Empty values are ignored.
+func setCallOptions(r *http.Request, opts []CallOption) error {
+	for _, o := range opts {
+		switch {
+		case o.value == "":
+			continue
+		case o.name == "Content-Type":
+			if r.Body == nil || r.Body == http.NoBody {
+				continue
+			}
+		case o.name == "Accept":
+		case o.name == "" || blockedHeaders[o.name] || strings.ContainsAny(o.name+o.value, "\r\n"):
+			return fmt.Errorf("%w: %q", ErrInvalidHeader, o.name)
+		}
+		r.Header.Set(o.name, o.value)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_WithHeaders(t *testing.T) {
+	var hdrs []http.Header
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hdrs = append(hdrs, r.Header)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	for _, k := range []string{"connection", "Transfer-Encoding", "Authorization", "content-type", ""} {
+		if _, err := sv.WithHeaders(map[string]string{k: "x"}); !errors.Is(err, salesforce.ErrInvalidHeader) {
+			t.Errorf("%q: expected ErrInvalidHeader; got %v", k, err)
+		}
+	}
+	if _, err := sv.WithHeaders(map[string]string{"X-Tenant": "a\r\nHost: b"}); !errors.Is(err, salesforce.ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader for line break; got %v", err)
+	}
+
+	hsv, err := sv.WithHeaders(map[string]string{"sforce-call-options": "client=Tenant1", "X-Namespace": "ns", "Sforce-Auto-Assign": "TRUE"})
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := hsv.WithAutoAssign(false).Delete(ctx, "Account", "001A"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := sv.Delete(ctx, "Account", "001A"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(hdrs) != 2 {
+		t.Fatalf("expected 2 calls; got %d", len(hdrs))
+	}
+	if hdrs[0].Get("Sforce-Call-Options") != "client=Tenant1" || hdrs[0].Get("X-Namespace") != "ns" ||
+		hdrs[0].Get("Sforce-Auto-Assign") != "FALSE" || hdrs[0].Get("Authorization") != "Bearer CALL OK" {
+		t.Errorf("unexpected headers %v", hdrs[0])
+	}
+	if hdrs[1].Get("Sforce-Call-Options") != "" {
+		t.Errorf("expected original service without default headers; got %v", hdrs[1])
+	}
+}
+
+func TestService_CallOptions(t *testing.T) {
+	var hdrs []http.Header
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hdrs = append(hdrs, r.Header)
+		w.Write([]byte(`{}`))
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var res map[string]interface{}
+	if err := sv.Call(ctx, "sobjects/Account/001A", "PATCH", map[string]string{"Name": "X"}, &res,
+		salesforce.AcceptHeader("application/xml"), salesforce.ContentTypeHeader("application/vnd+json"),
+		salesforce.CallHeader("x-tenant", "t1")); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := sv.Call(ctx, "sobjects/Account/001A", "GET", nil, &res, salesforce.ContentTypeHeader("text/csv")); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	body, err := sv.GetAttachment(ctx, "Attachment", "00PA", salesforce.AcceptHeader("image/png"))
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	body.Close()
+	if err := sv.Call(ctx, "limits", "GET", nil, &res, salesforce.CallHeader("Host", "evil")); !errors.Is(err, salesforce.ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader; got %v", err)
+	}
+	if len(hdrs) != 3 {
+		t.Fatalf("expected 3 calls; got %d", len(hdrs))
+	}
+	if hdrs[0].Get("Accept") != "application/xml" || hdrs[0].Get("Content-Type") != "application/vnd+json" || hdrs[0].Get("X-Tenant") != "t1" {
+		t.Errorf("unexpected headers %v", hdrs[0])
+	}
+	if hdrs[1].Get("Content-Type") != "" || hdrs[1].Get("Accept") != "application/json" {
+		t.Errorf("expected default accept and no content type without a body; got %v", hdrs[1])
+	}
+	if hdrs[2].Get("Accept") != "image/png" {
+		t.Errorf("expected attachment accept override; got %v", hdrs[2])
+	}
+}
+
+func TestService_WithUserAgent(t *testing.T) {
+	var hdr http.Header
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hdr = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv, err := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").
+		WithHeaders(map[string]string{"Sforce-Call-Options": "client=Old, defaultNamespace=ns"})
+	if err != nil {
+		t.Fatalf("WithHeaders: %v", err)
+	}
+
+	tests := []struct {
+		sv         *salesforce.Service
+		ua, callOp string
+	}{
+		{sv: sv.WithUserAgent("Order Sync", "1.2"), ua: "Order_Sync/1.2", callOp: "client=Order_Sync/1.2, defaultNamespace=ns"},
+		{sv: sv.WithUserAgent("billing", ""), ua: "billing", callOp: "client=billing, defaultNamespace=ns"},
+		{sv: sv.WithUserAgent("billing", "1").WithUserAgent("", ""), callOp: "client=Old, defaultNamespace=ns"},
+	}
+	for i, tt := range tests {
+		if err := tt.sv.Call(ctx, "sobjects/", "GET", nil, nil); err != nil {
+			t.Fatalf("%d: call failed %v", i, err)
+		}
+		if ua := hdr.Get("User-Agent"); tt.ua > "" && ua != tt.ua || tt.ua == "" && strings.HasPrefix(ua, "billing") {
+			t.Errorf("%d: expected User-Agent %q; got %q", i, tt.ua, ua)
+		}
+		if co := hdr.Get("Sforce-Call-Options"); co != tt.callOp {
+			t.Errorf("%d: expected Sforce-Call-Options %q; got %q", i, tt.callOp, co)
+		}
+	}
+}
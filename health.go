@@ -0,0 +1,102 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// APIVersionInfo describes a version available on an instance
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_versions.htm
+type APIVersionInfo struct {
+	Label   string `json:"label,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Diagnostic reports the result of each check made by Ping.  A check that
+// was not made because an earlier check failed is reported as false.
+type Diagnostic struct {
+	Instance   string `json:"instance"`
+	APIVersion string `json:"apiVersion"`
+	// Reachable indicates that the instance was resolved, connected to and
+	// returned its available versions
+	Reachable bool `json:"reachable"`
+	// VersionAvailable indicates that APIVersion is supported by the instance
+	VersionAvailable bool   `json:"versionAvailable"`
+	LatestVersion    string `json:"latestVersion,omitempty"`
+	// Authorized indicates that the service's token was accepted
+	Authorized bool `json:"authorized"`
+	// DailyAPIRequests is the org's api quota when authorized
+	DailyAPIRequests *Limit `json:"dailyApiRequests,omitempty"`
+	// Latency is the round trip time of the versions call
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// OK returns true when all checks passed
+func (d *Diagnostic) OK() bool {
+	return d != nil && d.Reachable && d.VersionAvailable && d.Authorized
+}
+
+// Ping checks connectivity to the instance, availability of the service's api
+// version and validity of the service's token, using the unauthenticated versions
+// resource and the limits resource.  The returned Diagnostic is always non-nil
+// and may be logged at startup or returned from a health endpoint.  The error is
+// that of the first failed check.
+func (sv *Service) Ping(ctx context.Context) (*Diagnostic, error) {
+	d := &Diagnostic{Instance: sv.Instance(), APIVersion: sv.APIVersion()}
+	err := sv.ping(ctx, d)
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return d, err
+}
+
+func (sv *Service) ping(ctx context.Context, d *Diagnostic) error {
+	var versions []APIVersionInfo
+	start := time.Now()
+	err := sv.Call(ctx, "/services/data/", "GET", nil, &versions)
+	d.Latency = time.Since(start)
+	var ns *ctxclient.NotSuccess
+	if err != nil && !errors.As(err, &ns) {
+		return fmt.Errorf("instance %s unreachable: %w", d.Instance, err)
+	}
+	d.Reachable = true
+	if err != nil {
+		return fmt.Errorf("versions: %w", err)
+	}
+	want := strings.TrimPrefix(d.APIVersion, "v")
+	for _, v := range versions {
+		if v.Version == want {
+			d.VersionAvailable = true
+		}
+	}
+	if len(versions) > 0 {
+		d.LatestVersion = "v" + versions[len(versions)-1].Version
+	}
+	if !d.VersionAvailable {
+		return fmt.Errorf("api version %s not available on %s; latest is %s", d.APIVersion, d.Instance, d.LatestVersion)
+	}
+	limits, err := sv.Limits(ctx)
+	if err != nil {
+		if IsAuth(err) {
+			return fmt.Errorf("token rejected: %w", err)
+		}
+		return fmt.Errorf("limits: %w", err)
+	}
+	d.Authorized = true
+	if lmt, ok := limits[DailyAPIRequests]; ok {
+		d.DailyAPIRequests = &lmt
+	}
+	return nil
+}
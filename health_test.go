@@ -0,0 +1,63 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_Ping(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data/":
+			encodeObject(w, []salesforce.APIVersionInfo{
+				{Label: "Winter '22", URL: "/services/data/v53.0", Version: "53.0"},
+				{Label: "Spring '22", URL: "/services/data/v54.0", Version: "54.0"},
+			})
+		case "/services/data/v53.0/limits":
+			if r.Header.Get("Authorization") != "Bearer CALL OK" {
+				http.Error(w, `[{"errorCode":"INVALID_SESSION_ID","message":"Session expired or invalid"}]`, http.StatusUnauthorized)
+				return
+			}
+			encodeObject(w, map[string]salesforce.Limit{salesforce.DailyAPIRequests: {Max: 15000, Remaining: 14000}})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v53.0/")
+
+	d, err := sv.Ping(context.WithValue(context.Background(), "TK", "CALL OK"))
+	if err != nil || !d.OK() {
+		t.Fatalf("expected success; got %v %#v", err, d)
+	}
+	if d.LatestVersion != "v54.0" || d.DailyAPIRequests == nil || d.DailyAPIRequests.Remaining != 14000 {
+		t.Errorf("unexpected diagnostic %#v", d)
+	}
+
+	d, err = sv.Ping(context.WithValue(context.Background(), "TK", "EXPIRED"))
+	if err == nil || !salesforce.IsAuth(err) || !d.Reachable || !d.VersionAvailable || d.Authorized || d.Error == "" {
+		t.Errorf("expected auth failure; got %v %#v", err, d)
+	}
+
+	d, err = sv.WithURL(ws.URL + "/services/data/v99.0/").Ping(context.WithValue(context.Background(), "TK", "CALL OK"))
+	if err == nil || !d.Reachable || d.VersionAvailable || d.Authorized {
+		t.Errorf("expected unavailable version; got %v %#v", err, d)
+	}
+
+	ws.Close()
+	d, err = sv.Ping(context.WithValue(context.Background(), "TK", "CALL OK"))
+	if err == nil || d.Reachable {
+		t.Errorf("expected unreachable; got %v %#v", err, d)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package jsonfield maps json names to struct fields for the salesforce
+// packages that decode records using reflection.
+package jsonfield // import github.com/jfcote87/salesforce/internal/jsonfield
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Index maps the json tag names of a struct's exported fields to their
+// field index.  Fields of untagged embedded structs are included unless
+// the name is already used by an outer field.
+func Index(ty reflect.Type) map[string][]int {
+	var m = make(map[string][]int)
+	for i := 0; i < ty.NumField(); i++ {
+		fld := ty.Field(i)
+		nm := strings.Split(fld.Tag.Get("json"), ",")[0]
+		if fld.Anonymous && fld.PkgPath == "" && nm == "" && fld.Type.Kind() == reflect.Struct {
+			for k, idx := range Index(fld.Type) {
+				if _, ok := m[k]; !ok {
+					m[k] = append([]int{i}, idx...)
+				}
+			}
+			continue
+		}
+		if fld.PkgPath != "" || nm == "-" {
+			continue
+		}
+		if nm == "" {
+			nm = fld.Name
+		}
+		m[nm] = fld.Index
+	}
+	return m
+}
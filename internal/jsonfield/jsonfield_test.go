@@ -0,0 +1,39 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonfield_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+type Base struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+type record struct {
+	Base
+	Name    string `json:"Name__c"`
+	Email   string
+	Skip    string `json:"-"`
+	private string
+}
+
+func TestIndex(t *testing.T) {
+	got := jsonfield.Index(reflect.TypeOf(record{}))
+	want := map[string][]int{
+		"Id":      {0, 0},
+		"Name":    {0, 1},
+		"Name__c": {1},
+		"Email":   {2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v; got %v", want, got)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidJobData is wrapped by errors returned from ValidateJobData
+var ErrInvalidJobData = errors.New("invalid job data")
+
+// jobDelimiters maps a job's ColumnDelimiter to its character
+var jobDelimiters = map[string]rune{
+	"":          ',',
+	"COMMA":     ',',
+	"TAB":       '\t',
+	"PIPE":      '|',
+	"SEMICOLON": ';',
+	"CARET":     '^',
+	"BACKQUOTE": '`',
+}
+
+// JobDataStats describes the csv data checked by ValidateJobData
+type JobDataStats struct {
+	Header []string
+	Rows   int   // number of records excluding the header
+	Bytes  int64 // size of the data
+}
+
+// jobDataReader counts bytes read and records the line ending of the first line
+type jobDataReader struct {
+	rdr       io.Reader
+	n         int64
+	lineFound bool
+	crlf      bool
+	lastCR    bool
+}
+
+func (jr *jobDataReader) Read(p []byte) (int, error) {
+	n, err := jr.rdr.Read(p)
+	jr.n += int64(n)
+	for i := 0; i < n && !jr.lineFound; i++ {
+		if p[i] == '\n' {
+			jr.lineFound, jr.crlf = true, jr.lastCR
+			break
+		}
+		jr.lastCR = p[i] == '\r'
+	}
+	return n, err
+}
+
+// ValidateJobData reads the csv data of an ingest job checking that the data uses the job's
+// ColumnDelimiter and LineEnding, that each header is a field (or relationship.externalIdField)
+// of the job's object and that no record is malformed or has a differing number of fields.
+// Objects are described using CachedDescribe.  Use to avoid InvalidBatch failures found only after
+// the job is closed.  The returned stats report the number of rows and the size of the data.
+func (sv *Service) ValidateJobData(ctx context.Context, job *Job, rdr io.Reader) (*JobDataStats, error) {
+	if job == nil || job.Object == "" {
+		return nil, errors.New("job object must be specified")
+	}
+	delim, ok := jobDelimiters[job.ColumnDelimiter]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown column delimiter %s", ErrInvalidJobData, job.ColumnDelimiter)
+	}
+	jr := &jobDataReader{rdr: rdr}
+	cr := csv.NewReader(jr)
+	cr.Comma = delim
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: no header row", ErrInvalidJobData)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJobData, err)
+	}
+	var stats = &JobDataStats{Header: append([]string{}, header...)}
+	if len(header) == 1 {
+		for nm, r := range jobDelimiters {
+			if nm > "" && r != delim && strings.ContainsRune(header[0], r) {
+				return stats, fmt.Errorf("%w: header appears to be delimited by %s not %s", ErrInvalidJobData, nm, job.ColumnDelimiter)
+			}
+		}
+	}
+	if crlf := job.LineEnding == "CRLF"; jr.lineFound && jr.crlf != crlf {
+		return stats, fmt.Errorf("%w: line ending does not match job line ending %s", ErrInvalidJobData, job.LineEnding)
+	}
+	def, err := sv.CachedDescribe(ctx, job.Object)
+	if err != nil {
+		return stats, err
+	}
+	var missing []string
+	for _, h := range stats.Header {
+		if !jobDataField(def, h) {
+			missing = append(missing, h)
+		}
+	}
+	if len(missing) > 0 {
+		return stats, fmt.Errorf("%w: %s has no fields %s", ErrInvalidJobData, job.Object, strings.Join(missing, ", "))
+	}
+	for {
+		if _, err = cr.Read(); err != nil {
+			break
+		}
+		stats.Rows++
+	}
+	stats.Bytes = jr.n
+	if err != io.EOF {
+		return stats, fmt.Errorf("%w: %v", ErrInvalidJobData, err)
+	}
+	if stats.Bytes > DefaultMaxUploadBytes {
+		return stats, fmt.Errorf("%w: %d bytes exceeds maximum upload size", ErrInvalidJobData, stats.Bytes)
+	}
+	return stats, nil
+}
+
+// jobDataField checks that header h is a field of def.  A relationship header
+// (e.g. Account.External_ID__c or polymorphic ObjectType:RelationshipName.Field)
+// is checked by its relationship name.
+func jobDataField(def *SObjectDefinition, h string) bool {
+	ix := strings.Index(h, ".")
+	if ix < 0 {
+		return hasField(def, h)
+	}
+	for _, rel := range strings.Split(h[:ix], ":") {
+		for _, f := range def.Fields {
+			if strings.EqualFold(f.RelationshipName, rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UploadValidatedJobData validates rdr using ValidateJobData and then rewinds and uploads
+// the data to the job.  Nothing is uploaded when validation fails.  If rdr is an io.Closer,
+// function will close stream.
+func (sv *Service) UploadValidatedJobData(ctx context.Context, job *Job, rdr io.ReadSeeker) (*JobDataStats, error) {
+	start, err := rdr.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := sv.ValidateJobData(ctx, job, rdr)
+	if err == nil {
+		_, err = rdr.Seek(start, io.SeekStart)
+	}
+	if err != nil {
+		if rdrc, ok := rdr.(io.Closer); ok {
+			rdrc.Close()
+		}
+		return stats, err
+	}
+	return stats, sv.UploadJobData(ctx, job.ID, rdr)
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_ValidateJobData(t *testing.T) {
+	var uploaded []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sobjects/Contact/describe":
+			encodeObject(w, salesforce.SObjectDefinition{Name: "Contact", Fields: []salesforce.Field{
+				{Name: "Id"}, {Name: "LastName"}, {Name: "AccountId", RelationshipName: "Account"},
+				{Name: "OwnerId", RelationshipName: "Owner"},
+			}})
+		case "/jobs/ingest/JOB0000/batches":
+			b, _ := ioutil.ReadAll(r.Body)
+			uploaded = append(uploaded, string(b))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+	job := &salesforce.Job{ID: "JOB0000", Object: "Contact", ColumnDelimiter: "COMMA", LineEnding: "LF"}
+
+	data := "LastName,Account.External_ID__c,User:Owner.Email\nSmith,A1,a@example.com\n\"Jones, Jr\",A2,b@example.com\n"
+	stats, err := sv.ValidateJobData(ctx, job, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if stats.Rows != 2 || stats.Bytes != int64(len(data)) || len(stats.Header) != 3 || stats.Header[0] != "LastName" {
+		t.Errorf("unexpected stats %#v", stats)
+	}
+
+	tests := []struct {
+		name  string
+		job   salesforce.Job
+		data  string
+		match string
+	}{
+		{name: "delimiter", job: *job, data: "LastName|AccountId\nSmith|001A\n", match: "delimited by PIPE"},
+		{name: "lineending", job: *job, data: "LastName,AccountId\r\nSmith,001A\r\n", match: "line ending"},
+		{name: "fields", job: *job, data: "LastName,FirstName,Parent.Name\nSmith,John,X\n", match: "no fields FirstName, Parent.Name"},
+		{name: "fieldcount", job: *job, data: "LastName,AccountId\nSmith,001A\nJones\n", match: "wrong number of fields"},
+		{name: "empty", job: *job, data: "", match: "no header row"},
+		{name: "unknown", job: salesforce.Job{Object: "Contact", ColumnDelimiter: "SPACE"}, data: "LastName\n", match: "unknown column delimiter"},
+	}
+	for _, tt := range tests {
+		_, err := sv.ValidateJobData(ctx, &tt.job, strings.NewReader(tt.data))
+		if !errors.Is(err, salesforce.ErrInvalidJobData) || !strings.Contains(err.Error(), tt.match) {
+			t.Errorf("%s: expected %s; got %v", tt.name, tt.match, err)
+		}
+	}
+	crlfJob := &salesforce.Job{Object: "Contact", ColumnDelimiter: "TAB", LineEnding: "CRLF"}
+	if _, err := sv.ValidateJobData(ctx, crlfJob, strings.NewReader("LastName\tAccountId\r\nSmith\t001A\r\n")); err != nil {
+		t.Errorf("expected tab crlf success; got %v", err)
+	}
+
+	if _, err := sv.UploadValidatedJobData(ctx, job, strings.NewReader("FirstName\nJohn\n")); err == nil || len(uploaded) > 0 {
+		t.Errorf("expected validation failure without upload; got %v %v", err, uploaded)
+	}
+	if _, err := sv.UploadValidatedJobData(ctx, job, strings.NewReader(data)); err != nil || len(uploaded) != 1 || uploaded[0] != data {
+		t.Errorf("expected upload of data; got %v %q", err, uploaded)
+	}
+}
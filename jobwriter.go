@@ -0,0 +1,228 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// JobWriter streams records as csv to ingest jobs created by a BulkPipeline, so records
+// may be uploaded as they are generated rather than materializing a file.  A new job is
+// created each time the pipeline's MaxBytes is reached.  Use either WriteRecord/WriteRecords
+// or Write (raw csv including the header row) but not both.  Close must be called to upload
+// the final chunk; it returns the first upload error.
+type JobWriter struct {
+	// Fields are the columns of the header row.  If empty, the FieldNames of
+	// the first record are used.
+	Fields []string
+
+	ctx     context.Context
+	bp      *BulkPipeline
+	delim   rune
+	useCRLF bool
+
+	once      sync.Once
+	closeOnce sync.Once
+	pw        *io.PipeWriter
+	cw        *csv.Writer
+	done      chan error
+	err       error
+	row       []string
+}
+
+// errUploadComplete closes the pipe when the upload succeeds
+var errUploadComplete = errors.New("job writer upload complete")
+
+// NewJobWriter returns a JobWriter creating jobs from jd.  Each job receives no more
+// than maxBytes of data (DefaultMaxUploadBytes if <= 0).  jd's ColumnDelimiter and
+// LineEnding determine the csv format of records.
+func (sv *Service) NewJobWriter(ctx context.Context, jd JobDefinition, maxBytes int64, fields ...string) (*JobWriter, error) {
+	delim, ok := jobDelimiters[jd.ColumnDelimiter]
+	if !ok {
+		return nil, fmt.Errorf("unknown column delimiter %s", jd.ColumnDelimiter)
+	}
+	return &JobWriter{
+		Fields:  fields,
+		ctx:     ctx,
+		bp:      sv.NewBulkPipeline(jd, maxBytes),
+		delim:   delim,
+		useCRLF: jd.LineEnding == "CRLF",
+	}, nil
+}
+
+// Pipeline returns the BulkPipeline used to upload data.  Use to retrieve
+// job ids and results after Close.
+func (jw *JobWriter) Pipeline() *BulkPipeline {
+	return jw.bp
+}
+
+// start begins the pipeline upload reading from a pipe
+func (jw *JobWriter) start() {
+	jw.once.Do(func() {
+		pr, pw := io.Pipe()
+		jw.pw, jw.done = pw, make(chan error, 1)
+		jw.cw = csv.NewWriter(pw)
+		jw.cw.Comma, jw.cw.UseCRLF = jw.delim, jw.useCRLF
+		go func() {
+			// hide Close from Upload so that the upload error is returned to writes
+			err := jw.bp.Upload(jw.ctx, struct{ io.Reader }{pr})
+			if err == nil {
+				err = errUploadComplete
+			}
+			pr.CloseWithError(err)
+			jw.done <- err
+		}()
+	})
+}
+
+// Write writes raw csv data, beginning with the header row, to the pipeline
+func (jw *JobWriter) Write(p []byte) (int, error) {
+	jw.start()
+	return jw.pw.Write(p)
+}
+
+// WriteRecord writes rec, a struct, pointer to a struct or map (e.g. RecordMap),
+// as a csv row.  The header row is written before the first record.  Nil and zero
+// omitempty values are written as empty fields which salesforce ignores; use "#N/A"
+// to set a field to null.  Dot-notation fields (e.g. Account.External_ID__c) are read
+// from parent structs or maps.
+func (jw *JobWriter) WriteRecord(rec interface{}) error {
+	jw.start()
+	if jw.row == nil {
+		if len(jw.Fields) == 0 {
+			jw.Fields = FieldNames(rec)
+		}
+		if len(jw.Fields) == 0 {
+			return fmt.Errorf("no fields found for %T", rec)
+		}
+		if err := jw.cw.Write(jw.Fields); err != nil {
+			return err
+		}
+		jw.row = make([]string, len(jw.Fields))
+	}
+	val := reflect.ValueOf(rec)
+	for i, f := range jw.Fields {
+		jw.row[i] = csvFieldValue(val, strings.Split(f, "."))
+	}
+	if err := jw.cw.Write(jw.row); err != nil {
+		return err
+	}
+	jw.cw.Flush()
+	return jw.cw.Error()
+}
+
+// WriteRecords writes each element of recs, a slice of structs, pointers to
+// structs or maps, using WriteRecord.
+func (jw *JobWriter) WriteRecords(recs interface{}) error {
+	val := reflect.ValueOf(recs)
+	if val.Kind() != reflect.Slice {
+		return &TypeError{Expected: "slice of records", Got: reflect.TypeOf(recs)}
+	}
+	for i := 0; i < val.Len(); i++ {
+		if err := jw.WriteRecord(val.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close completes the upload of data and waits for the final job to be closed.
+// Nothing is uploaded if no data was written.
+func (jw *JobWriter) Close() error {
+	if jw.pw == nil {
+		return nil
+	}
+	jw.closeOnce.Do(func() {
+		jw.cw.Flush()
+		jw.pw.Close()
+		if jw.err = <-jw.done; jw.err == errUploadComplete {
+			jw.err = nil
+		}
+	})
+	return jw.err
+}
+
+// csvFieldValue returns the csv string of the field of v found by path
+func csvFieldValue(v reflect.Value, path []string) string {
+	var omitEmpty bool
+	for _, seg := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			idx, ok := jsonfield.Index(v.Type())[seg]
+			if !ok {
+				return ""
+			}
+			omitEmpty = strings.Contains(v.Type().FieldByIndex(idx).Tag.Get("json"), ",omitempty")
+			v = v.FieldByIndex(idx)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return ""
+			}
+			v = v.MapIndex(reflect.ValueOf(seg).Convert(v.Type().Key()))
+			omitEmpty = true
+			if !v.IsValid() {
+				return ""
+			}
+		default:
+			return ""
+		}
+	}
+	return csvString(v, omitEmpty)
+}
+
+// csvString formats v as a csv field
+func csvString(v reflect.Value, omitEmpty bool) string {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Ptr && omitEmpty && v.IsZero() {
+		return ""
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if tm, ok := v.Interface().(encoding.TextMarshaler); ok && v.Kind() != reflect.String {
+		if b, err := tm.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+type jobWriterContact struct {
+	LastName  string                 `json:"LastName,omitempty"`
+	Age       int                    `json:"Age__c,omitempty"`
+	Active    *bool                  `json:"Active__c,omitempty"`
+	Score     float64                `json:"Score__c"`
+	Account   map[string]interface{} `json:"Account,omitempty"`
+	Birthdate *salesforce.Date       `json:"Birthdate,omitempty"`
+}
+
+func TestJobWriter(t *testing.T) {
+	bs := &bulkTestServer{uploads: make(map[string][]byte)}
+	ws := httptest.NewServer(bs)
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	jd := salesforce.JobDefinition{Object: "Contact", Operation: "insert", ColumnDelimiter: "PIPE"}
+	jw, err := sv.NewJobWriter(ctx, jd, 120, "LastName", "Age__c", "Active__c", "Score__c", "Account.Ext_ID__c", "Birthdate")
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	active := false
+	dt := salesforce.Date("2001-02-03")
+	if err := jw.WriteRecord(&jobWriterContact{LastName: "Smith", Age: 30, Active: &active, Account: map[string]interface{}{"Ext_ID__c": "A1"}, Birthdate: &dt}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := jw.WriteRecords([]jobWriterContact{{LastName: "Jones|Jr", Score: 1.5}, {LastName: "Brown"}}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := jw.WriteRecord(salesforce.RecordMap{"LastName": "Green", "Age__c": float64(40), "Active__c": true}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := jw.Close(); err != nil {
+		t.Fatalf("expected close success; got %v", err)
+	}
+	ids := jw.Pipeline().JobIDs()
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 jobs; got %v", ids)
+	}
+	header := "LastName|Age__c|Active__c|Score__c|Account.Ext_ID__c|Birthdate\n"
+	var got = string(bs.uploads[ids[0]]) + strings.TrimPrefix(string(bs.uploads[ids[1]]), header)
+	want := header +
+		"Smith|30|false|0|A1|2001-02-03\n" +
+		"\"Jones|Jr\"|||1.5||\n" +
+		"Brown|||0||\n" +
+		"Green|40|true|||\n"
+	if got != want {
+		t.Errorf("expected %q; got %q", want, got)
+	}
+
+	jw, _ = sv.NewJobWriter(ctx, jd, 0)
+	if err := jw.Close(); err != nil {
+		t.Errorf("expected nil for unused writer; got %v", err)
+	}
+	if err := jw.WriteRecords(jobWriterContact{}); err == nil {
+		t.Errorf("expected type error for non-slice")
+	}
+	if _, err := sv.NewJobWriter(ctx, salesforce.JobDefinition{ColumnDelimiter: "SPACE"}, 0); err == nil {
+		t.Errorf("expected unknown delimiter error")
+	}
+	jw, _ = sv.NewJobWriter(ctx, jd, 0)
+	if _, err := jw.Write([]byte("LastName\n")); err != nil {
+		t.Fatalf("expected write success; got %v", err)
+	}
+	if err := jw.Close(); err != salesforce.ErrZeroRecords {
+		t.Errorf("expected %v; got %v", salesforce.ErrZeroRecords, err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// Limiter regulates the calls made by a Service.  Acquire blocks until a call
+// may proceed and returns a release func that must be called with the result
+// of the call, allowing the Limiter to back off when salesforce reports that
+// a limit has been exceeded.  Share a Limiter between Services to keep all calls
+// to an org under the concurrent request and api limits.
+// https://developer.salesforce.com/docs/atlas.en-us.salesforce_app_limits_cheatsheet.meta/salesforce_app_limits_cheatsheet/salesforce_app_limits_platform_api.htm
+type Limiter interface {
+	Acquire(ctx context.Context) (release func(error), err error)
+}
+
+// DefaultLimiter is used by any Service without a Limiter set via WithLimiter.
+// Set before making any calls.  A nil value disables limiting.
+var DefaultLimiter Limiter
+
+// WithLimiter returns a service that regulates its calls with l.  A nil
+// l indicates the DefaultLimiter.
+func (sv *Service) WithLimiter(l Limiter) *Service {
+	snew := sv.clone()
+	snew.limiter = l
+	return snew
+}
+
+// acquire waits for the service's limiter
+func (sv *Service) acquire(ctx context.Context) (func(error), error) {
+	l := sv.limiter
+	if l == nil {
+		l = DefaultLimiter
+	}
+	if l == nil {
+		return func(error) {}, nil
+	}
+	return l.Acquire(ctx)
+}
+
+// Default backoff durations for TokenBucket
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// TokenBucket is a Limiter allowing a steady rate of calls per second, with bursts up to
+// the bucket size, and no more than a maximum number of concurrent calls.  When a call
+// returns a limit error (REQUEST_LIMIT_EXCEEDED, 429 or 503), new calls are paused using an
+// exponential backoff starting at MinBackoff and doubling to MaxBackoff.  A successful
+// call resets the backoff.
+type TokenBucket struct {
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	rate    float64
+	burst   float64
+	sem     chan struct{}
+	m       sync.Mutex
+	tokens  float64
+	last    time.Time
+	backoff time.Duration
+	until   time.Time
+}
+
+// NewTokenBucket creates a TokenBucket allowing callsPerSec with bursts of up to burst calls
+// and maxConcurrent simultaneous calls.  A callsPerSec <= 0 does not limit the rate, and a
+// maxConcurrent <= 0 does not limit concurrency.
+func NewTokenBucket(callsPerSec float64, burst, maxConcurrent int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	tb := &TokenBucket{
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		rate:       callsPerSec,
+		burst:      float64(burst),
+		tokens:     float64(burst),
+		last:       time.Now(),
+	}
+	if maxConcurrent > 0 {
+		tb.sem = make(chan struct{}, maxConcurrent)
+	}
+	return tb
+}
+
+// Acquire blocks until a call may proceed or the context is done.
+func (tb *TokenBucket) Acquire(ctx context.Context) (func(error), error) {
+	if tb.sem != nil {
+		select {
+		case tb.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	for {
+		wait := tb.reserve()
+		if wait <= 0 {
+			return tb.release, nil
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			tb.unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token returning 0 or returns the duration
+// to wait for the next token.
+func (tb *TokenBucket) reserve() time.Duration {
+	tb.m.Lock()
+	defer tb.m.Unlock()
+	now := time.Now()
+	if now.Before(tb.until) {
+		return tb.until.Sub(now)
+	}
+	if tb.rate <= 0 {
+		return 0
+	}
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return 0
+	}
+	return time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+// release frees the concurrency slot and adjusts the backoff
+func (tb *TokenBucket) release(err error) {
+	tb.unlock()
+	tb.m.Lock()
+	defer tb.m.Unlock()
+	if !IsLimitError(err) {
+		if err == nil {
+			tb.backoff = 0
+		}
+		return
+	}
+	switch {
+	case tb.backoff == 0:
+		tb.backoff = tb.MinBackoff
+	case tb.backoff < tb.MaxBackoff:
+		tb.backoff *= 2
+	}
+	if tb.backoff > tb.MaxBackoff {
+		tb.backoff = tb.MaxBackoff
+	}
+	tb.until = time.Now().Add(tb.backoff)
+}
+
+func (tb *TokenBucket) unlock() {
+	if tb.sem != nil {
+		<-tb.sem
+	}
+}
+
+// IsLimitError returns true when err indicates that salesforce
+// rejected the call due to exceeding a limit.
+func IsLimitError(err error) bool {
+	var ns *ctxclient.NotSuccess
+	if err == nil || !errors.As(err, &ns) {
+		return false
+	}
+	switch ns.StatusCode {
+	case 429, 503:
+		return true
+	case 403:
+		return bytes.Contains(ns.Body, []byte("REQUEST_LIMIT_EXCEEDED"))
+	}
+	return false
+}
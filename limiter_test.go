@@ -0,0 +1,134 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/ctxclient"
+	"github.com/jfcote87/salesforce"
+)
+
+type countLimiter struct {
+	acquired int
+	errs     []error
+}
+
+func (cl *countLimiter) Acquire(ctx context.Context) (func(error), error) {
+	cl.acquired++
+	return func(err error) {
+		cl.errs = append(cl.errs, err)
+	}, nil
+}
+
+func TestService_WithLimiter(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limit" {
+			http.Error(w, `[{"errorCode":"REQUEST_LIMIT_EXCEEDED"}]`, http.StatusForbidden)
+			return
+		}
+		encodeObject(w, map[string]string{"a": "b"})
+	}))
+	defer ws.Close()
+
+	cl := &countLimiter{}
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithLimiter(cl)
+	var result map[string]string
+	if err := sv.Call(ctx, "ok", "GET", nil, &result); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	err := sv.Call(ctx, "limit", "GET", nil, &result)
+	if !salesforce.IsLimitError(err) {
+		t.Errorf("expected limit error; got %v", err)
+	}
+	if cl.acquired != 2 || len(cl.errs) != 2 || cl.errs[0] != nil || !salesforce.IsLimitError(cl.errs[1]) {
+		t.Errorf("expected 2 acquires with nil and limit error releases; got %d %v", cl.acquired, cl.errs)
+	}
+
+	// an HTTPBody result holds the limiter until closed
+	var body *salesforce.HTTPBody
+	if err := sv.Call(ctx, "ok", "GET", nil, &body); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if cl.acquired != 3 || len(cl.errs) != 2 {
+		t.Errorf("expected release after body close; got %d releases", len(cl.errs))
+	}
+	body.Close()
+	body.Close()
+	if len(cl.errs) != 3 || cl.errs[2] != nil {
+		t.Errorf("expected a single release on close; got %v", cl.errs)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	tb := salesforce.NewTokenBucket(0, 1, 2)
+	r1, err := tb.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if _, err = tb.Acquire(ctx); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	_, err = tb.Acquire(tctx)
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v; got %v", context.DeadlineExceeded, err)
+	}
+	r1(nil)
+	if _, err = tb.Acquire(ctx); err != nil {
+		t.Errorf("expected success after release; got %v", err)
+	}
+
+	tb = salesforce.NewTokenBucket(50, 1, 0)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := tb.Acquire(ctx); err != nil {
+			t.Fatalf("expected success; got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected rate limit wait of at least 30ms; got %v", elapsed)
+	}
+
+	tb = salesforce.NewTokenBucket(0, 1, 0)
+	tb.MinBackoff = 50 * time.Millisecond
+	release, _ := tb.Acquire(ctx)
+	release(&ctxclient.NotSuccess{StatusCode: 429})
+	start = time.Now()
+	if _, err := tb.Acquire(ctx); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected backoff of at least 40ms; got %v", elapsed)
+	}
+}
+
+func TestIsLimitError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("other"), false},
+		{&ctxclient.NotSuccess{StatusCode: 503}, true},
+		{&ctxclient.NotSuccess{StatusCode: 403, Body: []byte("REQUEST_LIMIT_EXCEEDED")}, true},
+		{&ctxclient.NotSuccess{StatusCode: 403, Body: []byte("INSUFFICIENT_ACCESS")}, false},
+	}
+	for i, tt := range tests {
+		if got := salesforce.IsLimitError(tt.err); got != tt.want {
+			t.Errorf("test %d: expected %v; got %v", i, tt.want, got)
+		}
+	}
+}
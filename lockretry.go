@@ -0,0 +1,167 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// ErrUnableToLockRow is the error status code returned when a record, or
+// its parent, is locked by another transaction.
+// https://developer.salesforce.com/docs/atlas.en-us.salesforce_large_data_volumes_bp.meta/salesforce_large_data_volumes_bp/ldv_deployments_techniques_avoiding_locking.htm
+const ErrUnableToLockRow = "UNABLE_TO_LOCK_ROW"
+
+// Default delays for LockRetry
+const (
+	DefaultLockRetryMinDelay = 250 * time.Millisecond
+	DefaultLockRetryMaxDelay = 5 * time.Second
+)
+
+// LockRetry configures the retry of updates failing with UNABLE_TO_LOCK_ROW, a common
+// transient error when parallel loads update children of the same parent records.
+// The delay before each retry starts at MinDelay and doubles up to MaxDelay, with a
+// random jitter of up to half the delay.  Zero delays use the defaults.
+type LockRetry struct {
+	MaxRetries int // retries after the first attempt
+	MinDelay   time.Duration
+	MaxDelay   time.Duration
+}
+
+// WithLockRetry returns a service that retries single and collection updates (including
+// upserts) failing with UNABLE_TO_LOCK_ROW.  A nil lr, the default, disables retries.
+// For a collection call without AllOrNone, only the locked records are resent.
+func (sv *Service) WithLockRetry(lr *LockRetry) *Service {
+	snew := sv.clone()
+	snew.lockRetry = nil
+	if lr != nil {
+		lrCopy := *lr
+		snew.lockRetry = &lrCopy
+	}
+	return snew
+}
+
+// IsLockError returns true when err indicates that salesforce was
+// unable to lock a record.
+func IsLockError(err error) bool {
+	var ns *ctxclient.NotSuccess
+	if err == nil || !errors.As(err, &ns) {
+		return false
+	}
+	return bytes.Contains(ns.Body, []byte(ErrUnableToLockRow))
+}
+
+// lockFailed returns true when the response failed due to a locked row
+func lockFailed(r OpResponse) bool {
+	for _, e := range r.Errors {
+		if e.StatusCode == ErrUnableToLockRow {
+			return true
+		}
+	}
+	return false
+}
+
+// lockJitter is the seeded source of retry jitter.  The global math/rand
+// source is not seeded before go 1.20, and a rand.Rand is not safe for
+// concurrent use.
+var lockJitter = struct {
+	sync.Mutex
+	r *rand.Rand
+}{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// jitter returns a random duration in [0, n)
+func jitter(n int64) time.Duration {
+	lockJitter.Lock()
+	defer lockJitter.Unlock()
+	return time.Duration(lockJitter.r.Int63n(n))
+}
+
+// wait sleeps before the retry numbered attempt (zero based) or until ctx is done
+func (lr *LockRetry) wait(ctx context.Context, attempt int) error {
+	minDelay, maxDelay := lr.MinDelay, lr.MaxDelay
+	if minDelay <= 0 {
+		minDelay = DefaultLockRetryMinDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultLockRetryMaxDelay
+	}
+	delay := minDelay
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half) + jitter(half)
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryLockError calls fn, retrying when fn returns a lock error.  When ctx is
+// done while waiting to retry, the returned error wraps ctx.Err().
+func (sv *Service) retryLockError(ctx context.Context, fn func() error) error {
+	err := fn()
+	lr := sv.lockRetry
+	for attempt := 0; lr != nil && attempt < lr.MaxRetries && IsLockError(err); attempt++ {
+		if werr := lr.wait(ctx, attempt); werr != nil {
+			return fmt.Errorf("%w waiting to retry: %v", werr, err)
+		}
+		err = fn()
+	}
+	return err
+}
+
+// retryLockedRecords resends the records of res that failed with UNABLE_TO_LOCK_ROW.  When
+// allOrNone is set, the entire batch is resent.  send is passed the indexes of the records
+// to resend and returns their responses in the same order.
+func (sv *Service) retryLockedRecords(ctx context.Context, allOrNone bool, res []OpResponse, send func([]int) ([]OpResponse, error)) ([]OpResponse, error) {
+	lr := sv.lockRetry
+	for attempt := 0; lr != nil && attempt < lr.MaxRetries; attempt++ {
+		var idxs []int
+		for i := range res {
+			if lockFailed(res[i]) {
+				idxs = append(idxs, i)
+			}
+		}
+		if len(idxs) == 0 {
+			break
+		}
+		if allOrNone {
+			idxs = idxs[:0]
+			for i := range res {
+				idxs = append(idxs, i)
+			}
+		}
+		if err := lr.wait(ctx, attempt); err != nil {
+			return res, err
+		}
+		rres, err := send(idxs)
+		if err != nil {
+			return nil, err
+		}
+		for j, ix := range idxs {
+			if j < len(rres) {
+				res[ix] = rres[j]
+			}
+		}
+	}
+	return res, nil
+}
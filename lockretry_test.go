@@ -0,0 +1,113 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/ctxclient"
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_WithLockRetry(t *testing.T) {
+	var singleCalls, batchCalls int
+	var retried []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sobjects/Contact/003A":
+			singleCalls++
+			if singleCalls < 3 {
+				http.Error(w, `[{"errorCode":"UNABLE_TO_LOCK_ROW","message":"unable to obtain exclusive access"}]`, http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case "/composite/sobjects":
+			batchCalls++
+			var body struct {
+				Records []map[string]interface{} `json:"records"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			var res []salesforce.OpResponse
+			for _, rec := range body.Records {
+				id, _ := rec["Id"].(string)
+				if batchCalls > 1 {
+					retried = append(retried, id)
+				}
+				if id == "003B" && batchCalls == 1 {
+					res = append(res, salesforce.OpResponse{Errors: []salesforce.Error{{StatusCode: salesforce.ErrUnableToLockRow}}})
+					continue
+				}
+				res = append(res, salesforce.OpResponse{ID: id, Success: true})
+			}
+			encodeObject(w, res)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	err := sv.Update(ctx, Contact{LastName: "Smith"}, "003A")
+	if !salesforce.IsLockError(err) || singleCalls != 1 {
+		t.Fatalf("expected lock error without retry; got %v after %d calls", err, singleCalls)
+	}
+
+	sv = sv.WithLockRetry(&salesforce.LockRetry{MaxRetries: 3, MinDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond})
+	singleCalls = 0
+	if err := sv.Update(ctx, Contact{LastName: "Smith"}, "003A"); err != nil || singleCalls != 3 {
+		t.Errorf("expected success after 3 calls; got %v after %d calls", err, singleCalls)
+	}
+
+	recs := []salesforce.SObject{
+		salesforce.RecordMap{"attributes": map[string]interface{}{"type": "Contact"}, "Id": "003A"},
+		salesforce.RecordMap{"attributes": map[string]interface{}{"type": "Contact"}, "Id": "003B"},
+	}
+	res, err := sv.UpdateRecords(ctx, false, recs)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(res) != 2 || !res[0].Success || !res[1].Success || res[1].RecordIndex != 1 {
+		t.Errorf("expected 2 successful responses; got %v", res)
+	}
+	if batchCalls != 2 || len(retried) != 1 || retried[0] != "003B" {
+		t.Errorf("expected retry of 003B only; got %d calls %v", batchCalls, retried)
+	}
+
+	// a done context during the retry wait is returned
+	batchCalls = 0
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	slow := sv.WithLockRetry(&salesforce.LockRetry{MaxRetries: 3, MinDelay: time.Minute, MaxDelay: time.Minute})
+	if _, err := slow.UpdateRecords(tctx, false, recs); !errors.Is(err, context.DeadlineExceeded) || batchCalls != 1 {
+		t.Errorf("expected deadline exceeded after 1 call; got %v after %d calls", err, batchCalls)
+	}
+	singleCalls = 0
+	tctx, cancel = context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := slow.Update(tctx, Contact{LastName: "Smith"}, "003A"); !errors.Is(err, context.DeadlineExceeded) || singleCalls != 1 ||
+		!strings.Contains(err.Error(), "unable to obtain exclusive access") {
+		t.Errorf("expected deadline exceeded with lock error after 1 call; got %v after %d calls", err, singleCalls)
+	}
+}
+
+func TestIsLockError(t *testing.T) {
+	if salesforce.IsLockError(nil) || salesforce.IsLockError(&ctxclient.NotSuccess{StatusCode: 400, Body: []byte("INVALID_FIELD")}) {
+		t.Errorf("expected false")
+	}
+	if !salesforce.IsLockError(&ctxclient.NotSuccess{StatusCode: 400, Body: []byte(`[{"errorCode":"UNABLE_TO_LOCK_ROW"}]`)}) {
+		t.Errorf("expected true")
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"html"
+	"strings"
+	"sync"
+)
+
+// MigrateOptions configures MigrateAttachments and MigrateNotes
+type MigrateOptions struct {
+	// Where is a SOQL condition limiting the Attachment or Note records migrated
+	// (e.g. ParentId IN ('001...')).  Empty migrates all records.
+	Where string
+	// Concurrency is the number of records converted simultaneously.  Values
+	// less than 2 convert records sequentially.
+	Concurrency int
+	// Link determines the access given to the parent record
+	Link FileLink
+	// KeepOwner sets the owner of the new record to the original owner
+	KeepOwner bool
+	// DeleteOriginal deletes each Attachment or Note after it is converted
+	DeleteOriginal bool
+	// Progress is called after each record is converted.  Calls are not concurrent.
+	Progress func(MigrateProgress)
+}
+
+// MigrateResult reports the conversion of a single Attachment or Note
+type MigrateResult struct {
+	SourceID          string // id of the Attachment or Note
+	ParentID          string
+	ContentDocumentID string
+	ContentVersionID  string // empty for notes
+	Err               error
+}
+
+// MigrateProgress reports the completion of a record conversion
+type MigrateProgress struct {
+	Completed int
+	Total     int
+	Errors    int
+	Last      MigrateResult
+}
+
+type legacyAttachment struct {
+	ID          string `json:"Id"`
+	Name        string `json:"Name"`
+	Description string `json:"Description"`
+	ParentID    string `json:"ParentId"`
+	OwnerID     string `json:"OwnerId"`
+}
+
+type legacyNote struct {
+	ID       string `json:"Id"`
+	Title    string `json:"Title"`
+	Body     string `json:"Body"`
+	ParentID string `json:"ParentId"`
+	OwnerID  string `json:"OwnerId"`
+}
+
+// contentNote is the insert body of a ContentNote
+type contentNote struct {
+	Title   string `json:"Title"`
+	Content Binary `json:"Content"`
+	OwnerID string `json:"OwnerId,omitempty"`
+}
+
+func (cn contentNote) SObjectName() string {
+	return "ContentNote"
+}
+
+func (cn contentNote) WithAttr(ref string) SObject {
+	return cn
+}
+
+// MigrateAttachments recreates classic Attachments as Files.  The body of each Attachment
+// is streamed to a new ContentVersion (see UploadFile) that is linked to the Attachment's
+// parent.  Results are returned in query order; conversion errors are reported in each
+// result's Err and do not stop the migration.  The returned error is that of the query
+// or context; records not converted before the context is done have an empty SourceID.
+// https://developer.salesforce.com/docs/atlas.en-us.object_reference.meta/object_reference/sforce_api_objects_attachment.htm
+func (sv *Service) MigrateAttachments(ctx context.Context, opts MigrateOptions) ([]MigrateResult, error) {
+	var recs []legacyAttachment
+	if err := sv.Query(ctx, migrateSOQL("SELECT Id, Name, Description, ParentId, OwnerId FROM Attachment", opts.Where), &recs); err != nil {
+		return nil, err
+	}
+	return sv.migrate(ctx, len(recs), opts, "Attachment", func(ctx context.Context, i int) MigrateResult {
+		a := recs[i]
+		res := MigrateResult{SourceID: a.ID, ParentID: a.ParentID}
+		body, err := sv.GetAttachment(ctx, "Attachment", a.ID+"/Body")
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		defer body.Close()
+		cv := ContentVersion{PathOnClient: a.Name, Title: a.Name, Description: a.Description}
+		if opts.KeepOwner {
+			cv.OwnerID = a.OwnerID
+		}
+		uf, err := sv.UploadFile(ctx, body, cv, opts.Link, a.ParentID)
+		if uf != nil {
+			res.ContentVersionID, res.ContentDocumentID = uf.ContentVersionID, uf.ContentDocumentID
+		}
+		res.Err = err
+		return res
+	})
+}
+
+// MigrateNotes recreates classic Notes as ContentNotes linked to each Note's parent.  The
+// text of a Note is html escaped as required by ContentNote.  Results are returned in
+// query order; conversion errors are reported in each result's Err and do not stop the
+// migration.  The returned error is that of the query or context.
+// https://developer.salesforce.com/docs/atlas.en-us.object_reference.meta/object_reference/sforce_api_objects_contentnote.htm
+func (sv *Service) MigrateNotes(ctx context.Context, opts MigrateOptions) ([]MigrateResult, error) {
+	var recs []legacyNote
+	if err := sv.Query(ctx, migrateSOQL("SELECT Id, Title, Body, ParentId, OwnerId FROM Note", opts.Where), &recs); err != nil {
+		return nil, err
+	}
+	return sv.migrate(ctx, len(recs), opts, "Note", func(ctx context.Context, i int) MigrateResult {
+		n := recs[i]
+		res := MigrateResult{SourceID: n.ID, ParentID: n.ParentID}
+		cn := contentNote{Title: n.Title, Content: Binary(noteContent(n.Body))}
+		if opts.KeepOwner {
+			cn.OwnerID = n.OwnerID
+		}
+		op, err := sv.Create(ctx, cn)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		// a ContentNote id is its ContentDocument id
+		res.ContentDocumentID = op.ID
+		_, res.Err = sv.linkDocument(ctx, op.ID, opts.Link, []string{n.ParentID})
+		return res
+	})
+}
+
+// noteContent converts note text to the html of a ContentNote.  An empty
+// body returns a single space as ContentNote content may not be empty.
+func noteContent(body string) string {
+	if body == "" {
+		return " "
+	}
+	s := html.EscapeString(strings.Replace(body, "\r\n", "\n", -1))
+	return "<p>" + strings.Replace(s, "\n", "</p><p>", -1) + "</p>"
+}
+
+func migrateSOQL(qry, where string) string {
+	if where = strings.TrimSpace(where); where > "" {
+		qry += " WHERE " + where
+	}
+	return qry
+}
+
+// migrate calls convert for each of cnt records using the options' concurrency, deleting
+// converted originals when DeleteOriginal is set.
+func (sv *Service) migrate(ctx context.Context, cnt int, opts MigrateOptions, sobjectName string, convert func(context.Context, int) MigrateResult) ([]MigrateResult, error) {
+	var (
+		results = make([]MigrateResult, cnt)
+		m       sync.Mutex
+		wg      sync.WaitGroup
+		mp      = MigrateProgress{Total: cnt}
+		sem     = make(chan struct{}, 1)
+	)
+	if opts.Concurrency > 1 {
+		sem = make(chan struct{}, opts.Concurrency)
+	}
+	for i := 0; i < cnt; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res := convert(ctx, i)
+			if res.Err == nil && opts.DeleteOriginal {
+				res.Err = sv.Delete(ctx, sobjectName, res.SourceID)
+			}
+			m.Lock()
+			defer m.Unlock()
+			results[i] = res
+			mp.Completed++
+			if res.Err != nil {
+				mp.Errors++
+			}
+			mp.Last = res
+			if opts.Progress != nil {
+				opts.Progress(mp)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return results, ctx.Err()
+}
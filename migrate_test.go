@@ -0,0 +1,110 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_MigrateAttachments(t *testing.T) {
+	var m sync.Mutex
+	var calls []string
+	var noteContent string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		m.Unlock()
+		switch r.Method + " " + r.URL.Path {
+		case "GET /query/":
+			q := r.URL.Query().Get("q")
+			switch {
+			case strings.HasSuffix(q, "FROM Attachment WHERE ParentId = '001A'"):
+				encodeObject(w, map[string]interface{}{"totalSize": 2, "done": true, "records": []map[string]interface{}{
+					{"Id": "00PA", "Name": "a.pdf", "ParentId": "001A", "OwnerId": "005A"},
+					{"Id": "00PB", "Name": "b.pdf", "ParentId": "001A", "OwnerId": "005A"},
+				}})
+			case strings.HasSuffix(q, "FROM Note"):
+				encodeObject(w, map[string]interface{}{"totalSize": 1, "done": true, "records": []map[string]interface{}{
+					{"Id": "002A", "Title": "Call", "Body": "a < b\nsecond", "ParentId": "001A"},
+				}})
+			default:
+				http.Error(w, "bad query "+q, http.StatusBadRequest)
+			}
+		case "GET /sobjects/Attachment/00PA/Body":
+			w.Write([]byte("pdf A"))
+		case "GET /sobjects/Attachment/00PB/Body":
+			http.Error(w, `[{"errorCode":"NOT_FOUND","message":"missing"}]`, http.StatusNotFound)
+		case "POST /sobjects/ContentVersion":
+			ioutil.ReadAll(r.Body)
+			encodeObject(w, salesforce.OpResponse{ID: "068A", Success: true})
+		case "GET /sobjects/ContentVersion/068A":
+			encodeObject(w, map[string]interface{}{"ContentDocumentId": "069A"})
+		case "POST /sobjects/ContentNote":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			b, _ := base64.StdEncoding.DecodeString(fmt.Sprint(body["Content"]))
+			noteContent = string(b)
+			encodeObject(w, salesforce.OpResponse{ID: "069N", Success: true})
+		case "POST /composite/sobjects":
+			encodeObject(w, []salesforce.OpResponse{{ID: "06AA", Success: true}})
+		case "DELETE /sobjects/Attachment/00PA", "DELETE /sobjects/Note/002A":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "not found "+r.URL.Path, http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var progress []salesforce.MigrateProgress
+	res, err := sv.MigrateAttachments(ctx, salesforce.MigrateOptions{
+		Where:          "ParentId = '001A'",
+		Concurrency:    2,
+		DeleteOriginal: true,
+		Progress:       func(mp salesforce.MigrateProgress) { progress = append(progress, mp) },
+	})
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(res) != 2 || res[0].Err != nil || res[0].ContentDocumentID != "069A" || res[0].ContentVersionID != "068A" ||
+		res[1].SourceID != "00PB" || !salesforce.HasErrorCode(res[1].Err, salesforce.ErrNotFound) {
+		t.Fatalf("unexpected results %+v", res)
+	}
+	if len(progress) != 2 || progress[1].Completed != 2 || progress[1].Total != 2 || progress[1].Errors != 1 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+
+	res, err = sv.MigrateNotes(ctx, salesforce.MigrateOptions{DeleteOriginal: true})
+	if err != nil || len(res) != 1 || res[0].Err != nil || res[0].ContentDocumentID != "069N" {
+		t.Fatalf("expected note migration; got %+v %v", res, err)
+	}
+	if noteContent != "<p>a &lt; b</p><p>second</p>" {
+		t.Errorf("unexpected note content %q", noteContent)
+	}
+	var deletes int
+	for _, c := range calls {
+		if strings.HasPrefix(c, "DELETE") {
+			deletes++
+		}
+	}
+	if deletes != 2 {
+		t.Errorf("expected 2 deletes; got %v", calls)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/jfcote87/oauth2"
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// OutboundMessage is a workflow outbound message sent by salesforce
+// https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/sforce_api_om_outboundmessaging_understanding.htm
+type OutboundMessage struct {
+	OrganizationID string                 `xml:"OrganizationId"`
+	ActionID       string                 `xml:"ActionId"`
+	SessionID      string                 `xml:"SessionId"`
+	EnterpriseURL  string                 `xml:"EnterpriseUrl"`
+	PartnerURL     string                 `xml:"PartnerUrl"`
+	Notifications  []OutboundNotification `xml:"Notification"`
+}
+
+// OutboundNotification contains a single record of an OutboundMessage.  Fields
+// are keyed by api name.  Null fields are not included.
+type OutboundNotification struct {
+	ID          string
+	SObjectName string
+	Fields      map[string]string
+}
+
+type outboundEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    struct {
+		Notifications *struct {
+			OutboundMessage
+			Notifications []struct {
+				ID      string `xml:"Id"`
+				SObject struct {
+					Type   string `xml:"type,attr"`
+					Fields []struct {
+						XMLName xml.Name
+						Nil     string `xml:"nil,attr"`
+						Value   string `xml:",chardata"`
+					} `xml:",any"`
+				} `xml:"sObject"`
+			} `xml:"Notification"`
+		} `xml:"notifications"`
+	} `xml:"Body"`
+}
+
+// ParseOutboundMessage decodes an outbound message soap envelope
+func ParseOutboundMessage(r io.Reader) (*OutboundMessage, error) {
+	var env outboundEnvelope
+	if err := xml.NewDecoder(r).Decode(&env); err != nil {
+		return nil, err
+	}
+	n := env.Body.Notifications
+	if n == nil {
+		return nil, errors.New("envelope contains no notifications")
+	}
+	msg := n.OutboundMessage
+	msg.Notifications = make([]OutboundNotification, 0, len(n.Notifications))
+	for _, nx := range n.Notifications {
+		on := OutboundNotification{
+			ID:     nx.ID,
+			Fields: make(map[string]string),
+		}
+		on.SObjectName = nx.SObject.Type
+		if idx := strings.Index(on.SObjectName, ":"); idx >= 0 {
+			on.SObjectName = on.SObjectName[idx+1:]
+		}
+		for _, f := range nx.SObject.Fields {
+			if f.Nil == "true" {
+				continue
+			}
+			on.Fields[f.XMLName.Local] = f.Value
+		}
+		msg.Notifications = append(msg.Notifications, on)
+	}
+	return &msg, nil
+}
+
+// Decode sets the fields of rec, a pointer to a struct, from the notification's
+// fields.  Fields are matched to struct fields using the field's json tag name.
+func (on OutboundNotification) Decode(rec interface{}) error {
+	const expected = "pointer to a struct"
+	val, err := validatePtrTo(rec, reflect.Struct, expected)
+	if err != nil {
+		return err
+	}
+	fieldMap := jsonfield.Index(val.Type())
+	for nm, s := range on.Fields {
+		idx, ok := fieldMap[nm]
+		if !ok {
+			continue
+		}
+		if err := setFieldFromString(val.FieldByIndex(idx), s); err != nil {
+			return fmt.Errorf("notification %s field %s: %w", on.ID, nm, err)
+		}
+	}
+	return nil
+}
+
+const outboundAck = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body><notificationsResponse xmlns="http://soap.sforce.com/2005/09/outbound"><Ack>%t</Ack></notificationsResponse></soapenv:Body>
+</soapenv:Envelope>`
+
+// WriteOutboundAck writes the acknowledgement response of an outbound message.  Salesforce
+// resends messages that are not acknowledged (ack = false).
+func WriteOutboundAck(w http.ResponseWriter, ack bool) error {
+	w.Header().Set("Content-Type", "text/xml; charset=UTF-8")
+	_, err := fmt.Fprintf(w, outboundAck, ack)
+	return err
+}
+
+// ErrOutboundUnauthorized indicates an outbound message that failed verification
+var ErrOutboundUnauthorized = errors.New("outbound message not authorized")
+
+// OutboundVerifier validates the source of outbound messages.  Salesforce
+// identifies itself with a client certificate, so the server's tls.Config
+// must request client certificates (e.g. ClientAuth: tls.VerifyClientCertIfGiven
+// with ClientCAs containing the salesforce certificate's issuer).
+// https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/sforce_api_om_outboundmessaging_listener.htm
+type OutboundVerifier struct {
+	OrganizationIDs   []string                      // permitted org ids; empty permits all
+	RequireClientCert bool                          // require a tls client certificate
+	CommonNames       []string                      // permitted client certificate subject common names
+	VerifyCertificate func(*x509.Certificate) error // additional client certificate check
+}
+
+// VerifyRequest checks the client certificate of r
+func (ov *OutboundVerifier) VerifyRequest(r *http.Request) error {
+	if !ov.RequireClientCert && len(ov.CommonNames) == 0 && ov.VerifyCertificate == nil {
+		return nil
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return fmt.Errorf("%w: no client certificate", ErrOutboundUnauthorized)
+	}
+	cert := r.TLS.PeerCertificates[0]
+	if len(ov.CommonNames) > 0 && !containsString(ov.CommonNames, cert.Subject.CommonName) {
+		return fmt.Errorf("%w: invalid certificate common name %s", ErrOutboundUnauthorized, cert.Subject.CommonName)
+	}
+	if ov.VerifyCertificate != nil {
+		if err := ov.VerifyCertificate(cert); err != nil {
+			return fmt.Errorf("%w: %v", ErrOutboundUnauthorized, err)
+		}
+	}
+	return nil
+}
+
+// VerifyMessage checks the organization id of msg.  Ids are compared
+// using their case-sensitive 15 character form.
+func (ov *OutboundVerifier) VerifyMessage(msg *OutboundMessage) error {
+	if len(ov.OrganizationIDs) == 0 {
+		return nil
+	}
+	for _, id := range ov.OrganizationIDs {
+		if shortID(id) == shortID(msg.OrganizationID) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: invalid organization id %s", ErrOutboundUnauthorized, msg.OrganizationID)
+}
+
+func shortID(id string) string {
+	if len(id) == 18 {
+		return id[:15]
+	}
+	return id
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+var soapVersion = regexp.MustCompile(`/services/Soap/[a-z]/(\d+\.\d+)`)
+
+// Service returns a service authorized by the message's session id using the
+// instance and api version of the message's EnterpriseUrl (or PartnerUrl).  The
+// outbound message definition must have "Send Session ID" selected.
+func (msg *OutboundMessage) Service() (*Service, error) {
+	if msg.SessionID == "" {
+		return nil, errors.New("outbound message has no session id")
+	}
+	msgURL := msg.EnterpriseURL
+	if msgURL == "" {
+		msgURL = msg.PartnerURL
+	}
+	u, err := url.Parse(msgURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid outbound message url %q", msgURL)
+	}
+	var version string
+	if m := soapVersion.FindStringSubmatch(u.Path); m != nil {
+		version = "v" + m[1]
+	}
+	return New(u.Host, version, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: msg.SessionID, TokenType: "Bearer"})), nil
+}
+
+// OutboundFunc processes the records of an outbound message.  Each rec is a pointer to
+// a new value of the handler's type.  Returning nil acknowledges the message.
+type OutboundFunc func(ctx context.Context, msg *OutboundMessage, recs []SObject) error
+
+// OutboundHandler is an http.Handler receiving outbound messages for a single SObject
+// type.  Messages are verified, decoded and passed to Func.  An Ack of true is returned
+// when Func returns nil, otherwise salesforce will resend the message.  Use the message's
+// Service method to make calls using the message's session id.
+type OutboundHandler struct {
+	Func     OutboundFunc
+	Verifier *OutboundVerifier // nil skips verification
+	ErrorLog *log.Logger       // nil uses the log package's standard logger
+	recType  reflect.Type
+	name     string
+}
+
+// NewOutboundHandler creates a handler decoding notifications into new values
+// of rec's type.  rec must be a struct or pointer to a struct.
+func NewOutboundHandler(rec SObject, fn OutboundFunc) (*OutboundHandler, error) {
+	ty := reflect.TypeOf(rec)
+	st, err := validateStructElem(ty, ty, "struct or pointer to struct SObject")
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSObjectType(reflect.PtrTo(st), ty, "struct or pointer to struct SObject"); err != nil {
+		return nil, err
+	}
+	if fn == nil {
+		return nil, errors.New("fn may not be nil")
+	}
+	return &OutboundHandler{Func: fn, recType: st, name: rec.SObjectName()}, nil
+}
+
+func (h *OutboundHandler) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// ServeHTTP handles an outbound message request
+func (h *OutboundHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Verifier != nil {
+		if err := h.Verifier.VerifyRequest(r); err != nil {
+			h.logf("outbound message: %v", err)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	msg, err := ParseOutboundMessage(r.Body)
+	if err != nil {
+		h.logf("outbound message: %v", err)
+		http.Error(w, "invalid outbound message", http.StatusBadRequest)
+		return
+	}
+	if h.Verifier != nil {
+		if err := h.Verifier.VerifyMessage(msg); err != nil {
+			h.logf("outbound message: %v", err)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	var recs = make([]SObject, 0, len(msg.Notifications))
+	for _, n := range msg.Notifications {
+		if n.SObjectName != h.name {
+			h.logf("outbound message %s: expected %s; got %s", n.ID, h.name, n.SObjectName)
+			http.Error(w, "invalid sobject type", http.StatusBadRequest)
+			return
+		}
+		rec := reflect.New(h.recType)
+		if err := n.Decode(rec.Interface()); err != nil {
+			h.logf("outbound message: %v", err)
+			http.Error(w, "invalid outbound message", http.StatusBadRequest)
+			return
+		}
+		recs = append(recs, rec.Interface().(SObject))
+	}
+	ack := true
+	if err := h.Func(r.Context(), msg, recs); err != nil {
+		h.logf("outbound message %s: %v", msg.ActionID, err)
+		ack = false
+	}
+	if err := WriteOutboundAck(w, ack); err != nil {
+		h.logf("outbound message ack: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+const testOutboundMessage = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+ <soapenv:Body>
+  <notifications xmlns="http://soap.sforce.com/2005/09/outbound">
+   <OrganizationId>00D000000000001AAA</OrganizationId>
+   <ActionId>04k000000000001AAA</ActionId>
+   <SessionId>SESSION!ID</SessionId>
+   <EnterpriseUrl>https://aninstance.my.salesforce.com/services/Soap/c/55.0/00D000000000001</EnterpriseUrl>
+   <PartnerUrl>https://aninstance.my.salesforce.com/services/Soap/u/55.0/00D000000000001</PartnerUrl>
+   <Notification>
+    <Id>04l000000000001AAA</Id>
+    <sObject xsi:type="sf:Account" xmlns:sf="urn:sobject.enterprise.soap.sforce.com">
+     <sf:Id>001000000000001AAA</sf:Id>
+     <sf:Name>Acme &amp; Sons</sf:Name>
+     <sf:Website xsi:nil="true"/>
+     <sf:Vendor_ID__c>V100</sf:Vendor_ID__c>
+    </sObject>
+   </Notification>
+   <Notification>
+    <Id>04l000000000002AAA</Id>
+    <sObject xsi:type="sf:Account" xmlns:sf="urn:sobject.enterprise.soap.sforce.com">
+     <sf:Id>001000000000002AAA</sf:Id>
+     <sf:Name>Widgets</sf:Name>
+    </sObject>
+   </Notification>
+  </notifications>
+ </soapenv:Body>
+</soapenv:Envelope>`
+
+func TestParseOutboundMessage(t *testing.T) {
+	msg, err := salesforce.ParseOutboundMessage(strings.NewReader(testOutboundMessage))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if msg.OrganizationID != "00D000000000001AAA" || msg.SessionID != "SESSION!ID" || len(msg.Notifications) != 2 {
+		t.Fatalf("unexpected message %#v", msg)
+	}
+	n := msg.Notifications[0]
+	if n.ID != "04l000000000001AAA" || n.SObjectName != "Account" {
+		t.Errorf("unexpected notification %#v", n)
+	}
+	if _, ok := n.Fields["Website"]; ok {
+		t.Errorf("expected nil Website to be omitted")
+	}
+	var acct Account
+	if err := n.Decode(&acct); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if acct.AccountID != "001000000000001AAA" || acct.AccountName != "Acme & Sons" || acct.VendorID != "V100" {
+		t.Errorf("unexpected account %#v", acct)
+	}
+	if err := n.Decode(acct); err == nil {
+		t.Errorf("expected TypeError decoding into value")
+	}
+	if _, err := salesforce.ParseOutboundMessage(strings.NewReader(`<Envelope><Body></Body></Envelope>`)); err == nil {
+		t.Errorf("expected no notifications error")
+	}
+
+	w := httptest.NewRecorder()
+	if err := salesforce.WriteOutboundAck(w, true); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if !strings.Contains(w.Body.String(), "<Ack>true</Ack>") {
+		t.Errorf("expected Ack true; got %s", w.Body.String())
+	}
+}
+
+func TestOutboundVerifier(t *testing.T) {
+	msg := &salesforce.OutboundMessage{OrganizationID: "00D000000000001AAA"}
+	ov := &salesforce.OutboundVerifier{}
+	r := httptest.NewRequest("POST", "/outbound", nil)
+	if err := ov.VerifyRequest(r); err != nil {
+		t.Errorf("expected empty verifier to pass; got %v", err)
+	}
+	if err := ov.VerifyMessage(msg); err != nil {
+		t.Errorf("expected empty verifier to pass; got %v", err)
+	}
+
+	ov = &salesforce.OutboundVerifier{
+		OrganizationIDs: []string{"00D000000000001"},
+		CommonNames:     []string{"proxy.salesforce.com"},
+	}
+	if err := ov.VerifyMessage(msg); err != nil {
+		t.Errorf("expected 15 char org id match; got %v", err)
+	}
+	if err := ov.VerifyMessage(&salesforce.OutboundMessage{OrganizationID: "00D000000000002AAA"}); !errors.Is(err, salesforce.ErrOutboundUnauthorized) {
+		t.Errorf("expected ErrOutboundUnauthorized; got %v", err)
+	}
+	if err := ov.VerifyRequest(r); !errors.Is(err, salesforce.ErrOutboundUnauthorized) {
+		t.Errorf("expected ErrOutboundUnauthorized without certificate; got %v", err)
+	}
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "other.example.com"}}}}
+	if err := ov.VerifyRequest(r); !errors.Is(err, salesforce.ErrOutboundUnauthorized) {
+		t.Errorf("expected ErrOutboundUnauthorized for common name; got %v", err)
+	}
+	r.TLS.PeerCertificates[0].Subject.CommonName = "proxy.salesforce.com"
+	if err := ov.VerifyRequest(r); err != nil {
+		t.Errorf("expected success; got %v", err)
+	}
+	ov.VerifyCertificate = func(c *x509.Certificate) error { return errors.New("expired") }
+	if err := ov.VerifyRequest(r); !errors.Is(err, salesforce.ErrOutboundUnauthorized) {
+		t.Errorf("expected ErrOutboundUnauthorized from VerifyCertificate; got %v", err)
+	}
+}
+
+func TestOutboundHandler(t *testing.T) {
+	if _, err := salesforce.NewOutboundHandler(salesforce.RecordMap{}, nil); err == nil {
+		t.Errorf("expected TypeError for RecordMap")
+	}
+	var received []*Account
+	var msgSv *salesforce.Service
+	var fail bool
+	h, err := salesforce.NewOutboundHandler(Account{}, func(ctx context.Context, msg *salesforce.OutboundMessage, recs []salesforce.SObject) error {
+		for _, r := range recs {
+			received = append(received, r.(*Account))
+		}
+		var err error
+		if msgSv, err = msg.Service(); err != nil {
+			return err
+		}
+		if fail {
+			return errors.New("processing failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewOutboundHandler: %v", err)
+	}
+	var logBuf bytes.Buffer
+	h.ErrorLog = log.New(&logBuf, "", 0)
+	h.Verifier = &salesforce.OutboundVerifier{OrganizationIDs: []string{"00D000000000001AAA"}}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/outbound", strings.NewReader(testOutboundMessage)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<Ack>true</Ack>") {
+		t.Fatalf("expected Ack true; got %d %s", w.Code, w.Body.String())
+	}
+	if len(received) != 2 || received[0].AccountName != "Acme & Sons" || received[1].AccountID != "001000000000002AAA" {
+		t.Errorf("unexpected records %v", received)
+	}
+	if msgSv == nil || msgSv.Instance() != "aninstance.my.salesforce.com" {
+		t.Errorf("expected service for aninstance.my.salesforce.com; got %v", msgSv)
+	}
+
+	fail = true
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/outbound", strings.NewReader(testOutboundMessage)))
+	if !strings.Contains(w.Body.String(), "<Ack>false</Ack>") || !strings.Contains(logBuf.String(), "processing failed") {
+		t.Errorf("expected Ack false and logged error; got %s %s", w.Body.String(), logBuf.String())
+	}
+
+	tests := []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{"GET", "", http.StatusMethodNotAllowed},
+		{"POST", "<notxml", http.StatusBadRequest},
+		{"POST", strings.Replace(testOutboundMessage, "00D000000000001AAA", "00D000000000002AAA", 1), http.StatusForbidden},
+		{"POST", strings.Replace(testOutboundMessage, "sf:Account", "sf:Contact", 1), http.StatusBadRequest},
+	}
+	for i, tt := range tests {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, "/outbound", strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Errorf("test %d: expected %d; got %d", i, tt.code, w.Code)
+		}
+	}
+}
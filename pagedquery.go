@@ -0,0 +1,116 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// MaxQueryOffset is the largest OFFSET allowed in a SOQL query
+// https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_offset.htm
+const MaxQueryOffset = 2000
+
+// DefaultPageSize is the page size of a PagedQuery created with a page size <= 0
+const DefaultPageSize = 20
+
+// ErrMaxOffset is returned when a page's offset exceeds MaxQueryOffset
+var ErrMaxOffset = errors.New("page offset exceeds the SOQL maximum of 2000")
+
+// PagedQuery pages the records of a query using LIMIT and OFFSET for UI style pagination
+// (e.g. page 3 of 12), distinct from iterating all records using nextRecordsUrl.  Salesforce
+// limits OFFSET to 2000, so only pages beginning at or before the 2000th record are available.
+type PagedQuery struct {
+	sv       *Service
+	fields   *FieldList
+	where    string
+	orderBy  string
+	pageSize int
+}
+
+// NewPagedQuery creates a PagedQuery selecting fl's fields.  where and orderBy are the
+// conditions and ordering of the query without the WHERE and ORDER BY keywords (e.g.
+// "Type = 'Customer'" and "Name, Id").  Specify a unique ordering for stable pages.
+func (sv *Service) NewPagedQuery(fl *FieldList, where, orderBy string, pageSize int) *PagedQuery {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &PagedQuery{sv: sv, fields: fl, where: where, orderBy: orderBy, pageSize: pageSize}
+}
+
+// PageSize returns the number of records in a page
+func (pq *PagedQuery) PageSize() int {
+	return pq.pageSize
+}
+
+// whereClause returns the WHERE clause or an empty string
+func (pq *PagedQuery) whereClause() string {
+	if pq.where == "" {
+		return ""
+	}
+	return " WHERE " + pq.where
+}
+
+// Count returns the number of records matching the query using a COUNT() query.
+// https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_count.htm
+func (pq *PagedQuery) Count(ctx context.Context) (int, error) {
+	if pq.fields == nil {
+		return 0, errors.New("nil field list")
+	}
+	var res struct {
+		TotalSize int `json:"totalSize"`
+	}
+	qry := "SELECT COUNT() FROM " + pq.fields.SObjectName + pq.whereClause()
+	if err := pq.sv.Call(ctx, "query/?q="+url.QueryEscape(qry), "GET", nil, &res); err != nil {
+		return 0, err
+	}
+	return res.TotalSize, nil
+}
+
+// Pages returns the number of pages available for total records, limited
+// to pages with an offset no greater than MaxQueryOffset.
+func (pq *PagedQuery) Pages(total int) int {
+	pages := (total + pq.pageSize - 1) / pq.pageSize
+	if maxPages := MaxQueryOffset/pq.pageSize + 1; pages > maxPages {
+		pages = maxPages
+	}
+	return pages
+}
+
+// SOQL returns the query of the zero based page
+func (pq *PagedQuery) SOQL(page int) (string, error) {
+	if pq.fields == nil {
+		return "", errors.New("nil field list")
+	}
+	if page < 0 {
+		return "", fmt.Errorf("invalid page %d", page)
+	}
+	offset := page * pq.pageSize
+	if offset > MaxQueryOffset {
+		return "", ErrMaxOffset
+	}
+	clause := pq.whereClause()
+	if pq.orderBy > "" {
+		clause += " ORDER BY " + pq.orderBy
+	}
+	clause += fmt.Sprintf(" LIMIT %d", pq.pageSize)
+	if offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return pq.fields.SOQL(clause[1:]), nil
+}
+
+// Page decodes the records of the zero based page into results, a
+// pointer to a slice as used by Query.
+func (pq *PagedQuery) Page(ctx context.Context, page int, results interface{}) error {
+	qry, err := pq.SOQL(page)
+	if err != nil {
+		return err
+	}
+	return pq.sv.Query(ctx, qry, results)
+}
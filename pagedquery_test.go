@@ -0,0 +1,65 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestPagedQuery(t *testing.T) {
+	var queries []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qry := r.URL.Query().Get("q")
+		queries = append(queries, qry)
+		if qry == "SELECT COUNT() FROM Contact WHERE LastName = 'Smith'" {
+			encodeObject(w, map[string]interface{}{"totalSize": 4500, "done": true, "records": []interface{}{}})
+			return
+		}
+		encodeObject(w, map[string]interface{}{"totalSize": 2, "done": true, "records": []map[string]interface{}{
+			{"attributes": map[string]string{"type": "Contact"}, "Id": "003A", "LastName": "Smith"},
+			{"attributes": map[string]string{"type": "Contact"}, "Id": "003B", "LastName": "Smith"},
+		}})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	pq := sv.NewPagedQuery(salesforce.MustSelect(Contact{}, "Id", "LastName"), "LastName = 'Smith'", "Id", 100)
+	total, err := pq.Count(ctx)
+	if err != nil || total != 4500 {
+		t.Fatalf("expected count 4500; got %d %v", total, err)
+	}
+	if pages := pq.Pages(total); pages != 21 {
+		t.Errorf("expected 21 pages limited by max offset; got %d", pages)
+	}
+	if pages := pq.Pages(150); pages != 2 {
+		t.Errorf("expected 2 pages; got %d", pages)
+	}
+	var contacts []Contact
+	if err := pq.Page(ctx, 3, &contacts); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(contacts) != 2 || contacts[1].ContactID != "003B" {
+		t.Errorf("unexpected contacts %v", contacts)
+	}
+	if want := "SELECT Id, LastName FROM Contact WHERE LastName = 'Smith' ORDER BY Id LIMIT 100 OFFSET 300"; queries[1] != want {
+		t.Errorf("expected %s; got %s", want, queries[1])
+	}
+	if err := pq.Page(ctx, 21, &contacts); err != salesforce.ErrMaxOffset {
+		t.Errorf("expected %v; got %v", salesforce.ErrMaxOffset, err)
+	}
+	qry, _ := sv.NewPagedQuery(salesforce.MustSelect(Contact{}, "Id"), "", "", 0).SOQL(0)
+	if want := "SELECT Id FROM Contact LIMIT 20"; qry != want {
+		t.Errorf("expected %s; got %s", want, qry)
+	}
+}
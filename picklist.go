@@ -0,0 +1,90 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// MasterRecordTypeID is the id of the master record type used by objects
+// without record types.
+const MasterRecordTypeID = "012000000000000AAA"
+
+// RecordTypePicklist contains the values of a picklist available to a record type.
+// ControllerValues maps the values of the controlling field of a dependent picklist
+// to the indexes found in each value's ValidFor.
+// https://developer.salesforce.com/docs/atlas.en-us.uiapi.meta/uiapi/ui_api_responses_picklist_values.htm
+type RecordTypePicklist struct {
+	ControllerValues map[string]int            `json:"controllerValues,omitempty"`
+	DefaultValue     *RecordTypePicklistValue  `json:"defaultValue,omitempty"`
+	ETag             string                    `json:"eTag,omitempty"`
+	URL              string                    `json:"url,omitempty"`
+	Values           []RecordTypePicklistValue `json:"values,omitempty"`
+}
+
+// RecordTypePicklistValue is a picklist value available to a record type
+// https://developer.salesforce.com/docs/atlas.en-us.uiapi.meta/uiapi/ui_api_responses_picklist_value.htm
+type RecordTypePicklistValue struct {
+	Label    string `json:"label,omitempty"`
+	ValidFor []int  `json:"validFor,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+// ValuesFor returns the values of a dependent picklist that are valid
+// when the controlling field is set to controllerValue.
+func (rp *RecordTypePicklist) ValuesFor(controllerValue string) []RecordTypePicklistValue {
+	idx, ok := rp.ControllerValues[controllerValue]
+	if !ok {
+		return nil
+	}
+	var vals []RecordTypePicklistValue
+	for _, v := range rp.Values {
+		for _, ix := range v.ValidFor {
+			if ix == idx {
+				vals = append(vals, v)
+				break
+			}
+		}
+	}
+	return vals
+}
+
+// PicklistValues returns the values of the object's picklist field available to the
+// record type using the UI API.  Unlike the picklist values of Describe, the values are
+// limited to those assigned to the record type.  Use MasterRecordTypeID for objects
+// without record types.
+// https://developer.salesforce.com/docs/atlas.en-us.uiapi.meta/uiapi/ui_api_resources_picklist_values.htm
+func (sv *Service) PicklistValues(ctx context.Context, sobjectName, recordTypeID, field string) (*RecordTypePicklist, error) {
+	if sobjectName == "" || recordTypeID == "" || field == "" {
+		return nil, errors.New("sobject name, record type id and field must be specified")
+	}
+	var res *RecordTypePicklist
+	path := "ui-api/object-info/" + url.PathEscape(sobjectName) + "/picklist-values/" +
+		url.PathEscape(recordTypeID) + "/" + url.PathEscape(field)
+	if err := sv.Call(ctx, path, "GET", nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RecordTypePicklists returns the values of all of the object's picklist fields available to
+// the record type keyed by field name.
+// https://developer.salesforce.com/docs/atlas.en-us.uiapi.meta/uiapi/ui_api_resources_picklist_values_collection.htm
+func (sv *Service) RecordTypePicklists(ctx context.Context, sobjectName, recordTypeID string) (map[string]*RecordTypePicklist, error) {
+	if sobjectName == "" || recordTypeID == "" {
+		return nil, errors.New("sobject name and record type id must be specified")
+	}
+	var res struct {
+		PicklistFieldValues map[string]*RecordTypePicklist `json:"picklistFieldValues"`
+	}
+	path := "ui-api/object-info/" + url.PathEscape(sobjectName) + "/picklist-values/" + url.PathEscape(recordTypeID)
+	if err := sv.Call(ctx, path, "GET", nil, &res); err != nil {
+		return nil, err
+	}
+	return res.PicklistFieldValues, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+const testPicklist = `{"controllerValues":{"East":0,"West":1},"defaultValue":null,"eTag":"abc",` +
+	`"url":"/services/data/v55.0/ui-api/object-info/Account/picklist-values/012A/Region__c",` +
+	`"values":[{"attributes":null,"label":"Boston","validFor":[0],"value":"BOS"},` +
+	`{"attributes":null,"label":"Denver","validFor":[1],"value":"DEN"},` +
+	`{"attributes":null,"label":"Remote","validFor":[0,1],"value":"REM"}]}`
+
+func TestService_PicklistValues(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ui-api/object-info/Account/picklist-values/012A/Region__c":
+			io.WriteString(w, testPicklist)
+		case "/ui-api/object-info/Account/picklist-values/012A":
+			io.WriteString(w, `{"eTag":"def","picklistFieldValues":{"Region__c":`+testPicklist+`}}`)
+		default:
+			http.Error(w, `[{"errorCode":"NOT_FOUND"}]`, http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	if _, err := sv.PicklistValues(ctx, "Account", "", "Region__c"); err == nil {
+		t.Errorf("expected error for missing record type id")
+	}
+	pl, err := sv.PicklistValues(ctx, "Account", "012A", "Region__c")
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(pl.Values) != 3 || pl.DefaultValue != nil || pl.Values[1].Label != "Denver" {
+		t.Errorf("unexpected picklist %#v", pl)
+	}
+	if vals := pl.ValuesFor("West"); len(vals) != 2 || vals[0].Value != "DEN" || vals[1].Value != "REM" {
+		t.Errorf("expected DEN, REM; got %v", vals)
+	}
+	if vals := pl.ValuesFor("North"); vals != nil {
+		t.Errorf("expected nil values for unknown controller value; got %v", vals)
+	}
+	pls, err := sv.RecordTypePicklists(ctx, "Account", "012A")
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if p := pls["Region__c"]; p == nil || len(p.Values) != 3 {
+		t.Errorf("expected Region__c picklist; got %v", pls)
+	}
+	if _, err := sv.PicklistValues(ctx, "Contact", salesforce.MasterRecordTypeID, "Region__c"); err == nil {
+		t.Errorf("expected not found error")
+	}
+}
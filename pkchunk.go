@@ -0,0 +1,193 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PKChunking defines the Sforce-Enable-PKChunking header settings used
+// by Bulk API (v1) query jobs.  Salesforce splits the query into
+// separate batches based upon the record ID.
+// https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/async_api_headers_enable_pk_chunking.htm
+type PKChunking struct {
+	ChunkSize int    // number of records per chunk, salesforce default is 100,000 and max is 250,000
+	Parent    string // parent object when querying a sharing object
+	StartRow  string // 15 or 18 character id used as lower boundary of first chunk
+}
+
+// String returns the header value
+func (pk *PKChunking) String() string {
+	if pk == nil {
+		return "false"
+	}
+	var opts []string
+	if pk.ChunkSize > 0 {
+		opts = append(opts, fmt.Sprintf("chunkSize=%d", pk.ChunkSize))
+	}
+	if pk.Parent > "" {
+		opts = append(opts, "parent="+pk.Parent)
+	}
+	if pk.StartRow > "" {
+		opts = append(opts, "startRow="+pk.StartRow)
+	}
+	if len(opts) == 0 {
+		return "true"
+	}
+	return strings.Join(opts, "; ")
+}
+
+// WithPKChunking returns a service that sends the Sforce-Enable-PKChunking
+// header with each call.  A nil pk disables the header.
+func (sv *Service) WithPKChunking(pk *PKChunking) *Service {
+	snew := sv.clone()
+	snew.pkChunking = ""
+	if pk != nil {
+		snew.pkChunking = pk.String()
+	}
+	return snew
+}
+
+// BatchInfo describes a Bulk API (v1) batch
+// https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/asynch_api_reference_batchinfo.htm
+type BatchInfo struct {
+	ID                     string `json:"id,omitempty"`
+	JobID                  string `json:"jobId,omitempty"`
+	State                  string `json:"state,omitempty"`
+	StateMessage           string `json:"stateMessage,omitempty"`
+	CreatedDate            string `json:"createdDate,omitempty"`
+	SystemModstamp         string `json:"systemModstamp,omitempty"`
+	NumberRecordsProcessed int    `json:"numberRecordsProcessed"`
+	NumberRecordsFailed    int    `json:"numberRecordsFailed"`
+}
+
+// Batch states
+const (
+	BatchStateQueued       = "Queued"
+	BatchStateInProgress   = "InProgress"
+	BatchStateCompleted    = "Completed"
+	BatchStateFailed       = "Failed"
+	BatchStateNotProcessed = "Not Processed"
+)
+
+// asyncPath returns the Bulk API (v1) path for the service's api version.
+// Only the version segment of the base url is used, so tooling services
+// share the data api's async path.
+func (sv *Service) asyncPath(path string) string {
+	return "/services/async/" + strings.TrimPrefix(sv.APIVersion(), "v") + "/" + path
+}
+
+// PKChunkJob is a Bulk API (v1) query job using pk chunking.  Salesforce creates
+// a batch for each chunk, and the job aggregates these batches' results.
+type PKChunkJob struct {
+	ID string
+	sv *Service
+}
+
+// PKChunkQuery creates a Bulk API (v1) query job with PK chunking enabled,
+// adds the query as the job's batch and closes the job.  Use the returned
+// PKChunkJob to monitor the chunked batches and read results.  Only query
+// and queryAll jobs are supported as Salesforce ignores pk chunking for
+// ingest operations.
+// https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/asynch_api_code_curl_walkthrough_pk_chunking.htm
+func (sv *Service) PKChunkQuery(ctx context.Context, object, query string, pk *PKChunking, queryAll bool) (*PKChunkJob, error) {
+	if pk == nil {
+		pk = &PKChunking{}
+	}
+	op := JobOperationQuery
+	if queryAll {
+		op = JobOperationQueryAll
+	}
+	var jobInfo *Job
+	jd := &JobDefinition{Object: object, Operation: op, ContentType: "CSV"}
+	if err := sv.WithPKChunking(pk).Call(ctx, sv.asyncPath("job"), "POST", jd, &jobInfo); err != nil {
+		return nil, err
+	}
+	var bi *BatchInfo
+	if err := sv.Call(ctx, sv.asyncPath("job/"+jobInfo.ID+"/batch"), "POST", strings.NewReader(query), &bi,
+		AcceptHeader("application/json"), ContentTypeHeader("text/csv")); err != nil {
+		return nil, err
+	}
+	var mx = map[string]string{"state": "Closed"}
+	if err := sv.Call(ctx, sv.asyncPath("job/"+jobInfo.ID), "POST", mx, &jobInfo); err != nil {
+		return nil, err
+	}
+	return &PKChunkJob{ID: jobInfo.ID, sv: sv}, nil
+}
+
+// Batches returns the chunk batches of the job.  The original batch
+// containing the query is not returned as it is never processed.
+func (pj *PKChunkJob) Batches(ctx context.Context) ([]BatchInfo, error) {
+	var result = struct {
+		BatchInfo []BatchInfo `json:"batchInfo"`
+	}{}
+	if err := pj.sv.Call(ctx, pj.sv.asyncPath("job/"+pj.ID+"/batch"), "GET", nil, &result); err != nil {
+		return nil, err
+	}
+	var batches = make([]BatchInfo, 0, len(result.BatchInfo))
+	for _, b := range result.BatchInfo {
+		if b.State != BatchStateNotProcessed {
+			batches = append(batches, b)
+		}
+	}
+	return batches, nil
+}
+
+// Done returns true when every chunk batch has completed.  An error
+// is returned if any batch failed.
+func (pj *PKChunkJob) Done(ctx context.Context) (bool, error) {
+	batches, err := pj.Batches(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(batches) == 0 {
+		return false, nil
+	}
+	for _, b := range batches {
+		switch b.State {
+		case BatchStateFailed:
+			return false, fmt.Errorf("batch %s failed: %s", b.ID, b.StateMessage)
+		case BatchStateCompleted:
+			continue
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// Results returns a single csv stream combining the results of every completed
+// chunk batch.  The header row is written once.
+func (pj *PKChunkJob) Results(ctx context.Context) (io.ReadCloser, error) {
+	batches, err := pj.Batches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resultPaths []string
+	for _, b := range batches {
+		if b.State != BatchStateCompleted {
+			continue
+		}
+		var resultIDs []string
+		path := pj.sv.asyncPath("job/" + pj.ID + "/batch/" + b.ID + "/result")
+		if err := pj.sv.Call(ctx, path, "GET", nil, &resultIDs); err != nil {
+			return nil, err
+		}
+		for _, id := range resultIDs {
+			resultPaths = append(resultPaths, path+"/"+id)
+		}
+	}
+	return &combinedReader{
+		ctx:    ctx,
+		jobIDs: resultPaths,
+		fetch: func(ctx context.Context, path string) (*HTTPBody, error) {
+			var body *HTTPBody
+			return body, pj.sv.Call(ctx, path, "GET", nil, &body, AcceptHeader("text/csv"))
+		},
+	}, nil
+}
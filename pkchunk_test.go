@@ -0,0 +1,92 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func pkChunkHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	const jobPath = "/services/async/53.0/job"
+	switch r.Method + " " + r.URL.Path {
+	case "POST " + jobPath:
+		if hdr := r.Header.Get("Sforce-Enable-PKChunking"); hdr != "chunkSize=2; startRow=001000000000001" {
+			http.Error(w, "invalid pk chunking header "+hdr, http.StatusBadRequest)
+			return
+		}
+		encodeObject(w, salesforce.Job{ID: "750PK", State: "Open"})
+	case "POST " + jobPath + "/750PK/batch":
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != "SELECT Id, Name FROM Account" {
+			http.Error(w, "invalid query "+string(b), http.StatusBadRequest)
+			return
+		}
+		encodeObject(w, salesforce.BatchInfo{ID: "751A", JobID: "750PK", State: "Queued"})
+	case "POST " + jobPath + "/750PK":
+		encodeObject(w, salesforce.Job{ID: "750PK", State: "Closed"})
+	case "GET " + jobPath + "/750PK/batch":
+		encodeObject(w, map[string]interface{}{
+			"batchInfo": []salesforce.BatchInfo{
+				{ID: "751A", State: salesforce.BatchStateNotProcessed},
+				{ID: "751B", State: salesforce.BatchStateCompleted},
+				{ID: "751C", State: salesforce.BatchStateCompleted},
+			},
+		})
+	case "GET " + jobPath + "/750PK/batch/751B/result", "GET " + jobPath + "/750PK/batch/751C/result":
+		encodeObject(w, []string{"752X"})
+	case "GET " + jobPath + "/750PK/batch/751B/result/752X":
+		w.Header().Set("Content-type", "text/csv")
+		io.WriteString(w, "Id,Name\n001A,Acct A\n001B,Acct B\n")
+	case "GET " + jobPath + "/750PK/batch/751C/result/752X":
+		w.Header().Set("Content-type", "text/csv")
+		io.WriteString(w, "Id,Name\n001C,Acct C\n")
+	default:
+		http.Error(w, "not found "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+func TestService_PKChunkQuery(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(pkChunkHandlerFunc))
+	defer ws.Close()
+
+	ctx := context.Background()
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithURL(ws.URL + "/services/data/v53.0/")
+	pk := &salesforce.PKChunking{ChunkSize: 2, StartRow: "001000000000001"}
+	job, err := sv.PKChunkQuery(ctx, "Account", "SELECT Id, Name FROM Account", pk, false)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if done, err := job.Done(ctx); !done || err != nil {
+		t.Fatalf("expected done; got %v %v", done, err)
+	}
+	rdr, err := job.Results(ctx)
+	if err != nil {
+		t.Fatalf("expected results; got %v", err)
+	}
+	defer rdr.Close()
+	rows, err := csv.NewReader(rdr).ReadAll()
+	if err != nil || len(rows) != 4 {
+		t.Errorf("expected 4 rows; got %d %v", len(rows), err)
+	}
+	if _, err := sv.WithTooling().PKChunkQuery(ctx, "Account", "SELECT Id, Name FROM Account", pk, false); err != nil {
+		t.Errorf("expected tooling service to use the async path; got %v", err)
+	}
+	var nilpk *salesforce.PKChunking
+	if nilpk.String() != "false" || (&salesforce.PKChunking{}).String() != "true" ||
+		!strings.Contains((&salesforce.PKChunking{Parent: "Account"}).String(), "parent=Account") {
+		t.Errorf("unexpected PKChunking header values")
+	}
+}
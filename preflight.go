@@ -0,0 +1,119 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrNotQueryable is wrapped by the QueryableError returned when the query
+// preflight finds that an object may not be queried
+var ErrNotQueryable = errors.New("object is not queryable")
+
+// QueryableError describes the object of a query rejected by the preflight
+// check.  Queryable and Retrieveable are the flags of the object's describe
+// result; both are false when the object does not exist or is not accessible.
+type QueryableError struct {
+	SObject      string
+	Queryable    bool
+	Retrieveable bool
+	Exists       bool
+}
+
+// Error describes the reason the query was not sent
+func (e *QueryableError) Error() string {
+	if !e.Exists {
+		return fmt.Sprintf("object %s does not exist or is not accessible for this user", e.SObject)
+	}
+	return fmt.Sprintf("object %s is not queryable for this user", e.SObject)
+}
+
+// Unwrap returns ErrNotQueryable
+func (e *QueryableError) Unwrap() error {
+	return ErrNotQueryable
+}
+
+// WithQueryPreflight returns a service whose Query and QueryAll funcs check that
+// the object of the FROM clause is queryable before sending the query, returning a
+// *QueryableError rather than the INVALID_TYPE error of the server.  The check uses
+// CachedDescribe, so set a DescribeStore (see WithDescribeStore) to avoid a
+// describe call per query.
+func (sv *Service) WithQueryPreflight(on bool) *Service {
+	snew := sv.clone()
+	snew.queryPreflight = on
+	return snew
+}
+
+// checkQueryable returns a *QueryableError when the object of soql is not
+// queryable.  Describe errors other than ErrNotFound are returned as is.
+func (sv *Service) checkQueryable(ctx context.Context, soql string) error {
+	name := QueryObject(soql)
+	if name == "" {
+		return nil
+	}
+	def, err := sv.CachedDescribe(ctx, name)
+	if err != nil {
+		if HasErrorCode(err, ErrNotFound) {
+			return &QueryableError{SObject: name}
+		}
+		return fmt.Errorf("query preflight describe %s: %w", name, err)
+	}
+	if def == nil || !def.Queryable {
+		qe := &QueryableError{SObject: name, Exists: def != nil}
+		if def != nil {
+			qe.Retrieveable = def.Retrieveable
+		}
+		return qe
+	}
+	return nil
+}
+
+var soqlFromObject = regexp.MustCompile(`(?i)\bFROM\s+([A-Za-z0-9_]+)`)
+
+// QueryObject returns the object name of the outer FROM clause of soql,
+// skipping the FROM clauses of parenthesized subqueries.  An empty string
+// is returned when no FROM clause is found.
+func QueryObject(soql string) string {
+	var sb strings.Builder
+	var depth int
+	var inQuote bool
+	for i := 0; i < len(soql); i++ {
+		c := soql[i]
+		switch {
+		case inQuote:
+			if c == '\\' {
+				i++
+			} else if c == '\'' {
+				inQuote = false
+			}
+			continue
+		case c == '\'':
+			inQuote = true
+			continue
+		case c == '(':
+			depth++
+			continue
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+			sb.WriteByte(' ')
+			continue
+		}
+		if depth == 0 {
+			sb.WriteByte(c)
+		}
+	}
+	m := soqlFromObject.FindStringSubmatch(sb.String())
+	if len(m) < 2 {
+		return ""
+	}
+	return m[1]
+}
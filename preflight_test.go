@@ -0,0 +1,87 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestQueryObject(t *testing.T) {
+	tests := []struct {
+		soql, want string
+	}{
+		{"SELECT Id FROM Contact", "Contact"},
+		{"select Id, (SELECT Id FROM Contacts) from Account WHERE Name = 'x FROM y'", "Account"},
+		{"SELECT Id FROM Account WHERE Id IN (SELECT AccountId FROM Opportunity)", "Account"},
+		{"SELECT Name FROM Account WHERE Name = 'O\\'Brien (FROM Lead'", "Account"},
+		{"SELECT COUNT()", ""},
+	}
+	for _, tt := range tests {
+		if got := salesforce.QueryObject(tt.soql); got != tt.want {
+			t.Errorf("QueryObject(%q) expected %q; got %q", tt.soql, tt.want, got)
+		}
+	}
+}
+
+func TestService_WithQueryPreflight(t *testing.T) {
+	var describes, queries int
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sobjects/Contact/describe":
+			describes++
+			encodeObject(w, salesforce.SObjectDefinition{Name: "Contact", Queryable: true, Retrieveable: true})
+		case "/sobjects/SetupThing/describe":
+			describes++
+			encodeObject(w, salesforce.SObjectDefinition{Name: "SetupThing", Retrieveable: true})
+		case "/sobjects/Missing__c/describe":
+			http.Error(w, `[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`, http.StatusNotFound)
+		case "/query/":
+			queries++
+			encodeObject(w, map[string]interface{}{"done": true, "totalSize": 0, "records": []interface{}{}})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var recs []Contact
+	if err := sv.Query(ctx, "SELECT Id FROM SetupThing", &recs); err != nil || describes != 0 {
+		t.Fatalf("expected no preflight by default; got %v describes=%d", err, describes)
+	}
+	sv = sv.WithQueryPreflight(true).WithDescribeStore(salesforce.NewMemoryDescribeStore(), time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := sv.Query(ctx, "SELECT Id, (SELECT Id FROM Cases) FROM Contact", &recs); err != nil {
+			t.Fatalf("expected Contact query success; got %v", err)
+		}
+	}
+	if describes != 1 || queries != 3 {
+		t.Errorf("expected 1 cached describe and 3 queries; got %d %d", describes, queries)
+	}
+
+	var qe *salesforce.QueryableError
+	err := sv.QueryAll(ctx, "SELECT Id FROM SetupThing", &recs)
+	if !errors.As(err, &qe) || !errors.Is(err, salesforce.ErrNotQueryable) || qe.SObject != "SetupThing" ||
+		!qe.Exists || !qe.Retrieveable || err.Error() != "object SetupThing is not queryable for this user" {
+		t.Errorf("expected SetupThing QueryableError; got %v", err)
+	}
+	err = sv.Query(ctx, "SELECT Id FROM Missing__c", &recs)
+	if !errors.As(err, &qe) || qe.Exists || qe.SObject != "Missing__c" {
+		t.Errorf("expected Missing__c QueryableError; got %v", err)
+	}
+	if queries != 3 {
+		t.Errorf("expected rejected queries not to be sent; got %d queries", queries)
+	}
+}
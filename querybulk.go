@@ -0,0 +1,330 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// DefaultBulkThreshold is the number of rows at which QueryBulk uses a bulk query job
+const DefaultBulkThreshold = 10000
+
+// QueryBulkOptions determine how QueryBulk runs a query
+type QueryBulkOptions struct {
+	// EstimatedRows is the expected number of rows.  When zero, the totalSize of
+	// the first REST query page is used and the REST query is abandoned if
+	// totalSize reaches BulkThreshold.
+	EstimatedRows int
+	// BulkThreshold is the minimum number of rows to use a bulk query job.  A
+	// negative value always uses a bulk job.  Zero indicates DefaultBulkThreshold.
+	BulkThreshold int
+	// QueryAll includes deleted and archived records
+	QueryAll bool
+	// PollInterval is the time between job status checks.  Zero indicates DefaultJobPollInterval.
+	PollInterval time.Duration
+	// MaxRecords is the maximum number of rows of each bulk results download.
+	// Zero lets salesforce choose.
+	MaxRecords int
+}
+
+// WithQueryBulkOptions returns a service that uses opts for QueryBulk calls
+func (sv *Service) WithQueryBulkOptions(opts *QueryBulkOptions) *Service {
+	snew := sv.clone()
+	snew.queryBulk = nil
+	if opts != nil {
+		o := *opts
+		snew.queryBulk = &o
+	}
+	return snew
+}
+
+// errUseBulk stops a REST query when the first page indicates a bulk job is needed
+var errUseBulk = errors.New("use bulk query")
+
+// QueryBulk runs soql either as a REST query or, for large results, as a bulk query
+// job that is created, waited on and whose csv result pages are downloaded and decoded.
+// The choice is based on the estimated row count of the service's QueryBulkOptions (see
+// WithQueryBulkOptions).  results must be of the form *[]<struct>, *[]*<struct>, *[]RecordMap
+// or a func(<struct|*struct|RecordMap>) error that is called with each record as it is decoded.
+// Csv columns are matched to struct fields using json tag names, and dot-notation columns
+// (e.g. Account.Name) set nested structs and maps.  Csv values of maps are strings.
+// https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/queries.htm
+func (sv *Service) QueryBulk(ctx context.Context, soql string, results interface{}) error {
+	target, err := newQueryTarget(results)
+	if err != nil {
+		return err
+	}
+	var opts QueryBulkOptions
+	if sv.queryBulk != nil {
+		opts = *sv.queryBulk
+	}
+	if opts.BulkThreshold == 0 {
+		opts.BulkThreshold = DefaultBulkThreshold
+	}
+	if opts.BulkThreshold > 0 && (opts.EstimatedRows == 0 || opts.EstimatedRows < opts.BulkThreshold) {
+		err := sv.queryBulkREST(ctx, soql, opts, target)
+		if err != errUseBulk {
+			return err
+		}
+	}
+	return sv.queryBulkJob(ctx, soql, opts, target)
+}
+
+// queryBulkREST pages through a REST query adding each record to target
+func (sv *Service) queryBulkREST(ctx context.Context, soql string, opts QueryBulkOptions, target *queryTarget) error {
+	path := "query/?q="
+	if opts.QueryAll {
+		path = "queryAll/?q="
+	}
+	var firstPage = true
+	qsv := sv.querySvc()
+	var recs = reflect.New(reflect.SliceOf(target.elemType))
+	if _, err := NewRecordSlice(recs.Interface()); err != nil {
+		return err
+	}
+	return qsv.paginate(ctx, path+url.QueryEscape(soql), func() pager {
+		// each page decodes into a new slice
+		recs = reflect.New(reflect.SliceOf(target.elemType))
+		rs, _ := NewRecordSlice(recs.Interface())
+		return &QueryResponse{Records: rs}
+	}, func(pg pager) error {
+		res := pg.(*QueryResponse)
+		if firstPage && opts.EstimatedRows == 0 && res.TotalSize >= opts.BulkThreshold {
+			return errUseBulk
+		}
+		firstPage = false
+		for i := 0; i < recs.Elem().Len(); i++ {
+			if err := target.add(recs.Elem().Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// queryBulkJob creates a query job, waits for completion and decodes the results
+func (sv *Service) queryBulkJob(ctx context.Context, soql string, opts QueryBulkOptions, target *queryTarget) error {
+	job, err := sv.QueryCreateJob(ctx, BulkQuery{Query: soql}, opts.QueryAll)
+	if err != nil {
+		return err
+	}
+	if job, err = sv.WaitQueryJob(ctx, job.ID, opts.PollInterval); err != nil {
+		return err
+	}
+	return sv.decodeQueryJob(ctx, job, opts.MaxRecords, target)
+}
+
+// decodeQueryJob decodes the result pages of a completed query job into target
+func (sv *Service) decodeQueryJob(ctx context.Context, job *Job, maxRecords int, target *queryTarget) error {
+	if !job.State.IsSuccess() {
+		return fmt.Errorf("query job %s %s: %s", job.ID, job.State, job.ErrorMessage)
+	}
+	delim, ok := jobDelimiters[job.ColumnDelimiter]
+	if !ok {
+		return fmt.Errorf("query job %s has unknown column delimiter %s", job.ID, job.ColumnDelimiter)
+	}
+	var locator string
+	for {
+		body, next, err := sv.QueryJobResults(ctx, job.ID, locator, maxRecords)
+		if err != nil {
+			return err
+		}
+		err = target.decodeCSV(body, delim)
+		body.Close()
+		if err != nil || next == "" {
+			return err
+		}
+		locator = next
+	}
+}
+
+// GetQueryJob returns the status of a query job
+// https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/query_get_one_job.htm
+func (sv *Service) GetQueryJob(ctx context.Context, jobID string) (*Job, error) {
+	var result *Job
+	err := sv.Call(ctx, "jobs/query/"+jobID, "GET", nil, &result)
+	return result, err
+}
+
+// WaitQueryJob polls the query job every interval until the job reaches a terminal
+// state or ctx is done.  An interval <= 0 indicates DefaultJobPollInterval.
+func (sv *Service) WaitQueryJob(ctx context.Context, jobID string, interval time.Duration) (*Job, error) {
+	return waitJob(ctx, jobID, interval, sv.GetQueryJob)
+}
+
+// QueryJobResults returns a page of the csv results of a completed query job.  Pass an
+// empty locator for the first page and the returned next locator for subsequent pages.
+// An empty next locator indicates the last page.  maxRecords limits the rows of the page
+// when greater than zero.
+// https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/query_get_job_results.htm
+func (sv *Service) QueryJobResults(ctx context.Context, jobID, locator string, maxRecords int) (body *HTTPBody, next string, err error) {
+	var q = make(url.Values)
+	if locator > "" {
+		q.Set("locator", locator)
+	}
+	if maxRecords > 0 {
+		q.Set("maxRecords", fmt.Sprintf("%d", maxRecords))
+	}
+	path := "jobs/query/" + jobID + "/results"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var ci CallInfo
+	if err = sv.Call(WithCallInfo(ctx, &ci), path, "GET", nil, &body, AcceptHeader("text/csv")); err != nil {
+		return nil, "", err
+	}
+	if next = ci.Header.Get("Sforce-Locator"); next == "null" {
+		next = ""
+	}
+	return body, next, nil
+}
+
+// queryTarget appends records to a slice or passes them to a func
+type queryTarget struct {
+	elemType reflect.Type
+	add      func(reflect.Value) error
+	fields   map[reflect.Type]map[string][]int
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// newQueryTarget validates results as a pointer to a slice of records or a record func
+func newQueryTarget(results interface{}) (*queryTarget, error) {
+	const expected = "*[]<struct>, *[]*<struct>, *[]RecordMap or func(<record>) error"
+	ty := reflect.TypeOf(results)
+	if ty != nil && ty.Kind() == reflect.Func {
+		if ty.NumIn() != 1 || ty.NumOut() != 1 || ty.Out(0) != errorType || !isRecordType(ty.In(0)) ||
+			ty.In(0).Kind() == reflect.Interface {
+			return nil, &TypeError{Expected: expected, Got: ty}
+		}
+		fn := reflect.ValueOf(results)
+		return &queryTarget{elemType: ty.In(0), add: func(v reflect.Value) error {
+			if err, _ := fn.Call([]reflect.Value{v})[0].Interface().(error); err != nil {
+				return err
+			}
+			return nil
+		}}, nil
+	}
+	slice, err := validatePtrTo(results, reflect.Slice, expected)
+	if err != nil {
+		return nil, err
+	}
+	elemType := slice.Type().Elem()
+	if !isRecordType(elemType) || elemType.Kind() == reflect.Interface {
+		return nil, &TypeError{Expected: expected, Got: ty}
+	}
+	return &queryTarget{elemType: elemType, add: func(v reflect.Value) error {
+		slice.Set(reflect.Append(slice, v))
+		return nil
+	}}, nil
+}
+
+// decodeCSV adds each row of the csv data, delimited by comma, as a record
+func (qt *queryTarget) decodeCSV(rdr io.Reader, comma rune) error {
+	cr := csv.NewReader(rdr)
+	cr.Comma = comma
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var paths = make([][]string, len(header))
+	for i, col := range header {
+		paths[i] = strings.Split(col, ".")
+	}
+	for rowNum := 1; ; rowNum++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rec := reflect.New(qt.elemType).Elem()
+		for i, val := range row {
+			if err := qt.setPath(rec, paths[i], val); err != nil {
+				return fmt.Errorf("row %d column %s: %w", rowNum, header[i], err)
+			}
+		}
+		if err := qt.add(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// setPath sets the field of v found by the json names of path to s
+func (qt *queryTarget) setPath(v reflect.Value, path []string, s string) error {
+	if s == "" {
+		return nil
+	}
+	for i, seg := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			idx, ok := qt.fieldIndex(v.Type())[seg]
+			if !ok {
+				return nil
+			}
+			v = v.FieldByIndex(idx)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.Interface {
+				return nil
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			m := v.Convert(reflect.TypeOf(map[string]interface{}{})).Interface().(map[string]interface{})
+			setMapPath(m, path[i:], s)
+			return nil
+		default:
+			return nil
+		}
+	}
+	return setFieldFromString(v, s)
+}
+
+// fieldIndex caches the jsonfield.Index of struct types
+func (qt *queryTarget) fieldIndex(ty reflect.Type) map[string][]int {
+	if qt.fields == nil {
+		qt.fields = make(map[reflect.Type]map[string][]int)
+	}
+	m, ok := qt.fields[ty]
+	if !ok {
+		m = jsonfield.Index(ty)
+		qt.fields[ty] = m
+	}
+	return m
+}
+
+// setMapPath sets the value of a dot-notation path in nested maps
+func setMapPath(m map[string]interface{}, path []string, s string) {
+	for _, seg := range path[:len(path)-1] {
+		child, ok := m[seg].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			m[seg] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = s
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_QueryBulk(t *testing.T) {
+	var restCalls, jobChecks int
+	var jobCreated bool
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query/":
+			restCalls++
+			total := 2
+			if r.URL.Query().Get("q") == "SELECT Id FROM Contact WHERE Big = true" {
+				total = 50000
+			}
+			encodeObject(w, map[string]interface{}{"totalSize": total, "done": true, "records": []map[string]interface{}{
+				{"Id": "003A", "LastName": "Smith", "Account": map[string]interface{}{"Name": "Acme"}},
+				{"Id": "003B", "LastName": "Jones"},
+			}})
+		case "/jobs/query":
+			jobCreated = true
+			encodeObject(w, map[string]interface{}{"id": "750Q", "state": "UploadComplete", "operation": "query"})
+		case "/jobs/query/750Q":
+			jobChecks++
+			state := "InProgress"
+			if jobChecks > 1 {
+				state = "JobComplete"
+			}
+			encodeObject(w, map[string]interface{}{"id": "750Q", "state": state})
+		case "/jobs/query/750Q/results":
+			w.Header().Set("Content-Type", "text/csv")
+			switch r.URL.Query().Get("locator") {
+			case "":
+				w.Header().Set("Sforce-Locator", "L2")
+				io.WriteString(w, "Id,LastName,DoNotCall,Account.Name\n003A,Smith,true,Acme\n003B,\"Jones, Jr\",false,\n")
+			case "L2":
+				w.Header().Set("Sforce-Locator", "null")
+				io.WriteString(w, "Id,LastName,DoNotCall,Account.Name\n003C,Brown,,Other\n")
+			default:
+				http.Error(w, "bad locator", http.StatusBadRequest)
+			}
+		default:
+			http.Error(w, "not found "+r.URL.Path, http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	var small []Contact
+	if err := sv.QueryBulk(ctx, "SELECT Id FROM Contact", &small); err != nil {
+		t.Fatalf("rest query: expected success; got %v", err)
+	}
+	if len(small) != 2 || restCalls != 1 || jobCreated {
+		t.Errorf("expected rest query of 2 records; got %d %d %v", len(small), restCalls, jobCreated)
+	}
+
+	var big []*Contact
+	bsv := sv.WithQueryBulkOptions(&salesforce.QueryBulkOptions{PollInterval: time.Millisecond})
+	if err := bsv.QueryBulk(ctx, "SELECT Id FROM Contact WHERE Big = true", &big); err != nil {
+		t.Fatalf("bulk query: expected success; got %v", err)
+	}
+	if len(big) != 3 || restCalls != 2 || !jobCreated || jobChecks != 2 {
+		t.Fatalf("expected 3 records from bulk job; got %d %d %v %d", len(big), restCalls, jobCreated, jobChecks)
+	}
+	if big[1].LastName != "Jones, Jr" || !big[0].DoNotCall || big[0].AccountIDRel["Name"] != "Acme" || big[1].AccountIDRel != nil {
+		t.Errorf("unexpected bulk records %v %v", big[0], big[1])
+	}
+
+	var names []string
+	jobChecks = 0
+	bsv = sv.WithQueryBulkOptions(&salesforce.QueryBulkOptions{EstimatedRows: 1000000, PollInterval: time.Millisecond})
+	err := bsv.QueryBulk(ctx, "SELECT Id FROM Contact", func(rec salesforce.RecordMap) error {
+		names = append(names, fmt.Sprint(rec.Value("Account.Name")))
+		if len(names) == 3 {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "stop" || restCalls != 2 || len(names) != 3 || names[0] != "Acme" || names[2] != "Other" {
+		t.Errorf("expected callback stop after 3 records; got %v %d %v", err, restCalls, names)
+	}
+
+	if err := sv.QueryBulk(ctx, "SELECT Id FROM Contact", func(c Contact) {}); err == nil {
+		t.Errorf("expected type error for func without error result")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// ErrInvalidQueryLocator is the error code returned when a nextRecordsUrl has
+// expired or has been closed by salesforce.
+const ErrInvalidQueryLocator = "INVALID_QUERY_LOCATOR"
+
+// QueryCursor records the position of a query so that an extract may be resumed
+// after a restart using ResumeQuery.  The cursor is updated after each page and may
+// be serialized (e.g. saved to a file) along with the records retrieved.  Salesforce
+// expires query locators after a period of inactivity, so resume promptly.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_query.htm
+type QueryCursor struct {
+	Query          string    `json:"query,omitempty"`
+	NextRecordsURL string    `json:"nextRecordsUrl,omitempty"`
+	TotalSize      int       `json:"totalSize,omitempty"`
+	Fetched        int       `json:"fetched,omitempty"` // number of records decoded
+	Done           bool      `json:"done,omitempty"`
+	Updated        time.Time `json:"updated,omitempty"`
+	// PageFunc, if not nil, is called after each page is decoded into results.  Use to
+	// process (and truncate) results and save the cursor.  Returning ErrStopPaging ends
+	// the query without error leaving the cursor positioned at the next page.
+	PageFunc func(context.Context, *QueryCursor) error `json:"-"`
+}
+
+// QueryWithCursor executes the query like Query updating cursor with the position of the
+// query after each page.  The cursor is reset before the query begins.
+func (sv *Service) QueryWithCursor(ctx context.Context, qry string, results interface{}, cursor *QueryCursor) error {
+	if cursor == nil {
+		return errors.New("nil cursor")
+	}
+	cursor.Query, cursor.NextRecordsURL, cursor.TotalSize, cursor.Fetched, cursor.Done = qry, "", 0, 0, false
+	return sv.queryCursor(ctx, "query/?q="+url.QueryEscape(sv.forClause.Append(qry)), results, cursor)
+}
+
+// ResumeQuery continues the query of cursor beginning with its NextRecordsURL decoding
+// the remaining records into results.  Nothing is retrieved when the cursor is done.  Use
+// IsInvalidQueryLocator to check whether the locator expired.
+func (sv *Service) ResumeQuery(ctx context.Context, cursor *QueryCursor, results interface{}) error {
+	if cursor == nil {
+		return errors.New("nil cursor")
+	}
+	if cursor.Done {
+		return nil
+	}
+	if cursor.NextRecordsURL == "" {
+		return errors.New("cursor has no nextRecordsUrl")
+	}
+	return sv.queryCursor(ctx, cursor.NextRecordsURL, results, cursor)
+}
+
+func (sv *Service) queryCursor(ctx context.Context, path string, results interface{}, cursor *QueryCursor) error {
+	rs, err := NewRecordSlice(results)
+	if err != nil {
+		return err
+	}
+	var rows int
+	qsv := sv.querySvc()
+	return qsv.paginate(ctx, path, func() pager {
+		// PageFunc may truncate results, so count rows before each page
+		rows = rs.rows()
+		return &QueryResponse{Records: rs}
+	}, func(pg pager) error {
+		res := pg.(*QueryResponse)
+		cursor.TotalSize = res.TotalSize
+		cursor.Fetched += rs.rows() - rows
+		cursor.Done = res.Done || res.NextRecordsURL == ""
+		cursor.NextRecordsURL = res.NextRecordsURL
+		cursor.Updated = time.Now()
+		if cursor.PageFunc != nil {
+			return cursor.PageFunc(ctx, cursor)
+		}
+		return nil
+	})
+}
+
+// IsInvalidQueryLocator returns true when err indicates that the query locator
+// of a ResumeQuery call has expired.
+func IsInvalidQueryLocator(err error) bool {
+	var ns *ctxclient.NotSuccess
+	if err == nil || !errors.As(err, &ns) {
+		return false
+	}
+	return bytes.Contains(ns.Body, []byte(ErrInvalidQueryLocator))
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_QueryWithCursor(t *testing.T) {
+	pages := map[string]map[string]interface{}{
+		"/query/": {"totalSize": 5, "done": false, "nextRecordsUrl": "/services/data/v53.0/query/01gA-2",
+			"records": []map[string]interface{}{{"Id": "003A"}, {"Id": "003B"}}},
+		"/services/data/v53.0/query/01gA-2": {"totalSize": 5, "done": false, "nextRecordsUrl": "/services/data/v53.0/query/01gA-4",
+			"records": []map[string]interface{}{{"Id": "003C"}, {"Id": "003D"}}},
+		"/services/data/v53.0/query/01gA-4": {"totalSize": 5, "done": true,
+			"records": []map[string]interface{}{{"Id": "003E"}}},
+	}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/data/v53.0/query/01gX-2" {
+			http.Error(w, `[{"errorCode":"INVALID_QUERY_LOCATOR","message":"invalid query locator"}]`, http.StatusBadRequest)
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		encodeObject(w, page)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	// stop after first page and save cursor
+	var contacts []Contact
+	cursor := &salesforce.QueryCursor{PageFunc: func(ctx context.Context, c *salesforce.QueryCursor) error {
+		return salesforce.ErrStopPaging
+	}}
+	if err := sv.QueryWithCursor(ctx, "SELECT Id FROM Contact", &contacts, cursor); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(contacts) != 2 || cursor.Fetched != 2 || cursor.Done || cursor.TotalSize != 5 ||
+		cursor.NextRecordsURL != "/services/data/v53.0/query/01gA-2" || cursor.Updated.IsZero() {
+		t.Fatalf("unexpected cursor %#v", cursor)
+	}
+	b, _ := json.Marshal(cursor)
+
+	// resume with a deserialized cursor truncating results after each page
+	var saved salesforce.QueryCursor
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatalf("cursor unmarshal failed %v", err)
+	}
+	var ids []string
+	contacts = nil
+	saved.PageFunc = func(ctx context.Context, c *salesforce.QueryCursor) error {
+		for _, c := range contacts {
+			ids = append(ids, c.ContactID)
+		}
+		contacts = contacts[:0]
+		return nil
+	}
+	if err := sv.ResumeQuery(ctx, &saved, &contacts); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(ids) != 3 || ids[2] != "003E" || !saved.Done || saved.Fetched != 5 || saved.NextRecordsURL != "" {
+		t.Errorf("unexpected resume %v %#v", ids, saved)
+	}
+	if err := sv.ResumeQuery(ctx, &saved, &contacts); err != nil {
+		t.Errorf("expected done cursor to return nil; got %v", err)
+	}
+
+	expired := &salesforce.QueryCursor{NextRecordsURL: "/services/data/v53.0/query/01gX-2"}
+	if err := sv.ResumeQuery(ctx, expired, &contacts); !salesforce.IsInvalidQueryLocator(err) {
+		t.Errorf("expected invalid query locator; got %v", err)
+	}
+	if err := sv.ResumeQuery(ctx, &salesforce.QueryCursor{}, &contacts); err == nil {
+		t.Errorf("expected error for cursor without nextRecordsUrl")
+	}
+}
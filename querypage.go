@@ -0,0 +1,69 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+)
+
+// RawQueryResponse is a page of query results whose records are not decoded.  Use
+// TotalSize to preallocate results or estimate progress and NextRecordsURL to request
+// the following page with QueryNextPage.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/dome_query.htm
+type RawQueryResponse struct {
+	TotalSize      int               `json:"totalSize"`
+	Done           bool              `json:"done"`
+	NextRecordsURL string            `json:"nextRecordsUrl,omitempty"`
+	Records        []json.RawMessage `json:"records"`
+}
+
+// Decode appends the page's records to results which must be of the
+// form accepted by Query.
+func (rq *RawQueryResponse) Decode(results interface{}) error {
+	rs, err := NewRecordSlice(results)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(rq.Records)
+	if err != nil {
+		return err
+	}
+	return rs.UnmarshalJSON(b)
+}
+
+// QueryFirstPage executes qry returning the first page of results without decoding records.
+// To include deleted records, set queryAll to true.  The service's QueryBatchSize (see
+// WithQueryBatchSize) determines the page size.
+func (sv *Service) QueryFirstPage(ctx context.Context, qry string, queryAll bool) (*RawQueryResponse, error) {
+	path := "query/?q="
+	if queryAll {
+		path = "queryAll/?q="
+	}
+	return sv.queryPage(ctx, path+url.QueryEscape(sv.forClause.Append(qry)))
+}
+
+// QueryNextPage returns the page of results found at the nextRecordsURL of a previous page
+func (sv *Service) QueryNextPage(ctx context.Context, nextRecordsURL string) (*RawQueryResponse, error) {
+	if nextRecordsURL == "" {
+		return nil, errors.New("nextRecordsURL may not be empty")
+	}
+	return sv.queryPage(ctx, nextRecordsURL)
+}
+
+func (sv *Service) queryPage(ctx context.Context, path string) (*RawQueryResponse, error) {
+	qsv := sv.querySvc()
+	var res *RawQueryResponse
+	if err := qsv.Call(ctx, path, "GET", nil, &res); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("empty query response")
+	}
+	return res, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_QueryFirstPage(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/queryAll/":
+			if r.URL.Query().Get("q") != "SELECT Id, LastName FROM Contact" {
+				http.Error(w, "bad query", http.StatusBadRequest)
+				return
+			}
+			encodeObject(w, map[string]interface{}{"totalSize": 3, "done": false, "nextRecordsUrl": "/query/01gA-2",
+				"records": []map[string]interface{}{{"Id": "003A", "LastName": "Smith"}, {"Id": "003B", "LastName": "Jones"}}})
+		case "/query/01gA-2":
+			encodeObject(w, map[string]interface{}{"totalSize": 3, "done": true,
+				"records": []map[string]interface{}{{"Id": "003C", "LastName": "Brown"}}})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	page, err := sv.QueryFirstPage(ctx, "SELECT Id, LastName FROM Contact", true)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if page.TotalSize != 3 || page.Done || page.NextRecordsURL != "/query/01gA-2" || len(page.Records) != 2 {
+		t.Fatalf("unexpected first page %#v", page)
+	}
+	contacts := make([]Contact, 0, page.TotalSize)
+	if err := page.Decode(&contacts); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if page, err = sv.QueryNextPage(ctx, page.NextRecordsURL); err != nil || !page.Done {
+		t.Fatalf("expected last page; got %v %v", page, err)
+	}
+	if err := page.Decode(&contacts); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(contacts) != 3 || contacts[0].LastName != "Smith" || contacts[2].ContactID != "003C" {
+		t.Errorf("unexpected contacts %v", contacts)
+	}
+	if _, err := sv.QueryNextPage(ctx, ""); err == nil {
+		t.Errorf("expected error for empty nextRecordsURL")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"reflect"
+)
+
+// QueryJobStream waits for the query job to complete and sends each row of its csv
+// results to ch, a channel (chan T or chan<- T) of structs, struct pointers or RecordMaps,
+// as the row is decoded.  Result pages are downloaded using the job's locators and parsed
+// using the job's ColumnDelimiter; quoted values may contain delimiters and newlines.  Rows
+// are decoded as by QueryBulk, and the service's QueryBulkOptions (see WithQueryBulkOptions)
+// set the poll interval and page size.  ch is closed when QueryJobStream returns, so consume
+// ch in a separate goroutine and check the returned error after ch is closed.
+//
+//	ch, errCh := make(chan Contact, 100), make(chan error, 1)
+//	go func() { errCh <- sv.QueryJobStream(ctx, jobID, ch) }()
+//	for c := range ch {
+//		// process c
+//	}
+//	err := <-errCh
+//
+// https://developer.salesforce.com/docs/atlas.en-us.api_bulk_v2.meta/api_bulk_v2/query_get_job_results.htm
+func (sv *Service) QueryJobStream(ctx context.Context, jobID string, ch interface{}) error {
+	target, err := newChanTarget(ctx, ch)
+	if err != nil {
+		return err
+	}
+	defer reflect.ValueOf(ch).Close()
+	var opts QueryBulkOptions
+	if sv.queryBulk != nil {
+		opts = *sv.queryBulk
+	}
+	job, err := sv.WaitQueryJob(ctx, jobID, opts.PollInterval)
+	if err != nil {
+		return err
+	}
+	return sv.decodeQueryJob(ctx, job, opts.MaxRecords, target)
+}
+
+// newChanTarget validates ch as a sendable channel of records and returns a
+// queryTarget sending to ch until ctx is done
+func newChanTarget(ctx context.Context, ch interface{}) (*queryTarget, error) {
+	const expected = "chan <struct>, chan *<struct> or chan RecordMap"
+	ty := reflect.TypeOf(ch)
+	rv := reflect.ValueOf(ch)
+	if ty == nil || ty.Kind() != reflect.Chan || ty.ChanDir()&reflect.SendDir == 0 || rv.IsNil() ||
+		!isRecordType(ty.Elem()) || ty.Elem().Kind() == reflect.Interface {
+		return nil, &TypeError{Expected: expected, Got: ty}
+	}
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: rv},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	return &queryTarget{elemType: ty.Elem(), add: func(v reflect.Value) error {
+		cases[0].Send = v
+		if chosen, _, _ := reflect.Select(cases); chosen == 1 {
+			return ctx.Err()
+		}
+		return nil
+	}}, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_QueryJobStream(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jobs/query/750Q":
+			encodeObject(w, map[string]interface{}{"id": "750Q", "state": "JobComplete", "columnDelimiter": "PIPE"})
+		case "/jobs/query/750Q/results":
+			w.Header().Set("Content-Type", "text/csv")
+			switch r.URL.Query().Get("locator") {
+			case "":
+				w.Header().Set("Sforce-Locator", "L2")
+				io.WriteString(w, "Id|LastName|DoNotCall\n003A|\"Smith|Jones\nJr\"|true\n003B|Brown|false\n")
+			case "L2":
+				w.Header().Set("Sforce-Locator", "null")
+				io.WriteString(w, "Id|LastName|DoNotCall\n003C|Green|\n")
+			}
+		default:
+			http.Error(w, "not found "+r.URL.Path, http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithQueryBulkOptions(&salesforce.QueryBulkOptions{PollInterval: time.Millisecond})
+
+	ch, errCh := make(chan *Contact), make(chan error, 1)
+	go func() { errCh <- sv.QueryJobStream(ctx, "750Q", ch) }()
+	var contacts []*Contact
+	for c := range ch {
+		contacts = append(contacts, c)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(contacts) != 3 || contacts[0].LastName != "Smith|Jones\nJr" || !contacts[0].DoNotCall ||
+		contacts[2].ContactID != "003C" {
+		t.Errorf("unexpected contacts %v", contacts)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	rm := make(chan salesforce.RecordMap)
+	go func() { errCh <- sv.QueryJobStream(cctx, "750Q", rm) }()
+	if rec := <-rm; rec["Id"] != "003A" {
+		t.Errorf("expected 003A; got %v", rec)
+	}
+	cancel()
+	for range rm {
+	}
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+
+	var te *salesforce.TypeError
+	if err := sv.QueryJobStream(ctx, "750Q", make(<-chan Contact)); !errors.As(err, &te) {
+		t.Errorf("expected TypeError for receive only channel; got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DailyAPIRequests is the Limits key of the org's rolling 24 hour api call quota
+const DailyAPIRequests = "DailyApiRequests"
+
+// Limit is the maximum and remaining values of an org limit
+type Limit struct {
+	Max       int `json:"Max"`
+	Remaining int `json:"Remaining"`
+}
+
+// Limits returns the org's limits keyed by name (e.g. DailyApiRequests)
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_limits.htm
+func (sv *Service) Limits(ctx context.Context) (map[string]Limit, error) {
+	var res map[string]Limit
+	if err := sv.Call(ctx, "limits", "GET", nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// QuotaScheduler is a Limiter that paces a planned number of calls so that they complete
+// without exhausting the org's daily api quota.  Calls proceed immediately while the
+// remaining quota, less a reserve left for other integrations, covers the planned calls.
+// Once it does not, calls are paced at the rate the rolling 24 hour quota replenishes,
+// pausing batch loops until calls are available.  Set the scheduler on the service making
+// the calls using WithLimiter.
+type QuotaScheduler struct {
+	// RefreshEvery re-reads the org's remaining quota after the number of
+	// calls, adjusting for calls made by other integrations.  A zero value
+	// does not refresh.
+	RefreshEvery int
+
+	sv       *Service
+	reserve  int
+	planned  int
+	m        sync.Mutex
+	max      int
+	tokens   float64
+	last     time.Time
+	acquired int
+}
+
+// NewQuotaScheduler reads the org's DailyApiRequests limit and returns a scheduler pacing
+// planned calls while leaving reserve calls unused.  sv should not use the returned scheduler
+// as its Limiter; use sv.WithLimiter(qs) for the service making the planned calls.
+func (sv *Service) NewQuotaScheduler(ctx context.Context, planned, reserve int) (*QuotaScheduler, error) {
+	qs := &QuotaScheduler{sv: sv, planned: planned, reserve: reserve}
+	if err := qs.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return qs, nil
+}
+
+// Refresh reads the org's remaining daily api calls
+func (qs *QuotaScheduler) Refresh(ctx context.Context) error {
+	limits, err := qs.sv.Limits(ctx)
+	if err != nil {
+		return err
+	}
+	lmt, ok := limits[DailyAPIRequests]
+	if !ok || lmt.Max <= qs.reserve {
+		return errors.New("daily api request limit unavailable or below reserve")
+	}
+	qs.m.Lock()
+	defer qs.m.Unlock()
+	qs.max = lmt.Max
+	qs.tokens = float64(lmt.Remaining - qs.reserve)
+	if qs.tokens < 0 {
+		qs.tokens = 0
+	}
+	qs.last = time.Now()
+	return nil
+}
+
+// rate returns the calls per second replenished by the rolling quota
+func (qs *QuotaScheduler) rate() float64 {
+	return float64(qs.max-qs.reserve) / (24 * time.Hour).Seconds()
+}
+
+// CallsPerHour returns the pace of the remaining planned calls.  A zero
+// value indicates the calls are not paced.
+func (qs *QuotaScheduler) CallsPerHour() float64 {
+	qs.m.Lock()
+	defer qs.m.Unlock()
+	if float64(qs.planned-qs.acquired) <= qs.tokens {
+		return 0
+	}
+	return qs.rate() * 3600
+}
+
+// EstimatedDuration returns the minimum time needed to complete the remaining
+// planned calls within the quota.
+func (qs *QuotaScheduler) EstimatedDuration() time.Duration {
+	qs.m.Lock()
+	defer qs.m.Unlock()
+	over := float64(qs.planned-qs.acquired) - qs.tokens
+	if over <= 0 {
+		return 0
+	}
+	return time.Duration(over / qs.rate() * float64(time.Second))
+}
+
+// Acquire blocks until the quota allows a call or the context is done.
+func (qs *QuotaScheduler) Acquire(ctx context.Context) (func(error), error) {
+	qs.m.Lock()
+	qs.acquired++
+	refresh := qs.RefreshEvery > 0 && qs.acquired%qs.RefreshEvery == 0
+	qs.m.Unlock()
+	if refresh {
+		if err := qs.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		wait := qs.reserveCall()
+		if wait <= 0 {
+			return qs.release, nil
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserveCall takes a token returning 0 or returns the duration
+// to wait for the next token.
+func (qs *QuotaScheduler) reserveCall() time.Duration {
+	qs.m.Lock()
+	defer qs.m.Unlock()
+	now := time.Now()
+	rate := qs.rate()
+	qs.tokens += now.Sub(qs.last).Seconds() * rate
+	if maxTokens := float64(qs.max - qs.reserve); qs.tokens > maxTokens {
+		qs.tokens = maxTokens
+	}
+	qs.last = now
+	if qs.tokens >= 1 {
+		qs.tokens--
+		return 0
+	}
+	return time.Duration((1 - qs.tokens) / rate * float64(time.Second))
+}
+
+// release empties the bucket when salesforce reports the quota is exceeded
+func (qs *QuotaScheduler) release(err error) {
+	if IsLimitError(err) {
+		qs.m.Lock()
+		qs.tokens = 0
+		qs.m.Unlock()
+	}
+}
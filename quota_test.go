@@ -0,0 +1,89 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestQuotaScheduler(t *testing.T) {
+	// a max of 8,640,100 replenishes 100 calls per second above the reserve of 100
+	var remaining = 102
+	var limitCalls int
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limits":
+			limitCalls++
+			encodeObject(w, map[string]interface{}{
+				"DailyApiRequests":    map[string]interface{}{"Max": 8640100, "Remaining": remaining},
+				"DailyBulkApiBatches": map[string]interface{}{"Max": 15000, "Remaining": 15000},
+			})
+		case "/ok":
+			encodeObject(w, map[string]string{"a": "b"})
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	limits, err := sv.Limits(ctx)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if lmt := limits[salesforce.DailyAPIRequests]; lmt.Max != 8640100 || lmt.Remaining != 102 {
+		t.Errorf("unexpected limit %v", lmt)
+	}
+
+	qs, err := sv.NewQuotaScheduler(ctx, 2, 100)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if qs.CallsPerHour() != 0 || qs.EstimatedDuration() != 0 {
+		t.Errorf("expected no pacing; got %v %v", qs.CallsPerHour(), qs.EstimatedDuration())
+	}
+
+	qs, _ = sv.NewQuotaScheduler(ctx, 6, 100)
+	if cph := qs.CallsPerHour(); cph < 359999 || cph > 360001 {
+		t.Errorf("expected 360000 calls per hour; got %v", cph)
+	}
+	if d := qs.EstimatedDuration(); d < 39*time.Millisecond || d > 40*time.Millisecond {
+		t.Errorf("expected 40ms duration; got %v", d)
+	}
+	svq := sv.WithLimiter(qs)
+	start := time.Now()
+	var result map[string]string
+	for i := 0; i < 6; i++ {
+		if err := svq.Call(ctx, "ok", "GET", nil, &result); err != nil {
+			t.Fatalf("expected success; got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected pacing of at least 30ms; got %v", elapsed)
+	}
+
+	remaining = 100
+	qs, _ = sv.NewQuotaScheduler(ctx, 1, 100)
+	qs.RefreshEvery = 1
+	limitCalls = 0
+	tctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if _, err := qs.Acquire(tctx); err != context.DeadlineExceeded {
+		t.Errorf("expected %v; got %v", context.DeadlineExceeded, err)
+	}
+	if limitCalls != 1 {
+		t.Errorf("expected refresh; got %d limit calls", limitCalls)
+	}
+}
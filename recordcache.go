@@ -0,0 +1,120 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordEntry is a record saved in a RecordStore
+type RecordEntry struct {
+	Record  json.RawMessage `json:"record,omitempty"`
+	Fetched time.Time       `json:"fetched"` // time of retrieval
+}
+
+// RecordStore caches records retrieved by Get and GetByExternalID.  Get
+// returns a nil entry when key is not found.  Put with a nil entry removes the key.
+type RecordStore interface {
+	Get(ctx context.Context, key string) (*RecordEntry, error)
+	Put(ctx context.Context, key string, entry *RecordEntry) error
+}
+
+// WithRecordStore returns a service whose Get and GetByExternalID funcs read through
+// store.  Entries are keyed by object, id and fields, and entries younger than ttl are
+// returned without a call.  Use for read heavy services that repeatedly fetch reference
+// data such as RecordTypes and Users.  Updates are not written to the store, so choose
+// a ttl appropriate to the data.  A nil store disables caching.  Caching is skipped
+// for services using an Encoding other than JSON.
+func (sv *Service) WithRecordStore(store RecordStore, ttl time.Duration) *Service {
+	snew := sv.clone()
+	snew.recordStore = store
+	snew.recordTTL = ttl
+	return snew
+}
+
+// RecordKey returns the RecordStore key of a Get or GetByExternalID call
+func (sv *Service) RecordKey(sobjectName, idField, id string, flds ...string) string {
+	sorted := append([]string{}, flds...)
+	for i := range sorted {
+		sorted[i] = strings.ToLower(sorted[i])
+	}
+	sort.Strings(sorted)
+	var base string
+	if sv.baseURL != nil {
+		base = sv.baseURL.Host + sv.baseURL.Path
+	}
+	return base + sobjectName + "/" + idField + "/" + id + "?" + strings.Join(sorted, ",")
+}
+
+// cachedGet decodes the record found in the service's record store or retrieved from path
+func (sv *Service) cachedGet(ctx context.Context, path string, result interface{}, sobjectName, idField, id string, flds []string) error {
+	if sv.recordStore == nil || sv.enc() != JSON {
+		return sv.Call(ctx, path, "GET", nil, result)
+	}
+	key := sv.RecordKey(sobjectName, idField, id, flds...)
+	entry, err := sv.recordStore.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if entry != nil && len(entry.Record) > 0 && time.Since(entry.Fetched) < sv.recordTTL {
+		return json.Unmarshal(entry.Record, result)
+	}
+	var rec json.RawMessage
+	if err := sv.Call(ctx, path, "GET", nil, &rec); err != nil {
+		return err
+	}
+	if err := sv.recordStore.Put(ctx, key, &RecordEntry{Record: rec, Fetched: time.Now()}); err != nil {
+		return err
+	}
+	return json.Unmarshal(rec, result)
+}
+
+// MemoryRecordStore is a RecordStore for the life of a process.  Expired
+// entries are replaced when next retrieved.
+type MemoryRecordStore struct {
+	m       sync.Mutex
+	entries map[string]RecordEntry
+}
+
+// NewMemoryRecordStore creates an empty MemoryRecordStore
+func NewMemoryRecordStore() *MemoryRecordStore {
+	return &MemoryRecordStore{entries: make(map[string]RecordEntry)}
+}
+
+// Get returns a copy of the key's entry
+func (ms *MemoryRecordStore) Get(ctx context.Context, key string) (*RecordEntry, error) {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	entry, ok := ms.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Put saves a copy of entry
+func (ms *MemoryRecordStore) Put(ctx context.Context, key string, entry *RecordEntry) error {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	if entry == nil {
+		delete(ms.entries, key)
+		return nil
+	}
+	ms.entries[key] = *entry
+	return nil
+}
+
+// Clear removes all entries
+func (ms *MemoryRecordStore) Clear() {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	ms.entries = make(map[string]RecordEntry)
+}
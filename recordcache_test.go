@@ -0,0 +1,77 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_WithRecordStore(t *testing.T) {
+	var calls []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		switch r.URL.Path {
+		case "/sobjects/Contact/003A", "/sobjects/Contact/Ext_ID__c/E1":
+			encodeObject(w, map[string]interface{}{"attributes": map[string]string{"type": "Contact"},
+				"Id": "003A", "LastName": "Smith"})
+		default:
+			http.Error(w, `[{"errorCode":"NOT_FOUND","message":"not found"}]`, http.StatusNotFound)
+		}
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	store := salesforce.NewMemoryRecordStore()
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL+"/").WithRecordStore(store, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		var c Contact
+		if err := sv.Get(ctx, &c, "003A", "Id", "LastName"); err != nil || c.LastName != "Smith" || c.ContactID != "003A" {
+			t.Fatalf("get %d: expected Smith; got %v %v", i, c, err)
+		}
+	}
+	var c Contact
+	if err := sv.Get(ctx, &c, "003A", "lastname", "Id"); err != nil || c.LastName != "Smith" {
+		t.Fatalf("expected Smith; got %v %v", c, err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("expected 1 call for cached gets; got %d", len(calls))
+	}
+	if err := sv.GetByExternalID(ctx, &c, "Ext_ID__c", "E1", "Id", "LastName"); err != nil || len(calls) != 2 {
+		t.Errorf("expected external id call; got %v %d", err, len(calls))
+	}
+	if err := sv.Get(ctx, &c, "003B", "Id"); err == nil {
+		t.Errorf("expected not found error")
+	}
+	if err := sv.Get(ctx, &c, "003B", "Id"); err == nil || len(calls) != 4 {
+		t.Errorf("expected errors not cached; got %v %d", err, len(calls))
+	}
+
+	store.Clear()
+	if err := sv.Get(ctx, &c, "003A", "Id", "LastName"); err != nil || len(calls) != 5 {
+		t.Errorf("expected call after clear; got %v %d", err, len(calls))
+	}
+	key := sv.RecordKey("Contact", "Id", "003A", "LastName", "Id")
+	entry, _ := store.Get(ctx, key)
+	if entry == nil {
+		t.Fatalf("expected entry for %s", key)
+	}
+	entry.Fetched = time.Now().Add(-time.Hour)
+	store.Put(ctx, key, entry)
+	if err := sv.Get(ctx, &c, "003A", "Id", "LastName"); err != nil || len(calls) != 6 {
+		t.Errorf("expected call for expired entry; got %v %d", err, len(calls))
+	}
+	if err := sv.WithRecordStore(nil, 0).Get(ctx, &c, "003A", "Id", "LastName"); err != nil || len(calls) != 7 {
+		t.Errorf("expected call without store; got %v %d", err, len(calls))
+	}
+}
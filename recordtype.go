@@ -0,0 +1,109 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordTypeCache holds the record type ids of an object keyed by
+// lowercase developer name.  Entries are keyed by instance and object.
+var recordTypeCache = struct {
+	m       sync.Mutex
+	entries map[string]map[string]string
+}{entries: make(map[string]map[string]string)}
+
+// ClearRecordTypeCache removes the record type ids cached by RecordTypeID for services
+// without a RecordStore, so that record types added or renamed since are found.
+func ClearRecordTypeCache() {
+	recordTypeCache.m.Lock()
+	recordTypeCache.entries = make(map[string]map[string]string)
+	recordTypeCache.m.Unlock()
+}
+
+// RecordTypeID returns the id of the object's record type with developerName, so ids need
+// not be hard-coded for each sandbox and production org.  The record types of an object are
+// queried once and saved in the service's RecordStore for its ttl (see WithRecordStore).
+// Without a RecordStore, they are cached per instance for the life of the process or until
+// ClearRecordTypeCache is called.  ErrRecordNotFound is wrapped when no record type exists.
+// https://developer.salesforce.com/docs/atlas.en-us.object_reference.meta/object_reference/sforce_api_objects_recordtype.htm
+func (sv *Service) RecordTypeID(ctx context.Context, object, developerName string) (string, error) {
+	if sv == nil || sv.baseURL == nil {
+		return "", errors.New("nil baseURL")
+	}
+	ids, err := sv.recordTypeIDs(ctx, object)
+	if err != nil {
+		return "", err
+	}
+	if id, ok := ids[strings.ToLower(developerName)]; ok {
+		return id, nil
+	}
+	return "", fmt.Errorf("%s record type %s: %w", object, developerName, ErrRecordNotFound)
+}
+
+// recordTypeIDs returns the record type ids of object from the service's
+// RecordStore or the process cache, querying them when not found.
+func (sv *Service) recordTypeIDs(ctx context.Context, object string) (map[string]string, error) {
+	if sv.recordStore != nil {
+		key := sv.RecordKey("RecordType", "SobjectType", strings.ToLower(object))
+		entry, err := sv.recordStore.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		var ids map[string]string
+		if entry != nil && len(entry.Record) > 0 && time.Since(entry.Fetched) < sv.recordTTL {
+			return ids, json.Unmarshal(entry.Record, &ids)
+		}
+		if ids, err = sv.queryRecordTypeIDs(ctx, object); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(ids)
+		if err != nil {
+			return nil, err
+		}
+		return ids, sv.recordStore.Put(ctx, key, &RecordEntry{Record: b, Fetched: time.Now()})
+	}
+	key := sv.baseURL.Host + sv.baseURL.Path + strings.ToLower(object)
+	recordTypeCache.m.Lock()
+	ids, ok := recordTypeCache.entries[key]
+	recordTypeCache.m.Unlock()
+	if ok {
+		return ids, nil
+	}
+	ids, err := sv.queryRecordTypeIDs(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	recordTypeCache.m.Lock()
+	recordTypeCache.entries[key] = ids
+	recordTypeCache.m.Unlock()
+	return ids, nil
+}
+
+// queryRecordTypeIDs queries the record types of object returning
+// their ids keyed by lowercase developer name
+func (sv *Service) queryRecordTypeIDs(ctx context.Context, object string) (map[string]string, error) {
+	var recs []struct {
+		ID            string `json:"Id"`
+		DeveloperName string `json:"DeveloperName"`
+	}
+	qsv := sv.clone()
+	qsv.forClause, qsv.maxrows = "", 0
+	if err := qsv.Query(ctx, "SELECT Id, DeveloperName FROM RecordType WHERE SobjectType = "+SOQLString(object), &recs); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string)
+	for _, r := range recs {
+		ids[strings.ToLower(r.DeveloperName)] = r.ID
+	}
+	return ids, nil
+}
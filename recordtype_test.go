@@ -0,0 +1,79 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestService_RecordTypeID(t *testing.T) {
+	var qrys []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qry := r.URL.Query().Get("q")
+		qrys = append(qrys, qry)
+		var recs = []map[string]string{}
+		if qry == "SELECT Id, DeveloperName FROM RecordType WHERE SobjectType = 'Account'" {
+			recs = append(recs, map[string]string{"Id": "012A", "DeveloperName": "Business"},
+				map[string]string{"Id": "012B", "DeveloperName": "Person_Account"})
+		}
+		encodeObject(w, map[string]interface{}{"totalSize": len(recs), "done": true, "records": recs})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithForClause(salesforce.ForUpdate)
+
+	tests := []struct {
+		object string
+		name   string
+		want   string
+	}{
+		{object: "Account", name: "Business", want: "012A"},
+		{object: "account", name: "person_account", want: "012B"},
+		{object: "Account", name: "Missing"},
+		{object: "Contact", name: "Business"},
+	}
+	for _, tt := range tests {
+		id, err := sv.RecordTypeID(ctx, tt.object, tt.name)
+		if tt.want == "" {
+			if !errors.Is(err, salesforce.ErrRecordNotFound) {
+				t.Errorf("%s %s: expected ErrRecordNotFound; got %v", tt.object, tt.name, err)
+			}
+			continue
+		}
+		if err != nil || id != tt.want {
+			t.Errorf("%s %s: expected %s; got %s %v", tt.object, tt.name, tt.want, id, err)
+		}
+	}
+	if len(qrys) != 2 {
+		t.Errorf("expected 2 cached queries; got %q", qrys)
+	}
+	salesforce.ClearRecordTypeCache()
+	if _, err := sv.RecordTypeID(ctx, "Account", "Business"); err != nil || len(qrys) != 3 {
+		t.Errorf("expected query after clear; got %d queries %v", len(qrys), err)
+	}
+
+	// a RecordStore caches record types for its ttl
+	qrys = nil
+	store := salesforce.NewMemoryRecordStore()
+	ssv := sv.WithRecordStore(store, time.Hour)
+	for i := 0; i < 2; i++ {
+		if id, err := ssv.RecordTypeID(ctx, "Account", "Person_Account"); err != nil || id != "012B" {
+			t.Errorf("store %d: expected 012B; got %s %v", i, id, err)
+		}
+	}
+	if _, err := sv.WithRecordStore(store, 0).RecordTypeID(ctx, "Account", "Business"); err != nil || len(qrys) != 2 {
+		t.Errorf("expected 1 cached and 1 expired store query; got %d queries %v", len(qrys), err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// MaxRefIDLength is the maximum length of a reference id generated by RefIDs.
+// Longer ids are shortened and suffixed with a checksum of the full id.
+const MaxRefIDLength = 80
+
+// ErrRefIDCollision is returned by RefIDs when different keys generate the same
+// reference id
+var ErrRefIDCollision = errors.New("reference id collision")
+
+// RefIDs generates unique, deterministic reference ids for the records of composite
+// trees, graphs and CreateRecordsWithRefs.  A reference id is built from a prefix,
+// generally the SObject name, and a key such as an external id, with characters
+// other than letters, digits and underscores replaced by underscores.  The same
+// prefix and key always return the same id, so a parent's reference may be computed
+// when building a child record.  A RefIDs is safe for concurrent use.
+type RefIDs struct {
+	m    sync.Mutex
+	keys map[string]string // reference id to prefix and key
+	seq  map[string]int    // last sequence number of each prefix
+}
+
+// NewRefIDs returns an empty reference id generator
+func NewRefIDs() *RefIDs {
+	return &RefIDs{keys: make(map[string]string), seq: make(map[string]int)}
+}
+
+var refIDInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// refIDFor returns the reference id of prefix and key
+func refIDFor(prefix, key string) string {
+	id := refIDInvalid.ReplaceAllString(prefix, "_") + "_" + refIDInvalid.ReplaceAllString(key, "_")
+	if c := id[0]; !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+		id = "ref" + id
+	}
+	if len(id) > MaxRefIDLength {
+		id = fmt.Sprintf("%s_%08x", id[:MaxRefIDLength-9], crc32.ChecksumIEEE([]byte(prefix+"\x00"+key)))
+	}
+	return id
+}
+
+// Ref returns the reference id of prefix and key.  An error wrapping ErrRefIDCollision
+// is returned when a different prefix and key previously generated the same id
+// (e.g. keys A-1 and A_1).
+func (r *RefIDs) Ref(prefix, key string) (string, error) {
+	if key == "" {
+		return "", errors.New("reference key may not be empty")
+	}
+	id := refIDFor(prefix, key)
+	r.m.Lock()
+	defer r.m.Unlock()
+	return id, r.claim(id, prefix+"\x00"+key)
+}
+
+// claim registers id for src.  Caller must hold lock.
+func (r *RefIDs) claim(id, src string) error {
+	if r.keys == nil {
+		r.keys, r.seq = make(map[string]string), make(map[string]int)
+	}
+	if prev, ok := r.keys[id]; ok && prev != src {
+		return fmt.Errorf("%w: %s generated by %q and %q", ErrRefIDCollision, id, prev, src)
+	}
+	r.keys[id] = src
+	return nil
+}
+
+// ForRecord returns the reference id of rec using its SObject name as prefix and the
+// value of field, the json name of a struct field or a RecordMap key, as key.  An
+// empty field uses the record's id (see RecordID).
+func (r *RefIDs) ForRecord(rec SObject, field string) (string, error) {
+	if rec == nil {
+		return "", errors.New("nil record")
+	}
+	var key string
+	if field == "" {
+		key = RecordID(rec)
+	} else {
+		key = fieldValue(rec, field)
+	}
+	if key == "" {
+		return "", fmt.Errorf("%s record has no value for reference key %q", rec.SObjectName(), field)
+	}
+	return r.Ref(rec.SObjectName(), key)
+}
+
+// Next returns a sequential reference id (e.g. Contact_1, Contact_2) for records
+// without a natural key.  Ids are deterministic in call order and skip ids already
+// generated.
+func (r *RefIDs) Next(prefix string) string {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for {
+		if r.seq == nil {
+			r.keys, r.seq = make(map[string]string), make(map[string]int)
+		}
+		r.seq[prefix]++
+		key := strconv.Itoa(r.seq[prefix])
+		if id := refIDFor(prefix, key); r.keys[id] == "" {
+			r.keys[id] = prefix + "\x00#" + key // sequence ids collide with any Ref
+			return id
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestRefIDs(t *testing.T) {
+	refs := salesforce.NewRefIDs()
+	id, err := refs.Ref("Account", "VN-001")
+	if err != nil || id != "Account_VN_001" {
+		t.Fatalf("expected Account_VN_001; got %s %v", id, err)
+	}
+	if again, err := refs.Ref("Account", "VN-001"); err != nil || again != id {
+		t.Errorf("expected same id for same key; got %s %v", again, err)
+	}
+	if _, err := refs.Ref("Account", "VN 001"); !errors.Is(err, salesforce.ErrRefIDCollision) {
+		t.Errorf("expected collision; got %v", err)
+	}
+	if id, _ := refs.Ref("", "1"); id != "ref_1" {
+		t.Errorf("expected id to begin with a letter; got %s", id)
+	}
+	long, err := refs.Ref("Contact", strings.Repeat("x", 100))
+	if err != nil || len(long) != salesforce.MaxRefIDLength {
+		t.Errorf("expected shortened id; got %d %v", len(long), err)
+	}
+	if other, _ := refs.Ref("Contact", strings.Repeat("x", 101)); other == long || len(other) != salesforce.MaxRefIDLength {
+		t.Errorf("expected distinct shortened ids; got %s", other)
+	}
+
+	id, err = refs.ForRecord(&Account{AccountName: "A", VendorID: "VN.002"}, "Vendor_ID__c")
+	if err != nil || id != "Account_VN_002" {
+		t.Errorf("expected Account_VN_002; got %s %v", id, err)
+	}
+	id, err = refs.ForRecord(salesforce.RecordMap{"attributes": map[string]interface{}{"type": "Lead"}, "Id": "00Q1"}, "")
+	if err != nil || id != "Lead_00Q1" {
+		t.Errorf("expected Lead_00Q1; got %s %v", id, err)
+	}
+	if _, err := refs.ForRecord(&Account{}, "Vendor_ID__c"); err == nil {
+		t.Errorf("expected error for empty key")
+	}
+
+	if _, err := refs.Ref("Case", "2"); err != nil {
+		t.Fatalf("Ref: %v", err)
+	}
+	if a, b := refs.Next("Case"), refs.Next("Case"); a != "Case_1" || b != "Case_3" {
+		t.Errorf("expected Case_1 and Case_3; got %s %s", a, b)
+	}
+	if _, err := refs.Ref("Case", "1"); !errors.Is(err, salesforce.ErrRefIDCollision) {
+		t.Errorf("expected collision with sequence id; got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// remapQuerySize is the maximum number of values in the IN clause of a remap query
+const remapQuerySize = 200
+
+// RemapLookup identifies a lookup field translated by IDMapper.Remap
+type RemapLookup struct {
+	Field   string // lookup field of the records (e.g. AccountId)
+	SObject string // object referenced by the lookup (e.g. Account)
+	// ExternalIDField is the field of SObject matching records between orgs.  Empty
+	// indicates the target service's ExternalIDField(SObject) (see WithExternalIDs).
+	ExternalIDField string
+	// ClearUnmatched sets lookups without a matching target record to nil (or the
+	// zero value of a struct field).  Otherwise unmatched lookups are unchanged.
+	ClearUnmatched bool
+}
+
+// RemapError lists the source ids of lookups without a matching target record
+type RemapError struct {
+	Unmatched map[string][]string // lookup field to source ids
+}
+
+// Error lists the unmatched ids by field
+func (e *RemapError) Error() string {
+	var flds = make([]string, 0, len(e.Unmatched))
+	for f := range e.Unmatched {
+		flds = append(flds, f)
+	}
+	sort.Strings(flds)
+	var msgs = make([]string, 0, len(flds))
+	for _, f := range flds {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f, strings.Join(e.Unmatched[f], ",")))
+	}
+	return "unmatched lookups; " + strings.Join(msgs, "; ")
+}
+
+// IDMapper translates record ids of a source org, such as production, to the ids of
+// the same records in a target org, such as a refreshed sandbox, by matching external id
+// values.  Matched ids are cached, so a single IDMapper should be used for the loads of
+// a migration.  An IDMapper is safe for concurrent use.
+type IDMapper struct {
+	source *Service
+	target *Service
+	m      sync.Mutex
+	ids    map[string]map[string]string // sobject to source id to target id; empty indicates no match
+}
+
+// NewIDMapper returns an IDMapper that reads external ids from source and
+// matches them to records of target
+func NewIDMapper(source, target *Service) *IDMapper {
+	return &IDMapper{source: source, target: target, ids: make(map[string]map[string]string)}
+}
+
+// Map returns the target ids of the sobject's source ids keyed by source id.  Records
+// are matched using extField, and an empty extField indicates the target service's
+// ExternalIDField(sobject).  Source ids without a match are not in the returned map.
+func (im *IDMapper) Map(ctx context.Context, sobject, extField string, ids []string) (map[string]string, error) {
+	extField, err := im.target.externalIDField(sobject, extField)
+	if err != nil {
+		return nil, err
+	}
+	im.m.Lock()
+	cache := im.ids[sobject]
+	if cache == nil {
+		cache = make(map[string]string)
+		im.ids[sobject] = cache
+	}
+	var missing []string
+	var seen = make(map[string]bool)
+	for _, id := range ids {
+		if _, ok := cache[id]; !ok && id > "" && !seen[id] {
+			missing = append(missing, id)
+			seen[id] = true
+		}
+	}
+	im.m.Unlock()
+
+	for len(missing) > 0 {
+		chunk := missing
+		if len(chunk) > remapQuerySize {
+			chunk = chunk[:remapQuerySize]
+		}
+		missing = missing[len(chunk):]
+		found, err := im.match(ctx, sobject, extField, chunk)
+		if err != nil {
+			return nil, err
+		}
+		im.m.Lock()
+		for _, id := range chunk {
+			cache[id] = found[id]
+		}
+		im.m.Unlock()
+	}
+
+	var results = make(map[string]string)
+	im.m.Lock()
+	defer im.m.Unlock()
+	for _, id := range ids {
+		if tid := cache[id]; tid > "" {
+			results[id] = tid
+		}
+	}
+	return results, nil
+}
+
+// match queries the external ids of source ids and returns the target
+// ids of records with the same external ids
+func (im *IDMapper) match(ctx context.Context, sobject, extField string, ids []string) (map[string]string, error) {
+	var srcRecs, tgtRecs []RecordMap
+	if err := im.source.Query(ctx, SOQLf("SELECT Id, "+extField+" FROM "+sobject+" WHERE Id IN %v", ids), &srcRecs); err != nil {
+		return nil, fmt.Errorf("source query: %w", err)
+	}
+	var extToSource = make(map[string][]string)
+	var extValues []string
+	for _, r := range srcRecs {
+		ext := fieldValue(r, extField)
+		if ext == "" {
+			continue
+		}
+		if _, ok := extToSource[ext]; !ok {
+			extValues = append(extValues, ext)
+		}
+		extToSource[ext] = append(extToSource[ext], fieldValue(r, "Id"))
+	}
+	var found = make(map[string]string)
+	if len(extValues) == 0 {
+		return found, nil
+	}
+	if err := im.target.Query(ctx, SOQLf("SELECT Id, "+extField+" FROM "+sobject+" WHERE "+extField+" IN %v", extValues), &tgtRecs); err != nil {
+		return nil, fmt.Errorf("target query: %w", err)
+	}
+	for _, r := range tgtRecs {
+		for _, srcID := range extToSource[fieldValue(r, extField)] {
+			found[srcID] = fieldValue(r, "Id")
+		}
+	}
+	return found, nil
+}
+
+// Remap replaces the source ids in the lookup fields of recs with target ids.  recs must
+// be RecordMaps or pointers to structs whose json tags are field names.  Lookups without
+// a matching target record are returned in a *RemapError after all recs are updated.
+func (im *IDMapper) Remap(ctx context.Context, recs []SObject, lookups ...RemapLookup) error {
+	var unmatched = make(map[string][]string)
+	for _, lk := range lookups {
+		if lk.Field == "" || lk.SObject == "" {
+			return errors.New("remap lookup must have a field and sobject")
+		}
+		var ids []string
+		for _, rec := range recs {
+			if id := fieldValue(rec, lk.Field); id > "" {
+				ids = append(ids, id)
+			}
+		}
+		idMap, err := im.Map(ctx, lk.SObject, lk.ExternalIDField, ids)
+		if err != nil {
+			return fmt.Errorf("%s: %w", lk.Field, err)
+		}
+		var missing = make(map[string]bool)
+		for _, rec := range recs {
+			id := fieldValue(rec, lk.Field)
+			if id == "" {
+				continue
+			}
+			tid, ok := idMap[id]
+			if !ok {
+				if !missing[id] {
+					unmatched[lk.Field] = append(unmatched[lk.Field], id)
+					missing[id] = true
+				}
+				if !lk.ClearUnmatched {
+					continue
+				}
+			}
+			if err := setLookup(rec, lk.Field, tid); err != nil {
+				return err
+			}
+		}
+	}
+	if len(unmatched) > 0 {
+		return &RemapError{Unmatched: unmatched}
+	}
+	return nil
+}
+
+// setLookup sets the field of rec to id.  An empty id sets a RecordMap value to
+// nil and a struct field to its zero value.
+func setLookup(rec SObject, field, id string) error {
+	if m, ok := rec.(RecordMap); ok {
+		if id == "" {
+			m[field] = nil
+			return nil
+		}
+		m[field] = id
+		return nil
+	}
+	rv := reflect.ValueOf(rec)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%s record must be a RecordMap or struct pointer", rec.SObjectName())
+	}
+	idx, ok := jsonfield.Index(rv.Elem().Type())[field]
+	if !ok {
+		return fmt.Errorf("%s record has no field %s", rec.SObjectName(), field)
+	}
+	fv := rv.Elem().FieldByIndex(idx)
+	fv.Set(reflect.Zero(fv.Type()))
+	return setFieldFromString(fv, id)
+}
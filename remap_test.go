@@ -0,0 +1,92 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestIDMapper_Remap(t *testing.T) {
+	var srcQueries, tgtQueries []string
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qry := r.URL.Query().Get("q")
+		srcQueries = append(srcQueries, qry)
+		var recs []map[string]interface{}
+		for _, id := range []string{"001S1", "001S2", "001S3"} {
+			if strings.Contains(qry, "'"+id+"'") {
+				recs = append(recs, map[string]interface{}{"Id": id, "Vendor_ID__c": "V" + id[4:]})
+			}
+		}
+		encodeObject(w, map[string]interface{}{"done": true, "records": recs})
+	}))
+	defer src.Close()
+	tgt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qry := r.URL.Query().Get("q")
+		tgtQueries = append(tgtQueries, qry)
+		var recs []map[string]interface{}
+		for _, ext := range []string{"V1", "V2"} {
+			if strings.Contains(qry, "'"+ext+"'") {
+				recs = append(recs, map[string]interface{}{"Id": "001T" + ext[1:], "Vendor_ID__c": ext})
+			}
+		}
+		encodeObject(w, map[string]interface{}{"done": true, "records": recs})
+	}))
+	defer tgt.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	source := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(src.URL + "/")
+	target := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(tgt.URL + "/").WithExternalIDs(map[string]string{"Account": "Vendor_ID__c"})
+	im := salesforce.NewIDMapper(source, target)
+
+	c1 := &Contact{LastName: "A", AccountID: "001S1"}
+	c3 := &Contact{LastName: "C", AccountID: "001S3"}
+	recs := []salesforce.SObject{
+		c1,
+		salesforce.RecordMap{"attributes": map[string]interface{}{"type": "Contact"}, "AccountId": "001S2"},
+		c3,
+		&Contact{LastName: "D"},
+	}
+	err := im.Remap(ctx, recs, salesforce.RemapLookup{Field: "AccountId", SObject: "Account"})
+	var re *salesforce.RemapError
+	if !errors.As(err, &re) || len(re.Unmatched["AccountId"]) != 1 || re.Unmatched["AccountId"][0] != "001S3" {
+		t.Fatalf("expected 001S3 unmatched; got %v", err)
+	}
+	if c1.AccountID != "001T1" || recs[1].(salesforce.RecordMap)["AccountId"] != "001T2" || c3.AccountID != "001S3" {
+		t.Errorf("unexpected remap %v %v %v", c1.AccountID, recs[1], c3.AccountID)
+	}
+	want := "SELECT Id, Vendor_ID__c FROM Account WHERE Id IN ('001S1','001S2','001S3')"
+	if len(srcQueries) != 1 || srcQueries[0] != want {
+		t.Errorf("expected source query %s; got %v", want, srcQueries)
+	}
+	want = "SELECT Id, Vendor_ID__c FROM Account WHERE Vendor_ID__c IN ('V1','V2','V3')"
+	if len(tgtQueries) != 1 || tgtQueries[0] != want {
+		t.Errorf("expected target query %s; got %v", want, tgtQueries)
+	}
+
+	// cached ids are not queried again
+	c3.AccountID = "001S3"
+	c1.AccountID = "001S1"
+	err = im.Remap(ctx, []salesforce.SObject{c1, c3}, salesforce.RemapLookup{Field: "AccountId", SObject: "Account", ClearUnmatched: true})
+	if !errors.As(err, &re) || c1.AccountID != "001T1" || c3.AccountID != "" {
+		t.Errorf("expected cleared lookup; got %v %s %s", err, c1.AccountID, c3.AccountID)
+	}
+	if len(srcQueries) != 1 || len(tgtQueries) != 1 {
+		t.Errorf("expected cached ids; got %d %d queries", len(srcQueries), len(tgtQueries))
+	}
+
+	if _, err := im.Map(ctx, "Contact", "", []string{"003S1"}); !errors.Is(err, salesforce.ErrNoExternalIDField) {
+		t.Errorf("expected ErrNoExternalIDField; got %v", err)
+	}
+}
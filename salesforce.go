@@ -11,8 +11,10 @@ package salesforce // import github.org/jfcote87/salesforce
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 )
@@ -25,6 +27,14 @@ type QueryResponse struct {
 	Records        *RecordSlice `json:"records"`
 }
 
+// nextPage returns the NextRecordsURL of a response that is not done
+func (qr *QueryResponse) nextPage() string {
+	if qr.Done {
+		return ""
+	}
+	return qr.NextRecordsURL
+}
+
 // RecordSlice wraps pointer to the result slice allowing
 // custom unmarshaling that adds rows through multiple
 // next record calls
@@ -42,21 +52,41 @@ func (rs *RecordSlice) slice(i, j int) {
 	rs.resultsVal.Set(rs.resultsVal.Slice(i, j))
 }
 
-// NewRecordSlice creates a RecordSlice pointer based upon *[]<struct> of the results
-// parameter; error created when result is an invalid type
+// NewRecordSlice creates a RecordSlice pointer based upon the *[]<struct> or *[]*<struct>
+// results parameter.  A slice of pointers avoids copying large structs when appending
+// rows.  Slices of maps (e.g. []RecordMap) and interfaces are also accepted.  An error
+// is returned when results is an invalid type.
 func NewRecordSlice(results interface{}) (*RecordSlice, error) {
-	ptr := reflect.ValueOf(results)
-	pType := ptr.Type()
-	if pType.Kind() != reflect.Ptr || pType.Elem().Kind() != reflect.Slice {
-		return nil, fmt.Errorf("expected *[]<struct>; got %v", ptr.Type())
+	const expected = "*[]<struct> or *[]*<struct>"
+	if results == nil {
+		return nil, errors.New("results parameter may not be nil")
+	}
+	slice, err := validatePtrTo(results, reflect.Slice, expected)
+	if err != nil {
+		return nil, err
+	}
+	if elem := slice.Type().Elem(); !isRecordType(elem) {
+		return nil, &TypeError{Expected: expected, Got: reflect.TypeOf(results),
+			Hint: fmt.Sprintf("%s is not a struct, pointer to a struct or map", elem)}
 	}
-	slice := ptr.Elem()
 	return &RecordSlice{
 		resultsVal:  slice,
 		resultsType: slice.Type(),
 	}, nil
 }
 
+// isRecordType returns true if ty may be decoded from a record
+func isRecordType(ty reflect.Type) bool {
+	if ty.Kind() == reflect.Ptr {
+		return ty.Elem().Kind() == reflect.Struct
+	}
+	switch ty.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
 // UnmarshalJSON creates a temporary slice for unmarshaling a call.  This temp
 // slice is then appended to the resultsVal
 func (rs *RecordSlice) UnmarshalJSON(b []byte) error {
@@ -207,28 +237,6 @@ func (t *Time) UnmarshalText(b []byte) error {
 	return nil
 }
 
-// Binary handles base64Binary type
-type Binary []byte
-
-// MarshalJSON handles outputting json of base64Binary.  Empty value
-// outputs null, a nil ptr is omitted with omitempty.
-func (b Binary) MarshalJSON() ([]byte, error) {
-	if len(b) > 0 {
-		return json.Marshal([]byte(b))
-	}
-	return []byte("null"), nil
-}
-
-// UnmarshalJSON does null handling during json decode,
-func (b *Binary) UnmarshalJSON(buff []byte) error {
-	var bx []byte
-	if err := json.Unmarshal(buff, &bx); err != nil {
-		return err
-	}
-	*b = bx
-	return nil
-}
-
 // NullValue represents a sent or received null in json
 type NullValue struct{}
 
@@ -265,29 +273,84 @@ func (m RecordMap) WithAttr(ref string) SObject {
 	return m
 }
 
-// Any is used to unmarshal an SObject json for undetermined objects.
+// Value returns the value found at the dot-notation path of the record.
+// For example, a Contact query selecting Account.Owner.Name may use
+// rec.Value("Account.Owner.Name").  A nil is returned if the path is not found.
+func (m RecordMap) Value(path string) interface{} {
+	var ix interface{} = map[string]interface{}(m)
+	for _, nm := range strings.Split(path, ".") {
+		switch mx := ix.(type) {
+		case map[string]interface{}:
+			ix = mx[nm]
+		case RecordMap:
+			ix = mx[nm]
+		default:
+			return nil
+		}
+	}
+	return ix
+}
+
+// DecodeRelationship decodes a related record returned from a query selecting
+// dot-notation fields (e.g. SELECT Account.Name FROM Contact) into result which
+// must be a pointer to a struct.  rel may be a map[string]interface{}, RecordMap,
+// json.RawMessage or []byte.  Use to convert the map[string]interface{} relationship
+// fields of generated structs into typed structs.
+//
+// var acct Account
+// err := DecodeRelationship(contact.AccountIDRel, &acct)
+func DecodeRelationship(rel interface{}, result interface{}) error {
+	if ty := reflect.TypeOf(result); ty == nil || ty.Kind() != reflect.Ptr || reflect.ValueOf(result).IsNil() {
+		return &TypeError{Expected: "result to be a non-nil pointer", Got: ty, Hint: "did you pass a value instead of a pointer?"}
+	}
+	var b []byte
+	switch rx := rel.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		b = rx
+	case []byte:
+		b = rx
+	default:
+		var err error
+		if b, err = json.Marshal(rel); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(b, result)
+}
+
+// Any is used to unmarshal an SObject json for undetermined objects and compound
+// fields (e.g. addresses, locations and lists).  Raw holds the json of the value.
+// SObject is set only when the value is an object with attributes.
 type Any struct {
 	SObject
+	Raw json.RawMessage
 }
 
-// UnmarshalJSON uses the attributes data to determine the SObject type
-// the registered structs.  SObject
+// UnmarshalJSON saves b as Raw and, when b is an object with attributes, uses the
+// attributes type to decode the SObject into a registered struct (see
+// RegisterSObjectTypes) or a RecordMap.
 func (a *Any) UnmarshalJSON(b []byte) error {
-	dec := json.NewDecoder(bytes.NewReader(b))
+	a.Raw = append(json.RawMessage(nil), b...)
+	a.SObject = nil
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	attrb, ok := fields["attributes"]
+	if !ok {
+		return nil
+	}
 	var attr *Attributes
-	for {
-		t, err := dec.Token()
-		if err == nil {
-			nm, _ := t.(string)
-			if nm == "attributes" {
-				if err = dec.Decode(&attr); err != nil {
-					return fmt.Errorf("attributes decode %w", err)
-				}
-				break
-			}
-			continue
-		}
-		return fmt.Errorf("attributes not found %v", err)
+	if err := json.Unmarshal(attrb, &attr); err != nil {
+		return fmt.Errorf("attributes decode %w", err)
+	}
+	if attr == nil {
+		return nil
 	}
 	sobjPtrVal := sobjCatalog.getNewValue(attr.Type)
 
@@ -298,6 +361,65 @@ func (a *Any) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// MarshalJSON encodes the SObject or, if not set, Raw
+func (a Any) MarshalJSON() ([]byte, error) {
+	switch {
+	case a.SObject != nil:
+		return json.Marshal(a.SObject)
+	case len(a.Raw) > 0:
+		return a.Raw, nil
+	}
+	return []byte("null"), nil
+}
+
+// IsNull returns true when the value is empty or json null
+func (a *Any) IsNull() bool {
+	if a == nil {
+		return true
+	}
+	if a.SObject != nil {
+		return false
+	}
+	raw := bytes.TrimSpace(a.Raw)
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
+
+// AsString returns the value of a json string.  ok is false when the
+// value is not a string.
+func (a *Any) AsString() (s string, ok bool) {
+	if a == nil || a.SObject != nil {
+		return "", false
+	}
+	if err := json.Unmarshal(a.Raw, &s); err != nil {
+		return "", false
+	}
+	return s, bytes.HasPrefix(bytes.TrimSpace(a.Raw), []byte(`"`))
+}
+
+// AsMap returns the value of a json object as a map (e.g. the street, city, etc.
+// of a BillingAddress).  A null value returns a nil map.
+func (a *Any) AsMap() (map[string]interface{}, error) {
+	if a != nil {
+		if rm, ok := a.SObject.(RecordMap); ok {
+			return rm, nil
+		}
+	}
+	var m map[string]interface{}
+	return m, a.Decode(&m)
+}
+
+// Decode unmarshals the value into v (e.g. a *salesforce.Address)
+func (a *Any) Decode(v interface{}) error {
+	if a == nil {
+		return json.Unmarshal([]byte("null"), v)
+	}
+	b, err := a.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
 var mapSObjectStructs = make(map[string]reflect.Type)
 
 var sobjCatalog = &catalog{sobjects: make(map[string]reflect.Type)}
@@ -333,11 +455,36 @@ type SObject interface {
 	WithAttr(string) SObject
 }
 
+// SObjectWithID is an SObject providing access to its record id.  Generated
+// structs implement GetID with a value receiver and SetID with a pointer
+// receiver, so only pointers to generated structs implement SObjectWithID.
+type SObjectWithID interface {
+	SObject
+	GetID() string
+	SetID(string)
+}
+
+// RecordID returns the id of rec when rec implements GetID, is a
+// DeleteID or is a RecordMap.  Otherwise an empty string is returned.
+func RecordID(rec SObject) string {
+	switch r := rec.(type) {
+	case DeleteID:
+		return string(r)
+	case RecordMap:
+		id, _ := r["Id"].(string)
+		return id
+	case interface{ GetID() string }:
+		return r.GetID()
+	}
+	return ""
+}
+
 // Error is the error response for most calls
 type Error struct {
-	StatusCode string   `json:"statusCode,omitempty"`
-	Message    string   `json:"message,omitempty"`
-	Fields     []string `json:"fields,omitempty"`
+	StatusCode      string           `json:"statusCode,omitempty"`
+	Message         string           `json:"message,omitempty"`
+	Fields          []string         `json:"fields,omitempty"`
+	DuplicateResult *DuplicateResult `json:"duplicateResult,omitempty"` // set when blocked by a duplicate rule
 }
 
 // LogError used to report individual record errors
@@ -6,6 +6,7 @@
 package salesforce_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -238,7 +239,8 @@ func TestAny_UnmarshalJSON(t *testing.T) {
 			"Email__c": "bsmith@example.com",
 			"Name__c": "Bob Smith"
 		}`},
-		{name: "noattr", errPrefix: "attributes not found", jsonb: `{
+		// values without attributes are kept in Raw with a nil SObject
+		{name: "noattr", jsonb: `{
 			"Id": "0034S000003Quz6QZX",
 			"IsDeleted": false,
 			"External_ID__c": "ABCDEFG",
@@ -301,6 +303,10 @@ func TestAny_UnmarshalJSON(t *testing.T) {
 				return
 			}
 			switch rec := a.SObject.(type) {
+			case nil:
+				if tt.name != "noattr" || !strings.Contains(string(a.Raw), `"Name__c": "Bob Smith"`) {
+					t.Errorf("%s expected nil SObject with raw json; got %s", tt.name, a.Raw)
+				}
 			case Contact:
 				if rec.AccountID != "0014S000004pwrpQAA" {
 					t.Errorf("expected Contact.AccountID = 0014S000004pwrpQAA; got %s", rec.AccountID)
@@ -320,3 +326,165 @@ func TestAny_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeRelationship(t *testing.T) {
+	var qryRow = []byte(`{
+		"attributes": {"type": "Contact"},
+		"Id": "0034S000003Quz6QAC",
+		"Account": {
+			"attributes": {"type": "Account"},
+			"Name": "Acct Name",
+			"Owner": {"attributes": {"type": "User"}, "Name": "Owner Name"}
+		}
+	}`)
+	var cx Contact
+	if err := json.Unmarshal(qryRow, &cx); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	var acct Account
+	if err := salesforce.DecodeRelationship(cx.AccountIDRel, acct); err == nil {
+		t.Errorf("expected non-pointer error")
+	}
+	if err := salesforce.DecodeRelationship(cx.AccountIDRel, &acct); err != nil || acct.AccountName != "Acct Name" {
+		t.Errorf("expected Acct Name; got %s %v", acct.AccountName, err)
+	}
+
+	var rm salesforce.RecordMap
+	if err := salesforce.DecodeRelationship(json.RawMessage(qryRow), &rm); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if nm, _ := rm.Value("Account.Owner.Name").(string); nm != "Owner Name" {
+		t.Errorf("expected Owner Name; got %v", rm.Value("Account.Owner.Name"))
+	}
+	if v := rm.Value("Account.Name.Missing"); v != nil {
+		t.Errorf("expected nil; got %v", v)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	fl, err := salesforce.Select(Contact{}, "FirstName", "AccountId", "Account.Name")
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if qry := fl.SOQL("WHERE LastName = 'Smith'"); qry != "SELECT FirstName, AccountId, Account.Name FROM Contact WHERE LastName = 'Smith'" {
+		t.Errorf("unexpected soql %s", qry)
+	}
+	if fl = salesforce.MustSelect(&Account{}, "Name"); fl.SOQL("") != "SELECT Name FROM Account" {
+		t.Errorf("unexpected soql %s", fl.SOQL(""))
+	}
+	tests := []struct {
+		sobj   salesforce.SObject
+		fields []string
+		errMsg string
+	}{
+		{Contact{}, []string{"FirstNme"}, "Contact has no field FirstNme"},
+		{Contact{}, []string{"LastName.Name"}, "Contact field LastName is not a relationship"},
+		{Contact{}, nil, "no fields specified"},
+		{salesforce.DeleteID("A"), []string{"Id"}, "salesforce.DeleteID is not a struct"},
+	}
+	for _, tt := range tests {
+		if _, err := salesforce.Select(tt.sobj, tt.fields...); err == nil || err.Error() != tt.errMsg {
+			t.Errorf("expected %s; got %v", tt.errMsg, err)
+		}
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected MustSelect panic")
+		}
+	}()
+	salesforce.MustSelect(Contact{}, "Bad")
+}
+
+func TestFilterFields(t *testing.T) {
+	def := &salesforce.SObjectDefinition{
+		Name: "Contact",
+		Fields: []salesforce.Field{
+			{Name: "Id"},
+			{Name: "FirstName", Createable: true, Updateable: true},
+			{Name: "AccountId", Createable: true, RelationshipName: "Account"},
+		},
+	}
+	tests := []struct {
+		access    salesforce.FieldAccess
+		permitted string
+		dropped   string
+	}{
+		{salesforce.AccessRead, "Id,firstname,AccountId,Account.Name", "Secret__c"},
+		{salesforce.AccessCreate, "firstname,AccountId", "Id,Account.Name,Secret__c"},
+		{salesforce.AccessUpdate, "firstname", "Id,AccountId,Account.Name,Secret__c"},
+	}
+	for _, tt := range tests {
+		permitted, dropped := salesforce.FilterFields(def, tt.access, "Id", "firstname", "AccountId", "Account.Name", "Secret__c")
+		if strings.Join(permitted, ",") != tt.permitted || strings.Join(dropped, ",") != tt.dropped {
+			t.Errorf("access %d: expected %s and %s; got %v and %v", tt.access, tt.permitted, tt.dropped, permitted, dropped)
+		}
+	}
+	fl, dropped := salesforce.MustSelect(Contact{}, "FirstName", "PID__c").Filter(def, salesforce.AccessRead)
+	if fl.SOQL("") != "SELECT FirstName FROM Contact" || len(dropped) != 1 || dropped[0] != "PID__c" {
+		t.Errorf("expected PID__c dropped; got %s %v", fl.SOQL(""), dropped)
+	}
+}
+
+type contactAccount struct {
+	ID         string                 `json:"Id,omitempty"`
+	AccountRel map[string]interface{} `json:"AccountRel,omitempty"`
+	Account    *CustomTable           `json:"Account,omitempty"`
+	skip       string
+}
+
+func TestFieldNames(t *testing.T) {
+	want := "Id,Account.Id,Account.IsDeleted,Account.Name__c,Account.Email__c,Account.External_ID__c"
+	if got := strings.Join(salesforce.FieldNames([]contactAccount{}), ","); got != want {
+		t.Errorf("expected %s; got %s", want, got)
+	}
+	if salesforce.FieldNames(nil) != nil {
+		t.Errorf("expected nil for nil value")
+	}
+}
+
+// ExtraContact mimics a genpkgs struct generated with include_extras
+type ExtraContact struct {
+	Attributes *salesforce.Attributes     `json:"attributes,omitempty"`
+	ContactID  string                     `json:"Id,omitempty"`
+	LastName   string                     `json:"LastName,omitempty"`
+	Extras     map[string]json.RawMessage `json:"-"`
+}
+
+func (c *ExtraContact) UnmarshalJSON(b []byte) error {
+	type record ExtraContact
+	var rec record
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return err
+	}
+	extras, err := salesforce.ExtraFields(b, rec)
+	if err != nil {
+		return err
+	}
+	*c = ExtraContact(rec)
+	c.Extras = extras
+	return nil
+}
+
+func TestExtraFields(t *testing.T) {
+	var contacts []ExtraContact
+	b := []byte(`[{"attributes":{"type":"Contact"},"Id":"003A","lastname":"Smith","Region__c":"West","Score__c":5},
+	{"Id":"003B","LastName":"Jones"}]`)
+	if err := json.Unmarshal(b, &contacts); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if len(contacts) != 2 || contacts[0].LastName != "Smith" || contacts[0].Attributes == nil {
+		t.Fatalf("unexpected decode %#v", contacts)
+	}
+	if len(contacts[0].Extras) != 2 || string(contacts[0].Extras["Region__c"]) != `"West"` || string(contacts[0].Extras["Score__c"]) != "5" {
+		t.Errorf("expected Region__c and Score__c extras; got %v", contacts[0].Extras)
+	}
+	if contacts[1].Extras != nil {
+		t.Errorf("expected nil extras; got %v", contacts[1].Extras)
+	}
+	if _, err := salesforce.ExtraFields([]byte(`{}`), "string"); err == nil {
+		t.Errorf("expected error for non-struct")
+	}
+	if _, err := salesforce.ExtraFields([]byte(`[]`), Contact{}); err == nil {
+		t.Errorf("expected error for non-object json")
+	}
+}
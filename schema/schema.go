@@ -0,0 +1,207 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package schema compares salesforce sobject definitions to detect
+// schema drift between an org and previously saved or generated definitions.
+package schema // import github.com/jfcote87/salesforce/schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jfcote87/salesforce"
+)
+
+// Report lists the differences between two sets of SObjectDefinitions
+type Report struct {
+	AddedObjects   []string     `json:"addedObjects,omitempty"`
+	RemovedObjects []string     `json:"removedObjects,omitempty"`
+	ChangedObjects []ObjectDiff `json:"changedObjects,omitempty"`
+}
+
+// ObjectDiff lists the field differences of an sobject
+type ObjectDiff struct {
+	Name            string            `json:"name"`
+	AddedFields     []string          `json:"addedFields,omitempty"`
+	RemovedFields   []string          `json:"removedFields,omitempty"`
+	RetypedFields   []FieldTypeChange `json:"retypedFields,omitempty"`
+	PicklistChanges []PicklistChange  `json:"picklistChanges,omitempty"`
+}
+
+// FieldTypeChange describes a field whose type has changed
+type FieldTypeChange struct {
+	Name    string `json:"name"`
+	OldType string `json:"oldType"`
+	NewType string `json:"newType"`
+}
+
+// PicklistChange lists the added and removed active values of a picklist field
+type PicklistChange struct {
+	Field   string   `json:"field"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Diff compares the old and current definitions reporting added/removed objects,
+// added/removed/retyped fields and changed picklist values.  Objects and fields
+// are matched by name.
+func Diff(old, current []salesforce.SObjectDefinition) *Report {
+	oldMap := objectMap(old)
+	curMap := objectMap(current)
+	var rpt = &Report{}
+	for _, nm := range sortedKeys(curMap) {
+		if _, ok := oldMap[nm]; !ok {
+			rpt.AddedObjects = append(rpt.AddedObjects, nm)
+		}
+	}
+	for _, nm := range sortedKeys(oldMap) {
+		cur, ok := curMap[nm]
+		if !ok {
+			rpt.RemovedObjects = append(rpt.RemovedObjects, nm)
+			continue
+		}
+		if od := diffObject(oldMap[nm], cur); od != nil {
+			rpt.ChangedObjects = append(rpt.ChangedObjects, *od)
+		}
+	}
+	return rpt
+}
+
+// HasChanges returns true if the report contains any differences
+func (r *Report) HasChanges() bool {
+	return r != nil && (len(r.AddedObjects) > 0 || len(r.RemovedObjects) > 0 || len(r.ChangedObjects) > 0)
+}
+
+// String returns a human readable report
+func (r *Report) String() string {
+	if !r.HasChanges() {
+		return "no changes"
+	}
+	var sb strings.Builder
+	for _, nm := range r.AddedObjects {
+		fmt.Fprintf(&sb, "+ %s\n", nm)
+	}
+	for _, nm := range r.RemovedObjects {
+		fmt.Fprintf(&sb, "- %s\n", nm)
+	}
+	for _, od := range r.ChangedObjects {
+		fmt.Fprintf(&sb, "~ %s\n", od.Name)
+		for _, nm := range od.AddedFields {
+			fmt.Fprintf(&sb, "    + %s\n", nm)
+		}
+		for _, nm := range od.RemovedFields {
+			fmt.Fprintf(&sb, "    - %s\n", nm)
+		}
+		for _, fc := range od.RetypedFields {
+			fmt.Fprintf(&sb, "    ~ %s: %s -> %s\n", fc.Name, fc.OldType, fc.NewType)
+		}
+		for _, pc := range od.PicklistChanges {
+			fmt.Fprintf(&sb, "    ~ %s picklist:", pc.Field)
+			for _, v := range pc.Added {
+				fmt.Fprintf(&sb, " +%q", v)
+			}
+			for _, v := range pc.Removed {
+				fmt.Fprintf(&sb, " -%q", v)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// diffObject returns nil if no field differences are found
+func diffObject(old, current salesforce.SObjectDefinition) *ObjectDiff {
+	oldFlds := fieldMap(old.Fields)
+	curFlds := fieldMap(current.Fields)
+	var od = &ObjectDiff{Name: current.Name}
+	for _, nm := range sortedKeys(curFlds) {
+		if _, ok := oldFlds[nm]; !ok {
+			od.AddedFields = append(od.AddedFields, nm)
+		}
+	}
+	for _, nm := range sortedKeys(oldFlds) {
+		of := oldFlds[nm]
+		cf, ok := curFlds[nm]
+		if !ok {
+			od.RemovedFields = append(od.RemovedFields, nm)
+			continue
+		}
+		if of.Type != cf.Type {
+			od.RetypedFields = append(od.RetypedFields, FieldTypeChange{Name: nm, OldType: of.Type, NewType: cf.Type})
+		}
+		added, removed := diffStrings(picklistValues(of), picklistValues(cf))
+		if len(added) > 0 || len(removed) > 0 {
+			od.PicklistChanges = append(od.PicklistChanges, PicklistChange{Field: nm, Added: added, Removed: removed})
+		}
+	}
+	if len(od.AddedFields) == 0 && len(od.RemovedFields) == 0 &&
+		len(od.RetypedFields) == 0 && len(od.PicklistChanges) == 0 {
+		return nil
+	}
+	return od
+}
+
+func objectMap(defs []salesforce.SObjectDefinition) map[string]salesforce.SObjectDefinition {
+	var m = make(map[string]salesforce.SObjectDefinition)
+	for _, d := range defs {
+		m[d.Name] = d
+	}
+	return m
+}
+
+func fieldMap(flds []salesforce.Field) map[string]salesforce.Field {
+	var m = make(map[string]salesforce.Field)
+	for _, f := range flds {
+		m[f.Name] = f
+	}
+	return m
+}
+
+// picklistValues returns the active values of a field
+func picklistValues(f salesforce.Field) map[string]bool {
+	var m = make(map[string]bool)
+	for _, p := range f.PicklistValues {
+		if p.Active {
+			m[p.Value] = true
+		}
+	}
+	return m
+}
+
+// diffStrings returns sorted keys added to and removed from old
+func diffStrings(old, current map[string]bool) (added, removed []string) {
+	for _, v := range sortedKeys(current) {
+		if !old[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range sortedKeys(old) {
+		if !current[v] {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch mx := m.(type) {
+	case map[string]salesforce.SObjectDefinition:
+		for k := range mx {
+			keys = append(keys, k)
+		}
+	case map[string]salesforce.Field:
+		for k := range mx {
+			keys = append(keys, k)
+		}
+	case map[string]bool:
+		for k := range mx {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+	"github.com/jfcote87/salesforce/schema"
+)
+
+func TestDiff(t *testing.T) {
+	old := []salesforce.SObjectDefinition{
+		{Name: "Account", Fields: []salesforce.Field{
+			{Name: "Id", Type: "id"},
+			{Name: "Name", Type: "string"},
+			{Name: "Rating", Type: "picklist", PicklistValues: []salesforce.PickListValue{
+				{Value: "Hot", Active: true}, {Value: "Cold", Active: true}, {Value: "Old", Active: false},
+			}},
+			{Name: "Score__c", Type: "double"},
+		}},
+		{Name: "Contact", Fields: []salesforce.Field{{Name: "Id", Type: "id"}}},
+		{Name: "Lead"},
+	}
+	current := []salesforce.SObjectDefinition{
+		{Name: "Account", Fields: []salesforce.Field{
+			{Name: "Id", Type: "id"},
+			{Name: "Rating", Type: "picklist", PicklistValues: []salesforce.PickListValue{
+				{Value: "Hot", Active: true}, {Value: "Warm", Active: true}, {Value: "Cold", Active: false},
+			}},
+			{Name: "Score__c", Type: "string"},
+			{Name: "Tier__c", Type: "string"},
+		}},
+		{Name: "Contact", Fields: []salesforce.Field{{Name: "Id", Type: "id"}}},
+		{Name: "Case"},
+	}
+
+	if rpt := schema.Diff(old, old); rpt.HasChanges() || rpt.String() != "no changes" {
+		t.Errorf("expected no changes; got %s", rpt)
+	}
+	rpt := schema.Diff(old, current)
+	if !rpt.HasChanges() {
+		t.Fatalf("expected changes")
+	}
+	if strings.Join(rpt.AddedObjects, ",") != "Case" || strings.Join(rpt.RemovedObjects, ",") != "Lead" {
+		t.Errorf("expected added Case and removed Lead; got %v %v", rpt.AddedObjects, rpt.RemovedObjects)
+	}
+	if len(rpt.ChangedObjects) != 1 {
+		t.Fatalf("expected 1 changed object; got %d", len(rpt.ChangedObjects))
+	}
+	od := rpt.ChangedObjects[0]
+	if od.Name != "Account" || strings.Join(od.AddedFields, ",") != "Tier__c" || strings.Join(od.RemovedFields, ",") != "Name" {
+		t.Errorf("unexpected object diff %#v", od)
+	}
+	if len(od.RetypedFields) != 1 || od.RetypedFields[0] != (schema.FieldTypeChange{Name: "Score__c", OldType: "double", NewType: "string"}) {
+		t.Errorf("unexpected retyped fields %#v", od.RetypedFields)
+	}
+	if len(od.PicklistChanges) != 1 || strings.Join(od.PicklistChanges[0].Added, ",") != "Warm" ||
+		strings.Join(od.PicklistChanges[0].Removed, ",") != "Cold" {
+		t.Errorf("unexpected picklist changes %#v", od.PicklistChanges)
+	}
+	expected := "+ Case\n- Lead\n~ Account\n    + Tier__c\n    - Name\n    ~ Score__c: double -> string\n" +
+		"    ~ Rating picklist: +\"Warm\" -\"Cold\"\n"
+	if rpt.String() != expected {
+		t.Errorf("expected %s; got %s", expected, rpt.String())
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// FieldList is a list of field names validated against the json
+// tags of an SObject struct.
+type FieldList struct {
+	SObjectName string
+	Fields      []string
+}
+
+// Select validates fields against the json tags of sobj's struct, so typos fail
+// when the program starts rather than when salesforce parses the SOQL.  A
+// dot-notation field (e.g. Account.Name) is validated by its first segment
+// which must be a relationship (map, struct or pointer) field.
+//
+// var contactFields = salesforce.MustSelect(Contact{}, "FirstName", "AccountId", "Account.Name")
+// ...
+// err := sv.Query(ctx, contactFields.SOQL("WHERE LastName = 'Smith'"), &contacts)
+func Select(sobj SObject, fields ...string) (*FieldList, error) {
+	if sobj == nil {
+		return nil, fmt.Errorf("nil sobject")
+	}
+	ty := reflect.TypeOf(sobj)
+	for ty.Kind() == reflect.Ptr {
+		ty = ty.Elem()
+	}
+	if ty.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", ty)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields specified")
+	}
+	fieldMap := jsonfield.Index(ty)
+	for _, nm := range fields {
+		parts := strings.SplitN(nm, ".", 2)
+		idx, ok := fieldMap[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("%s has no field %s", ty.Name(), parts[0])
+		}
+		if len(parts) > 1 {
+			switch ty.FieldByIndex(idx).Type.Kind() {
+			case reflect.Map, reflect.Struct, reflect.Ptr, reflect.Interface:
+			default:
+				return nil, fmt.Errorf("%s field %s is not a relationship", ty.Name(), parts[0])
+			}
+		}
+	}
+	return &FieldList{SObjectName: sobj.SObjectName(), Fields: fields}, nil
+}
+
+// MustSelect is like Select but panics if a field is invalid.  Use
+// to initialize package level variables.
+func MustSelect(sobj SObject, fields ...string) *FieldList {
+	fl, err := Select(sobj, fields...)
+	if err != nil {
+		panic("salesforce: Select " + err.Error())
+	}
+	return fl
+}
+
+// String returns the comma separated field list
+func (fl *FieldList) String() string {
+	return strings.Join(fl.Fields, ", ")
+}
+
+// SOQL returns a SELECT statement of the fields from the sobject
+// followed by clause (e.g. WHERE ..., ORDER BY ..., LIMIT ...).
+func (fl *FieldList) SOQL(clause string) string {
+	qry := "SELECT " + fl.String() + " FROM " + fl.SObjectName
+	if clause > "" {
+		qry += " " + clause
+	}
+	return qry
+}
+
+// FieldAccess is the permission required of a field
+type FieldAccess int
+
+// Field access values used by FilterFields
+const (
+	AccessRead FieldAccess = iota
+	AccessCreate
+	AccessUpdate
+)
+
+// FilterFields returns the fields permitted by def, the Describe of the sobject for
+// the integration user, and the dropped fields.  Describe only returns fields visible
+// to the user so AccessRead checks that the field exists, while AccessCreate and
+// AccessUpdate check the Createable and Updateable properties.  A dot-notation field
+// is permitted for reads when its first segment is a visible relationship.  Use to
+// avoid INVALID_FIELD errors when profiles differ between orgs.
+func FilterFields(def *SObjectDefinition, access FieldAccess, fields ...string) (permitted, dropped []string) {
+	var flds = make(map[string]Field)
+	var rels = make(map[string]bool)
+	if def != nil {
+		for _, f := range def.Fields {
+			flds[strings.ToLower(f.Name)] = f
+			if f.RelationshipName > "" {
+				rels[strings.ToLower(f.RelationshipName)] = true
+			}
+		}
+	}
+	for _, nm := range fields {
+		var ok bool
+		parts := strings.SplitN(strings.ToLower(nm), ".", 2)
+		if len(parts) > 1 {
+			ok = access == AccessRead && rels[parts[0]]
+		} else if f, exists := flds[parts[0]]; exists {
+			switch access {
+			case AccessCreate:
+				ok = f.Createable
+			case AccessUpdate:
+				ok = f.Updateable
+			default:
+				ok = true
+			}
+		}
+		if ok {
+			permitted = append(permitted, nm)
+			continue
+		}
+		dropped = append(dropped, nm)
+	}
+	return permitted, dropped
+}
+
+// Filter returns a FieldList containing only the fields permitted by def
+// along with the dropped fields.  See FilterFields.
+func (fl *FieldList) Filter(def *SObjectDefinition, access FieldAccess) (*FieldList, []string) {
+	permitted, dropped := FilterFields(def, access, fl.Fields...)
+	return &FieldList{SObjectName: fl.SObjectName, Fields: permitted}, dropped
+}
+
+// maxRelationshipDepth is the number of parent levels
+// allowed in a SOQL relationship field
+const maxRelationshipDepth = 5
+
+var sobjectInterface = reflect.TypeOf((*SObject)(nil)).Elem()
+
+// FieldNames returns the json field names of v's struct type for use with
+// RetrieveRecords or Select.  Struct (or pointer to struct) fields implementing
+// SObject are treated as parent relationships and their fields are returned in
+// dot-notation (e.g. Account.Name) so that parent values decode into the typed
+// field.  The attributes field and map fields are skipped.  For example, a struct
+// with an Account *Account `json:"Account"` field returns Account.Id, Account.Name, etc.
+//
+// err := sv.RetrieveRecords(ctx, &contacts, ids, salesforce.FieldNames(ContactWithAccount{})...)
+func FieldNames(v interface{}) []string {
+	ty := reflect.TypeOf(v)
+	if ty == nil {
+		return nil
+	}
+	for ty.Kind() == reflect.Ptr || ty.Kind() == reflect.Slice {
+		ty = ty.Elem()
+	}
+	return fieldNames(ty, "", 0)
+}
+
+func fieldNames(ty reflect.Type, prefix string, depth int) []string {
+	if ty.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := 0; i < ty.NumField(); i++ {
+		fld := ty.Field(i)
+		nm := strings.Split(fld.Tag.Get("json"), ",")[0]
+		if fld.PkgPath != "" || nm == "-" || nm == "attributes" {
+			continue
+		}
+		if nm == "" {
+			nm = fld.Name
+		}
+		fty := fld.Type
+		if fty.Kind() == reflect.Map {
+			continue
+		}
+		if fty.Kind() == reflect.Ptr {
+			fty = fty.Elem()
+		}
+		if fty.Kind() == reflect.Struct && reflect.PtrTo(fty).Implements(sobjectInterface) {
+			if depth < maxRelationshipDepth {
+				names = append(names, fieldNames(fty, prefix+nm+".", depth+1)...)
+			}
+			continue
+		}
+		names = append(names, prefix+nm)
+	}
+	return names
+}
+
+// ValidateFields checks fields of sobjectName, including dot-notation relationship
+// fields, against CachedDescribe results.  Each parent object in a relationship path is
+// described to validate the next segment.
+func (sv *Service) ValidateFields(ctx context.Context, sobjectName string, fields ...string) error {
+	var defs = make(map[string]*SObjectDefinition)
+	describe := func(nm string) (*SObjectDefinition, error) {
+		if def, ok := defs[nm]; ok {
+			return def, nil
+		}
+		def, err := sv.CachedDescribe(ctx, nm)
+		if err != nil {
+			return nil, fmt.Errorf("describe %s %w", nm, err)
+		}
+		defs[nm] = def
+		return def, nil
+	}
+	for _, fldName := range fields {
+		cur := sobjectName
+		segments := strings.Split(fldName, ".")
+		for i, seg := range segments {
+			def, err := describe(cur)
+			if err != nil {
+				return err
+			}
+			if i == len(segments)-1 {
+				if !hasField(def, seg) {
+					return fmt.Errorf("%s: %s has no field %s", fldName, cur, seg)
+				}
+				break
+			}
+			if cur = parentObject(def, seg); cur == "" {
+				return fmt.Errorf("%s: %s has no relationship %s", fldName, def.Name, seg)
+			}
+		}
+	}
+	return nil
+}
+
+func hasField(def *SObjectDefinition, nm string) bool {
+	for _, f := range def.Fields {
+		if strings.EqualFold(f.Name, nm) {
+			return true
+		}
+	}
+	return false
+}
+
+// parentObject returns the referenced sobject of the relationship
+func parentObject(def *SObjectDefinition, relationship string) string {
+	for _, f := range def.Fields {
+		if strings.EqualFold(f.RelationshipName, relationship) && len(f.ReferenceTo) > 0 {
+			return f.ReferenceTo[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,225 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLiteral is a SOQL date literal (e.g. TODAY or LAST_N_DAYS:7).  SOQLValue
+// does not quote DateLiterals.
+// https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_dateformats.htm
+type DateLiteral string
+
+// Date literals
+const (
+	Yesterday         DateLiteral = "YESTERDAY"
+	Today             DateLiteral = "TODAY"
+	Tomorrow          DateLiteral = "TOMORROW"
+	LastWeek          DateLiteral = "LAST_WEEK"
+	ThisWeek          DateLiteral = "THIS_WEEK"
+	NextWeek          DateLiteral = "NEXT_WEEK"
+	LastMonth         DateLiteral = "LAST_MONTH"
+	ThisMonth         DateLiteral = "THIS_MONTH"
+	NextMonth         DateLiteral = "NEXT_MONTH"
+	Last90Days        DateLiteral = "LAST_90_DAYS"
+	Next90Days        DateLiteral = "NEXT_90_DAYS"
+	LastQuarter       DateLiteral = "LAST_QUARTER"
+	ThisQuarter       DateLiteral = "THIS_QUARTER"
+	NextQuarter       DateLiteral = "NEXT_QUARTER"
+	LastYear          DateLiteral = "LAST_YEAR"
+	ThisYear          DateLiteral = "THIS_YEAR"
+	NextYear          DateLiteral = "NEXT_YEAR"
+	LastFiscalQuarter DateLiteral = "LAST_FISCAL_QUARTER"
+	ThisFiscalQuarter DateLiteral = "THIS_FISCAL_QUARTER"
+	NextFiscalQuarter DateLiteral = "NEXT_FISCAL_QUARTER"
+	LastFiscalYear    DateLiteral = "LAST_FISCAL_YEAR"
+	ThisFiscalYear    DateLiteral = "THIS_FISCAL_YEAR"
+	NextFiscalYear    DateLiteral = "NEXT_FISCAL_YEAR"
+)
+
+func nLiteral(nm string, n int) DateLiteral {
+	return DateLiteral(nm + ":" + strconv.Itoa(n))
+}
+
+// LastNDays returns LAST_N_DAYS:n
+func LastNDays(n int) DateLiteral { return nLiteral("LAST_N_DAYS", n) }
+
+// NextNDays returns NEXT_N_DAYS:n
+func NextNDays(n int) DateLiteral { return nLiteral("NEXT_N_DAYS", n) }
+
+// NDaysAgo returns N_DAYS_AGO:n
+func NDaysAgo(n int) DateLiteral { return nLiteral("N_DAYS_AGO", n) }
+
+// LastNWeeks returns LAST_N_WEEKS:n
+func LastNWeeks(n int) DateLiteral { return nLiteral("LAST_N_WEEKS", n) }
+
+// NextNWeeks returns NEXT_N_WEEKS:n
+func NextNWeeks(n int) DateLiteral { return nLiteral("NEXT_N_WEEKS", n) }
+
+// NWeeksAgo returns N_WEEKS_AGO:n
+func NWeeksAgo(n int) DateLiteral { return nLiteral("N_WEEKS_AGO", n) }
+
+// LastNMonths returns LAST_N_MONTHS:n
+func LastNMonths(n int) DateLiteral { return nLiteral("LAST_N_MONTHS", n) }
+
+// NextNMonths returns NEXT_N_MONTHS:n
+func NextNMonths(n int) DateLiteral { return nLiteral("NEXT_N_MONTHS", n) }
+
+// NMonthsAgo returns N_MONTHS_AGO:n
+func NMonthsAgo(n int) DateLiteral { return nLiteral("N_MONTHS_AGO", n) }
+
+// LastNQuarters returns LAST_N_QUARTERS:n
+func LastNQuarters(n int) DateLiteral { return nLiteral("LAST_N_QUARTERS", n) }
+
+// NextNQuarters returns NEXT_N_QUARTERS:n
+func NextNQuarters(n int) DateLiteral { return nLiteral("NEXT_N_QUARTERS", n) }
+
+// NQuartersAgo returns N_QUARTERS_AGO:n
+func NQuartersAgo(n int) DateLiteral { return nLiteral("N_QUARTERS_AGO", n) }
+
+// LastNYears returns LAST_N_YEARS:n
+func LastNYears(n int) DateLiteral { return nLiteral("LAST_N_YEARS", n) }
+
+// NextNYears returns NEXT_N_YEARS:n
+func NextNYears(n int) DateLiteral { return nLiteral("NEXT_N_YEARS", n) }
+
+// NYearsAgo returns N_YEARS_AGO:n
+func NYearsAgo(n int) DateLiteral { return nLiteral("N_YEARS_AGO", n) }
+
+// LastNFiscalQuarters returns LAST_N_FISCAL_QUARTERS:n
+func LastNFiscalQuarters(n int) DateLiteral { return nLiteral("LAST_N_FISCAL_QUARTERS", n) }
+
+// NextNFiscalQuarters returns NEXT_N_FISCAL_QUARTERS:n
+func NextNFiscalQuarters(n int) DateLiteral { return nLiteral("NEXT_N_FISCAL_QUARTERS", n) }
+
+// LastNFiscalYears returns LAST_N_FISCAL_YEARS:n
+func LastNFiscalYears(n int) DateLiteral { return nLiteral("LAST_N_FISCAL_YEARS", n) }
+
+// NextNFiscalYears returns NEXT_N_FISCAL_YEARS:n
+func NextNFiscalYears(n int) DateLiteral { return nLiteral("NEXT_N_FISCAL_YEARS", n) }
+
+// SOQLDate formats tm as a SOQL date literal (yyyy-mm-dd) for comparison
+// with date fields.  Date literals are not quoted.
+func SOQLDate(tm time.Time) string {
+	return tm.Format(defaultDateFormat)
+}
+
+// SOQLDatetime formats tm as a SOQL datetime literal in UTC
+// (yyyy-mm-ddThh:mm:ssZ) for comparison with datetime fields.
+func SOQLDatetime(tm time.Time) string {
+	return tm.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+var soqlEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`,
+	"\n", `\n`, "\r", `\r`, "\t", `\t`, "\b", `\b`, "\f", `\f`)
+
+// SOQLString returns s as a quoted and escaped SOQL string literal
+func SOQLString(s string) string {
+	return "'" + soqlEscaper.Replace(s) + "'"
+}
+
+// SOQLValue formats v as a SOQL literal.  Strings are quoted and escaped,
+// time.Time values are formatted as datetimes, Date, Datetime and DateLiteral
+// values are not quoted, nil is null and slices are formatted as a
+// parenthesized list for use with IN.
+func SOQLValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return SOQLString(x)
+	case DateLiteral:
+		return string(x)
+	case Date:
+		return string(x)
+	case Datetime:
+		return string(x)
+	case time.Time:
+		return SOQLDatetime(x)
+	case bool:
+		return strconv.FormatBool(x)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", x)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "null"
+		}
+		return SOQLValue(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		var vals = make([]string, rv.Len())
+		for i := range vals {
+			vals[i] = SOQLValue(rv.Index(i).Interface())
+		}
+		return "(" + strings.Join(vals, ",") + ")"
+	}
+	return SOQLString(fmt.Sprint(v))
+}
+
+// SOQLf formats a SOQL statement replacing each verb in format with the
+// SOQLValue of the corresponding arg.  Use %v (or %s) for each arg.
+//
+//	salesforce.SOQLf("SELECT Id FROM Account WHERE Name = %v AND CreatedDate = %v", name, salesforce.LastNDays(7))
+func SOQLf(format string, args ...interface{}) string {
+	var vals = make([]interface{}, len(args))
+	for i, a := range args {
+		vals[i] = SOQLValue(a)
+	}
+	return fmt.Sprintf(format, vals...)
+}
+
+// SOQLf returns a SELECT statement of the fields from the sobject followed
+// by the clause created by SOQLf(format, args...).
+func (fl *FieldList) SOQLf(format string, args ...interface{}) string {
+	return fl.SOQL(SOQLf(format, args...))
+}
+
+// ForClause is a SOQL clause that updates recent item tracking or locks the selected records
+// https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_for_view.htm
+type ForClause string
+
+// Predefined ForClause values.  ForView and ForReference update the last viewed and last
+// referenced dates of the records and their recent items.  ForUpdate locks the records
+// against updates by other clients while the query runs; it may not be used with ORDER BY.
+const (
+	ForView      ForClause = "FOR VIEW"
+	ForReference ForClause = "FOR REFERENCE"
+	ForUpdate    ForClause = "FOR UPDATE"
+)
+
+// Append returns qry followed by the clause.  An empty clause, an empty
+// qry or a qry already ending with the clause returns qry unchanged.
+func (fc ForClause) Append(qry string) string {
+	if fc == "" || strings.TrimSpace(qry) == "" || strings.HasSuffix(strings.ToUpper(strings.TrimSpace(qry)), string(fc)) {
+		return qry
+	}
+	return strings.TrimRight(qry, " ") + " " + string(fc)
+}
+
+// SOQLFor returns a SELECT statement of the fields from the sobject
+// followed by clause and fc.
+func (fl *FieldList) SOQLFor(clause string, fc ForClause) string {
+	return fc.Append(fl.SOQL(clause))
+}
+
+// WithForClause returns a service that appends fc to the queries made by Query,
+// QueryAll and PagedQuery pages.  An empty fc removes the clause.
+func (sv *Service) WithForClause(fc ForClause) *Service {
+	snew := sv.clone()
+	snew.forClause = fc
+	return snew
+}
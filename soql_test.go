@@ -0,0 +1,92 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestSOQLValue(t *testing.T) {
+	var nilDate *salesforce.Date
+	dt := salesforce.Date("2022-03-04")
+	tm := time.Date(2022, 3, 4, 5, 6, 7, 0, time.FixedZone("EST", -5*3600))
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "null"},
+		{"O'Brien \\ \"x\"\n", `'O\'Brien \\ \"x\"\n'`},
+		{salesforce.Today, "TODAY"},
+		{salesforce.LastNDays(30), "LAST_N_DAYS:30"},
+		{dt, "2022-03-04"},
+		{&dt, "2022-03-04"},
+		{nilDate, "null"},
+		{salesforce.Datetime("2022-03-04T05:06:07.000+0000"), "2022-03-04T05:06:07.000+0000"},
+		{tm, "2022-03-04T10:06:07Z"},
+		{true, "true"},
+		{42, "42"},
+		{1.5, "1.5"},
+		{[]string{"a", "b'c"}, `('a','b\'c')`},
+		{[]int{1, 2}, "(1,2)"},
+	}
+	for i, tt := range tests {
+		if got := salesforce.SOQLValue(tt.v); got != tt.want {
+			t.Errorf("test %d: expected %s; got %s", i, tt.want, got)
+		}
+	}
+	if got := salesforce.SOQLDate(tm); got != "2022-03-04" {
+		t.Errorf("SOQLDate expected 2022-03-04; got %s", got)
+	}
+}
+
+func TestSOQLf(t *testing.T) {
+	fl := salesforce.MustSelect(Contact{}, "Id", "LastName")
+	got := fl.SOQLf("WHERE LastName = %v AND CreatedDate = %v AND Birthdate > %v LIMIT 10",
+		"O'Brien", salesforce.ThisMonth, salesforce.NYearsAgo(18))
+	want := `SELECT Id, LastName FROM Contact WHERE LastName = 'O\'Brien' AND CreatedDate = THIS_MONTH AND Birthdate > N_YEARS_AGO:18 LIMIT 10`
+	if got != want {
+		t.Errorf("expected %s; got %s", want, got)
+	}
+}
+
+func TestForClause(t *testing.T) {
+	fl := salesforce.MustSelect(Contact{}, "Id")
+	if got, want := fl.SOQLFor("LIMIT 1", salesforce.ForView), "SELECT Id FROM Contact LIMIT 1 FOR VIEW"; got != want {
+		t.Errorf("expected %s; got %s", want, got)
+	}
+	if got := salesforce.ForUpdate.Append("SELECT Id FROM Contact FOR UPDATE"); got != "SELECT Id FROM Contact FOR UPDATE" {
+		t.Errorf("expected unchanged query; got %s", got)
+	}
+	if got := salesforce.ForClause("").Append("SELECT Id FROM Contact"); got != "SELECT Id FROM Contact" {
+		t.Errorf("expected unchanged query; got %s", got)
+	}
+	if got := salesforce.ForView.Append(""); got != "" {
+		t.Errorf("expected empty query; got %s", got)
+	}
+
+	var qry string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qry = r.URL.Query().Get("q")
+		encodeObject(w, map[string]interface{}{"totalSize": 0, "done": true, "records": []interface{}{}})
+	}))
+	defer ws.Close()
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/").WithForClause(salesforce.ForReference)
+	var contacts []Contact
+	if err := sv.Query(ctx, "SELECT Id FROM Contact", &contacts); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if qry != "SELECT Id FROM Contact FOR REFERENCE" {
+		t.Errorf("expected FOR REFERENCE query; got %s", qry)
+	}
+}
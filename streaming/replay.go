@@ -0,0 +1,211 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package streaming provides support for streaming and Pub/Sub API subscribers.
+// https://developer.salesforce.com/docs/atlas.en-us.api_streaming.meta/api_streaming/intro_stream.htm
+package streaming // import github.com/jfcote87/salesforce/streaming
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// ReplayID identifies the position of an event in a channel's event stream.  Pub/Sub
+// API replay ids are opaque bytes.  Use Int64ReplayID for streaming (CometD) replay ids.
+// https://developer.salesforce.com/docs/atlas.en-us.platform_events.meta/platform_events/platform_events_subscribe_replay.htm
+type ReplayID []byte
+
+// Int64ReplayID encodes a streaming api replay id
+func Int64ReplayID(id int64) ReplayID {
+	var b = make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+// Int64 decodes a replay id created by Int64ReplayID
+func (r ReplayID) Int64() (int64, error) {
+	if len(r) != 8 {
+		return 0, fmt.Errorf("replay id length %d is not an int64", len(r))
+	}
+	return int64(binary.BigEndian.Uint64(r)), nil
+}
+
+// Streaming api replay options used when a ReplayStore has no replay id for a channel
+const (
+	ReplayLatest   int64 = -1 // new events only
+	ReplayEarliest int64 = -2 // all retained events
+)
+
+// ReplayStore saves the replay id of the last processed event of each channel so
+// that a subscriber may resume after a restart.  Load returns a nil ReplayID when
+// the channel has no saved replay id.
+type ReplayStore interface {
+	Load(ctx context.Context, channel string) (ReplayID, error)
+	Save(ctx context.Context, channel string, id ReplayID) error
+}
+
+// MemoryReplayStore is a ReplayStore for the life of a process
+type MemoryReplayStore struct {
+	m   sync.Mutex
+	ids map[string]ReplayID
+}
+
+// NewMemoryReplayStore creates an empty MemoryReplayStore
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{ids: make(map[string]ReplayID)}
+}
+
+// Load returns the channel's replay id
+func (ms *MemoryReplayStore) Load(ctx context.Context, channel string) (ReplayID, error) {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	return copyID(ms.ids[channel]), nil
+}
+
+// Save sets the channel's replay id
+func (ms *MemoryReplayStore) Save(ctx context.Context, channel string, id ReplayID) error {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+	ms.ids[channel] = copyID(id)
+	return nil
+}
+
+func copyID(id ReplayID) ReplayID {
+	if id == nil {
+		return nil
+	}
+	return append(ReplayID{}, id...)
+}
+
+// FileReplayStore is a ReplayStore saving each channel's replay id in
+// a file in Dir.  Files are replaced atomically.
+type FileReplayStore struct {
+	Dir string
+}
+
+// NewFileReplayStore creates a FileReplayStore using dir.  The directory
+// is created on the first Save.
+func NewFileReplayStore(dir string) *FileReplayStore {
+	return &FileReplayStore{Dir: dir}
+}
+
+var invalidFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+func (fs *FileReplayStore) filename(channel string) string {
+	return filepath.Join(fs.Dir, invalidFilenameChars.ReplaceAllString(channel, "_")+".replay")
+}
+
+// Load reads the channel's replay id
+func (fs *FileReplayStore) Load(ctx context.Context, channel string) (ReplayID, error) {
+	b, err := ioutil.ReadFile(fs.filename(channel))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// Save writes the channel's replay id
+func (fs *FileReplayStore) Save(ctx context.Context, channel string, id ReplayID) error {
+	if err := os.MkdirAll(fs.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(fs.Dir, ".replay")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(id); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fs.filename(channel))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// SQLReplayStore is a ReplayStore saving replay ids in a database table with
+// a channel (varchar primary key) column and a replay_id (binary) column:
+//
+//	CREATE TABLE sf_replay (channel VARCHAR(255) PRIMARY KEY, replay_id BLOB)
+//
+// Placeholder is the driver's parameter style; use "?" (the default) for
+// mysql and sqlite or "$" for postgres ($1, $2).
+type SQLReplayStore struct {
+	DB          *sql.DB
+	Table       string
+	Placeholder string
+}
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// NewSQLReplayStore creates a SQLReplayStore using table
+func NewSQLReplayStore(db *sql.DB, table string) (*SQLReplayStore, error) {
+	if db == nil {
+		return nil, errors.New("db may not be nil")
+	}
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	return &SQLReplayStore{DB: db, Table: table}, nil
+}
+
+func (ss *SQLReplayStore) param(n int) string {
+	if ss.Placeholder == "$" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// Load selects the channel's replay id
+func (ss *SQLReplayStore) Load(ctx context.Context, channel string) (ReplayID, error) {
+	var id []byte
+	qry := fmt.Sprintf("SELECT replay_id FROM %s WHERE channel = %s", ss.Table, ss.param(1))
+	err := ss.DB.QueryRowContext(ctx, qry, channel).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return id, err
+}
+
+// Save updates the channel's replay id, inserting a row if none exists.  An
+// update may report no affected rows for an existing row, as mysql does when
+// the value is unchanged, and another Save may insert the row first, so a
+// failed insert is followed by an update when the channel's row exists.
+func (ss *SQLReplayStore) Save(ctx context.Context, channel string, id ReplayID) error {
+	upd := fmt.Sprintf("UPDATE %s SET replay_id = %s WHERE channel = %s", ss.Table, ss.param(1), ss.param(2))
+	res, err := ss.DB.ExecContext(ctx, upd, []byte(id), channel)
+	if err != nil {
+		return err
+	}
+	if cnt, err := res.RowsAffected(); err != nil || cnt > 0 {
+		return err
+	}
+	ins := fmt.Sprintf("INSERT INTO %s (channel, replay_id) VALUES (%s, %s)", ss.Table, ss.param(1), ss.param(2))
+	if _, err = ss.DB.ExecContext(ctx, ins, channel, []byte(id)); err == nil {
+		return nil
+	}
+	var exists int
+	cnt := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE channel = %s", ss.Table, ss.param(1))
+	if ss.DB.QueryRowContext(ctx, cnt, channel).Scan(&exists) != nil || exists == 0 {
+		return err
+	}
+	_, err = ss.DB.ExecContext(ctx, upd, []byte(id), channel)
+	return err
+}
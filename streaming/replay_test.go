@@ -0,0 +1,178 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package streaming_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jfcote87/salesforce/streaming"
+)
+
+func testReplayStore(t *testing.T, nm string, rs streaming.ReplayStore) {
+	ctx := context.Background()
+	id, err := rs.Load(ctx, "/data/AccountChangeEvent")
+	if err != nil || id != nil {
+		t.Fatalf("%s: expected nil replay id; got %v %v", nm, id, err)
+	}
+	for _, n := range []int64{10, 42} {
+		if err := rs.Save(ctx, "/data/AccountChangeEvent", streaming.Int64ReplayID(n)); err != nil {
+			t.Fatalf("%s: save %v", nm, err)
+		}
+	}
+	if err := rs.Save(ctx, "/event/Order__e", streaming.ReplayID{1, 2, 3}); err != nil {
+		t.Fatalf("%s: save %v", nm, err)
+	}
+	if id, err = rs.Load(ctx, "/data/AccountChangeEvent"); err != nil {
+		t.Fatalf("%s: load %v", nm, err)
+	}
+	if n, err := id.Int64(); err != nil || n != 42 {
+		t.Errorf("%s: expected 42; got %d %v", nm, n, err)
+	}
+	if id, err = rs.Load(ctx, "/event/Order__e"); err != nil || !bytes.Equal(id, []byte{1, 2, 3}) {
+		t.Errorf("%s: expected [1 2 3]; got %v %v", nm, id, err)
+	}
+}
+
+func TestReplayStores(t *testing.T) {
+	testReplayStore(t, "memory", streaming.NewMemoryReplayStore())
+
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatalf("tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	testReplayStore(t, "file", streaming.NewFileReplayStore(dir))
+
+	if _, err := streaming.NewSQLReplayStore(nil, "sf_replay"); err == nil {
+		t.Errorf("expected nil db error")
+	}
+	mc := &memConnector{rows: make(map[string][]byte)}
+	db := sql.OpenDB(mc)
+	defer db.Close()
+	if _, err := streaming.NewSQLReplayStore(db, "sf_replay; DROP TABLE x"); err == nil {
+		t.Errorf("expected invalid table name error")
+	}
+	ss, err := streaming.NewSQLReplayStore(db, "sf_replay")
+	if err != nil {
+		t.Fatalf("NewSQLReplayStore: %v", err)
+	}
+	testReplayStore(t, "sql", ss)
+
+	ctx := context.Background()
+	if err := ss.Save(ctx, "/event/Order__e", streaming.ReplayID{1, 2, 3}); err != nil {
+		t.Errorf("expected unchanged save to succeed; got %v", err)
+	}
+	mc.beforeInsert = func(rows map[string][]byte) {
+		rows["/event/Other__e"] = []byte{9}
+	}
+	if err := ss.Save(ctx, "/event/Other__e", streaming.ReplayID{4}); err != nil {
+		t.Errorf("expected save after concurrent insert to succeed; got %v", err)
+	}
+	mc.beforeInsert = nil
+	if id, err := ss.Load(ctx, "/event/Other__e"); err != nil || !bytes.Equal(id, []byte{4}) {
+		t.Errorf("expected [4]; got %v %v", id, err)
+	}
+
+	if _, err := streaming.ReplayID([]byte{1}).Int64(); err == nil {
+		t.Errorf("expected invalid length error")
+	}
+}
+
+// memConnector is a minimal database/sql driver supporting the
+// statements of SQLReplayStore.  Like mysql, an update leaving a value
+// unchanged affects no rows and inserting an existing channel fails.
+// beforeInsert, when set, is called before an insert to simulate another
+// Save inserting the row first.
+type memConnector struct {
+	m            sync.Mutex
+	rows         map[string][]byte
+	beforeInsert func(rows map[string][]byte)
+}
+
+func (mc *memConnector) Connect(context.Context) (driver.Conn, error) { return &memConn{mc}, nil }
+func (mc *memConnector) Driver() driver.Driver                        { return nil }
+
+type memConn struct{ mc *memConnector }
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) { return &memStmt{c.mc, query}, nil }
+func (c *memConn) Close() error                              { return nil }
+func (c *memConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type memStmt struct {
+	mc  *memConnector
+	qry string
+}
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return 2 - strings.Count(s.qry, "SELECT") }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.mc.m.Lock()
+	defer s.mc.m.Unlock()
+	switch {
+	case strings.HasPrefix(s.qry, "UPDATE sf_replay SET replay_id = ? WHERE channel = ?"):
+		ch := args[1].(string)
+		if v, ok := s.mc.rows[ch]; !ok || bytes.Equal(v, args[0].([]byte)) {
+			return driver.RowsAffected(0), nil
+		}
+		s.mc.rows[ch] = args[0].([]byte)
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.qry, "INSERT INTO sf_replay (channel, replay_id) VALUES (?, ?)"):
+		if s.mc.beforeInsert != nil {
+			s.mc.beforeInsert(s.mc.rows)
+		}
+		ch := args[0].(string)
+		if _, ok := s.mc.rows[ch]; ok {
+			return nil, errors.New("Error 1062: Duplicate entry '" + ch + "' for key 'PRIMARY'")
+		}
+		s.mc.rows[ch] = args[1].([]byte)
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("unexpected statement " + s.qry)
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.mc.m.Lock()
+	defer s.mc.m.Unlock()
+	id, ok := s.mc.rows[args[0].(string)]
+	switch s.qry {
+	case "SELECT replay_id FROM sf_replay WHERE channel = ?":
+		return &memRows{val: id, done: !ok}, nil
+	case "SELECT COUNT(*) FROM sf_replay WHERE channel = ?":
+		var cnt int64
+		if ok {
+			cnt = 1
+		}
+		return &memRows{val: cnt}, nil
+	}
+	return nil, errors.New("unexpected query " + s.qry)
+}
+
+type memRows struct {
+	val  driver.Value
+	done bool
+}
+
+func (r *memRows) Columns() []string { return []string{"replay_id"} }
+func (r *memRows) Close() error      { return nil }
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.val
+	return nil
+}
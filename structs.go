@@ -201,39 +201,83 @@ type Attributes struct {
 	Ref  string `json:"referenceId,omitempty"`
 }
 
+// JobState is the current processing state of a bulk job
+// https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/get_job_info.htm
+type JobState string
+
+// JobState values
+const (
+	JobStateOpen           JobState = "Open"
+	JobStateUploadComplete JobState = "UploadComplete"
+	JobStateInProgress     JobState = "InProgress"
+	JobStateJobComplete    JobState = "JobComplete"
+	JobStateFailed         JobState = "Failed"
+	JobStateAborted        JobState = "Aborted"
+)
+
+// IsTerminal returns true if the job will not be processed further
+func (js JobState) IsTerminal() bool {
+	return js == JobStateJobComplete || js == JobStateFailed || js == JobStateAborted
+}
+
+// IsSuccess returns true if the job completed processing
+func (js JobState) IsSuccess() bool {
+	return js == JobStateJobComplete
+}
+
+// JobOperation is the processing operation for a bulk job
+type JobOperation string
+
+// JobOperation values
+const (
+	JobOperationInsert     JobOperation = "insert"
+	JobOperationUpsert     JobOperation = "upsert"
+	JobOperationUpdate     JobOperation = "update"
+	JobOperationDelete     JobOperation = "delete"
+	JobOperationHardDelete JobOperation = "hardDelete"
+	JobOperationQuery      JobOperation = "query"
+	JobOperationQueryAll   JobOperation = "queryAll"
+)
+
+// IsQuery returns true for query and queryAll operations
+func (op JobOperation) IsQuery() bool {
+	return op == JobOperationQuery || op == JobOperationQueryAll
+}
+
 // JobDefinition is the initialization data for a new bulk job
 type JobDefinition struct {
-	ExternalIDFieldName string `json:"externalIdFieldName,omitempty"`
-	Object              string `json:"object,omitempty"`
-	Operation           string `json:"operation,omitempty"`
-	ConcurrencyMode     string `json:"concurrencyMode,omitempty"`
-	ContentType         string `json:"contentType,omitempty"`
-	LineEnding          string `json:"lineEnding,omitempty"`
-	ColumnDelimiter     string `json:"columnDelimiter,omitempty"`
-	AssignmentRuleID    string `json:"assignmentRuleId,omitempty"`
+	ExternalIDFieldName string       `json:"externalIdFieldName,omitempty"`
+	Object              string       `json:"object,omitempty"`
+	Operation           JobOperation `json:"operation,omitempty"`
+	ConcurrencyMode     string       `json:"concurrencyMode,omitempty"`
+	ContentType         string       `json:"contentType,omitempty"`
+	LineEnding          string       `json:"lineEnding,omitempty"`
+	ColumnDelimiter     string       `json:"columnDelimiter,omitempty"`
+	AssignmentRuleID    string       `json:"assignmentRuleId,omitempty"`
 }
 
 // Job contains current state of a job and is returned from the
 // CreateJob, CloseJob, GetJob and AbortJob methods
 type Job struct {
-	APIVersion             float64 `json:"apiVersion,omitempty"`
-	AssignmentRuleID       string  `json:"assignmentRuleId,omitempty"`
-	ColumnDelimiter        string  `json:"columnDelimiter,omitempty"`
-	ConcurrencyMode        string  `json:"concurrencyMode,omitempty"`
-	ContentType            string  `json:"contentType,omitempty"`
-	ContentURL             string  `json:"contentURL,omitempty"`
-	CreatedByID            string  `json:"createdById,omitempty"`
-	CreatedDate            string  `json:"createdDate,omitempty"`
-	ExternalIDFieldName    string  `json:"externalIdFieldName,omitempty"`
-	ID                     string  `json:"id,omitempty"`
-	JobType                string  `json:"jobType,omitempty"`
-	LineEnding             string  `json:"lineEnding,omitempty"`
-	NumberRecordsFailed    int     `json:"numberRecordsFailed"`
-	NumberRecordsProcessed int     `json:"numberRecordsProcessed"`
-	Object                 string  `json:"object,omitempty"`
-	Operation              string  `json:"operation,omitempty"`
-	State                  string  `json:"state,omitempty"`
-	SystemModstamp         string  `json:"systemModstamp,omitempty"`
+	APIVersion             float64      `json:"apiVersion,omitempty"`
+	AssignmentRuleID       string       `json:"assignmentRuleId,omitempty"`
+	ColumnDelimiter        string       `json:"columnDelimiter,omitempty"`
+	ConcurrencyMode        string       `json:"concurrencyMode,omitempty"`
+	ContentType            string       `json:"contentType,omitempty"`
+	ContentURL             string       `json:"contentURL,omitempty"`
+	ErrorMessage           string       `json:"errorMessage,omitempty"`
+	CreatedByID            string       `json:"createdById,omitempty"`
+	CreatedDate            string       `json:"createdDate,omitempty"`
+	ExternalIDFieldName    string       `json:"externalIdFieldName,omitempty"`
+	ID                     string       `json:"id,omitempty"`
+	JobType                string       `json:"jobType,omitempty"`
+	LineEnding             string       `json:"lineEnding,omitempty"`
+	NumberRecordsFailed    int          `json:"numberRecordsFailed"`
+	NumberRecordsProcessed int          `json:"numberRecordsProcessed"`
+	Object                 string       `json:"object,omitempty"`
+	Operation              JobOperation `json:"operation,omitempty"`
+	State                  JobState     `json:"state,omitempty"`
+	SystemModstamp         string       `json:"systemModstamp,omitempty"`
 }
 
 // JobList returns all job status
@@ -6,7 +6,6 @@
 package salesforce_test
 
 import (
-	"reflect"
 	"testing"
 
 	"github.com/jfcote87/salesforce"
@@ -19,10 +18,17 @@ func TestNewRecordSlice(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    *salesforce.RecordSlice
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{name: "nil", args: args{nil}, wantErr: true},
+		{name: "slice", args: args{[]Contact{}}, wantErr: true},
+		{name: "nil ptr", args: args{(*[]Contact)(nil)}, wantErr: true},
+		{name: "strings", args: args{&[]string{}}, wantErr: true},
+		{name: "ptr to ptr", args: args{&[]**Contact{}}, wantErr: true},
+		{name: "structs", args: args{&[]Contact{}}},
+		{name: "struct ptrs", args: args{&[]*Contact{}}},
+		{name: "maps", args: args{&[]salesforce.RecordMap{}}},
+		{name: "interfaces", args: args{&[]interface{}{}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -31,8 +37,8 @@ func TestNewRecordSlice(t *testing.T) {
 				t.Errorf("NewRecordSlice() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewRecordSlice() = %v, want %v", got, tt.want)
+			if !tt.wantErr && got == nil {
+				t.Errorf("NewRecordSlice() returned nil RecordSlice")
 			}
 		})
 	}
@@ -146,6 +152,16 @@ func (c Contact) WithAttr(ref string) salesforce.SObject {
 	return c
 }
 
+// GetID returns the record id
+func (c Contact) GetID() string {
+	return c.ContactID
+}
+
+// SetID sets the record id
+func (c *Contact) SetID(id string) {
+	c.ContactID = id
+}
+
 // CustomTable describes custom salesforce object CTable__c
 type CustomTable struct {
 	Attributes *salesforce.Attributes `json:"attributes,omitempty"`
@@ -193,3 +209,25 @@ func TestAddress(t *testing.T) {
 	}
 
 }
+
+func TestJobState(t *testing.T) {
+	var terminal = map[salesforce.JobState]bool{
+		salesforce.JobStateOpen:           false,
+		salesforce.JobStateUploadComplete: false,
+		salesforce.JobStateInProgress:     false,
+		salesforce.JobStateJobComplete:    true,
+		salesforce.JobStateFailed:         true,
+		salesforce.JobStateAborted:        true,
+	}
+	for st, want := range terminal {
+		if st.IsTerminal() != want {
+			t.Errorf("%s expected IsTerminal() = %v", st, want)
+		}
+	}
+	if !salesforce.JobStateJobComplete.IsSuccess() || salesforce.JobStateFailed.IsSuccess() {
+		t.Errorf("expected only JobComplete to be success")
+	}
+	if !salesforce.JobOperationQueryAll.IsQuery() || salesforce.JobOperationUpsert.IsQuery() {
+		t.Errorf("expected queryAll to be query and upsert to not be query")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Handler replays fixtures.  A request is matched by method, api path and query,
+// then by method and api path.  Requests without an indexed fixture are served
+// from the default FileName of the request, with or without the query (e.g.
+// get/sobjects_Contact_describe.json), so that hand written files may be added
+// to a fixture directory.
+type Handler struct {
+	Dir string
+
+	fixtures map[string]Fixture
+	paths    map[string]Fixture
+}
+
+// NewHandler loads the index of dir.  A missing index is not an error.
+func NewHandler(dir string) (*Handler, error) {
+	fixtures, err := readIndex(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	h := &Handler{Dir: dir, fixtures: make(map[string]Fixture), paths: make(map[string]Fixture)}
+	for _, f := range fixtures {
+		h.fixtures[f.key()] = f
+		if _, ok := h.paths[f.Method+" "+f.Path]; !ok {
+			h.paths[f.Method+" "+f.Path] = f
+		}
+	}
+	return h, nil
+}
+
+// ServeHTTP writes the matching fixture.  A salesforce NOT_FOUND error
+// is returned when no fixture matches.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.Lookup(r)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode([]map[string]string{{
+			"errorCode": "NOT_FOUND",
+			"message":   fmt.Sprintf("testutil: no fixture for %s %s", r.Method, r.URL.RequestURI()),
+		}})
+		return
+	}
+	var body []byte
+	if f.File > "" {
+		var err error
+		if body, err = ioutil.ReadFile(filepath.Join(h.Dir, filepath.FromSlash(f.File))); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	for k, v := range f.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(f.Status)
+	w.Write(body)
+}
+
+// Lookup returns the fixture matching r
+func (h *Handler) Lookup(r *http.Request) (Fixture, bool) {
+	path := APIPath(r.URL.Path)
+	if f, ok := h.fixtures[r.Method+" "+path+"?"+r.URL.RawQuery]; ok {
+		return f, true
+	}
+	if f, ok := h.paths[r.Method+" "+path]; ok {
+		return f, true
+	}
+	for _, qry := range []string{r.URL.RawQuery, ""} {
+		for _, ct := range []string{"application/json", "text/csv"} {
+			fn := FileName(r.Method, path, qry, ct)
+			if _, err := os.Stat(filepath.Join(h.Dir, filepath.FromSlash(fn))); err == nil {
+				return Fixture{
+					Method: r.Method,
+					Path:   path,
+					Query:  qry,
+					Status: http.StatusOK,
+					Header: http.Header{"Content-Type": {ct}},
+					File:   fn,
+				}, true
+			}
+		}
+	}
+	return Fixture{}, false
+}
+
+func readIndex(dir string) ([]Fixture, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		return nil, fmt.Errorf("%s: %w", IndexFile, err)
+	}
+	return fixtures, nil
+}
+
+func sortFixtures(list []Fixture) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].File != list[j].File {
+			return list[i].File < list[j].File
+		}
+		return list[i].key() < list[j].key()
+	})
+}
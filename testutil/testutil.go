@@ -0,0 +1,311 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package testutil records salesforce api responses as test fixtures and replays
+// them from an http.Handler.  Fixtures are stored in the layout of this repo's
+// testfiles directory, <dir>/<method>/<name>.json|csv, along with an index.json
+// describing the request of each file.  Record calls against a sandbox org,
+// review the sanitized files, and replay them with httptest to build regression
+// tests without writing handlers.
+//
+//	rec, _ := testutil.NewRecorder("testfiles")
+//	sv := salesforce.New(host, "", nil).WithCtxClientFunc(rec.ClientFunc(cf))
+//	... make calls ...
+//	rec.Save()
+//
+//	h, _ := testutil.NewHandler("testfiles")
+//	ws := httptest.NewServer(h)
+//	sv := salesforce.New(host, "", nil).WithURL(ws.URL + "/")
+package testutil // import github.com/jfcote87/salesforce/testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jfcote87/ctxclient"
+)
+
+// IndexFile is the name of the fixture index within a fixture directory
+const IndexFile = "index.json"
+
+// ReplayHost replaces the recorded instance's host in response bodies
+const ReplayHost = "instance.my.salesforce.com"
+
+// Redacted replaces the values of sanitized fields
+const Redacted = "REDACTED"
+
+// DefaultRedactKeys are the json fields whose string values are replaced by
+// Redacted when a Recorder's RedactKeys is nil.  Record ids in paths and
+// bodies are not redacted as replay depends upon them.
+var DefaultRedactKeys = []string{
+	"access_token", "refresh_token", "id_token", "signature", "sessionId",
+	"Username", "Email", "Phone", "MobilePhone",
+}
+
+// recordedHeaders are the response headers saved in a Fixture
+var recordedHeaders = []string{"Content-Type", "Location", "Sforce-Limit-Info"}
+
+// Fixture describes a recorded response
+type Fixture struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"` // relative to /services/data/vXX.X/
+	Query  string      `json:"query,omitempty"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	File   string      `json:"file,omitempty"` // slash separated path relative to the fixture directory; empty for no body
+}
+
+func (f Fixture) key() string {
+	return f.Method + " " + f.Path + "?" + f.Query
+}
+
+// Recorder saves responses of calls made with its ClientFunc.  Response
+// bodies are written as they are received; Save writes the index.
+type Recorder struct {
+	// Dir is the fixture directory
+	Dir string
+	// RedactKeys lists json fields to redact.  Nil indicates DefaultRedactKeys.
+	RedactKeys []string
+	// Sanitize, if set, is called on each body after redaction allowing
+	// additional scrubbing of recorded data
+	Sanitize func(contentType string, body []byte) []byte
+
+	m        sync.Mutex
+	fixtures map[string]Fixture
+	files    map[string]string // file name -> fixture key
+}
+
+// NewRecorder returns a Recorder saving fixtures in dir.  Fixtures of an
+// existing index are kept unless re-recorded.
+func NewRecorder(dir string) (*Recorder, error) {
+	fixtures, err := readIndex(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	r := &Recorder{Dir: dir, fixtures: make(map[string]Fixture), files: make(map[string]string)}
+	for _, f := range fixtures {
+		r.fixtures[f.key()] = f
+		if f.File > "" {
+			r.files[f.File] = f.key()
+		}
+	}
+	return r, nil
+}
+
+// ClientFunc returns a ctxclient.Func whose clients record the responses of
+// the clients returned by f.  A nil f uses the default client.
+func (r *Recorder) ClientFunc(f ctxclient.Func) ctxclient.Func {
+	return func(ctx context.Context) (*http.Client, error) {
+		cl := f.Client(ctx)
+		if err := ctxclient.Error(cl); err != nil {
+			return nil, err
+		}
+		clx := *cl
+		clx.Transport = &transport{rec: r, base: cl.Transport}
+		return &clx, nil
+	}
+}
+
+// Fixtures returns the recorded fixtures sorted by file name
+func (r *Recorder) Fixtures() []Fixture {
+	r.m.Lock()
+	defer r.m.Unlock()
+	var list = make([]Fixture, 0, len(r.fixtures))
+	for _, f := range r.fixtures {
+		list = append(list, f)
+	}
+	sortFixtures(list)
+	return list
+}
+
+// Save writes the index of recorded fixtures
+func (r *Recorder) Save() error {
+	b, err := json.MarshalIndent(r.Fixtures(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(r.Dir, IndexFile), b, 0644)
+}
+
+// record writes the sanitized body and adds the fixture
+func (r *Recorder) record(req *http.Request, res *http.Response, body []byte) error {
+	f := Fixture{
+		Method: req.Method,
+		Path:   APIPath(req.URL.Path),
+		Query:  req.URL.RawQuery,
+		Status: res.StatusCode,
+		Header: make(http.Header),
+	}
+	for _, k := range recordedHeaders {
+		if v := res.Header.Get(k); v > "" {
+			f.Header.Set(k, strings.Replace(v, req.URL.Host, ReplayHost, -1))
+		}
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if len(body) > 0 {
+		ct := res.Header.Get("Content-Type")
+		body = r.sanitize(ct, bytes.Replace(body, []byte(req.URL.Host), []byte(ReplayHost), -1))
+		f.File = r.fileName(f, ct)
+		fn := filepath.Join(r.Dir, filepath.FromSlash(f.File))
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fn, body, 0644); err != nil {
+			return err
+		}
+		r.files[f.File] = f.key()
+	}
+	r.fixtures[f.key()] = f
+	return nil
+}
+
+// fileName returns an unused file name for the fixture.  Caller must hold lock.
+func (r *Recorder) fileName(f Fixture, contentType string) string {
+	base := FileName(f.Method, f.Path, f.Query, contentType)
+	ext := filepath.Ext(base)
+	fn := base
+	for i := 2; ; i++ {
+		if k, ok := r.files[fn]; !ok || k == f.key() {
+			return fn
+		}
+		fn = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(base, ext), i, ext)
+	}
+}
+
+func (r *Recorder) sanitize(contentType string, body []byte) []byte {
+	keys := r.RedactKeys
+	if keys == nil {
+		keys = DefaultRedactKeys
+	}
+	if len(keys) > 0 && extension(contentType) == ".json" {
+		body = redactJSON(body, keys)
+	}
+	if r.Sanitize != nil {
+		body = r.Sanitize(contentType, body)
+	}
+	return body
+}
+
+// redactJSON replaces string values of keys.  Invalid json is returned unchanged.
+func redactJSON(body []byte, keys []string) []byte {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	var redact = make(map[string]bool)
+	for _, k := range keys {
+		redact[k] = true
+	}
+	redactValue(v, redact)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+func redactValue(v interface{}, keys map[string]bool) {
+	switch vx := v.(type) {
+	case map[string]interface{}:
+		for k, val := range vx {
+			if s, ok := val.(string); ok && keys[k] && s > "" {
+				vx[k] = Redacted
+				continue
+			}
+			redactValue(val, keys)
+		}
+	case []interface{}:
+		for _, val := range vx {
+			redactValue(val, keys)
+		}
+	}
+}
+
+type transport struct {
+	rec  *Recorder
+	base http.RoundTripper
+}
+
+// RoundTrip records the response of the base transport.  The caller
+// receives the unsanitized body.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	res, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := t.rec.record(req, res, body); err != nil {
+		return nil, fmt.Errorf("record %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	return res, nil
+}
+
+var apiPrefix = regexp.MustCompile(`^/?services/data/v[0-9]+\.[0-9]+/`)
+
+// APIPath returns path relative to the versioned api path so that
+// fixtures replay for any api version or base url.
+func APIPath(path string) string {
+	return strings.TrimPrefix(apiPrefix.ReplaceAllString(path, ""), "/")
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// FileName returns the default fixture file name, <method>/<name>.<ext>, of a
+// request.  The name is the api path with separators replaced by underscores
+// followed by a checksum of the query, if any.
+func FileName(method, path, query, contentType string) string {
+	name := strings.Trim(unsafeChars.ReplaceAllString(strings.Replace(APIPath(path), "/", "_", -1), "-"), "_-")
+	if name == "" {
+		name = "root"
+	}
+	if query > "" {
+		name += fmt.Sprintf("_%08x", crc32.ChecksumIEEE([]byte(query)))
+	}
+	return strings.ToLower(method) + "/" + name + extension(contentType)
+}
+
+func extension(contentType string) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mt == "text/csv":
+		return ".csv"
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return ".json"
+	case mt == "application/xml" || mt == "text/xml":
+		return ".xml"
+	case strings.HasPrefix(mt, "text/"):
+		return ".txt"
+	}
+	return ".bin"
+}
@@ -0,0 +1,175 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+	"github.com/jfcote87/salesforce/testutil"
+)
+
+type contact struct {
+	ID       string `json:"Id,omitempty"`
+	LastName string `json:"LastName,omitempty"`
+	Email    string `json:"Email,omitempty"`
+}
+
+func (c contact) SObjectName() string {
+	return "Contact"
+}
+
+func (c contact) WithAttr(ref string) salesforce.SObject {
+	return c
+}
+
+func liveServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.Header().Set("Sforce-Limit-Info", "api-usage=10/15000")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /services/data/v55.0/query/":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"totalSize":      2,
+				"done":           false,
+				"nextRecordsUrl": "/services/data/v55.0/query/01gX-1",
+				"records":        []contact{{ID: "003A", LastName: "Smith", Email: "smith@example.com"}},
+			})
+		case "GET /services/data/v55.0/query/01gX-1":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"totalSize": 2,
+				"done":      true,
+				"records":   []contact{{ID: "003B", LastName: "Jones <" + r.Host + ">", Email: "jones@example.com"}},
+			})
+		case "DELETE /services/data/v55.0/sobjects/Contact/003A":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /services/data/v55.0/sobjects/Contact/":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`[{"errorCode":"REQUIRED_FIELD_MISSING","message":"Required fields are missing: [LastName]"}]`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRecordReplay(t *testing.T) {
+	live := liveServer()
+	defer live.Close()
+	dir, err := ioutil.TempDir("", "sftestutil")
+	if err != nil {
+		t.Fatalf("tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rec, err := testutil.NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	ctx := context.Background()
+	liveClient := live.Client()
+	sv := salesforce.New("live.my.salesforce.com", "v55.0", nil).
+		WithCtxClientFunc(rec.ClientFunc(func(ctx context.Context) (*http.Client, error) {
+			return liveClient, nil
+		})).
+		WithURL(live.URL + "/services/data/v55.0/")
+
+	var recorded []contact
+	if err := sv.Query(ctx, "SELECT Id, LastName, Email FROM Contact", &recorded); err != nil {
+		t.Fatalf("live query: %v", err)
+	}
+	if len(recorded) != 2 || recorded[0].Email != "smith@example.com" {
+		t.Fatalf("expected unsanitized live records; got %v", recorded)
+	}
+	if err := sv.Delete(ctx, "Contact", "003A"); err != nil {
+		t.Fatalf("live delete: %v", err)
+	}
+	_, createErr := sv.Create(ctx, contact{})
+	if createErr == nil {
+		t.Fatalf("expected live create error")
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	fixtures := rec.Fixtures()
+	if len(fixtures) != 4 {
+		t.Fatalf("expected 4 fixtures; got %d", len(fixtures))
+	}
+	for _, f := range fixtures {
+		if f.Method == "DELETE" && (f.File != "" || f.Status != http.StatusNoContent) {
+			t.Errorf("expected DELETE fixture without file; got %#v", f)
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "get", "query_01gX-1.json"))
+	if err != nil {
+		t.Fatalf("expected query page fixture; %v", err)
+	}
+	if s := string(b); strings.Contains(s, "example.com") || strings.Contains(s, live.Listener.Addr().String()) ||
+		!strings.Contains(s, testutil.Redacted) || !strings.Contains(s, testutil.ReplayHost) {
+		t.Errorf("expected sanitized fixture; got %s", s)
+	}
+
+	h, err := testutil.NewHandler(dir)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	ws := httptest.NewServer(h)
+	defer ws.Close()
+	sv = salesforce.New("instance.my.salesforce.com", "v56.0", nil).WithURL(ws.URL + "/")
+
+	var replayed []contact
+	if err := sv.Query(ctx, "SELECT Id, LastName, Email FROM Contact", &replayed); err != nil {
+		t.Fatalf("replay query: %v", err)
+	}
+	if len(replayed) != 2 || replayed[1].ID != "003B" || replayed[1].Email != testutil.Redacted ||
+		replayed[1].LastName != "Jones <"+testutil.ReplayHost+">" {
+		t.Errorf("unexpected replayed records %v", replayed)
+	}
+	if err := sv.Delete(ctx, "Contact", "003A"); err != nil {
+		t.Errorf("replay delete: %v", err)
+	}
+	if _, err := sv.Create(ctx, contact{}); err == nil || err.Error() != createErr.Error() {
+		t.Errorf("expected replay error %v; got %v", createErr, err)
+	}
+	if err := sv.Delete(ctx, "Contact", "003Z"); !salesforce.HasErrorCode(err, "NOT_FOUND") {
+		t.Errorf("expected NOT_FOUND for missing fixture; got %v", err)
+	}
+
+	// hand written file using the default name
+	os.MkdirAll(filepath.Join(dir, "get"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(dir, "get", "sobjects_Contact_003C.json"),
+		[]byte(`{"Id":"003C","LastName":"Doe"}`), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var c contact
+	if err := sv.Get(ctx, &c, "003C"); err != nil || c.LastName != "Doe" {
+		t.Errorf("expected Doe from hand written fixture; got %v %v", c, err)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		method, path, query, ct, want string
+	}{
+		{"GET", "/services/data/v55.0/sobjects/Contact/describe", "", "application/json;charset=UTF-8", "get/sobjects_Contact_describe.json"},
+		{"PUT", "jobs/ingest/750A/batches", "", "text/csv", "put/jobs_ingest_750A_batches.csv"},
+		{"GET", "/services/data/v55.0/", "", "", "get/root.bin"},
+		{"GET", "query/", "q=SELECT+Id+FROM+Contact", "application/json", "get/query_"},
+	}
+	for _, tt := range tests {
+		if got := testutil.FileName(tt.method, tt.path, tt.query, tt.ct); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("FileName(%s %s) expected %s; got %s", tt.method, tt.path, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jfcote87/oauth2"
+)
+
+// CachedTokenSource returns a TokenSource that caches tokens from ts and is safe
+// for concurrent use.  Salesforce access tokens usually have no expiry, so a token
+// without an Expiry is cached for 4 hours (default session timeout) from the time
+// it was retrieved.  A new token is retrieved skew before the expiry to protect
+// against clock differences and in-flight calls.
+func CachedTokenSource(ts oauth2.TokenSource, skew time.Duration) oauth2.TokenSource {
+	if skew < 0 {
+		skew = 0
+	}
+	return &cachedTokenSource{ts: ts, skew: skew}
+}
+
+type cachedTokenSource struct {
+	ts      oauth2.TokenSource
+	skew    time.Duration
+	m       sync.Mutex
+	tk      *oauth2.Token
+	expires time.Time
+}
+
+// Token returns the cached token or retrieves a new token
+// when the cached token is within skew of expiring.
+func (c *cachedTokenSource) Token(ctx context.Context) (*oauth2.Token, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	now := time.Now()
+	if c.tk != nil && now.Add(c.skew).Before(c.expires) {
+		return c.tk, nil
+	}
+	tk, err := c.ts.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.tk, c.expires = tk, tk.Expiry
+	if c.expires.IsZero() {
+		c.expires = now.Add(defaultTokenDuration)
+	}
+	return tk, nil
+}
+
+// RevokeToken revokes the service's current access token ending the session.
+// https://help.salesforce.com/s/articleView?id=sf.remoteaccess_revoke_token.htm&type=5
+func (sv *Service) RevokeToken(ctx context.Context) error {
+	tk, err := sv.token(ctx)
+	if err != nil {
+		return err
+	}
+	body := strings.NewReader(url.Values{"token": {tk.AccessToken}}.Encode())
+	return sv.Call(ctx, "/services/oauth2/revoke", "POST", body, nil, ContentTypeHeader(formContentType))
+}
+
+// TokenIntrospection describes the state of an access token
+// https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oidc_token_introspection_endpoint.htm&type=5
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+}
+
+// IntrospectToken returns the state and scopes of the service's current access token.
+// Salesforce requires the connected app's client id and secret to authorize introspection.
+// https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oidc_token_introspection_endpoint.htm&type=5
+func (sv *Service) IntrospectToken(ctx context.Context, clientID, clientSecret string) (*TokenIntrospection, error) {
+	tk, err := sv.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body := strings.NewReader(url.Values{
+		"token":           {tk.AccessToken},
+		"token_type_hint": {"access_token"},
+		"client_id":       {clientID},
+		"client_secret":   {clientSecret},
+	}.Encode())
+	var result *TokenIntrospection
+	return result, sv.Call(ctx, "/services/oauth2/introspect", "POST", body, &result, ContentTypeHeader(formContentType))
+}
+
+const formContentType = "application/x-www-form-urlencoded"
+
+// token returns the service's current token
+func (sv *Service) token(ctx context.Context) (*oauth2.Token, error) {
+	if sv == nil || sv.ts == nil {
+		return nil, errors.New("service has no token source")
+	}
+	return sv.ts.Token(ctx)
+}
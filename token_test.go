@@ -0,0 +1,124 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfcote87/oauth2"
+	"github.com/jfcote87/salesforce"
+)
+
+type countTokenSource struct {
+	m      sync.Mutex
+	cnt    int
+	expiry time.Duration
+	err    error
+}
+
+func (ct *countTokenSource) Token(ctx context.Context) (*oauth2.Token, error) {
+	ct.m.Lock()
+	defer ct.m.Unlock()
+	if ct.err != nil {
+		return nil, ct.err
+	}
+	ct.cnt++
+	tk := &oauth2.Token{AccessToken: fmt.Sprintf("TK%d", ct.cnt)}
+	if ct.expiry != 0 {
+		tk.Expiry = time.Now().Add(ct.expiry)
+	}
+	return tk, nil
+}
+
+func TestCachedTokenSource(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		expiry time.Duration
+		skew   time.Duration
+		want   int
+	}{
+		{name: "no expiry", want: 1},
+		{name: "valid expiry", expiry: time.Hour, skew: time.Minute, want: 1},
+		{name: "within skew", expiry: 30 * time.Second, skew: time.Minute, want: 10},
+		{name: "expired", expiry: -time.Second, want: 10},
+	}
+	for _, tt := range tests {
+		src := &countTokenSource{expiry: tt.expiry}
+		ts := salesforce.CachedTokenSource(src, tt.skew)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := ts.Token(ctx); err != nil {
+					t.Errorf("%s: expected success; got %v", tt.name, err)
+				}
+			}()
+		}
+		wg.Wait()
+		if src.cnt != tt.want {
+			t.Errorf("%s: expected %d token retrievals; got %d", tt.name, tt.want, src.cnt)
+		}
+	}
+
+	src := &countTokenSource{err: errors.New("token error")}
+	ts := salesforce.CachedTokenSource(src, 0)
+	if _, err := ts.Token(ctx); err == nil || err.Error() != "token error" {
+		t.Errorf("expected token error; got %v", err)
+	}
+	src.err = nil
+	if tk, err := ts.Token(ctx); err != nil || tk.AccessToken != "TK1" {
+		t.Errorf("expected TK1; got %v %v", tk, err)
+	}
+}
+
+func TestService_RevokeIntrospectToken(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("token") != "ABC" {
+			http.Error(w, "invalid token", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/services/oauth2/revoke":
+			return
+		case "/services/oauth2/introspect":
+			if r.Form.Get("client_id") != "ID" || r.Form.Get("client_secret") != "SECRET" {
+				http.Error(w, "invalid client", http.StatusUnauthorized)
+				return
+			}
+			encodeObject(w, salesforce.TokenIntrospection{Active: true, Scope: "api refresh_token"})
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer ws.Close()
+
+	ctx := context.Background()
+	sv := salesforce.New("", "", oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ABC"})).
+		WithURL(ws.URL + "/services/data/v53.0/")
+	if err := sv.RevokeToken(ctx); err != nil {
+		t.Errorf("revoke expected success; got %v", err)
+	}
+	ti, err := sv.IntrospectToken(ctx, "ID", "SECRET")
+	if err != nil || !ti.Active || ti.Scope != "api refresh_token" {
+		t.Errorf("introspect expected active token; got %#v %v", ti, err)
+	}
+	if _, err := sv.IntrospectToken(ctx, "ID", "BAD"); err == nil {
+		t.Errorf("introspect expected unauthorized error")
+	}
+	sv = salesforce.New("", "", nil).WithURL(ws.URL + "/services/data/v53.0/")
+	if err := sv.RevokeToken(ctx); err == nil || err.Error() != "service has no token source" {
+		t.Errorf("expected service has no token source; got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// String is a text field value that records whether it was set, allowing a
+// record to clear a field.  When a record is sent by a Service, an unset String
+// is omitted, a non-empty String is sent and a String set to "" is omitted or,
+// for a service created by WithNullEmptyStrings, sent as null to clear the field.
+// String fields of the record's struct (including embedded structs) and String
+// values of a RecordMap are tracked.  Outside of a Service call, an unset String marshals as null.
+type String struct {
+	Value string
+	Set   bool
+}
+
+// NewString returns a String set to s
+func NewString(s string) String {
+	return String{Value: s, Set: true}
+}
+
+// String returns the value
+func (s String) String() string {
+	return s.Value
+}
+
+// MarshalJSON outputs the value or null when not set
+func (s String) MarshalJSON() ([]byte, error) {
+	if !s.Set {
+		return []byte("null"), nil
+	}
+	return json.Marshal(s.Value)
+}
+
+// UnmarshalJSON sets the String to the received value.  A null
+// value sets an empty String.
+func (s *String) UnmarshalJSON(b []byte) error {
+	var v *string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*s = String{Set: true}
+	if v != nil {
+		s.Value = *v
+	}
+	return nil
+}
+
+// WithNullEmptyStrings returns a service that sends String fields set to ""
+// as null, clearing the field, rather than omitting them.
+func (sv *Service) WithNullEmptyStrings(on bool) *Service {
+	snew := sv.clone()
+	snew.nullEmpty = on
+	return snew
+}
+
+var stringType = reflect.TypeOf(String{})
+
+// stringFieldCache stores the json names and indexes of the String fields of struct types
+var stringFieldCache sync.Map
+
+// stringFields returns the json names and indexes of the String fields of ty
+func stringFields(ty reflect.Type) map[string][]int {
+	if m, ok := stringFieldCache.Load(ty); ok {
+		return m.(map[string][]int)
+	}
+	var m = make(map[string][]int)
+	for nm, idx := range jsonfield.Index(ty) {
+		if ty.FieldByIndex(idx).Type == stringType {
+			m[nm] = idx
+		}
+	}
+	stringFieldCache.Store(ty, m)
+	return m
+}
+
+// prepareBody wraps the records of a call body whose structs contain String
+// fields, or all records when the service has a FieldMask, in a sendRecord.
+func (sv *Service) prepareBody(body interface{}) interface{} {
+	if _, ok := sv.enc().(jsonEncoding); !ok {
+		return body
+	}
+	switch b := body.(type) {
+	case BatchBody:
+		recs := make([]SObject, len(b.Records))
+		for i, r := range b.Records {
+			recs[i] = sv.prepareRecord(r)
+		}
+		b.Records = recs
+		return b
+	case CompositeRequest:
+		subs := make([]*CompositeSubrequest, len(b.Subrequests))
+		for i, sr := range b.Subrequests {
+			s := *sr
+			if rec, ok := s.Body.(SObject); ok {
+				s.Body = sv.prepareRecord(rec)
+			}
+			subs[i] = &s
+		}
+		b.Subrequests = subs
+		return b
+	case SObject:
+		return sv.prepareRecord(b)
+	}
+	return body
+}
+
+// prepareRecord returns rec wrapped in a sendRecord when rec's struct has
+// String fields, rec is a RecordMap with String values or the service has
+// a FieldMask
+func (sv *Service) prepareRecord(rec SObject) SObject {
+	ty := reflect.TypeOf(rec)
+	if ty == nil {
+		return rec
+	}
+	if ty.Kind() == reflect.Ptr {
+		ty = ty.Elem()
+	}
+	if sv.fieldMask != nil && (ty.Kind() == reflect.Struct || ty.Kind() == reflect.Map) {
+		return sendRecord{SObject: rec, nullEmpty: sv.nullEmpty, mask: sv.fieldMask}
+	}
+	if m, ok := rec.(RecordMap); ok && hasStringValues(m) ||
+		ty.Kind() == reflect.Struct && len(stringFields(ty)) > 0 {
+		return sendRecord{SObject: rec, nullEmpty: sv.nullEmpty}
+	}
+	return rec
+}
+
+// hasStringValues returns true when a value of m is a String
+func hasStringValues(m RecordMap) bool {
+	for _, v := range m {
+		if _, ok := v.(String); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sendRecord marshals an SObject omitting unset and empty String fields
+// or, when nullEmpty is set, sending empty String fields as null.  When
+// mask is not nil, only fields of the mask, Id and attributes are sent.
+type sendRecord struct {
+	SObject
+	nullEmpty bool
+	mask      map[string]bool
+}
+
+// MarshalJSON encodes the record and then removes or nulls String fields
+// and removes fields not found in the mask
+func (sr sendRecord) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(sr.SObject)
+	if err != nil {
+		return nil, err
+	}
+	val := reflect.ValueOf(sr.SObject)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return b, nil
+		}
+		val = val.Elem()
+	}
+	var flds map[string]json.RawMessage
+	if err := json.Unmarshal(b, &flds); err != nil {
+		return b, nil
+	}
+	if val.Kind() == reflect.Struct {
+		for nm, idx := range stringFields(val.Type()) {
+			sr.setString(flds, nm, val.FieldByIndex(idx).Interface().(String))
+		}
+	}
+	if m, ok := sr.SObject.(RecordMap); ok {
+		for nm, v := range m {
+			if s, ok := v.(String); ok {
+				sr.setString(flds, nm, s)
+			}
+		}
+	}
+	if sr.mask != nil {
+		var goNames map[string]string
+		if val.Kind() == reflect.Struct {
+			goNames = make(map[string]string)
+			for nm, idx := range jsonfield.Index(val.Type()) {
+				goNames[nm] = strings.ToLower(val.Type().FieldByIndex(idx).Name)
+			}
+		}
+		for nm := range flds {
+			if nm != "attributes" && nm != "Id" && !sr.mask[strings.ToLower(nm)] && !sr.mask[goNames[nm]] {
+				delete(flds, nm)
+			}
+		}
+	}
+	return json.Marshal(flds)
+}
+
+// setString removes the nm field from flds when s is unset or empty, or
+// sets it to null when s is empty and nullEmpty is set
+func (sr sendRecord) setString(flds map[string]json.RawMessage, nm string, s String) {
+	switch {
+	case !s.Set || s.Value == "" && !sr.nullEmpty:
+		delete(flds, nm)
+	case s.Value == "":
+		flds[nm] = json.RawMessage("null")
+	}
+}
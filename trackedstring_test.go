@@ -0,0 +1,87 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+type trackedLead struct {
+	Attributes *salesforce.Attributes `json:"attributes,omitempty"`
+	LastName   salesforce.String      `json:"LastName"`
+	Company    salesforce.String      `json:"Company"`
+	Title      salesforce.String      `json:"Title"`
+	Phone      string                 `json:"Phone,omitempty"`
+}
+
+func (l trackedLead) SObjectName() string {
+	return "Lead"
+}
+
+func (l trackedLead) WithAttr(ref string) salesforce.SObject {
+	l.Attributes = &salesforce.Attributes{Type: "Lead", Ref: ref}
+	return l
+}
+
+func TestString(t *testing.T) {
+	var bodies []string
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if r.URL.Path == "/composite/sobjects" {
+			encodeObject(w, []salesforce.OpResponse{{ID: "00QA", Success: true}})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/")
+
+	rec := &trackedLead{LastName: salesforce.NewString("Smith"), Title: salesforce.NewString("")}
+	if err := sv.Update(ctx, rec, "00QA"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := sv.WithNullEmptyStrings(true).Update(ctx, rec, "00QA"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if _, err := sv.WithNullEmptyStrings(true).UpdateRecords(ctx, false, []salesforce.SObject{*rec}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	want := []string{
+		`{"LastName":"Smith"}`,
+		`{"LastName":"Smith","Title":null}`,
+		`{"records":[{"LastName":"Smith","Title":null,"attributes":{"type":"Lead"}}]}`,
+	}
+	if len(bodies) != len(want) {
+		t.Fatalf("expected %d bodies; got %d", len(want), len(bodies))
+	}
+	for i, w := range want {
+		if bodies[i] != w+"\n" {
+			t.Errorf("call %d: expected %s; got %s", i, w, bodies[i])
+		}
+	}
+
+	var l trackedLead
+	if err := json.Unmarshal([]byte(`{"LastName":"Jones","Company":null}`), &l); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if l.LastName != salesforce.NewString("Jones") || l.Company != salesforce.NewString("") || l.Title.Set {
+		t.Errorf("unexpected unmarshal %#v", l)
+	}
+	if b, _ := json.Marshal(l); string(b) != `{"LastName":"Jones","Company":"","Title":null}` {
+		t.Errorf("unexpected marshal %s", b)
+	}
+}
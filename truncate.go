@@ -0,0 +1,160 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/jfcote87/salesforce/internal/jsonfield"
+)
+
+// WithAutoAssign returns a service that sends the Sforce-Auto-Assign header
+// on each call.  Setting assign to false prevents active assignment rules from
+// running on created or updated Cases and Leads.
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers_autoassign.htm
+func (sv *Service) WithAutoAssign(assign bool) *Service {
+	snew := sv.clone()
+	snew.autoAssign = "FALSE"
+	if assign {
+		snew.autoAssign = "TRUE"
+	}
+	return snew
+}
+
+// WithAutoTruncate returns a service that truncates string values longer than the
+// field lengths found in defs before sending records in Create, Update, Upsert and
+// collection calls.  The REST api has no equivalent of the SOAP AllowFieldTruncationHeader,
+// so truncation is done by the client to avoid STRING_TOO_LONG errors.  The truncated
+// characters are silently dropped and no error is returned; use WithTruncateFunc to be
+// told which fields of a record were truncated.  Use Describe to retrieve defs.  No defs
+// disables truncation.
+func (sv *Service) WithAutoTruncate(defs ...*SObjectDefinition) *Service {
+	snew := sv.clone()
+	snew.truncLengths = nil
+	for _, d := range defs {
+		if d == nil {
+			continue
+		}
+		if snew.truncLengths == nil {
+			snew.truncLengths = make(map[string]map[string]int)
+		}
+		lengths := make(map[string]int)
+		for _, f := range d.Fields {
+			if f.Length > 0 && f.SoapType == "xsd:string" {
+				lengths[f.Name] = f.Length
+			}
+		}
+		snew.truncLengths[d.Name] = lengths
+	}
+	return snew
+}
+
+// TruncateFunc is passed a record and the names of its fields that were
+// truncated by a service created with WithAutoTruncate.  rec is the caller's
+// record holding the original values.
+type TruncateFunc func(rec SObject, fields []string)
+
+// WithTruncateFunc returns a service that calls f whenever WithAutoTruncate
+// lengths shorten a field of a record.  Use f to log or count data loss.
+// A nil f removes the func.
+func (sv *Service) WithTruncateFunc(f TruncateFunc) *Service {
+	snew := sv.clone()
+	snew.truncFunc = f
+	return snew
+}
+
+// truncate returns rec with string values shortened to the field lengths
+// set by WithAutoTruncate.  rec is returned unchanged when no lengths exist
+// for its SObject.  Struct and struct pointer records are copied, so the
+// caller's record is never modified.  The names of truncated fields are
+// passed to the service's TruncateFunc.
+func (sv *Service) truncate(rec SObject) SObject {
+	if sv == nil || rec == nil || len(sv.truncLengths) == 0 {
+		return rec
+	}
+	lengths, ok := sv.truncLengths[rec.SObjectName()]
+	if !ok || len(lengths) == 0 {
+		return rec
+	}
+	var truncated []string
+	defer func() {
+		if len(truncated) > 0 && sv.truncFunc != nil {
+			sort.Strings(truncated)
+			sv.truncFunc(rec, truncated)
+		}
+	}()
+	if m, ok := rec.(RecordMap); ok {
+		var mnew = make(RecordMap, len(m))
+		for k, v := range m {
+			if s, ok := v.(string); ok && lengths[k] > 0 {
+				if v = truncateString(s, lengths[k]); v != s {
+					truncated = append(truncated, k)
+				}
+			}
+			mnew[k] = v
+		}
+		return mnew
+	}
+	val := reflect.ValueOf(rec)
+	isPtr := val.Kind() == reflect.Ptr
+	if isPtr {
+		if val.IsNil() {
+			return rec
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return rec
+	}
+	newPtr := reflect.New(val.Type())
+	newVal := newPtr.Elem()
+	newVal.Set(val)
+	for nm, idx := range jsonfield.Index(val.Type()) {
+		max := lengths[nm]
+		if max == 0 {
+			continue
+		}
+		fld := newVal.FieldByIndex(idx)
+		switch {
+		case fld.Kind() == reflect.String:
+			if s := fld.String(); truncateString(s, max) != s {
+				fld.SetString(truncateString(s, max))
+				truncated = append(truncated, nm)
+			}
+		case fld.Kind() == reflect.Ptr && !fld.IsNil() && fld.Elem().Kind() == reflect.String:
+			if s := fld.Elem().String(); truncateString(s, max) != s {
+				ptr := reflect.New(fld.Type().Elem())
+				ptr.Elem().SetString(truncateString(s, max))
+				fld.Set(ptr)
+				truncated = append(truncated, nm)
+			}
+		}
+	}
+	result := newVal
+	if isPtr {
+		result = newPtr
+	}
+	if sobj, ok := result.Interface().(SObject); ok {
+		return sobj
+	}
+	return rec
+}
+
+// truncateString shortens s to max characters
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	var cnt int
+	for i := range s {
+		if cnt == max {
+			return s[:i]
+		}
+		cnt++
+	}
+	return s
+}
@@ -0,0 +1,411 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UnitOfWork collects creates, updates and deletes across sobjects and commits
+// them together.  A record refers to a record created earlier in the unit by using
+// UnitOfWorkRef(ref) as a field value or id, e.g. Contact{AccountID: UnitOfWorkRef("acct")}.
+// Records are copied when registered, so later changes to a record are not sent.
+type UnitOfWork struct {
+	sv   *Service
+	ops  []*uowOp
+	refs map[string]*uowOp
+}
+
+// uowOp is a registered create, update or delete
+type uowOp struct {
+	method  string // POST, PATCH or DELETE
+	ref     string
+	sobject string
+	id      string
+	rec     RecordMap
+	deps    []string
+}
+
+// UnitOfWorkResult contains the results of a committed UnitOfWork
+type UnitOfWorkResult struct {
+	// IDs maps the reference of each created record to its new id
+	IDs map[string]string
+	// Responses are in registration order.  RecordIndex is the
+	// registration index of the operation.
+	Responses []OpResponse
+	// RolledBack lists the ids of created records deleted after a failure
+	RolledBack []string
+}
+
+// UnitOfWorkError reports the operation that caused a Commit to fail.
+type UnitOfWorkError struct {
+	Ref         string
+	Errors      []Error
+	RollbackErr error // error deleting created records
+}
+
+// Error returns the reference and errors of the failed operation
+func (e *UnitOfWorkError) Error() string {
+	var msgs []string
+	for _, x := range e.Errors {
+		msgs = append(msgs, x.StatusCode+": "+x.Message)
+	}
+	s := fmt.Sprintf("unit of work failed at %s: %s", e.Ref, strings.Join(msgs, "; "))
+	if e.RollbackErr != nil {
+		s += fmt.Sprintf(" (rollback: %v)", e.RollbackErr)
+	}
+	return s
+}
+
+var (
+	uowRefPattern   = regexp.MustCompile(`@\{(\w+)\.id\}`)
+	uowValidPattern = regexp.MustCompile(`^\w+$`)
+)
+
+// UnitOfWorkRef returns a value that is replaced by the id of the record
+// registered with ref when the UnitOfWork is committed.
+func UnitOfWorkRef(ref string) string {
+	return "@{" + ref + ".id}"
+}
+
+// NewUnitOfWork returns an empty UnitOfWork committed using sv.
+func (sv *Service) NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{sv: sv, refs: make(map[string]*uowOp)}
+}
+
+// RegisterNew adds rec to the records created by the unit.  Later records
+// refer to rec's new id using UnitOfWorkRef(ref).  An empty ref is assigned
+// a generated value.
+func (uow *UnitOfWork) RegisterNew(ref string, rec SObject) error {
+	return uow.register("POST", ref, rec, "", "")
+}
+
+// RegisterDirty adds rec to the records updated by the unit.  id may be
+// a UnitOfWorkRef of a previously registered new record.
+func (uow *UnitOfWork) RegisterDirty(ref string, rec SObject, id string) error {
+	if id == "" {
+		return errors.New("update requires an id")
+	}
+	return uow.register("PATCH", ref, rec, "", id)
+}
+
+// RegisterDeleted adds the sobjectName record with id to the records deleted by
+// the unit.  id may be a UnitOfWorkRef of a previously registered new record.
+func (uow *UnitOfWork) RegisterDeleted(ref string, sobjectName, id string) error {
+	if sobjectName == "" || id == "" {
+		return errors.New("delete requires an sobject name and id")
+	}
+	return uow.register("DELETE", ref, nil, sobjectName, id)
+}
+
+// Len returns the number of registered operations
+func (uow *UnitOfWork) Len() int {
+	return len(uow.ops)
+}
+
+func (uow *UnitOfWork) register(method, ref string, rec SObject, sobjectName, id string) error {
+	if ref == "" {
+		ref = fmt.Sprintf("uow%d", len(uow.ops))
+	}
+	if !uowValidPattern.MatchString(ref) {
+		return fmt.Errorf("invalid reference %q; use letters, digits and underscores", ref)
+	}
+	if _, ok := uow.refs[ref]; ok {
+		return fmt.Errorf("duplicate reference id %s", ref)
+	}
+	op := &uowOp{method: method, ref: ref, sobject: sobjectName, id: id}
+	if rec != nil {
+		m, err := uowRecordMap(uow.sv.prepareRecord(uow.sv.truncate(rec)))
+		if err != nil {
+			return err
+		}
+		op.sobject, op.rec = rec.SObjectName(), m
+	}
+	if op.sobject == "" {
+		return errors.New("record has no sobject name")
+	}
+	var found = make(map[string]bool)
+	for _, s := range append(uowStrings(op.rec), id) {
+		for _, m := range uowRefPattern.FindAllStringSubmatch(s, -1) {
+			dep := m[1]
+			if p, ok := uow.refs[dep]; !ok || p.method != "POST" {
+				return fmt.Errorf("reference %s is not a new record registered earlier in the unit of work", dep)
+			}
+			if !found[dep] {
+				found[dep] = true
+				op.deps = append(op.deps, dep)
+			}
+		}
+	}
+	uow.ops = append(uow.ops, op)
+	uow.refs[ref] = op
+	return nil
+}
+
+// uowRecordMap copies rec into a RecordMap without attributes or Id.  rec
+// should be prepared (see prepareRecord) so that unset Strings are omitted.
+func uowRecordMap(rec SObject) (RecordMap, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	var m RecordMap
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	delete(m, "attributes")
+	delete(m, "Id")
+	return m, nil
+}
+
+// uowStrings returns the string values found in v
+func uowStrings(v interface{}) []string {
+	switch x := v.(type) {
+	case string:
+		return []string{x}
+	case RecordMap:
+		return uowStrings(map[string]interface{}(x))
+	case map[string]interface{}:
+		var s []string
+		for _, val := range x {
+			s = append(s, uowStrings(val)...)
+		}
+		return s
+	case []interface{}:
+		var s []string
+		for _, val := range x {
+			s = append(s, uowStrings(val)...)
+		}
+		return s
+	}
+	return nil
+}
+
+// uowResolve replaces references in v with the ids of created records
+func uowResolve(v interface{}, ids map[string]string) interface{} {
+	switch x := v.(type) {
+	case string:
+		return uowRefPattern.ReplaceAllStringFunc(x, func(s string) string {
+			if id, ok := ids[uowRefPattern.FindStringSubmatch(s)[1]]; ok {
+				return id
+			}
+			return s
+		})
+	case RecordMap:
+		return RecordMap(uowResolve(map[string]interface{}(x), ids).(map[string]interface{}))
+	case map[string]interface{}:
+		var m = make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[k] = uowResolve(val, ids)
+		}
+		return m
+	case []interface{}:
+		var l = make([]interface{}, len(x))
+		for i, val := range x {
+			l[i] = uowResolve(val, ids)
+		}
+		return l
+	}
+	return v
+}
+
+// Commit sends the registered operations in order.  A unit of up to
+// MaxCompositeSubrequests operations is sent as a single all-or-none
+// composite request with references resolved by salesforce.  Larger units
+// are sent as ordered collection calls, grouping consecutive operations of
+// the same kind and sobject, with references resolved between calls.  When
+// a collection call fails, records created by the unit are deleted; updates
+// and deletes already made are not reverted.  A failed operation returns a
+// *UnitOfWorkError along with the result.
+func (uow *UnitOfWork) Commit(ctx context.Context) (*UnitOfWorkResult, error) {
+	if uow == nil || len(uow.ops) == 0 {
+		return nil, ErrZeroRecords
+	}
+	if len(uow.ops) <= MaxCompositeSubrequests {
+		return uow.commitComposite(ctx)
+	}
+	return uow.commitCollections(ctx)
+}
+
+// commitComposite sends the unit as an all-or-none composite request
+// https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_composite_composite.htm
+func (uow *UnitOfWork) commitComposite(ctx context.Context) (*UnitOfWorkResult, error) {
+	req := &CompositeRequest{AllOrNone: true}
+	for _, op := range uow.ops {
+		path := "sobjects/" + op.sobject
+		var body interface{}
+		if op.method != "POST" {
+			path += "/" + op.id
+		}
+		if op.rec != nil {
+			body = op.rec
+		}
+		req.Add(op.method, path, op.ref, body, nil)
+	}
+	res, err := uow.sv.Composite(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var result = &UnitOfWorkResult{IDs: make(map[string]string), Responses: make([]OpResponse, len(uow.ops))}
+	var uerr *UnitOfWorkError
+	for i, op := range uow.ops {
+		opr := OpResponse{RecordIndex: i, ID: op.id}
+		sub := res.Subresponse(op.ref)
+		switch {
+		case sub == nil:
+			opr.Errors = []Error{{StatusCode: "MISSING_RESPONSE", Message: "no subresponse for " + op.ref}}
+		case sub.Success():
+			opr.Success = true
+			if op.method == "POST" {
+				var cr OpResponse
+				if err := json.Unmarshal(sub.Body, &cr); err != nil {
+					return nil, fmt.Errorf("%s: %v", op.ref, err)
+				}
+				opr.ID, opr.Created = cr.ID, true
+				result.IDs[op.ref] = cr.ID
+			}
+		default:
+			opr.Errors = sub.Errors()
+		}
+		result.Responses[i] = opr
+		if !opr.Success && (uerr == nil || uowHalted(uerr.Errors) && !uowHalted(opr.Errors)) {
+			uerr = &UnitOfWorkError{Ref: op.ref, Errors: opr.Errors}
+		}
+	}
+	for i, op := range uow.ops {
+		result.Responses[i].ID = uowResolve(op.id, result.IDs).(string)
+		if op.method == "POST" {
+			result.Responses[i].ID = result.IDs[op.ref]
+		}
+	}
+	if uerr != nil {
+		return result, uerr
+	}
+	return result, nil
+}
+
+// uowHalted returns true when errs indicate that the operation was rolled
+// back due to the failure of another operation.
+func uowHalted(errs []Error) bool {
+	for _, e := range errs {
+		if e.StatusCode == "PROCESSING_HALTED" || e.StatusCode == "ALL_OR_NONE_OPERATION_ROLLED_BACK" {
+			return true
+		}
+	}
+	return false
+}
+
+// commitCollections sends the unit as ordered collection calls
+func (uow *UnitOfWork) commitCollections(ctx context.Context) (*UnitOfWorkResult, error) {
+	var result = &UnitOfWorkResult{IDs: make(map[string]string), Responses: make([]OpResponse, len(uow.ops))}
+	var created []string
+	for i := 0; i < len(uow.ops); {
+		j := i + 1
+		for j < len(uow.ops) && uow.ops[j].method == uow.ops[i].method &&
+			uow.ops[j].sobject == uow.ops[i].sobject && !uowDependsOn(uow.ops[j], uow.ops[i:j]) {
+			j++
+		}
+		batch := uow.ops[i:j]
+		res, err := uow.sendBatch(ctx, batch, result.IDs)
+		var uerr *UnitOfWorkError
+		for k := range batch {
+			opr := OpResponse{RecordIndex: i + k, ID: uowResolve(batch[k].id, result.IDs).(string)}
+			if k < len(res) {
+				opr.ID, opr.Success, opr.Errors, opr.Created = res[k].ID, res[k].Success, res[k].Errors, res[k].Created
+			}
+			if opr.Success && batch[k].method == "POST" {
+				result.IDs[batch[k].ref] = opr.ID
+				created = append(created, opr.ID)
+			}
+			result.Responses[i+k] = opr
+			if !opr.Success && uerr == nil && len(opr.Errors) > 0 {
+				uerr = &UnitOfWorkError{Ref: batch[k].ref, Errors: opr.Errors}
+			}
+		}
+		if err != nil || uerr != nil {
+			if uerr == nil {
+				uerr = &UnitOfWorkError{Ref: batch[0].ref, Errors: []Error{{Message: fmt.Sprintf("%v", err)}}}
+			}
+			result.RolledBack, uerr.RollbackErr = uow.rollback(ctx, created)
+			return result, uerr
+		}
+		i = j
+	}
+	return result, nil
+}
+
+// uowDependsOn returns true when op references a record created in ops
+func uowDependsOn(op *uowOp, ops []*uowOp) bool {
+	for _, dep := range op.deps {
+		for _, o := range ops {
+			if o.ref == dep {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendBatch sends ops of the same kind and sobject as a collection call
+func (uow *UnitOfWork) sendBatch(ctx context.Context, ops []*uowOp, ids map[string]string) ([]OpResponse, error) {
+	var opts = CollectionOptions{AllOrNone: true}
+	if ops[0].method == "DELETE" {
+		var delIDs = make([]string, len(ops))
+		for i, op := range ops {
+			delIDs[i] = uowResolve(op.id, ids).(string)
+		}
+		return uow.sv.DeleteRecordsWithOptions(ctx, delIDs, opts)
+	}
+	var recs = make([]SObject, len(ops))
+	for i, op := range ops {
+		m := uowResolve(op.rec, ids).(RecordMap)
+		m["attributes"] = map[string]interface{}{"type": op.sobject}
+		if op.method == "PATCH" {
+			m["Id"] = uowResolve(op.id, ids).(string)
+		}
+		recs[i] = m
+	}
+	if ops[0].method == "PATCH" {
+		return uow.sv.UpdateRecordsWithOptions(ctx, recs, opts)
+	}
+	return uow.sv.CreateRecordsWithOptions(ctx, recs, opts)
+}
+
+// rollback deletes created records in reverse order of creation
+func (uow *UnitOfWork) rollback(ctx context.Context, created []string) ([]string, error) {
+	if len(created) == 0 {
+		return nil, nil
+	}
+	var ids = make([]string, len(created))
+	for i, id := range created {
+		ids[len(created)-1-i] = id
+	}
+	res, err := uow.sv.DeleteRecordsWithOptions(ctx, ids, CollectionOptions{})
+	var deleted []string
+	var failed []string
+	for _, r := range res {
+		if r.RecordIndex >= len(ids) {
+			continue
+		}
+		if r.Success {
+			deleted = append(deleted, ids[r.RecordIndex])
+			continue
+		}
+		failed = append(failed, ids[r.RecordIndex])
+	}
+	if err == nil && len(failed) > 0 {
+		err = fmt.Errorf("unable to delete %s", strings.Join(failed, ","))
+	}
+	return deleted, err
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+func TestUnitOfWork_Composite(t *testing.T) {
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v55.0/composite" || r.Method != "POST" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			AllOrNone   bool `json:"allOrNone"`
+			Subrequests []struct {
+				Method      string                 `json:"method"`
+				URL         string                 `json:"url"`
+				ReferenceID string                 `json:"referenceId"`
+				Body        map[string]interface{} `json:"body"`
+			} `json:"compositeRequest"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.AllOrNone || len(req.Subrequests) != 3 ||
+			req.Subrequests[1].Body["AccountId"] != "@{acct.id}" ||
+			req.Subrequests[2].URL != "/services/data/v55.0/sobjects/Account/@{acct.id}" ||
+			req.Subrequests[2].Method != "PATCH" {
+			http.Error(w, "invalid subrequests", http.StatusBadRequest)
+			return
+		}
+		if _, ok := req.Subrequests[0].Body["attributes"]; ok {
+			http.Error(w, "attributes in body", http.StatusBadRequest)
+			return
+		}
+		encodeObject(w, map[string]interface{}{
+			"compositeResponse": []map[string]interface{}{
+				{"httpStatusCode": 201, "referenceId": "acct", "body": map[string]interface{}{"id": "001A", "success": true}},
+				{"httpStatusCode": 201, "referenceId": "contact", "body": map[string]interface{}{"id": "003A", "success": true}},
+				{"httpStatusCode": 204, "referenceId": "uow2"},
+			},
+		})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/")
+
+	uow := sv.NewUnitOfWork()
+	if _, err := uow.Commit(ctx); err != salesforce.ErrZeroRecords {
+		t.Errorf("expected %v; got %v", salesforce.ErrZeroRecords, err)
+	}
+	if err := uow.RegisterNew("bad", Contact{AccountID: salesforce.UnitOfWorkRef("missing")}); err == nil {
+		t.Errorf("expected missing reference error")
+	}
+	if err := uow.RegisterNew("acct", &Account{Attributes: &salesforce.Attributes{Type: "Account"}, AccountName: "Acme"}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := uow.RegisterNew("acct", &Account{AccountName: "Dup"}); err == nil {
+		t.Errorf("expected duplicate reference error")
+	}
+	if err := uow.RegisterNew("contact", Contact{LastName: "Smith", AccountID: salesforce.UnitOfWorkRef("acct")}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := uow.RegisterDirty("", Account{Website: "www.example.com"}, salesforce.UnitOfWorkRef("acct")); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	res, err := uow.Commit(ctx)
+	if err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if res.IDs["acct"] != "001A" || res.IDs["contact"] != "003A" || len(res.Responses) != 3 ||
+		res.Responses[2].ID != "001A" || !res.Responses[2].Success {
+		t.Errorf("unexpected result %#v", res)
+	}
+}
+
+func TestUnitOfWork_String(t *testing.T) {
+	var bodies []map[string]interface{}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Subrequests []struct {
+				ReferenceID string                 `json:"referenceId"`
+				Body        map[string]interface{} `json:"body"`
+			} `json:"compositeRequest"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var subs []map[string]interface{}
+		for _, sr := range req.Subrequests {
+			bodies = append(bodies, sr.Body)
+			subs = append(subs, map[string]interface{}{"httpStatusCode": 204, "referenceId": sr.ReferenceID})
+		}
+		encodeObject(w, map[string]interface{}{"compositeResponse": subs})
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/")
+
+	uow := sv.NewUnitOfWork()
+	if err := uow.RegisterDirty("lead", trackedLead{Company: salesforce.NewString("Acme"), Title: salesforce.NewString("")}, "00QA"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	rm := salesforce.RecordMap{"attributes": map[string]string{"type": "Lead"}, "Company": salesforce.NewString("Acme"), "Title": salesforce.String{}}
+	if err := uow.RegisterDirty("leadmap", rm, "00QB"); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if _, err := uow.Commit(ctx); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	for i, b := range bodies {
+		if _, ok := b["LastName"]; ok || len(b) != 1 || b["Company"] != "Acme" {
+			t.Errorf("body %d: expected only Company; got %v", i, b)
+		}
+	}
+	if len(bodies) != 2 {
+		t.Errorf("expected 2 subrequests; got %d", len(bodies))
+	}
+}
+
+func TestUnitOfWork_Collections(t *testing.T) {
+	var deleted string
+	var patched []map[string]interface{}
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v55.0/composite/sobjects" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Records []map[string]interface{} `json:"records"`
+		}
+		var res []salesforce.OpResponse
+		switch r.Method {
+		case "POST":
+			json.NewDecoder(r.Body).Decode(&body)
+			for _, rec := range body.Records {
+				id := fmt.Sprintf("003%v", rec["LastName"])
+				if rec["attributes"].(map[string]interface{})["type"] == "Account" {
+					id = fmt.Sprintf("001%v", rec["Name"])
+				}
+				res = append(res, salesforce.OpResponse{ID: id, Success: true})
+			}
+		case "PATCH":
+			json.NewDecoder(r.Body).Decode(&body)
+			patched = body.Records
+			for range body.Records {
+				res = append(res, salesforce.OpResponse{Errors: []salesforce.Error{{StatusCode: "UNABLE_TO_LOCK_ROW", Message: "locked"}}})
+			}
+		case "DELETE":
+			deleted = r.URL.Query().Get("ids")
+			for range strings.Split(deleted, ",") {
+				res = append(res, salesforce.OpResponse{Success: true})
+			}
+		}
+		encodeObject(w, res)
+	}))
+	defer ws.Close()
+
+	ctx := context.WithValue(context.Background(), "TK", "CALL OK")
+	sv := salesforce.New("aninstance.my.salesforce", "", nil).WithCtxClientFunc(getTokenClientFunc()).
+		WithURL(ws.URL + "/services/data/v55.0/")
+
+	uow := sv.NewUnitOfWork()
+	for i := 0; i < salesforce.MaxCompositeSubrequests; i++ {
+		ref := fmt.Sprintf("a%d", i)
+		if err := uow.RegisterNew(ref, Account{AccountName: ref}); err != nil {
+			t.Fatalf("expected success; got %v", err)
+		}
+	}
+	if err := uow.RegisterNew("c", Contact{LastName: "C", AccountID: salesforce.UnitOfWorkRef("a1")}); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	if err := uow.RegisterDirty("upd", Contact{FirstName: "F"}, salesforce.UnitOfWorkRef("c")); err != nil {
+		t.Fatalf("expected success; got %v", err)
+	}
+	res, err := uow.Commit(ctx)
+	var uerr *salesforce.UnitOfWorkError
+	if !errors.As(err, &uerr) || uerr.Ref != "upd" || uerr.RollbackErr != nil {
+		t.Fatalf("expected UnitOfWorkError at upd; got %v", err)
+	}
+	if res.IDs["c"] != "003C" || res.IDs["a24"] != "001a24" {
+		t.Errorf("unexpected ids %v", res.IDs)
+	}
+	if len(patched) != 1 || patched[0]["Id"] != "003C" {
+		t.Errorf("expected patch of 003C; got %v", patched)
+	}
+	if len(res.RolledBack) != 26 || !strings.HasPrefix(deleted, "003C,001a24,") {
+		t.Errorf("expected rollback of 26 records starting with contact; got %d %s", len(res.RolledBack), deleted)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypeError reports a parameter whose type cannot be used by a func
+type TypeError struct {
+	Expected string       // description of the expected type
+	Got      reflect.Type // nil when the parameter is nil
+	Missing  string       // interface method missing from Got
+	Hint     string
+}
+
+// Error includes the received type, missing method and hint
+func (e *TypeError) Error() string {
+	got := "nil"
+	if e.Got != nil {
+		got = e.Got.String()
+	}
+	msg := fmt.Sprintf("expected %s; got %s", e.Expected, got)
+	if e.Missing > "" {
+		msg += " (missing method " + e.Missing + ")"
+	}
+	if e.Hint > "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+// validatePtrTo returns the value pointed to by v after verifying that v is
+// a non-nil pointer to a value of kind k.
+func validatePtrTo(v interface{}, k reflect.Kind, expected string) (reflect.Value, error) {
+	ty := reflect.TypeOf(v)
+	te := &TypeError{Expected: expected, Got: ty}
+	switch {
+	case ty == nil:
+		te.Hint = "parameter may not be nil"
+	case ty.Kind() == k:
+		te.Hint = "did you pass a value instead of a pointer?"
+	case ty.Kind() != reflect.Ptr:
+		te.Hint = fmt.Sprintf("expected a pointer to a %s", k)
+	case ty.Elem().Kind() == reflect.Ptr && ty.Elem().Elem().Kind() == k:
+		te.Hint = "did you pass a pointer to a pointer?"
+	case ty.Elem().Kind() != k:
+		te.Hint = fmt.Sprintf("expected a pointer to a %s", k)
+	case reflect.ValueOf(v).IsNil():
+		te.Hint = "pointer is nil; pass the address of a variable"
+	default:
+		return reflect.ValueOf(v).Elem(), nil
+	}
+	return reflect.Value{}, te
+}
+
+// structType returns the struct type of a struct or pointer to struct
+func structType(ty reflect.Type) (reflect.Type, bool) {
+	if ty.Kind() == reflect.Ptr {
+		ty = ty.Elem()
+	}
+	return ty, ty.Kind() == reflect.Struct
+}
+
+// validateStructElem returns the struct type of a struct or pointer to
+// struct elem type.
+func validateStructElem(ty reflect.Type, elem reflect.Type, expected string) (reflect.Type, error) {
+	st, ok := structType(elem)
+	if !ok {
+		return nil, &TypeError{Expected: expected, Got: ty, Hint: fmt.Sprintf("%s is not a struct or pointer to a struct", elem)}
+	}
+	return st, nil
+}
+
+// missingSObjectMethod returns the first SObject method not implemented
+// by ty and a hint when *ty implements SObject.
+func missingSObjectMethod(ty reflect.Type) (missing, hint string) {
+	if ty.Implements(sobjectInterface) {
+		return "", ""
+	}
+	for i := 0; i < sobjectInterface.NumMethod(); i++ {
+		m := sobjectInterface.Method(i)
+		if _, ok := ty.MethodByName(m.Name); !ok {
+			missing = m.Name
+			break
+		}
+	}
+	if ty.Kind() != reflect.Ptr && ty.Kind() != reflect.Interface && reflect.PtrTo(ty).Implements(sobjectInterface) {
+		hint = fmt.Sprintf("methods of %s have pointer receivers; use *%s", ty, ty)
+	}
+	return missing, hint
+}
+
+// validateSObjectType returns a TypeError if ty does not implement SObject
+func validateSObjectType(ty reflect.Type, got reflect.Type, expected string) error {
+	missing, hint := missingSObjectMethod(ty)
+	if missing == "" && hint == "" {
+		return nil
+	}
+	if hint == "" {
+		hint = fmt.Sprintf("%s is not an SObject", ty)
+	}
+	return &TypeError{Expected: expected, Got: got, Missing: missing, Hint: hint}
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 James Cote
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package salesforce_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jfcote87/salesforce"
+)
+
+// ptrSObject implements SObject with pointer receivers
+type ptrSObject struct{}
+
+func (p *ptrSObject) SObjectName() string                    { return "Ptr" }
+func (p *ptrSObject) WithAttr(ref string) salesforce.SObject { return p }
+
+// nameOnly is missing the WithAttr method
+type nameOnly struct{}
+
+func (n nameOnly) SObjectName() string { return "NameOnly" }
+
+func TestTypeErrors(t *testing.T) {
+	var contacts []Contact
+	var contactsPtr = &contacts
+	var nilContacts *[]Contact
+	var acct Account
+	tests := []struct {
+		name string
+		f    func() error
+		want string
+	}{
+		{"slice value", func() error { _, err := salesforce.NewRecordSlice(contacts); return err },
+			"expected *[]<struct> or *[]*<struct>; got []salesforce_test.Contact; did you pass a value instead of a pointer?"},
+		{"ptr to ptr", func() error { _, err := salesforce.NewRecordSlice(&contactsPtr); return err },
+			"expected *[]<struct> or *[]*<struct>; got **[]salesforce_test.Contact; did you pass a pointer to a pointer?"},
+		{"nil ptr", func() error { _, err := salesforce.NewRecordSlice(nilContacts); return err },
+			"expected *[]<struct> or *[]*<struct>; got *[]salesforce_test.Contact; pointer is nil; pass the address of a variable"},
+		{"string elems", func() error { _, err := salesforce.NewRecordSlice(&[]string{}); return err },
+			"expected *[]<struct> or *[]*<struct>; got *[]string; string is not a struct, pointer to a struct or map"},
+		{"slice value", func() error {
+			_, err := salesforce.DecodeJobResults(strings.NewReader(""), nil, []Contact{})
+			return err
+		},
+			"expected *[]<struct>; got []salesforce_test.Contact; did you pass a value instead of a pointer?"},
+		{"slice elem", func() error {
+			_, err := salesforce.DecodeJobResults(strings.NewReader(""), nil, &[]string{})
+			return err
+		}, "expected *[]<struct>; got *[]string; string is not a struct or pointer to a struct"},
+		{"event log", func() error { return salesforce.DecodeEventLog(strings.NewReader(""), nil) },
+			"expected *[]<struct>; got nil; parameter may not be nil"},
+		{"relationship", func() error { return salesforce.DecodeRelationship(nil, acct) },
+			"expected result to be a non-nil pointer; got salesforce_test.Account; did you pass a value instead of a pointer?"},
+	}
+	for _, tt := range tests {
+		err := tt.f()
+		var te *salesforce.TypeError
+		if !errors.As(err, &te) || err.Error() != tt.want {
+			t.Errorf("%s: expected TypeError %s; got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestTypeErrors_SObject(t *testing.T) {
+	sv := salesforce.New("aninstance.my.salesforce", "", nil)
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		result interface{}
+		want   string
+	}{
+		{"value", Account{}, "expected pointer to an SObject; got salesforce_test.Account; did you pass a value instead of a pointer?"},
+		{"nil", nil, "expected pointer to an SObject; got nil; did you pass a value instead of a pointer?"},
+		{"missing method", &nameOnly{}, "expected pointer to an SObject; got *salesforce_test.nameOnly (missing method WithAttr); salesforce_test.nameOnly is not an SObject"},
+		{"pointer receivers", &[]ptrSObject{}, "expected *[]<SObject>; got *[]salesforce_test.ptrSObject (missing method SObjectName); methods of salesforce_test.ptrSObject have pointer receivers; use *salesforce_test.ptrSObject"},
+	}
+	for _, tt := range tests {
+		var err error
+		if tt.name == "pointer receivers" {
+			err = sv.RetrieveRecords(ctx, tt.result, []string{"1"}, "Id")
+		} else {
+			err = sv.Get(ctx, tt.result, "1", "Id")
+		}
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: expected %s; got %v", tt.name, tt.want, err)
+		}
+	}
+	var sobj salesforce.SObject
+	if err := sv.Get(ctx, &sobj, "1", "Id"); err == nil || !strings.HasSuffix(err.Error(), "interface value is nil") {
+		t.Errorf("expected interface value is nil; got %v", err)
+	}
+}